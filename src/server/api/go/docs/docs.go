@@ -1064,7 +1064,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get messages from session. Default format is openai. Can convert to acontext (original) or anthropic format.",
+                "description": "Get messages from session. Default format is openai. Can convert to acontext (original) or anthropic format; for anthropic, system messages are returned in a top-level system field.",
                 "consumes": [
                     "application/json"
                 ],
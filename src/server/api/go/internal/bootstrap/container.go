@@ -191,7 +191,11 @@ func BuildContainer() *do.Injector {
 		), nil
 	})
 	do.Provide(inj, func(i *do.Injector) (service.BlockService, error) {
-		return service.NewBlockService(do.MustInvoke[repo.BlockRepo](i)), nil
+		return service.NewBlockService(
+			do.MustInvoke[repo.BlockRepo](i),
+			service.WithPropsSchema(service.NewSpacePropsSchemas(do.MustInvoke[repo.SpaceRepo](i))),
+			service.WithSpaceProjects(service.NewSpaceProjects(do.MustInvoke[repo.SpaceRepo](i))),
+		), nil
 	})
 	do.Provide(inj, func(i *do.Injector) (service.DiskService, error) {
 		return service.NewDiskService(do.MustInvoke[repo.DiskRepo](i)), nil
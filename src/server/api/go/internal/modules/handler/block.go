@@ -10,6 +10,7 @@ import (
 	"github.com/memodb-io/Acontext/internal/modules/model"
 	"github.com/memodb-io/Acontext/internal/modules/serializer"
 	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/memodb-io/Acontext/internal/pkg/propschema"
 	"github.com/memodb-io/Acontext/internal/pkg/utils/path"
 	"gorm.io/datatypes"
 )
@@ -94,11 +95,21 @@ func (h *BlockHandler) CreateBlock(c *gin.Context) {
 	// 3. If parent_id is provided, validate parent-child relationship
 	if req.ParentID != nil {
 		parent, err := h.svc.GetBlockProperties(c.Request.Context(), *req.ParentID)
+		if errors.Is(err, service.ErrForbidden) {
+			c.JSON(http.StatusForbidden, serializer.Err(http.StatusForbidden, "forbidden", err))
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusBadRequest, serializer.ParamErr("parent_id", errors.New("parent block not found")))
 			return
 		}
 
+		// Core does not check locks, so a locked parent is refused here
+		if parent.IsLocked {
+			c.JSON(http.StatusLocked, serializer.Err(http.StatusLocked, "parent block is locked", service.ErrLocked))
+			return
+		}
+
 		// Check if parent can have children
 		if !parent.CanHaveChildren() {
 			c.JSON(http.StatusBadRequest, serializer.ParamErr("parent_id", errors.New("parent cannot have children")))
@@ -112,6 +123,20 @@ func (h *BlockHandler) CreateBlock(c *gin.Context) {
 		}
 	}
 
+	// 4. Core does not know the space's props schema, so props are checked here
+	if err := h.svc.ValidateProps(c.Request.Context(), spaceID, req.Type, req.Props); err != nil {
+		var invalid *propschema.ValidationError
+		switch {
+		case errors.As(err, &invalid):
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("props", err))
+		case errors.Is(err, service.ErrForbidden):
+			c.JSON(http.StatusForbidden, serializer.Err(http.StatusForbidden, "forbidden", err))
+		default:
+			c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		}
+		return
+	}
+
 	// Prepare request for Core service
 	coreReq := httpclient.InsertBlockRequest{
 		ParentID: req.ParentID,
@@ -236,7 +261,17 @@ func (h *BlockHandler) UpdateBlockProperties(c *gin.Context) {
 		Props: datatypes.NewJSONType(req.Props),
 	}
 	if err := h.svc.UpdateBlockProperties(c.Request.Context(), &b); err != nil {
-		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		var invalid *propschema.ValidationError
+		switch {
+		case errors.As(err, &invalid):
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("props", err))
+		case errors.Is(err, service.ErrLocked):
+			c.JSON(http.StatusLocked, serializer.Err(http.StatusLocked, "block is locked", err))
+		case errors.Is(err, service.ErrForbidden):
+			c.JSON(http.StatusForbidden, serializer.Err(http.StatusForbidden, "forbidden", err))
+		default:
+			c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		}
 		return
 	}
 
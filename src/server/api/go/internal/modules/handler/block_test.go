@@ -7,12 +7,15 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/bytedance/sonic"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/memodb-io/Acontext/internal/infra/httpclient"
 	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/memodb-io/Acontext/internal/pkg/propschema"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -47,6 +50,11 @@ func (m *MockBlockService) UpdateBlockProperties(ctx context.Context, b *model.B
 	return args.Error(0)
 }
 
+func (m *MockBlockService) ValidateProps(ctx context.Context, spaceID uuid.UUID, blockType string, props map[string]any) error {
+	args := m.Called(ctx, spaceID, blockType, props)
+	return args.Error(0)
+}
+
 func (m *MockBlockService) List(ctx context.Context, spaceID uuid.UUID, blockType string, parentID *uuid.UUID) ([]model.Block, error) {
 	args := m.Called(ctx, spaceID, blockType, parentID)
 	if args.Get(0) == nil {
@@ -65,6 +73,221 @@ func (m *MockBlockService) UpdateSort(ctx context.Context, blockID uuid.UUID, so
 	return args.Error(0)
 }
 
+func (m *MockBlockService) InstantiateTemplate(ctx context.Context, templateSpaceID uuid.UUID, newSpaceID uuid.UUID, vars map[string]string) error {
+	args := m.Called(ctx, templateSpaceID, newSpaceID, vars)
+	return args.Error(0)
+}
+
+func (m *MockBlockService) GetBacklinks(ctx context.Context, blockID uuid.UUID) ([]model.Block, error) {
+	args := m.Called(ctx, blockID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Block), args.Error(1)
+}
+
+func (m *MockBlockService) ListChildrenLite(ctx context.Context, parentID uuid.UUID) ([]model.BlockLite, error) {
+	args := m.Called(ctx, parentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.BlockLite), args.Error(1)
+}
+
+func (m *MockBlockService) UpdatePage(ctx context.Context, spaceID uuid.UUID, pageID uuid.UUID, title *string, newParentID *uuid.UUID, targetSort *int64) error {
+	args := m.Called(ctx, spaceID, pageID, title, newParentID, targetSort)
+	return args.Error(0)
+}
+
+func (m *MockBlockService) SerializeBlocks(ctx context.Context, ids []uuid.UUID) ([]byte, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+func (m *MockBlockService) PasteBlocks(ctx context.Context, spaceID uuid.UUID, parentID uuid.UUID, data []byte) ([]model.Block, error) {
+	args := m.Called(ctx, spaceID, parentID, data)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Block), args.Error(1)
+}
+
+func (m *MockBlockService) BlocksExist(ctx context.Context, spaceID uuid.UUID, ids []uuid.UUID) ([]uuid.UUID, error) {
+	args := m.Called(ctx, spaceID, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+func (m *MockBlockService) GetSubtreeTextStats(ctx context.Context, pageID uuid.UUID) (int, int, int, error) {
+	args := m.Called(ctx, pageID)
+	return args.Int(0), args.Int(1), args.Int(2), args.Error(3)
+}
+
+func (m *MockBlockService) GetPageTree(ctx context.Context, pageID uuid.UUID, maxDepth int) (*model.Block, error) {
+	args := m.Called(ctx, pageID, maxDepth)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Block), args.Error(1)
+}
+
+func (m *MockBlockService) MovePageToSpace(ctx context.Context, pageID uuid.UUID, targetSpaceID uuid.UUID, newParentID *uuid.UUID) error {
+	args := m.Called(ctx, pageID, targetSpaceID, newParentID)
+	return args.Error(0)
+}
+
+func (m *MockBlockService) MovePageToTop(ctx context.Context, spaceID uuid.UUID, pageID uuid.UUID) error {
+	args := m.Called(ctx, spaceID, pageID)
+	return args.Error(0)
+}
+
+func (m *MockBlockService) ListChildrenByTypes(ctx context.Context, parentID uuid.UUID, types []string) ([]model.Block, error) {
+	args := m.Called(ctx, parentID, types)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Block), args.Error(1)
+}
+
+func (m *MockBlockService) DuplicateBlock(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID) (*model.Block, error) {
+	args := m.Called(ctx, spaceID, blockID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Block), args.Error(1)
+}
+
+func (m *MockBlockService) LockBlock(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, cascade bool) error {
+	args := m.Called(ctx, spaceID, blockID, cascade)
+	return args.Error(0)
+}
+
+func (m *MockBlockService) UnlockBlock(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, cascade bool) error {
+	args := m.Called(ctx, spaceID, blockID, cascade)
+	return args.Error(0)
+}
+
+func (m *MockBlockService) GetAncestors(ctx context.Context, blockID uuid.UUID) ([]model.Block, error) {
+	args := m.Called(ctx, blockID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Block), args.Error(1)
+}
+
+func (m *MockBlockService) GetAncestorsBatch(ctx context.Context, blockIDs []uuid.UUID) (map[uuid.UUID][]model.Block, error) {
+	args := m.Called(ctx, blockIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[uuid.UUID][]model.Block), args.Error(1)
+}
+
+func (m *MockBlockService) ExportPageHTML(ctx context.Context, pageID uuid.UUID) (string, error) {
+	args := m.Called(ctx, pageID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockBlockService) ExportPageMarkdown(ctx context.Context, pageID uuid.UUID) (string, error) {
+	args := m.Called(ctx, pageID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockBlockService) ImportMarkdown(ctx context.Context, spaceID uuid.UUID, parentID *uuid.UUID, md string) (*model.Block, error) {
+	args := m.Called(ctx, spaceID, parentID, md)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Block), args.Error(1)
+}
+
+func (m *MockBlockService) UpdatePropsWhere(ctx context.Context, spaceID uuid.UUID, parentID uuid.UUID, match map[string]any, patch map[string]any) (int, error) {
+	args := m.Called(ctx, spaceID, parentID, match, patch)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockBlockService) RestoreBlock(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, restoreAncestors bool) error {
+	args := m.Called(ctx, spaceID, blockID, restoreAncestors)
+	return args.Error(0)
+}
+
+func (m *MockBlockService) ReorderChildren(ctx context.Context, spaceID uuid.UUID, parentID uuid.UUID, orderedIDs []uuid.UUID) error {
+	args := m.Called(ctx, spaceID, parentID, orderedIDs)
+	return args.Error(0)
+}
+
+func (m *MockBlockService) DiffTree(ctx context.Context, pageID uuid.UUID, since time.Time) (service.TreeDiff, error) {
+	args := m.Called(ctx, pageID, since)
+	return args.Get(0).(service.TreeDiff), args.Error(1)
+}
+
+func (m *MockBlockService) ArchiveBlock(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID) error {
+	args := m.Called(ctx, spaceID, blockID)
+	return args.Error(0)
+}
+
+func (m *MockBlockService) SearchBlocks(ctx context.Context, spaceID uuid.UUID, query string, opts service.SearchOptions) ([]model.Block, error) {
+	args := m.Called(ctx, spaceID, query, opts)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Block), args.Error(1)
+}
+
+func (m *MockBlockService) ConvertBlockType(ctx context.Context, spaceID uuid.UUID, id uuid.UUID, newType string) error {
+	args := m.Called(ctx, spaceID, id, newType)
+	return args.Error(0)
+}
+
+func (m *MockBlockService) ArchiveWhere(ctx context.Context, spaceID uuid.UUID, match map[string]any) (int, error) {
+	args := m.Called(ctx, spaceID, match)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockBlockService) CreateAt(ctx context.Context, b *model.Block, pos service.InsertPosition) error {
+	args := m.Called(ctx, b, pos)
+	return args.Error(0)
+}
+
+func (m *MockBlockService) DeletePageRecursive(ctx context.Context, spaceID uuid.UUID, pageID uuid.UUID) (int, error) {
+	args := m.Called(ctx, spaceID, pageID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockBlockService) PurgeDeleted(ctx context.Context, spaceID uuid.UUID, olderThan time.Time) (int, error) {
+	args := m.Called(ctx, spaceID, olderThan)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockBlockService) DuplicatePage(ctx context.Context, spaceID uuid.UUID, pageID uuid.UUID, newParentID *uuid.UUID) (*model.Block, error) {
+	args := m.Called(ctx, spaceID, pageID, newParentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Block), args.Error(1)
+}
+
+func (m *MockBlockService) ListChildrenPaged(ctx context.Context, parentID uuid.UUID, limit int, cursor string) ([]model.Block, string, error) {
+	args := m.Called(ctx, parentID, limit, cursor)
+	if args.Get(0) == nil {
+		return nil, args.String(1), args.Error(2)
+	}
+	return args.Get(0).([]model.Block), args.String(1), args.Error(2)
+}
+
+func (m *MockBlockService) ListArchivedPages(ctx context.Context, spaceID uuid.UUID) ([]model.Block, error) {
+	args := m.Called(ctx, spaceID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Block), args.Error(1)
+}
+
 func setupRouter() *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	return gin.New()
@@ -120,6 +343,22 @@ func TestBlockHandler_CreateBlock_Page(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 			expectedError:  true,
 		},
+		{
+			name:         "props do not match the space schema",
+			spaceIDParam: spaceID.String(),
+			requestBody: CreateBlockReq{
+				Type:  model.BlockTypePage,
+				Title: "Test Page",
+				Props: map[string]any{"priority": "high"},
+			},
+			setup: func(svc *MockBlockService) {
+				svc.On("ValidateProps", mock.Anything, spaceID, model.BlockTypePage, map[string]any{"priority": "high"}).Return(&propschema.ValidationError{
+					Violations: []propschema.Violation{{Path: "priority", Message: "expected integer, but got string"}},
+				})
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  true,
+		},
 		{
 			name:         "title contains path separator",
 			spaceIDParam: spaceID.String(),
@@ -316,6 +555,21 @@ func TestBlockHandler_CreateBlock_Text(t *testing.T) {
 			expectedStatus: http.StatusInternalServerError,
 			skip:           true, // Requires Core service integration
 		},
+		{
+			name:         "locked parent",
+			spaceIDParam: spaceID.String(),
+			requestBody: CreateBlockReq{
+				ParentID: &parentID,
+				Type:     "text",
+				Title:    "test block",
+			},
+			setup: func(svc *MockBlockService) {
+				svc.On("GetBlockProperties", mock.Anything, parentID).Return(&model.Block{
+					ID: parentID, SpaceID: spaceID, Type: model.BlockTypePage, IsLocked: true,
+				}, nil)
+			},
+			expectedStatus: http.StatusLocked,
+		},
 	}
 
 	for _, tt := range tests {
@@ -670,6 +924,37 @@ func TestBlockHandler_UpdateBlockProperties(t *testing.T) {
 			},
 			expectedStatus: http.StatusInternalServerError,
 		},
+		{
+			name:         "props do not match the space schema",
+			blockIDParam: blockID.String(),
+			requestBody: UpdateBlockPropertiesReq{
+				Props: map[string]any{"priority": "high"},
+			},
+			setup: func(svc *MockBlockService) {
+				svc.On("UpdateBlockProperties", mock.Anything, mock.Anything).Return(&propschema.ValidationError{
+					Violations: []propschema.Violation{{Path: "priority", Message: "expected integer, but got string"}},
+				})
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "locked block",
+			blockIDParam: blockID.String(),
+			requestBody:  UpdateBlockPropertiesReq{Title: "Updated Title"},
+			setup: func(svc *MockBlockService) {
+				svc.On("UpdateBlockProperties", mock.Anything, mock.Anything).Return(service.ErrLocked)
+			},
+			expectedStatus: http.StatusLocked,
+		},
+		{
+			name:         "forbidden",
+			blockIDParam: blockID.String(),
+			requestBody:  UpdateBlockPropertiesReq{Title: "Updated Title"},
+			setup: func(svc *MockBlockService) {
+				svc.On("UpdateBlockProperties", mock.Anything, mock.Anything).Return(service.ErrForbidden)
+			},
+			expectedStatus: http.StatusForbidden,
+		},
 	}
 
 	for _, tt := range tests {
@@ -469,18 +469,19 @@ func (h *SessionHandler) SendMessage(c *gin.Context) {
 }
 
 type GetMessagesReq struct {
-	Limit              *int   `form:"limit" json:"limit" binding:"omitempty,min=0,max=200" example:"20"`
-	Cursor             string `form:"cursor" json:"cursor" example:"cHJvdGVjdGVkIHZlcnNpb24gdG8gYmUgZXhjbHVkZWQgaW4gcGFyc2luZyB0aGUgY3Vyc29y"`
-	WithAssetPublicURL bool   `form:"with_asset_public_url,default=true" json:"with_asset_public_url" example:"true"`
-	Format             string `form:"format,default=openai" json:"format" binding:"omitempty,oneof=acontext openai anthropic" example:"openai" enums:"acontext,openai,anthropic"`
-	TimeDesc           bool   `form:"time_desc,default=false" json:"time_desc" example:"false"`
-	EditStrategies     string `form:"edit_strategies" json:"edit_strategies" example:"[{\"type\":\"remove_tool_result\",\"params\":{\"keep_recent_n_tool_results\":3}}]"`
+	Limit                *int   `form:"limit" json:"limit" binding:"omitempty,min=0,max=200" example:"20"`
+	Cursor               string `form:"cursor" json:"cursor" example:"cHJvdGVjdGVkIHZlcnNpb24gdG8gYmUgZXhjbHVkZWQgaW4gcGFyc2luZyB0aGUgY3Vyc29y"`
+	WithAssetPublicURL   bool   `form:"with_asset_public_url,default=true" json:"with_asset_public_url" example:"true"`
+	Format               string `form:"format,default=openai" json:"format" binding:"omitempty,oneof=acontext openai anthropic" example:"openai" enums:"acontext,openai,anthropic"`
+	TimeDesc             bool   `form:"time_desc,default=false" json:"time_desc" example:"false"`
+	EditStrategies       string `form:"edit_strategies" json:"edit_strategies" example:"[{\"type\":\"remove_tool_result\",\"params\":{\"keep_recent_n_tool_results\":3}}]"`
+	IncludeTokenEstimate bool   `form:"include_token_estimate,default=false" json:"include_token_estimate" example:"false"`
 }
 
 // GetMessages godoc
 //
 //	@Summary		Get messages from session
-//	@Description	Get messages from session. Default format is openai. Can convert to acontext (original) or anthropic format.
+//	@Description	Get messages from session. Default format is openai. Can convert to acontext (original) or anthropic format; for anthropic, system messages are returned in a top-level system field.
 //	@Tags			session
 //	@Accept			json
 //	@Produce		json
@@ -491,6 +492,7 @@ type GetMessagesReq struct {
 //	@Param			format					query	string	false	"Format to convert messages to: acontext (original), openai (default), anthropic."	enums(acontext,openai,anthropic)
 //	@Param			time_desc				query	string	false	"Order by created_at descending if true, ascending if false (default false)"		example(false)
 //	@Param			edit_strategies			query	string	false	"JSON array of edit strategies to apply before format conversion"					example([{"type":"remove_tool_result","params":{"keep_recent_n_tool_results":3}}])
+//	@Param			include_token_estimate	query	string	false	"Add a rough token_estimate of the converted messages (default false)"				example(false)
 //	@Security		BearerAuth
 //	@Success		200	{object}	serializer.Response{data=service.GetMessagesOutput}
 //	@Router			/session/{session_id}/messages [get]
@@ -549,12 +551,19 @@ func (h *SessionHandler) GetMessages(c *gin.Context) {
 		return
 	}
 
+	var tokenEstimate *converter.TokenEstimateOptions
+	if req.IncludeTokenEstimate {
+		tokenEstimate = &converter.TokenEstimateOptions{}
+	}
+
 	convertedOut, err := converter.GetConvertedMessagesOutput(
+		c.Request.Context(),
 		out.Items,
 		format,
 		out.PublicURLs,
 		out.NextCursor,
 		out.HasMore,
+		tokenEstimate,
 	)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, serializer.DBErr("failed to convert messages", err))
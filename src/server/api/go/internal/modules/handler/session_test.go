@@ -2040,6 +2040,27 @@ func TestSessionHandler_GetMessages(t *testing.T) {
 			},
 			expectedStatus: http.StatusOK,
 		},
+		{
+			name:           "with token estimate",
+			sessionIDParam: sessionID.String(),
+			queryParams:    "?limit=20&include_token_estimate=true",
+			setup: func(svc *MockSessionService) {
+				expectedOutput := &service.GetMessagesOutput{
+					Items: []model.Message{
+						{
+							ID:        uuid.New(),
+							SessionID: sessionID,
+							Role:      "user",
+						},
+					},
+					HasMore: false,
+				}
+				svc.On("GetMessages", mock.Anything, mock.MatchedBy(func(in service.GetMessagesInput) bool {
+					return in.SessionID == sessionID && in.Limit == 20
+				})).Return(expectedOutput, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
 		{
 			name:           "pagination with cursor",
 			sessionIDParam: sessionID.String(),
@@ -56,6 +56,28 @@ type Asset struct {
 	SHA256 string `json:"sha256"`
 	MIME   string `json:"mime"`
 	SizeB  int64  `json:"size_b"`
+
+	// Width and Height are the pixel dimensions of an image, zero when unknown
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+
+	// Variants are smaller renditions of an image stored alongside it, keyed
+	// by name (e.g. "thumbnail", "medium")
+	Variants map[string]AssetVariant `json:"variants,omitempty"`
+
+	// Data holds content extracted in memory that has not been uploaded yet.
+	// It is never serialized.
+	Data []byte `json:"-"`
+}
+
+// AssetVariant is a stored rendition of an asset
+type AssetVariant struct {
+	S3Key  string `json:"s3_key"`
+	SHA256 string `json:"sha256"`
+	MIME   string `json:"mime,omitempty"`
+	SizeB  int64  `json:"size_b,omitempty"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
 }
 
 // IsOrphaned returns true if this asset has no references
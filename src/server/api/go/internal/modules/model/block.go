@@ -6,6 +6,7 @@ import (
 
 	"github.com/google/uuid"
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 // BlockTypeConfig Define the configuration of block types
@@ -23,6 +24,9 @@ const (
 	BlockTypeSOP    = "sop"
 )
 
+// BlockPropLinks is the props key holding the IDs of blocks this block links to
+const BlockPropLinks = "links"
+
 // BlockType Define all supported block types
 var BlockTypes = map[string]BlockTypeConfig{
 	BlockTypeFolder: {
@@ -67,10 +71,13 @@ func GetAllBlockTypes() map[string]BlockTypeConfig {
 	return BlockTypes
 }
 
+// InitialSort is the sort of the first block in an empty (space_id, parent_id) group
+const InitialSort int64 = 0
+
 type Block struct {
 	ID uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
 
-	SpaceID uuid.UUID `gorm:"type:uuid;not null;index:idx_blocks_space;index:idx_blocks_space_type_archived,priority:1;uniqueIndex:ux_blocks_space_parent_sort,priority:1" json:"space_id"`
+	SpaceID uuid.UUID `gorm:"type:uuid;not null;index:idx_blocks_space;index:idx_blocks_space_type_archived,priority:1;uniqueIndex:ux_blocks_space_parent_sort,priority:1,where:is_archived = false AND deleted_at IS NULL" json:"space_id"`
 	Space   *Space    `gorm:"constraint:fk_blocks_space,OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
 
 	Type string `gorm:"type:text;not null;index:idx_blocks_space_type;index:idx_blocks_space_type_archived,priority:2" json:"type"`
@@ -79,19 +86,40 @@ type Block struct {
 	Parent   *Block     `gorm:"constraint:fk_blocks_parent,OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
 
 	Title string                             `gorm:"type:text;not null;default:''" json:"title"`
-	Props datatypes.JSONType[map[string]any] `gorm:"type:jsonb;not null;default:'{}'" swaggertype:"object" json:"props"`
-
-	Sort       int64 `gorm:"not null;default:0;uniqueIndex:ux_blocks_space_parent_sort,priority:3" json:"sort"`
-	IsArchived bool  `gorm:"not null;default:false;index:idx_blocks_space_type_archived,priority:3;index" json:"is_archived"`
+	Props datatypes.JSONType[map[string]any] `gorm:"type:jsonb;not null;default:'{}';index:idx_blocks_props,type:gin,class:jsonb_path_ops" swaggertype:"object" json:"props"`
+
+	Sort int64 `gorm:"not null;default:0;uniqueIndex:ux_blocks_space_parent_sort,priority:3" json:"sort"`
+	// IsArchived blocks hold no position: archiving closes the gap in their group and
+	// restoring reinserts them at their former sort
+	IsArchived bool `gorm:"not null;default:false;index:idx_blocks_space_type_archived,priority:3;index;index:idx_blocks_archived_updated,priority:1" json:"is_archived"`
+	// ArchivedWith is the block whose archiving archived this one along with it, nil for a
+	// block archived on its own. Restoring that block brings this one back too.
+	ArchivedWith *uuid.UUID `gorm:"type:uuid" json:"-"`
+	// IsLocked makes the block read-only: it cannot be edited or moved, and no child can be added, moved or removed under it
+	IsLocked bool `gorm:"not null;default:false" json:"is_locked"`
+	// MovedAt is when the block last changed parent, nil if it never did
+	MovedAt *time.Time `json:"moved_at,omitempty"`
+	// DeletedAt marks a soft-deleted block. Deleted blocks are hidden from every read
+	// and keep no position; PurgeDeleted removes them for good.
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
 	Children  []*Block  `gorm:"foreignKey:ParentID;constraint:fk_blocks_children,OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
 	ToolSOPs  []ToolSOP `gorm:"foreignKey:SOPBlockID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE;" json:"-"`
 	CreatedAt time.Time `gorm:"autoCreateTime;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
-	UpdatedAt time.Time `gorm:"autoUpdateTime;not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime;not null;default:CURRENT_TIMESTAMP;index:idx_blocks_archived_updated,priority:2" json:"updated_at"`
 }
 
 func (Block) TableName() string { return "blocks" }
 
+// BlockLite is the projection of a Block used for tree rendering; it carries
+// no Props so large property blobs are never loaded
+type BlockLite struct {
+	ID       uuid.UUID  `json:"id"`
+	Title    string     `json:"title"`
+	Sort     int64      `json:"sort"`
+	ParentID *uuid.UUID `json:"parent_id"`
+}
+
 // Validate Validate the fields of a Block
 func (b *Block) Validate() error {
 	// Check if the type is valid
@@ -188,3 +216,30 @@ func (b *Block) SetFolderPath(path string) {
 	propsData["path"] = path
 	b.Props = datatypes.NewJSONType(propsData)
 }
+
+// GetLinks Get the IDs of the blocks referenced from Props["links"], skipping invalid entries
+func (b *Block) GetLinks() []uuid.UUID {
+	propsData := b.Props.Data()
+	if propsData == nil {
+		return nil
+	}
+	var raw []string
+	switch v := propsData[BlockPropLinks].(type) {
+	case []string:
+		raw = v
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				raw = append(raw, s)
+			}
+		}
+	}
+
+	links := make([]uuid.UUID, 0, len(raw))
+	for _, s := range raw {
+		if id, err := uuid.Parse(s); err == nil {
+			links = append(links, id)
+		}
+	}
+	return links
+}
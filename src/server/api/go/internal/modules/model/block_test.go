@@ -691,3 +691,17 @@ func TestBlock_SetFolderPath(t *testing.T) {
 		})
 	}
 }
+
+func TestBlock_GetLinks(t *testing.T) {
+	linked := uuid.New()
+	block := &Block{
+		Type: BlockTypeText,
+		Props: datatypes.NewJSONType(map[string]any{
+			BlockPropLinks: []any{linked.String(), "not-a-uuid", 42},
+		}),
+	}
+	assert.Equal(t, []uuid.UUID{linked}, block.GetLinks())
+
+	empty := &Block{Type: BlockTypeText}
+	assert.Empty(t, empty.GetLinks())
+}
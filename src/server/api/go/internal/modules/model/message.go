@@ -14,6 +14,23 @@ const (
 	FormatAcontext  MessageFormat = "acontext"
 	FormatOpenAI    MessageFormat = "openai"
 	FormatAnthropic MessageFormat = "anthropic"
+	// FormatCompletion renders the conversation into a single prompt string
+	// for text-completion (non-chat) models. Output only.
+	FormatCompletion MessageFormat = "completion"
+	// FormatAzureOpenAI produces OpenAI-shaped messages for Azure OpenAI, whose
+	// request body additionally accepts data_sources. Output only.
+	FormatAzureOpenAI MessageFormat = "azure_openai"
+	// FormatPlainText renders the conversation as a readable "Role: content"
+	// transcript, for logs and embeddings. Output only.
+	FormatPlainText MessageFormat = "plain_text"
+	// FormatMemory condenses each message into a short record for a memory index
+	FormatMemory MessageFormat = "memory"
+	// FormatGemini produces Gemini (Vertex AI) contents with a separate
+	// systemInstruction. Output only.
+	FormatGemini MessageFormat = "gemini"
+	// FormatCohere produces Cohere chat input: the final user turn as message
+	// and the turns before it as chat_history. Output only.
+	FormatCohere MessageFormat = "cohere"
 )
 
 type Message struct {
@@ -47,7 +64,7 @@ type Message struct {
 func (Message) TableName() string { return "messages" }
 
 type Part struct {
-	// "text" | "image" | "audio" | "video" | "file" | "tool-call" | "tool-result" | "data"
+	// "text" | "image" | "audio" | "video" | "file" | "tool-call" | "tool-result" | "data" | "refusal" | "reasoning" | "output-audio"
 	Type string `json:"type"`
 
 	// text part
@@ -11,6 +11,8 @@ type Space struct {
 	ID        uuid.UUID         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
 	ProjectID uuid.UUID         `gorm:"type:uuid;not null;index" json:"project_id"`
 	Configs   datatypes.JSONMap `gorm:"type:jsonb" swaggertype:"object" json:"configs"`
+	// PropsSchema is an optional JSON Schema the props of the space's blocks must match
+	PropsSchema datatypes.JSONMap `gorm:"type:jsonb" swaggertype:"object" json:"props_schema,omitempty"`
 
 	CreatedAt time.Time `gorm:"autoCreateTime;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt time.Time `gorm:"autoUpdateTime;not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
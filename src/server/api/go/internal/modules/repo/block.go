@@ -2,7 +2,12 @@ package repo
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"math"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/memodb-io/Acontext/internal/modules/model"
@@ -13,7 +18,10 @@ import (
 
 type BlockRepo interface {
 	Create(ctx context.Context, b *model.Block) error
+	CreateAppend(ctx context.Context, b *model.Block) error
 	Delete(ctx context.Context, spaceID uuid.UUID, id uuid.UUID) error
+	DeleteTree(ctx context.Context, spaceID uuid.UUID, id uuid.UUID) (int, error)
+	PurgeDeleted(ctx context.Context, spaceID uuid.UUID, olderThan time.Time) (int, error)
 	Get(ctx context.Context, id uuid.UUID) (*model.Block, error)
 	Update(ctx context.Context, b *model.Block) error
 	ListBySpace(ctx context.Context, spaceID uuid.UUID, blockType string, parentID *uuid.UUID) ([]model.Block, error)
@@ -21,6 +29,58 @@ type BlockRepo interface {
 	MoveToParentAppend(ctx context.Context, id uuid.UUID, newParentID *uuid.UUID) error
 	ReorderWithinGroup(ctx context.Context, id uuid.UUID, newSort int64) error
 	MoveToParentAtSort(ctx context.Context, id uuid.UUID, newParentID *uuid.UUID, targetSort int64) error
+	MoveToSpace(ctx context.Context, id uuid.UUID, spaceID uuid.UUID, newParentID *uuid.UUID) error
+	UpdatePage(ctx context.Context, id uuid.UUID, title *string, newParentID *uuid.UUID, targetSort *int64) error
+	ListAllBySpace(ctx context.Context, spaceID uuid.UUID) ([]model.Block, error)
+	CreateBatch(ctx context.Context, blocks []*model.Block) error
+	ListBacklinks(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID) ([]model.Block, error)
+	ListPurgeCandidates(ctx context.Context, olderThan time.Time, limit int) ([]model.Block, error)
+	ListArchived(ctx context.Context, spaceID uuid.UUID, blockType string) ([]model.Block, error)
+	ListChildrenLite(ctx context.Context, parentID uuid.UUID) ([]model.BlockLite, error)
+	CountChildren(ctx context.Context, parentID uuid.UUID) (int64, error)
+	ListChildrenByTypes(ctx context.Context, parentID uuid.UUID, types []string) ([]model.Block, error)
+	ListChildrenAfter(ctx context.Context, parentID uuid.UUID, afterSort int64, afterID uuid.UUID, limit int) ([]model.Block, error)
+	DuplicateBlock(ctx context.Context, id uuid.UUID) (*model.Block, error)
+	DuplicateTo(ctx context.Context, id uuid.UUID, newParentID *uuid.UUID, title string) (*model.Block, error)
+	SetLocked(ctx context.Context, id uuid.UUID, locked bool, cascade bool) error
+	ListAncestorsBatch(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID][]model.Block, error)
+	ListExistingIDs(ctx context.Context, spaceID uuid.UUID, ids []uuid.UUID) ([]uuid.UUID, error)
+	SubtreeTextStats(ctx context.Context, rootID uuid.UUID, includeArchived bool) (chars int, words int, blocks int, err error)
+	UpdatePropsWhere(ctx context.Context, spaceID uuid.UUID, parentID uuid.UUID, match map[string]any, patch map[string]any) (int, error)
+	Archive(ctx context.Context, id uuid.UUID) error
+	Restore(ctx context.Context, ids []uuid.UUID) error
+	ReorderChildren(ctx context.Context, spaceID uuid.UUID, parentID uuid.UUID, orderedIDs []uuid.UUID) error
+	ListSubtreeChangedSince(ctx context.Context, rootID uuid.UUID, since time.Time) ([]model.Block, error)
+	ListSubtree(ctx context.Context, rootID uuid.UUID, maxDepth int) ([]model.Block, error)
+	SearchBlocks(ctx context.Context, spaceID uuid.UUID, query string, opts SearchOptions) ([]model.Block, error)
+	ConvertType(ctx context.Context, id uuid.UUID, newType string, newParentID *uuid.UUID) error
+	ArchiveWhere(ctx context.Context, spaceID uuid.UUID, match map[string]any) (int, error)
+	CreateAt(ctx context.Context, b *model.Block, pos InsertPosition) error
+}
+
+// ErrLockedDescendant is returned by Delete and DeleteTree when a descendant of the block is locked
+var ErrLockedDescendant = errors.New("block has a locked descendant")
+
+// ErrSpaceNotFound is returned by MoveToSpace when the target space does not exist
+var ErrSpaceNotFound = errors.New("target space not found")
+
+// InsertPosition places a new block among its siblings. At most one field is set,
+// except AfterID and BeforeID together; with none the block is appended.
+type InsertPosition struct {
+	// AfterID and BeforeID name the sibling the block goes right after or before.
+	// Set together, they must be adjacent and the block goes between them.
+	AfterID  *uuid.UUID
+	BeforeID *uuid.UUID
+	// AtSort is the sort the block takes, clamped to the group
+	AtSort *int64
+}
+
+// SearchOptions narrows a block search
+type SearchOptions struct {
+	// WithinPageID restricts results to the descendants of this page
+	WithinPageID *uuid.UUID
+	// Limit caps the number of results; zero means no limit
+	Limit int
 }
 
 type blockRepo struct{ db *gorm.DB }
@@ -28,17 +88,186 @@ type blockRepo struct{ db *gorm.DB }
 func NewBlockRepo(db *gorm.DB) BlockRepo { return &blockRepo{db: db} }
 
 func (r *blockRepo) Create(ctx context.Context, b *model.Block) error {
-	return r.db.WithContext(ctx).Create(b).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := r.lockGroup(tx, b.SpaceID, b.ParentID); err != nil {
+			return err
+		}
+		return tx.Create(b).Error
+	})
+}
+
+// CreateAppend inserts b at the tail of its (space_id, parent_id) group, overwriting b.Sort.
+// The group's advisory lock is held until the transaction ends, so concurrent appends
+// under the same parent serialize instead of colliding on ux_blocks_space_parent_sort.
+func (r *blockRepo) CreateAppend(ctx context.Context, b *model.Block) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := r.lockGroup(tx, b.SpaceID, b.ParentID); err != nil {
+			return err
+		}
+
+		next, err := r.nextSortInGroup(tx, b.SpaceID, b.ParentID)
+		if err != nil {
+			return err
+		}
+		b.Sort = next
+
+		return tx.Create(b).Error
+	})
 }
 
+// CreateAt inserts b at pos within its (space_id, parent_id) group, shifting the siblings
+// from there on down by one, in a single transaction. An anchor sibling must be an active
+// block of the same group. Any caller-provided b.Sort is overwritten.
+func (r *blockRepo) CreateAt(ctx context.Context, b *model.Block, pos InsertPosition) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := r.lockGroup(tx, b.SpaceID, b.ParentID); err != nil {
+			return err
+		}
+		next, err := r.nextSortInGroup(tx, b.SpaceID, b.ParentID)
+		if err != nil {
+			return err
+		}
+
+		target := next
+		switch {
+		case pos.AfterID != nil && pos.BeforeID != nil:
+			after, err := r.findAnchor(tx, b, *pos.AfterID)
+			if err != nil {
+				return err
+			}
+			before, err := r.findAnchor(tx, b, *pos.BeforeID)
+			if err != nil {
+				return err
+			}
+			if before.Sort != after.Sort+1 {
+				return fmt.Errorf("blocks %s and %s are not adjacent siblings", after.ID, before.ID)
+			}
+			target = before.Sort
+		case pos.AfterID != nil || pos.BeforeID != nil:
+			anchorID, after := pos.BeforeID, false
+			if pos.AfterID != nil {
+				anchorID, after = pos.AfterID, true
+			}
+			anchor, err := r.findAnchor(tx, b, *anchorID)
+			if err != nil {
+				return err
+			}
+			target = anchor.Sort
+			if after {
+				target++
+			}
+		case pos.AtSort != nil:
+			target = *pos.AtSort
+		}
+		if target < model.InitialSort {
+			target = model.InitialSort
+		}
+		if target > next {
+			target = next
+		}
+
+		group := r.buildGroupQuery(tx, b.SpaceID, b.ParentID)
+		if err := group.Where("sort >= ?", target).Update("sort", gorm.Expr("sort + 1")).Error; err != nil {
+			return err
+		}
+		b.Sort = target
+		return tx.Create(b).Error
+	})
+}
+
+// findAnchor loads the active sibling of b named by an InsertPosition
+func (r *blockRepo) findAnchor(tx *gorm.DB, b *model.Block, anchorID uuid.UUID) (*model.Block, error) {
+	var anchor model.Block
+	res := r.buildGroupQuery(tx, b.SpaceID, b.ParentID).Where("id = ?", anchorID).Limit(1).Find(&anchor)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, fmt.Errorf("anchor block %s is not an active sibling under the same parent", anchorID)
+	}
+	return &anchor, nil
+}
+
+// deleteSubtreeSQL soft-deletes the block @root of @space and all of its descendants
+const deleteSubtreeSQL = `
+WITH RECURSIVE subtree AS (
+	SELECT id FROM blocks WHERE id = @root AND space_id = @space AND deleted_at IS NULL
+	UNION ALL
+	SELECT b.id FROM blocks b
+	JOIN subtree s ON b.parent_id = s.id
+	WHERE b.deleted_at IS NULL
+)
+UPDATE blocks SET deleted_at = now()
+WHERE id IN (SELECT id FROM subtree)`
+
+// Delete soft-deletes a block together with its whole subtree, archived blocks
+// included. The rows stay in place until PurgeDeleted removes them. Like DeleteTree,
+// it deletes nothing and returns ErrLockedDescendant when a descendant is locked.
 func (r *blockRepo) Delete(ctx context.Context, spaceID uuid.UUID, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Where(&model.Block{ID: id, SpaceID: spaceID}).Delete(&model.Block{}).Error
+	_, err := r.DeleteTree(ctx, spaceID, id)
+	return err
+}
+
+// countLockedDescendantsSQL counts the locked descendants of @root, archived ones included
+const countLockedDescendantsSQL = `
+WITH RECURSIVE subtree AS (
+	SELECT id, is_locked FROM blocks WHERE parent_id = @root AND deleted_at IS NULL
+	UNION ALL
+	SELECT b.id, b.is_locked FROM blocks b
+	JOIN subtree s ON b.parent_id = s.id
+	WHERE b.deleted_at IS NULL
+)
+SELECT count(*) FROM subtree WHERE is_locked`
+
+// DeleteTree soft-deletes a block together with its whole subtree, as Delete does,
+// and returns how many blocks were deleted, archived ones included. The check and
+// the delete share one transaction: when a descendant is locked nothing is deleted
+// and ErrLockedDescendant is returned.
+func (r *blockRepo) DeleteTree(ctx context.Context, spaceID uuid.UUID, id uuid.UUID) (int, error) {
+	deleted := 0
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Holds off new children of the block until the delete commits
+		var b model.Block
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where(&model.Block{ID: id, SpaceID: spaceID}).First(&b).Error; err != nil {
+			return err
+		}
+
+		var locked int64
+		if err := tx.Raw(countLockedDescendantsSQL, map[string]any{"root": id}).Scan(&locked).Error; err != nil {
+			return err
+		}
+		if locked > 0 {
+			return ErrLockedDescendant
+		}
+
+		res := tx.Exec(deleteSubtreeSQL, map[string]any{"root": id, "space": spaceID})
+		if res.Error != nil {
+			return res.Error
+		}
+		deleted = int(res.RowsAffected)
+		return nil
+	})
+	return deleted, err
+}
+
+// PurgeDeleted permanently removes the blocks of spaceID soft-deleted before olderThan
+// and returns how many were removed. Their tool SOPs go with them through the foreign
+// key cascade.
+func (r *blockRepo) PurgeDeleted(ctx context.Context, spaceID uuid.UUID, olderThan time.Time) (int, error) {
+	res := r.db.WithContext(ctx).
+		Unscoped().
+		Where(&model.Block{SpaceID: spaceID}).
+		Where("deleted_at < ?", olderThan).
+		Delete(&model.Block{})
+	return int(res.RowsAffected), res.Error
 }
 
 func (r *blockRepo) Get(ctx context.Context, id uuid.UUID) (*model.Block, error) {
 	var b model.Block
 	err := r.db.WithContext(ctx).
 		Preload("ToolSOPs.ToolReference").
+		Scopes(withArchived(true)).
 		Where(&model.Block{ID: id}).
 		First(&b).Error
 
@@ -60,6 +289,7 @@ func (r *blockRepo) ListBySpace(ctx context.Context, spaceID uuid.UUID, blockTyp
 	var list []model.Block
 	query := r.db.WithContext(ctx).
 		Preload("ToolSOPs.ToolReference").
+		Scopes(withArchived(false)).
 		Where(&model.Block{SpaceID: spaceID})
 
 	if blockType != "" {
@@ -86,16 +316,625 @@ func (r *blockRepo) ListBySpace(ctx context.Context, spaceID uuid.UUID, blockTyp
 	return list, nil
 }
 
-// NextSort returns max(sort)+1 within group (space_id, parent_id)
-func (r *blockRepo) NextSort(ctx context.Context, spaceID uuid.UUID, parentID *uuid.UUID) (int64, error) {
-	type result struct{ Next int64 }
-	var res result
-	query := r.buildGroupQuery(r.db.WithContext(ctx), spaceID, parentID).
-		Select("COALESCE(MAX(sort), -1) + 1 AS next")
-	if err := query.Take(&res).Error; err != nil {
+// ListAllBySpace returns every non-archived block in a space, ordered by sort within each group
+func (r *blockRepo) ListAllBySpace(ctx context.Context, spaceID uuid.UUID) ([]model.Block, error) {
+	var list []model.Block
+	err := r.db.WithContext(ctx).
+		Where(&model.Block{SpaceID: spaceID}).
+		Scopes(withArchived(false)).
+		Order("sort ASC").
+		Find(&list).Error
+	return list, err
+}
+
+// CreateBatch inserts the blocks in order within a single transaction.
+// Parents must precede their children in the slice. Blocks whose parent is not
+// in the batch are appended to the end of their group in slice order, with the
+// group locked, so any caller-provided Sort on them is overwritten; the other
+// blocks keep their Sort.
+func (r *blockRepo) CreateBatch(ctx context.Context, blocks []*model.Block) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		type group struct{ spaceID, parentID uuid.UUID }
+		inBatch := make(map[uuid.UUID]bool, len(blocks))
+		tails := make(map[group]int64)
+		for _, b := range blocks {
+			if b.ParentID == nil || !inBatch[*b.ParentID] {
+				key := group{spaceID: b.SpaceID}
+				if b.ParentID != nil {
+					key.parentID = *b.ParentID
+				}
+				next, ok := tails[key]
+				if !ok {
+					if err := r.lockGroup(tx, b.SpaceID, b.ParentID); err != nil {
+						return err
+					}
+					var err error
+					if next, err = r.nextSortInGroup(tx, b.SpaceID, b.ParentID); err != nil {
+						return err
+					}
+				}
+				b.Sort = next
+				tails[key] = next + 1
+			}
+			if err := tx.Create(b).Error; err != nil {
+				return err
+			}
+			inBatch[b.ID] = true
+		}
+		return nil
+	})
+}
+
+// ListBacklinks returns the non-archived blocks of spaceID whose props.links contain
+// blockID; links from other spaces are not followed. The jsonb containment query is
+// served by the GIN index on props.
+func (r *blockRepo) ListBacklinks(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID) ([]model.Block, error) {
+	contains, err := json.Marshal(map[string]any{model.BlockPropLinks: []string{blockID.String()}})
+	if err != nil {
+		return nil, err
+	}
+
+	var list []model.Block
+	err = r.db.WithContext(ctx).
+		Where(&model.Block{SpaceID: spaceID}).
+		Where("props @> ?::jsonb", string(contains)).
+		Scopes(withArchived(false)).
+		Order("updated_at DESC").
+		Find(&list).Error
+	return list, err
+}
+
+// ListChildrenLite returns the non-archived children of parentID in sort order,
+// selecting only the columns of model.BlockLite
+func (r *blockRepo) ListChildrenLite(ctx context.Context, parentID uuid.UUID) ([]model.BlockLite, error) {
+	var list []model.BlockLite
+	err := r.db.WithContext(ctx).
+		Model(&model.Block{}).
+		Select("id", "title", "sort", "parent_id").
+		Where("parent_id = ?", parentID).
+		Scopes(withArchived(false)).
+		Order("sort ASC").
+		Find(&list).Error
+	return list, err
+}
+
+// ListChildrenByTypes returns the non-archived children of parentID whose type is one of types, in sort order
+func (r *blockRepo) ListChildrenByTypes(ctx context.Context, parentID uuid.UUID, types []string) ([]model.Block, error) {
+	var list []model.Block
+	err := r.db.WithContext(ctx).
+		Where("parent_id = ?", parentID).
+		Where("type IN ?", types).
+		Scopes(withArchived(false)).
+		Order("sort ASC").
+		Find(&list).Error
+	return list, err
+}
+
+// ListChildrenAfter returns up to limit non-archived children of parentID ordered by
+// (sort, id), starting after (afterSort, afterID). A nil afterID starts from the first child.
+func (r *blockRepo) ListChildrenAfter(ctx context.Context, parentID uuid.UUID, afterSort int64, afterID uuid.UUID, limit int) ([]model.Block, error) {
+	q := r.db.WithContext(ctx).
+		Preload("ToolSOPs.ToolReference").
+		Scopes(withArchived(false)).
+		Where("parent_id = ?", parentID)
+	if afterID != uuid.Nil {
+		q = q.Where("(sort > ?) OR (sort = ? AND id > ?)", afterSort, afterSort, afterID)
+	}
+
+	var list []model.Block
+	if err := q.Order("sort ASC, id ASC").Limit(limit).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	for i := range list {
+		r.mergeToolSOPsIntoProps(&list[i])
+	}
+	return list, nil
+}
+
+// CountChildren returns the number of active direct children of parentID; archived and
+// deleted children are not listed, so they do not count
+func (r *blockRepo) CountChildren(ctx context.Context, parentID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&model.Block{}).
+		Scopes(withArchived(false)).
+		Where("parent_id = ?", parentID).
+		Count(&count).Error
+	return count, err
+}
+
+// ListExistingIDs returns which of ids exist in spaceID, in a single query
+func (r *blockRepo) ListExistingIDs(ctx context.Context, spaceID uuid.UUID, ids []uuid.UUID) ([]uuid.UUID, error) {
+	var found []uuid.UUID
+	if len(ids) == 0 {
+		return found, nil
+	}
+	err := r.db.WithContext(ctx).
+		Model(&model.Block{}).
+		Where("space_id = ? AND id IN ?", spaceID, ids).
+		Pluck("id", &found).Error
+	return found, err
+}
+
+// subtreeTextStatsSQL sums the text of a block and all of its descendants.
+// A block's text is its title plus props.text; words are whitespace-separated runs.
+const subtreeTextStatsSQL = `
+WITH RECURSIVE subtree AS (
+	SELECT id, title, props FROM blocks
+	WHERE id = @root AND (@archived OR NOT is_archived) AND deleted_at IS NULL
+	UNION ALL
+	SELECT b.id, b.title, b.props FROM blocks b
+	JOIN subtree s ON b.parent_id = s.id
+	WHERE (@archived OR NOT b.is_archived) AND b.deleted_at IS NULL
+), texts AS (
+	SELECT
+		char_length(title) + COALESCE(char_length(props->>'text'), 0) AS chars,
+		btrim(concat_ws(' ', title, props->>'text')) AS body
+	FROM subtree
+)
+SELECT
+	COALESCE(SUM(chars), 0) AS chars,
+	COALESCE(SUM(CASE WHEN body = '' THEN 0 ELSE array_length(regexp_split_to_array(body, '\s+'), 1) END), 0) AS words,
+	COUNT(*) AS blocks
+FROM texts`
+
+// SubtreeTextStats returns the character, word and block counts of rootID's subtree,
+// rootID included, in a single recursive query
+func (r *blockRepo) SubtreeTextStats(ctx context.Context, rootID uuid.UUID, includeArchived bool) (int, int, int, error) {
+	var res struct {
+		Chars  int
+		Words  int
+		Blocks int
+	}
+	err := r.db.WithContext(ctx).
+		Raw(subtreeTextStatsSQL, map[string]any{"root": rootID, "archived": includeArchived}).
+		Scan(&res).Error
+	return res.Chars, res.Words, res.Blocks, err
+}
+
+// ancestorsBatchSQL walks up from every block in @ids at once; each row is one
+// ancestor of origin, depth 1 being the parent. The depth cap guards against cycles.
+const ancestorsBatchSQL = `
+WITH RECURSIVE chain AS (
+	SELECT b.id AS origin, p.*, 1 AS depth FROM blocks b
+	JOIN blocks p ON p.id = b.parent_id
+	WHERE b.id IN @ids AND b.deleted_at IS NULL
+	UNION ALL
+	SELECT c.origin, p.*, c.depth + 1 FROM chain c
+	JOIN blocks p ON p.id = c.parent_id
+	WHERE c.depth < 1000
+)
+SELECT * FROM chain ORDER BY origin, depth DESC`
+
+// ListAncestorsBatch returns the ancestor chain of each block, root first, in a single
+// recursive query. Blocks at the space root have no entry.
+func (r *blockRepo) ListAncestorsBatch(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID][]model.Block, error) {
+	var rows []struct {
+		Origin      uuid.UUID
+		model.Block `gorm:"embedded"`
+	}
+	err := r.db.WithContext(ctx).
+		Raw(ancestorsBatchSQL, map[string]any{"ids": ids}).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	chains := make(map[uuid.UUID][]model.Block)
+	for _, row := range rows {
+		chains[row.Origin] = append(chains[row.Origin], row.Block)
+	}
+	return chains, nil
+}
+
+// subtreeChangedSinceSQL walks the whole subtree of @root, archived blocks and blocks
+// soft-deleted after @since included, and keeps the blocks created, updated, moved or
+// deleted after @since
+const subtreeChangedSinceSQL = `
+WITH RECURSIVE subtree AS (
+	SELECT * FROM blocks WHERE id = @root AND (deleted_at IS NULL OR deleted_at > @since)
+	UNION ALL
+	SELECT b.* FROM blocks b
+	JOIN subtree s ON b.parent_id = s.id
+	WHERE b.deleted_at IS NULL OR b.deleted_at > @since
+)
+SELECT * FROM subtree
+WHERE created_at > @since OR updated_at > @since OR moved_at > @since OR deleted_at > @since
+ORDER BY updated_at ASC, id ASC`
+
+// ListSubtreeChangedSince returns the blocks of rootID's subtree, rootID and archived
+// blocks included, that were created, updated, moved or soft-deleted after since
+func (r *blockRepo) ListSubtreeChangedSince(ctx context.Context, rootID uuid.UUID, since time.Time) ([]model.Block, error) {
+	var list []model.Block
+	err := r.db.WithContext(ctx).
+		Raw(subtreeChangedSinceSQL, map[string]any{"root": rootID, "since": since}).
+		Scan(&list).Error
+	return list, err
+}
+
+// subtreeSQL walks the active descendants of @root down to depth @max (0 for no limit),
+// depth 1 being the children. The depth cap also guards against cycles.
+const subtreeSQL = `
+WITH RECURSIVE subtree AS (
+	SELECT b.*, 1 AS depth FROM blocks b
+	WHERE b.parent_id = @root AND NOT b.is_archived AND b.deleted_at IS NULL
+	UNION ALL
+	SELECT b.*, s.depth + 1 FROM blocks b
+	JOIN subtree s ON b.parent_id = s.id
+	WHERE NOT b.is_archived AND b.deleted_at IS NULL
+		AND s.depth < CASE WHEN @max > 0 THEN @max ELSE 1000 END
+)
+SELECT * FROM subtree ORDER BY depth, parent_id, sort, id`
+
+// ListSubtree returns the active descendants of rootID, rootID excluded, down to
+// maxDepth levels (0 for all) in a single recursive query. Blocks come level by
+// level, and the children of each parent in sort order, so every block follows its
+// parent. Tool SOPs are not merged into props.
+func (r *blockRepo) ListSubtree(ctx context.Context, rootID uuid.UUID, maxDepth int) ([]model.Block, error) {
+	var list []model.Block
+	err := r.db.WithContext(ctx).
+		Raw(subtreeSQL, map[string]any{"root": rootID, "max": maxDepth}).
+		Scan(&list).Error
+	return list, err
+}
+
+// blockSearchDocument is the text a block is searched by: its title plus props.text
+const blockSearchDocument = `to_tsvector('simple', blocks.title || ' ' || COALESCE(blocks.props->>'text', ''))`
+
+// withinSubtreeSQL selects the ids of the descendants of the block bound to ?. The
+// walk stops at archived and deleted blocks, so nothing under one is reached.
+const withinSubtreeSQL = `
+WITH RECURSIVE subtree AS (
+	SELECT id FROM blocks WHERE parent_id = ? AND NOT is_archived AND deleted_at IS NULL
+	UNION ALL
+	SELECT b.id FROM blocks b
+	JOIN subtree s ON b.parent_id = s.id
+	WHERE NOT b.is_archived AND b.deleted_at IS NULL
+)
+SELECT id FROM subtree`
+
+// SearchBlocks returns the active blocks of spaceID whose text matches query, best
+// matches first. With opts.WithinPageID only descendants of that page are searched.
+func (r *blockRepo) SearchBlocks(ctx context.Context, spaceID uuid.UUID, query string, opts SearchOptions) ([]model.Block, error) {
+	tsQuery := gorm.Expr("plainto_tsquery('simple', ?)", query)
+	q := r.db.WithContext(ctx).
+		Where(&model.Block{SpaceID: spaceID}).
+		Scopes(withArchived(false)).
+		Where(blockSearchDocument+" @@ ?", tsQuery)
+
+	if opts.WithinPageID != nil {
+		q = q.Where("id IN ("+withinSubtreeSQL+")", *opts.WithinPageID)
+	}
+	if opts.Limit > 0 {
+		q = q.Limit(opts.Limit)
+	}
+
+	var list []model.Block
+	err := q.Order(clause.OrderBy{
+		Expression: clause.Expr{SQL: "ts_rank(" + blockSearchDocument + ", ?) DESC, id ASC", Vars: []any{tsQuery}},
+	}).Find(&list).Error
+	return list, err
+}
+
+// setLockedSubtreeSQL sets is_locked on a block and all of its descendants; deleted
+// blocks are left as they are
+const setLockedSubtreeSQL = `
+WITH RECURSIVE subtree AS (
+	SELECT id FROM blocks WHERE id = @root AND deleted_at IS NULL
+	UNION ALL
+	SELECT b.id FROM blocks b
+	JOIN subtree s ON b.parent_id = s.id
+	WHERE b.deleted_at IS NULL
+)
+UPDATE blocks SET is_locked = @locked, updated_at = now()
+WHERE id IN (SELECT id FROM subtree)`
+
+// SetLocked sets the lock flag of a block, and of its whole subtree when cascade is set
+func (r *blockRepo) SetLocked(ctx context.Context, id uuid.UUID, locked bool, cascade bool) error {
+	if cascade {
+		return r.db.WithContext(ctx).
+			Exec(setLockedSubtreeSQL, map[string]any{"root": id, "locked": locked}).Error
+	}
+	return r.db.WithContext(ctx).
+		Model(&model.Block{}).
+		Where(&model.Block{ID: id}).
+		Update("is_locked", locked).Error
+}
+
+// updatePropsWhereSQL merges @patch into the props of every active, unlocked
+// descendant of @parent whose props contain @match
+const updatePropsWhereSQL = `
+WITH RECURSIVE subtree AS (
+	SELECT id FROM blocks
+	WHERE parent_id = @parent AND space_id = @space AND NOT is_archived AND deleted_at IS NULL
+	UNION ALL
+	SELECT b.id FROM blocks b
+	JOIN subtree s ON b.parent_id = s.id
+	WHERE NOT b.is_archived AND b.deleted_at IS NULL
+)
+UPDATE blocks SET props = props || @patch::jsonb, updated_at = now()
+WHERE id IN (SELECT id FROM subtree) AND props @> @match::jsonb AND NOT is_locked`
+
+// UpdatePropsWhere shallow-merges patch into the props of parentID's descendants
+// whose props contain match, in a single statement, and returns how many were updated
+func (r *blockRepo) UpdatePropsWhere(ctx context.Context, spaceID uuid.UUID, parentID uuid.UUID, match map[string]any, patch map[string]any) (int, error) {
+	if match == nil {
+		match = map[string]any{}
+	}
+	matchJSON, err := json.Marshal(match)
+	if err != nil {
+		return 0, err
+	}
+	patchJSON, err := json.Marshal(patch)
+	if err != nil {
 		return 0, err
 	}
-	return res.Next, nil
+
+	res := r.db.WithContext(ctx).Exec(updatePropsWhereSQL, map[string]any{
+		"space":  spaceID,
+		"parent": parentID,
+		"match":  string(matchJSON),
+		"patch":  string(patchJSON),
+	})
+	return int(res.RowsAffected), res.Error
+}
+
+// Archive archives a block with all its descendants and closes the gap it leaves, so
+// the sorts of the active blocks in its group stay contiguous. Archived blocks hold no
+// position; their sort only records where Restore puts them back. The descendants'
+// groups are archived whole, except for locked descendants, which stay active.
+func (r *blockRepo) Archive(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var b model.Block
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(&model.Block{ID: id}).First(&b).Error; err != nil {
+			return err
+		}
+		if b.IsArchived {
+			return nil
+		}
+		if err := r.lockGroup(tx, b.SpaceID, b.ParentID); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&model.Block{}).Where(&model.Block{ID: id}).Update("is_archived", true).Error; err != nil {
+			return err
+		}
+		group := r.buildGroupQuery(tx, b.SpaceID, b.ParentID)
+		if err := group.Where("sort > ?", b.Sort).Update("sort", gorm.Expr("sort - 1")).Error; err != nil {
+			return err
+		}
+		return tx.Exec(archiveDescendantsSQL, map[string]any{"roots": []uuid.UUID{id}}).Error
+	})
+}
+
+// archiveWhereRootsSQL selects, for update, the active and unlocked blocks of @space
+// whose props contain @match and whose parent is not locked, leaving out those under
+// another such block
+const archiveWhereRootsSQL = `
+WITH RECURSIVE matched AS (
+	SELECT b.id FROM blocks b
+	LEFT JOIN blocks p ON p.id = b.parent_id
+	WHERE b.space_id = @space AND NOT b.is_archived AND NOT b.is_locked AND b.deleted_at IS NULL
+		AND b.props @> @match::jsonb AND NOT COALESCE(p.is_locked, false)
+), below AS (
+	SELECT b.id FROM blocks b
+	JOIN matched m ON b.parent_id = m.id
+	UNION ALL
+	SELECT b.id FROM blocks b
+	JOIN below s ON b.parent_id = s.id
+)
+SELECT * FROM blocks
+WHERE id IN (SELECT id FROM matched) AND id NOT IN (SELECT id FROM below)
+ORDER BY parent_id, sort DESC
+FOR UPDATE`
+
+// archiveDescendantsSQL archives the active descendants of the blocks in @roots,
+// recording the root each was archived with. The walk stops at archived blocks, whose
+// subtrees go with them, and at locked ones, which are left active with their subtrees.
+const archiveDescendantsSQL = `
+WITH RECURSIVE subtree AS (
+	SELECT id, parent_id AS root FROM blocks
+	WHERE parent_id IN @roots AND NOT is_archived AND NOT is_locked AND deleted_at IS NULL
+	UNION ALL
+	SELECT b.id, s.root FROM blocks b
+	JOIN subtree s ON b.parent_id = s.id
+	WHERE NOT b.is_archived AND NOT b.is_locked AND b.deleted_at IS NULL
+)
+UPDATE blocks SET is_archived = true, archived_with = subtree.root, updated_at = now()
+FROM subtree WHERE blocks.id = subtree.id`
+
+// restoreDescendantsSQL un-archives the descendants archived along with @root
+const restoreDescendantsSQL = `
+WITH RECURSIVE subtree AS (
+	SELECT id FROM blocks WHERE parent_id = @root AND archived_with = @root AND deleted_at IS NULL
+	UNION ALL
+	SELECT b.id FROM blocks b
+	JOIN subtree s ON b.parent_id = s.id
+	WHERE b.archived_with = @root AND b.deleted_at IS NULL
+)
+UPDATE blocks SET is_archived = false, archived_with = NULL, updated_at = now()
+WHERE id IN (SELECT id FROM subtree)`
+
+// ArchiveWhere archives, in one transaction, the blocks of spaceID whose props contain
+// match together with all their descendants, and returns how many blocks were archived.
+// Matching blocks that are locked or under a locked parent are left alone, as are
+// locked descendants. As with Archive, each topmost archived block closes the gap in
+// its group; its descendants' groups are archived whole.
+func (r *blockRepo) ArchiveWhere(ctx context.Context, spaceID uuid.UUID, match map[string]any) (int, error) {
+	matchJSON, err := json.Marshal(match)
+	if err != nil {
+		return 0, err
+	}
+
+	archived := 0
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var roots []model.Block
+		if err := tx.Raw(archiveWhereRootsSQL, map[string]any{
+			"space": spaceID,
+			"match": string(matchJSON),
+		}).Scan(&roots).Error; err != nil {
+			return err
+		}
+		if len(roots) == 0 {
+			return nil
+		}
+
+		// Roots come highest sort first within each group, so closing one gap never
+		// moves a sibling that is archived after it
+		ids := make([]uuid.UUID, 0, len(roots))
+		for _, b := range roots {
+			if err := r.lockGroup(tx, b.SpaceID, b.ParentID); err != nil {
+				return err
+			}
+			if err := tx.Model(&model.Block{}).Where(&model.Block{ID: b.ID}).Update("is_archived", true).Error; err != nil {
+				return err
+			}
+			group := r.buildGroupQuery(tx, b.SpaceID, b.ParentID)
+			if err := group.Where("sort > ?", b.Sort).Update("sort", gorm.Expr("sort - 1")).Error; err != nil {
+				return err
+			}
+			ids = append(ids, b.ID)
+		}
+
+		res := tx.Exec(archiveDescendantsSQL, map[string]any{"roots": ids})
+		if res.Error != nil {
+			return res.Error
+		}
+		archived = len(roots) + int(res.RowsAffected)
+		return nil
+	})
+	return archived, err
+}
+
+// Restore un-archives the blocks in order, so ancestors must precede their
+// descendants. Each block is put back at its former sort, clamped to the end of
+// its group, and the active blocks from there on shift down to make room. The
+// descendants archived along with a block come back with it in place.
+func (r *blockRepo) Restore(ctx context.Context, ids []uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, id := range ids {
+			var b model.Block
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(&model.Block{ID: id}).First(&b).Error; err != nil {
+				return err
+			}
+			if !b.IsArchived {
+				continue
+			}
+			if err := r.lockGroup(tx, b.SpaceID, b.ParentID); err != nil {
+				return err
+			}
+
+			sort := b.Sort
+			next, err := r.nextSortInGroup(tx, b.SpaceID, b.ParentID)
+			if err != nil {
+				return err
+			}
+			if sort > next {
+				sort = next
+			}
+			group := r.buildGroupQuery(tx, b.SpaceID, b.ParentID)
+			if err := group.Where("sort >= ?", sort).Update("sort", gorm.Expr("sort + 1")).Error; err != nil {
+				return err
+			}
+
+			if err := tx.Model(&model.Block{}).Where(&model.Block{ID: id}).
+				Updates(map[string]any{"is_archived": false, "archived_with": nil, "sort": sort}).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec(restoreDescendantsSQL, map[string]any{"root": id}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ConvertType sets the type of a block, appending it to the newParentID group when
+// that differs from its current parent. It fails when the block has children,
+// archived ones included, and newType cannot have any.
+func (r *blockRepo) ConvertType(ctx context.Context, id uuid.UUID, newType string, newParentID *uuid.UUID) error {
+	config, err := model.GetBlockTypeConfig(newType)
+	if err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var b model.Block
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(&model.Block{ID: id}).First(&b).Error; err != nil {
+			return err
+		}
+
+		if !config.AllowChildren {
+			var children int64
+			if err := tx.Model(&model.Block{}).Where("parent_id = ?", id).Count(&children).Error; err != nil {
+				return err
+			}
+			if children > 0 {
+				return fmt.Errorf("block type '%s' cannot have children, block has %d", newType, children)
+			}
+		}
+
+		updates := map[string]any{"type": newType}
+		sameParent := (b.ParentID == nil && newParentID == nil) ||
+			(b.ParentID != nil && newParentID != nil && *b.ParentID == *newParentID)
+		if !sameParent {
+			if err := r.lockGroup(tx, b.SpaceID, b.ParentID); err != nil {
+				return err
+			}
+			if err := r.lockGroup(tx, b.SpaceID, newParentID); err != nil {
+				return err
+			}
+			next, err := r.nextSortInGroup(tx, b.SpaceID, newParentID)
+			if err != nil {
+				return err
+			}
+			updates["parent_id"] = newParentID
+			updates["sort"] = next
+			updates["moved_at"] = gorm.Expr("now()")
+		}
+
+		if err := tx.Model(&model.Block{}).Where(&model.Block{ID: id}).Updates(updates).Error; err != nil {
+			return err
+		}
+		if sameParent || b.IsArchived {
+			return nil
+		}
+		oldGroup := r.buildGroupQuery(tx, b.SpaceID, b.ParentID)
+		return oldGroup.Where("sort > ?", b.Sort).Update("sort", gorm.Expr("sort - 1")).Error
+	})
+}
+
+// ListPurgeCandidates returns archived blocks across all spaces that have not been
+// touched since olderThan, oldest first, so a background job can purge them in batches.
+// Served by idx_blocks_archived_updated.
+func (r *blockRepo) ListPurgeCandidates(ctx context.Context, olderThan time.Time, limit int) ([]model.Block, error) {
+	var list []model.Block
+	query := r.db.WithContext(ctx).
+		Where("is_archived = ?", true).
+		Where("updated_at < ?", olderThan).
+		Order("updated_at ASC, id ASC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err := query.Find(&list).Error
+	return list, err
+}
+
+// ListArchived returns the archived blocks of blockType in a space, most recently
+// archived first
+func (r *blockRepo) ListArchived(ctx context.Context, spaceID uuid.UUID, blockType string) ([]model.Block, error) {
+	var list []model.Block
+	err := r.db.WithContext(ctx).
+		Where(&model.Block{SpaceID: spaceID, Type: blockType}).
+		Where("is_archived = ?", true).
+		Order("updated_at DESC, id ASC").
+		Find(&list).Error
+	return list, err
+}
+
+// NextSort returns max(sort)+1 within group (space_id, parent_id), or model.InitialSort
+// when the group is empty
+func (r *blockRepo) NextSort(ctx context.Context, spaceID uuid.UUID, parentID *uuid.UUID) (int64, error) {
+	return r.nextSortInGroup(r.db.WithContext(ctx), spaceID, parentID)
 }
 
 // MoveToParentAppend moves the block to new parent and sets sort to tail in a single transaction.
@@ -107,9 +946,8 @@ func (r *blockRepo) MoveToParentAppend(ctx context.Context, id uuid.UUID, newPar
 		}
 
 		// Compute next sort in target group
-		var next int64
-		q := r.buildGroupQuery(tx, b.SpaceID, newParentID).Select("COALESCE(MAX(sort), -1) + 1")
-		if err := q.Take(&next).Error; err != nil {
+		next, err := r.nextSortInGroup(tx, b.SpaceID, newParentID)
+		if err != nil {
 			return err
 		}
 
@@ -117,6 +955,7 @@ func (r *blockRepo) MoveToParentAppend(ctx context.Context, id uuid.UUID, newPar
 		return tx.Model(&model.Block{}).Where(&model.Block{ID: id}).Updates(map[string]any{
 			"parent_id": newParentID,
 			"sort":      next,
+			"moved_at":  gorm.Expr("now()"),
 		}).Error
 	})
 }
@@ -132,6 +971,127 @@ func (r *blockRepo) ReorderWithinGroup(ctx context.Context, id uuid.UUID, newSor
 	})
 }
 
+// ReorderChildren gives parentID's active children sequential sorts in the order of
+// orderedIDs, which must list every one of them exactly once. It fails, naming the
+// offending ids, when an id is not a child, is listed twice or is left out. It holds
+// the group lock, so a concurrent CreateAppend either lands after the reordered
+// children or makes the reorder fail as incomplete.
+func (r *blockRepo) ReorderChildren(ctx context.Context, spaceID uuid.UUID, parentID uuid.UUID, orderedIDs []uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := r.lockGroup(tx, spaceID, &parentID); err != nil {
+			return err
+		}
+
+		var current []uuid.UUID
+		if err := r.buildGroupQuery(tx, spaceID, &parentID).Order("sort ASC").Pluck("id", &current).Error; err != nil {
+			return err
+		}
+		unlisted := make(map[uuid.UUID]struct{}, len(current))
+		for _, id := range current {
+			unlisted[id] = struct{}{}
+		}
+		var foreign []string
+		for _, id := range orderedIDs {
+			if _, ok := unlisted[id]; !ok {
+				foreign = append(foreign, id.String())
+				continue
+			}
+			delete(unlisted, id)
+		}
+		if len(foreign) > 0 {
+			return fmt.Errorf("blocks are not children of %s or are listed twice: %s", parentID, strings.Join(foreign, ", "))
+		}
+		if len(unlisted) > 0 {
+			missing := make([]string, 0, len(unlisted))
+			for _, id := range current {
+				if _, ok := unlisted[id]; ok {
+					missing = append(missing, id.String())
+				}
+			}
+			return fmt.Errorf("children of %s are missing from the order: %s", parentID, strings.Join(missing, ", "))
+		}
+		order := orderedIDs
+
+		// Park every child on a distinct sentinel first so the final sorts never collide
+		for i, id := range order {
+			if err := tx.Model(&model.Block{}).Where(&model.Block{ID: id}).Update("sort", math.MinInt64+int64(i)).Error; err != nil {
+				return err
+			}
+		}
+		for i, id := range order {
+			if err := tx.Model(&model.Block{}).Where(&model.Block{ID: id}).Update("sort", model.InitialSort+int64(i)).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// moveSubtreeToSpaceSQL sets the space of every descendant of @root, archived and
+// deleted ones included, so none is left behind under a parent in another space
+const moveSubtreeToSpaceSQL = `
+WITH RECURSIVE subtree AS (
+	SELECT id FROM blocks WHERE parent_id = @root
+	UNION ALL
+	SELECT b.id FROM blocks b
+	JOIN subtree s ON b.parent_id = s.id
+)
+UPDATE blocks SET space_id = @space, updated_at = now()
+WHERE id IN (SELECT id FROM subtree)`
+
+// MoveToSpace moves a block and its whole subtree to spaceID, appending the block to
+// the newParentID group there, in a single transaction. The gap it leaves in its old
+// group is closed; descendants keep their parents and sorts. It fails with
+// ErrSpaceNotFound when spaceID does not exist.
+func (r *blockRepo) MoveToSpace(ctx context.Context, id uuid.UUID, spaceID uuid.UUID, newParentID *uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Keep the target space from being deleted until the move commits
+		var space model.Space
+		res := tx.Clauses(clause.Locking{Strength: "SHARE"}).Select("id").
+			Where(&model.Space{ID: spaceID}).Limit(1).Find(&space)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrSpaceNotFound
+		}
+
+		var b model.Block
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(&model.Block{ID: id}).First(&b).Error; err != nil {
+			return err
+		}
+		if err := r.lockGroup(tx, b.SpaceID, b.ParentID); err != nil {
+			return err
+		}
+		if err := r.lockGroup(tx, spaceID, newParentID); err != nil {
+			return err
+		}
+
+		next, err := r.nextSortInGroup(tx, spaceID, newParentID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Block{}).Where(&model.Block{ID: id}).Updates(map[string]any{
+			"space_id":  spaceID,
+			"parent_id": newParentID,
+			"sort":      next,
+			"moved_at":  gorm.Expr("now()"),
+		}).Error; err != nil {
+			return err
+		}
+
+		// Archived blocks hold no position, so only an active block leaves a gap
+		if !b.IsArchived {
+			oldGroup := r.buildGroupQuery(tx, b.SpaceID, b.ParentID)
+			if err := oldGroup.Where("sort > ?", b.Sort).Update("sort", gorm.Expr("sort - 1")).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Exec(moveSubtreeToSpaceSQL, map[string]any{"root": id, "space": spaceID}).Error
+	})
+}
+
 // MoveToParentAtSort moves a block to a specific position in the target parent group.
 func (r *blockRepo) MoveToParentAtSort(ctx context.Context, id uuid.UUID, newParentID *uuid.UUID, targetSort int64) error {
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
@@ -155,14 +1115,209 @@ func (r *blockRepo) MoveToParentAtSort(ctx context.Context, id uuid.UUID, newPar
 	})
 }
 
+// UpdatePage applies a rename, a move and a reorder in a single transaction.
+// A nil title or newParentID leaves that field unchanged; a nil targetSort appends
+// when moving to a new parent and keeps the position otherwise.
+func (r *blockRepo) UpdatePage(ctx context.Context, id uuid.UUID, title *string, newParentID *uuid.UUID, targetSort *int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var b model.Block
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(&model.Block{ID: id}).First(&b).Error; err != nil {
+			return err
+		}
+
+		if title != nil {
+			if err := tx.Model(&model.Block{}).Where(&model.Block{ID: id}).Update("title", *title).Error; err != nil {
+				return err
+			}
+		}
+
+		sameGroup := newParentID == nil ||
+			(b.ParentID != nil && *b.ParentID == *newParentID)
+
+		if sameGroup {
+			if targetSort == nil {
+				return nil
+			}
+			return r.reorderInTransaction(tx, &b, *targetSort)
+		}
+
+		sort := int64(math.MaxInt64) // clamped to the tail of the new group
+		if targetSort != nil {
+			sort = *targetSort
+		}
+		return r.moveToNewParentInTransaction(tx, &b, id, newParentID, sort)
+	})
+}
+
+// DuplicateBlock copies the block and its non-archived descendants, tool SOPs included,
+// and inserts the copy directly after the original, shifting later siblings down.
+// Descendants keep their relative order. Returns the copy of the block itself.
+func (r *blockRepo) DuplicateBlock(ctx context.Context, id uuid.UUID) (*model.Block, error) {
+	var root *model.Block
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var src model.Block
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(&model.Block{ID: id}).First(&src).Error; err != nil {
+			return err
+		}
+		if err := r.lockGroup(tx, src.SpaceID, src.ParentID); err != nil {
+			return err
+		}
+
+		// Make room right after the original
+		group := r.buildGroupQuery(tx, src.SpaceID, src.ParentID)
+		if err := group.Where("sort > ?", src.Sort).Update("sort", gorm.Expr("sort + 1")).Error; err != nil {
+			return err
+		}
+
+		root = r.cloneBlock(&src, src.ParentID, src.Sort+1)
+		if err := r.createClone(tx, root, src.ID); err != nil {
+			return err
+		}
+		return r.copyDescendants(tx, src.ID, root.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// DuplicateTo copies the block id and its subtree under newParentID (nil for the root of
+// its space), appended after the last sibling, in a single transaction. The copy of id is
+// titled title; its descendants keep their titles and relative order.
+func (r *blockRepo) DuplicateTo(ctx context.Context, id uuid.UUID, newParentID *uuid.UUID, title string) (*model.Block, error) {
+	var root *model.Block
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var src model.Block
+		if err := tx.Where(&model.Block{ID: id}).First(&src).Error; err != nil {
+			return err
+		}
+		if err := r.lockGroup(tx, src.SpaceID, newParentID); err != nil {
+			return err
+		}
+
+		next, err := r.nextSortInGroup(tx, src.SpaceID, newParentID)
+		if err != nil {
+			return err
+		}
+
+		root = r.cloneBlock(&src, newParentID, next)
+		root.Title = title
+		if err := r.createClone(tx, root, src.ID); err != nil {
+			return err
+		}
+		return r.copyDescendants(tx, src.ID, root.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// copyDescendants copies the active descendants of srcID under its copy rootID, level by
+// level, mapping original ids to their copies. Children keep their sorts and sort keys.
+func (r *blockRepo) copyDescendants(tx *gorm.DB, srcID uuid.UUID, rootID uuid.UUID) error {
+	copies := map[uuid.UUID]uuid.UUID{srcID: rootID}
+	level := []uuid.UUID{srcID}
+	for len(level) > 0 {
+		var children []model.Block
+		if err := tx.Where("parent_id IN ?", level).
+			Scopes(withArchived(false)).
+			Order("sort ASC").
+			Find(&children).Error; err != nil {
+			return err
+		}
+
+		level = nil
+		for i := range children {
+			parentID := copies[*children[i].ParentID]
+			c := r.cloneBlock(&children[i], &parentID, children[i].Sort)
+			if err := r.createClone(tx, c, children[i].ID); err != nil {
+				return err
+			}
+			copies[children[i].ID] = c.ID
+			level = append(level, children[i].ID)
+		}
+	}
+	return nil
+}
+
+// cloneBlock returns an unsaved copy of b with a fresh id under parentID at sort. Its
+// props are deep-copied, so changing them leaves b untouched.
+func (r *blockRepo) cloneBlock(b *model.Block, parentID *uuid.UUID, sort int64) *model.Block {
+	props, _ := cloneJSON(b.Props.Data()).(map[string]any)
+	return &model.Block{
+		ID:       uuid.New(),
+		SpaceID:  b.SpaceID,
+		Type:     b.Type,
+		ParentID: parentID,
+		Title:    b.Title,
+		Props:    datatypes.NewJSONType(props),
+		Sort:     sort,
+	}
+}
+
+// cloneJSON deep-copies the maps and slices of decoded JSON
+func cloneJSON(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		if val == nil {
+			return val
+		}
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			out[k] = cloneJSON(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = cloneJSON(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// createClone inserts c and copies the tool SOPs of the block it was cloned from
+func (r *blockRepo) createClone(tx *gorm.DB, c *model.Block, srcID uuid.UUID) error {
+	if err := tx.Create(c).Error; err != nil {
+		return err
+	}
+
+	var sops []model.ToolSOP
+	if err := tx.Where("sop_block_id = ?", srcID).Order(`"order" ASC`).Find(&sops).Error; err != nil {
+		return err
+	}
+	if len(sops) == 0 {
+		return nil
+	}
+	for i := range sops {
+		sops[i].ID = uuid.New()
+		sops[i].SOPBlockID = c.ID
+		sops[i].CreatedAt = time.Time{}
+		sops[i].UpdatedAt = time.Time{}
+	}
+	return tx.Create(&sops).Error
+}
+
 // reorderInTransaction reorders a block within its current parent group
 func (r *blockRepo) reorderInTransaction(tx *gorm.DB, b *model.Block, targetSort int64) error {
-	if targetSort < 0 {
-		targetSort = 0
+	if targetSort < model.InitialSort {
+		targetSort = model.InitialSort
 	}
 	if targetSort == b.Sort {
 		return nil
 	}
+	// Archived blocks hold no position, so their sort only records where Restore
+	// puts them back and the group is left as it is
+	if b.IsArchived {
+		return tx.Model(&model.Block{}).Where(&model.Block{ID: b.ID}).Update("sort", targetSort).Error
+	}
+	// Serialize with appends and other reorders of the same group
+	if err := r.lockGroup(tx, b.SpaceID, b.ParentID); err != nil {
+		return err
+	}
 
 	// Set sentinel value to avoid conflicts
 	if err := tx.Model(&model.Block{}).Where(&model.Block{ID: b.ID}).Update("sort", math.MinInt64).Error; err != nil {
@@ -191,48 +1346,87 @@ func (r *blockRepo) reorderInTransaction(tx *gorm.DB, b *model.Block, targetSort
 
 // moveToNewParentInTransaction moves a block to a new parent group at a specific position
 func (r *blockRepo) moveToNewParentInTransaction(tx *gorm.DB, b *model.Block, id uuid.UUID, newParentID *uuid.UUID, targetSort int64) error {
-	// Get max sort in target group to normalize targetSort
-	var maxSort int64
-	q := r.buildGroupQuery(tx, b.SpaceID, newParentID).Select("COALESCE(MAX(sort), -1)")
-	if err := q.Take(&maxSort).Error; err != nil {
+	// Get next sort in target group to normalize targetSort
+	next, err := r.nextSortInGroup(tx, b.SpaceID, newParentID)
+	if err != nil {
 		return err
 	}
 
 	// Normalize targetSort
-	if targetSort < 0 {
-		targetSort = 0
+	if targetSort < model.InitialSort {
+		targetSort = model.InitialSort
 	}
-	if targetSort > maxSort+1 {
-		targetSort = maxSort + 1
+	if targetSort > next {
+		targetSort = next
 	}
 
-	// Set sentinel value to avoid conflicts
-	if err := tx.Model(&model.Block{}).Where(&model.Block{ID: id}).Update("sort", math.MinInt64).Error; err != nil {
-		return err
-	}
+	// Archived blocks hold no position, so they neither leave a gap in the old
+	// group nor take one in the new
+	if !b.IsArchived {
+		// Set sentinel value to avoid conflicts
+		if err := tx.Model(&model.Block{}).Where(&model.Block{ID: id}).Update("sort", math.MinInt64).Error; err != nil {
+			return err
+		}
 
-	// Close gap in old group
-	oldGroup := r.buildGroupQuery(tx, b.SpaceID, b.ParentID)
-	if err := oldGroup.Where("sort > ?", b.Sort).Update("sort", gorm.Expr("sort - 1")).Error; err != nil {
-		return err
-	}
+		// Close gap in old group
+		oldGroup := r.buildGroupQuery(tx, b.SpaceID, b.ParentID)
+		if err := oldGroup.Where("sort > ?", b.Sort).Update("sort", gorm.Expr("sort - 1")).Error; err != nil {
+			return err
+		}
 
-	// Make space in target group
-	newGroup := r.buildGroupQuery(tx, b.SpaceID, newParentID)
-	if err := newGroup.Where("sort >= ?", targetSort).Update("sort", gorm.Expr("sort + 1")).Error; err != nil {
-		return err
+		// Make space in target group
+		newGroup := r.buildGroupQuery(tx, b.SpaceID, newParentID)
+		if err := newGroup.Where("sort >= ?", targetSort).Update("sort", gorm.Expr("sort + 1")).Error; err != nil {
+			return err
+		}
 	}
 
 	// Move to new position
 	return tx.Model(&model.Block{}).Where(&model.Block{ID: id}).Updates(map[string]any{
 		"parent_id": newParentID,
 		"sort":      targetSort,
+		"moved_at":  gorm.Expr("now()"),
 	}).Error
 }
 
-// buildGroupQuery builds a query for blocks in the same group (same space_id and parent_id)
+// lockGroup takes a transaction-scoped Postgres advisory lock on the (space_id, parent_id)
+// group. It is released automatically on commit or rollback.
+func (r *blockRepo) lockGroup(tx *gorm.DB, spaceID uuid.UUID, parentID *uuid.UUID) error {
+	key := "blocks:" + spaceID.String() + ":"
+	if parentID != nil {
+		key += parentID.String()
+	}
+	return tx.Exec("SELECT pg_advisory_xact_lock(hashtextextended(?, 0))", key).Error
+}
+
+// nextSortInGroup returns the position after the last block of the group. An empty group
+// starts at model.InitialSort, so create and move agree on the first position.
+func (r *blockRepo) nextSortInGroup(tx *gorm.DB, spaceID uuid.UUID, parentID *uuid.UUID) (int64, error) {
+	var next int64
+	q := r.buildGroupQuery(tx, spaceID, parentID).Select("COALESCE(MAX(sort) + 1, ?)", model.InitialSort)
+	if err := q.Take(&next).Error; err != nil {
+		return 0, err
+	}
+	return next, nil
+}
+
+// withArchived scopes a block query to non-archived rows unless includeArchived is set.
+// Every read applies it explicitly so archived blocks are filtered the same way on all paths;
+// only Get, which addresses a single block by id, includes them. Soft-deleted blocks need no
+// scope: gorm leaves them out of every model.Block query, and raw SQL checks deleted_at itself.
+func withArchived(includeArchived bool) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if includeArchived {
+			return db
+		}
+		return db.Where("is_archived = ?", false)
+	}
+}
+
+// buildGroupQuery builds a query for the non-archived blocks in the same group (same space_id
+// and parent_id). Archived blocks hold no position, so sorts are computed and shifted without them.
 func (r *blockRepo) buildGroupQuery(tx *gorm.DB, spaceID uuid.UUID, parentID *uuid.UUID) *gorm.DB {
-	query := tx.Model(&model.Block{}).Where(&model.Block{SpaceID: spaceID})
+	query := tx.Model(&model.Block{}).Scopes(withArchived(false)).Where(&model.Block{SpaceID: spaceID})
 	if parentID == nil {
 		return query.Where("parent_id IS NULL")
 	}
@@ -2,7 +2,11 @@ package repo
 
 import (
 	"context"
+	"encoding/json"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/memodb-io/Acontext/internal/modules/model"
@@ -48,6 +52,25 @@ func cleanupTestDB(t *testing.T, db *gorm.DB, projectID uuid.UUID) {
 	db.Exec("DELETE FROM projects WHERE id = ?", projectID)
 }
 
+// createTestSpace creates a project and a space for integration tests and
+// registers their cleanup
+func createTestSpace(t *testing.T, db *gorm.DB) *model.Space {
+	project := &model.Project{
+		ID:               uuid.New(),
+		SecretKeyHMAC:    "test_hmac_" + uuid.NewString(),
+		SecretKeyHashPHC: "test_hash",
+	}
+	require.NoError(t, db.Create(project).Error)
+	t.Cleanup(func() { cleanupTestDB(t, db, project.ID) })
+
+	space := &model.Space{
+		ID:        uuid.New(),
+		ProjectID: project.ID,
+	}
+	require.NoError(t, db.Create(space).Error)
+	return space
+}
+
 // TestMergeToolSOPsIntoProps tests the merging logic without database
 func TestMergeToolSOPsIntoProps(t *testing.T) {
 	repo := &blockRepo{}
@@ -414,3 +437,1495 @@ func TestBlockRepo_GetNonSOPBlock(t *testing.T) {
 func strPtr(s string) *string {
 	return &s
 }
+
+// TestBlockRepo_ListBacklinks tests the jsonb containment backlink query
+func TestBlockRepo_ListBacklinks(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	target := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Target", Sort: 0}
+	other := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Other", Sort: 1}
+	require.NoError(t, repo.Create(ctx, target))
+	require.NoError(t, repo.Create(ctx, other))
+
+	linking := &model.Block{
+		ID:       uuid.New(),
+		SpaceID:  space.ID,
+		Type:     model.BlockTypeText,
+		ParentID: &other.ID,
+		Title:    "See target",
+		Props:    datatypes.NewJSONType(map[string]any{model.BlockPropLinks: []string{target.ID.String()}}),
+	}
+	require.NoError(t, repo.Create(ctx, linking))
+
+	// A link from another space is not a backlink of target
+	otherSpace := createTestSpace(t, db)
+	foreignPage := &model.Block{ID: uuid.New(), SpaceID: otherSpace.ID, Type: model.BlockTypePage, Title: "Foreign"}
+	require.NoError(t, repo.Create(ctx, foreignPage))
+	foreign := &model.Block{
+		ID:       uuid.New(),
+		SpaceID:  otherSpace.ID,
+		Type:     model.BlockTypeText,
+		ParentID: &foreignPage.ID,
+		Props:    datatypes.NewJSONType(map[string]any{model.BlockPropLinks: []string{target.ID.String()}}),
+	}
+	require.NoError(t, repo.Create(ctx, foreign))
+
+	backlinks, err := repo.ListBacklinks(ctx, space.ID, target.ID)
+	require.NoError(t, err)
+	require.Len(t, backlinks, 1)
+	assert.Equal(t, linking.ID, backlinks[0].ID)
+
+	backlinks, err = repo.ListBacklinks(ctx, space.ID, other.ID)
+	require.NoError(t, err)
+	assert.Empty(t, backlinks)
+}
+
+// TestBlockRepo_ListPurgeCandidates tests that only archived blocks older than the cutoff are returned
+func TestBlockRepo_ListPurgeCandidates(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	now := time.Now()
+	oldArchived := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "old archived", Sort: 0, IsArchived: true}
+	olderArchived := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "older archived", Sort: 1, IsArchived: true}
+	recentArchived := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "recent archived", Sort: 2, IsArchived: true}
+	oldActive := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "old active", Sort: 3}
+	for _, b := range []*model.Block{oldArchived, olderArchived, recentArchived, oldActive} {
+		require.NoError(t, repo.Create(ctx, b))
+	}
+
+	// Backdate updated_at directly, bypassing autoUpdateTime
+	setUpdatedAt := func(id uuid.UUID, at time.Time) {
+		require.NoError(t, db.Exec("UPDATE blocks SET updated_at = ? WHERE id = ?", at, id).Error)
+	}
+	setUpdatedAt(oldArchived.ID, now.Add(-48*time.Hour))
+	setUpdatedAt(olderArchived.ID, now.Add(-72*time.Hour))
+	setUpdatedAt(oldActive.ID, now.Add(-72*time.Hour))
+
+	candidates, err := repo.ListPurgeCandidates(ctx, now.Add(-24*time.Hour), 10)
+	require.NoError(t, err)
+
+	var ids []uuid.UUID
+	for _, b := range candidates {
+		if b.SpaceID == space.ID {
+			ids = append(ids, b.ID)
+		}
+	}
+	assert.Equal(t, []uuid.UUID{olderArchived.ID, oldArchived.ID}, ids)
+
+	limited, err := repo.ListPurgeCandidates(ctx, now.Add(-24*time.Hour), 1)
+	require.NoError(t, err)
+	assert.Len(t, limited, 1)
+}
+
+// TestBlockRepo_ListChildrenLite tests that the lite listing returns light fields in sort order
+func TestBlockRepo_ListChildrenLite(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	page := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Page", Sort: 0}
+	require.NoError(t, repo.Create(ctx, page))
+
+	big := datatypes.NewJSONType(map[string]any{"text": strings.Repeat("x", 1<<16)})
+	second := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &page.ID, Title: "Second", Sort: 1, Props: big}
+	first := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &page.ID, Title: "First", Sort: 0, Props: big}
+	require.NoError(t, repo.Create(ctx, second))
+	require.NoError(t, repo.Create(ctx, first))
+
+	children, err := repo.ListChildrenLite(ctx, page.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []model.BlockLite{
+		{ID: first.ID, Title: "First", Sort: 0, ParentID: &page.ID},
+		{ID: second.ID, Title: "Second", Sort: 1, ParentID: &page.ID},
+	}, children)
+
+	encoded, err := json.Marshal(children)
+	require.NoError(t, err)
+	assert.NotContains(t, string(encoded), "props")
+}
+
+func TestBlockRepo_ListChildrenByTypes(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	folder := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeFolder, Title: "Folder", Sort: 0}
+	require.NoError(t, repo.Create(ctx, folder))
+
+	page := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, ParentID: &folder.ID, Title: "Page", Sort: 0}
+	sub := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeFolder, ParentID: &folder.ID, Title: "Sub", Sort: 1}
+	other := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, ParentID: &folder.ID, Title: "Other", Sort: 2}
+	require.NoError(t, repo.Create(ctx, page))
+	require.NoError(t, repo.Create(ctx, sub))
+	require.NoError(t, repo.Create(ctx, other))
+
+	children, err := repo.ListChildrenByTypes(ctx, folder.ID, []string{model.BlockTypePage})
+	require.NoError(t, err)
+	require.Len(t, children, 2)
+	assert.Equal(t, page.ID, children[0].ID)
+	assert.Equal(t, other.ID, children[1].ID)
+}
+
+func TestBlockRepo_CountChildren(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	folder := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeFolder, Title: "Folder", Sort: 0}
+	require.NoError(t, repo.Create(ctx, folder))
+
+	active := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, ParentID: &folder.ID, Title: "Active", Sort: 0}
+	archived := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, ParentID: &folder.ID, Title: "Archived", Sort: 1}
+	deleted := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, ParentID: &folder.ID, Title: "Deleted", Sort: 2}
+	require.NoError(t, repo.Create(ctx, active))
+	require.NoError(t, repo.Create(ctx, archived))
+	require.NoError(t, repo.Create(ctx, deleted))
+	require.NoError(t, repo.Archive(ctx, archived.ID))
+	require.NoError(t, repo.Delete(ctx, space.ID, deleted.ID))
+
+	count, err := repo.CountChildren(ctx, folder.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestBlockRepo_DuplicateBlock(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	page := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Page", Sort: 0}
+	require.NoError(t, repo.Create(ctx, page))
+
+	first := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &page.ID, Title: "First", Sort: 0}
+	second := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &page.ID, Title: "Second", Sort: 1}
+	require.NoError(t, repo.Create(ctx, first))
+	require.NoError(t, repo.Create(ctx, second))
+
+	clone, err := repo.DuplicateBlock(ctx, first.ID)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ID, clone.ID)
+	assert.Equal(t, int64(1), clone.Sort)
+
+	children, err := repo.ListChildrenLite(ctx, page.ID)
+	require.NoError(t, err)
+	require.Len(t, children, 3)
+	assert.Equal(t, first.ID, children[0].ID)
+	assert.Equal(t, clone.ID, children[1].ID)
+	assert.Equal(t, "First", children[1].Title)
+	assert.Equal(t, second.ID, children[2].ID)
+	assert.Equal(t, int64(2), children[2].Sort)
+}
+
+func TestBlockRepo_DuplicateTo(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	folder := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeFolder, Title: "Folder", Sort: 0}
+	require.NoError(t, repo.Create(ctx, folder))
+	existing := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, ParentID: &folder.ID, Title: "Existing", Sort: 0}
+	require.NoError(t, repo.Create(ctx, existing))
+
+	page := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Page", Sort: 1,
+		Props: datatypes.NewJSONType(map[string]any{"tags": []any{"a"}})}
+	require.NoError(t, repo.Create(ctx, page))
+	first := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &page.ID, Title: "First", Sort: 0}
+	second := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &page.ID, Title: "Second", Sort: 1}
+	require.NoError(t, repo.Create(ctx, first))
+	require.NoError(t, repo.Create(ctx, second))
+
+	clone, err := repo.DuplicateTo(ctx, page.ID, &folder.ID, "Page (copy)")
+	require.NoError(t, err)
+	assert.NotEqual(t, page.ID, clone.ID)
+	assert.Equal(t, "Page (copy)", clone.Title)
+	assert.Equal(t, int64(1), clone.Sort)
+
+	children, err := repo.ListChildrenLite(ctx, clone.ID)
+	require.NoError(t, err)
+	require.Len(t, children, 2)
+	assert.Equal(t, "First", children[0].Title)
+	assert.Equal(t, "Second", children[1].Title)
+	assert.NotEqual(t, first.ID, children[0].ID)
+
+	original, err := repo.ListChildrenLite(ctx, page.ID)
+	require.NoError(t, err)
+	assert.Len(t, original, 2)
+}
+
+func TestBlockRepo_CloneBlock_IndependentProps(t *testing.T) {
+	parentID := uuid.New()
+	src := &model.Block{ID: uuid.New(), Type: model.BlockTypeText, Title: "Note",
+		Props: datatypes.NewJSONType(map[string]any{"meta": map[string]any{"color": "red"}, "tags": []any{"a"}})}
+
+	clone := (&blockRepo{}).cloneBlock(src, &parentID, 3)
+	assert.NotEqual(t, src.ID, clone.ID)
+	assert.Equal(t, src.Props.Data(), clone.Props.Data())
+
+	clone.Props.Data()["meta"].(map[string]any)["color"] = "blue"
+	clone.Props.Data()["tags"].([]any)[0] = "b"
+	assert.Equal(t, "red", src.Props.Data()["meta"].(map[string]any)["color"])
+	assert.Equal(t, "a", src.Props.Data()["tags"].([]any)[0])
+}
+
+func TestBlockRepo_ListChildrenAfter(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	page := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Page", Sort: 0}
+	require.NoError(t, repo.Create(ctx, page))
+	var children []*model.Block
+	for i := 0; i < 3; i++ {
+		child := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &page.ID, Title: "Child", Sort: int64(i)}
+		require.NoError(t, repo.Create(ctx, child))
+		children = append(children, child)
+	}
+
+	first, err := repo.ListChildrenAfter(ctx, page.ID, 0, uuid.Nil, 2)
+	require.NoError(t, err)
+	require.Len(t, first, 2)
+	assert.Equal(t, children[0].ID, first[0].ID)
+	assert.Equal(t, children[1].ID, first[1].ID)
+
+	rest, err := repo.ListChildrenAfter(ctx, page.ID, first[1].Sort, first[1].ID, 2)
+	require.NoError(t, err)
+	require.Len(t, rest, 1)
+	assert.Equal(t, children[2].ID, rest[0].ID)
+}
+
+// TestBlockRepo_NextSort_IgnoresArchived tests that archived siblings hold no position
+func TestBlockRepo_NextSort_IgnoresArchived(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	page := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Page", Sort: 0}
+	require.NoError(t, repo.Create(ctx, page))
+
+	active := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &page.ID, Title: "Active", Sort: 0}
+	archived := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &page.ID, Title: "Archived", Sort: 1, IsArchived: true}
+	require.NoError(t, repo.Create(ctx, active))
+	require.NoError(t, repo.Create(ctx, archived))
+
+	next, err := repo.NextSort(ctx, space.ID, &page.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), next)
+
+	// The freed position can be taken next to the archived block
+	appended := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &page.ID, Title: "Appended"}
+	require.NoError(t, repo.CreateAppend(ctx, appended))
+	assert.Equal(t, int64(1), appended.Sort)
+
+	list, err := repo.ListBySpace(ctx, space.ID, "", &page.ID)
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+
+	got, err := repo.Get(ctx, archived.ID)
+	require.NoError(t, err)
+	assert.True(t, got.IsArchived)
+}
+
+// TestBlockRepo_MoveIntoEmptyGroup tests that create and both move paths start an
+// empty group at model.InitialSort
+func TestBlockRepo_MoveIntoEmptyGroup(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	source := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Source", Sort: 0}
+	empty := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Empty", Sort: 1}
+	other := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Other", Sort: 2}
+	require.NoError(t, repo.Create(ctx, source))
+	require.NoError(t, repo.Create(ctx, empty))
+	require.NoError(t, repo.Create(ctx, other))
+
+	first := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &source.ID, Title: "First", Sort: 3}
+	second := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &source.ID, Title: "Second", Sort: 7}
+	require.NoError(t, repo.Create(ctx, first))
+	require.NoError(t, repo.Create(ctx, second))
+
+	next, err := repo.NextSort(ctx, space.ID, &empty.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.InitialSort, next)
+
+	require.NoError(t, repo.MoveToParentAppend(ctx, first.ID, &empty.ID))
+	moved, err := repo.Get(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.InitialSort, moved.Sort)
+
+	require.NoError(t, repo.MoveToParentAtSort(ctx, second.ID, &other.ID, 5))
+	moved, err = repo.Get(ctx, second.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.InitialSort, moved.Sort)
+}
+
+func TestBlockRepo_ListAncestorsBatch(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	folder := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeFolder, Title: "Folder", Sort: 0}
+	require.NoError(t, repo.Create(ctx, folder))
+	page := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, ParentID: &folder.ID, Title: "Page", Sort: 0}
+	require.NoError(t, repo.Create(ctx, page))
+	text := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &page.ID, Title: "Text", Sort: 0}
+	require.NoError(t, repo.Create(ctx, text))
+
+	chains, err := repo.ListAncestorsBatch(ctx, []uuid.UUID{folder.ID, page.ID, text.ID})
+	require.NoError(t, err)
+
+	ids := func(blocks []model.Block) []uuid.UUID {
+		out := make([]uuid.UUID, len(blocks))
+		for i, b := range blocks {
+			out[i] = b.ID
+		}
+		return out
+	}
+	assert.Empty(t, chains[folder.ID])
+	assert.Equal(t, []uuid.UUID{folder.ID}, ids(chains[page.ID]))
+	assert.Equal(t, []uuid.UUID{folder.ID, page.ID}, ids(chains[text.ID]))
+}
+
+// TestBlockRepo_CreateAppend_Concurrent tests that concurrent appends under one parent
+// serialize on the group lock and never hit a unique violation
+func TestBlockRepo_CreateAppend_Concurrent(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	page := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Page"}
+	require.NoError(t, repo.CreateAppend(ctx, page))
+
+	const workers = 20
+	var wg sync.WaitGroup
+	errs := make([]error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = repo.CreateAppend(ctx, &model.Block{
+				ID:       uuid.New(),
+				SpaceID:  space.ID,
+				Type:     model.BlockTypeText,
+				ParentID: &page.ID,
+				Title:    "Concurrent",
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+
+	children, err := repo.ListChildrenLite(ctx, page.ID)
+	require.NoError(t, err)
+	require.Len(t, children, workers)
+	for i, child := range children {
+		assert.Equal(t, int64(i), child.Sort)
+	}
+}
+
+func TestBlockRepo_CreateBatch_Concurrent(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	folder := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeFolder, Title: "Docs"}
+	require.NoError(t, repo.CreateAppend(ctx, folder))
+
+	// Each batch appends two roots under folder, one with a child of its own
+	const workers = 10
+	var wg sync.WaitGroup
+	errs := make([]error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			first := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeFolder, ParentID: &folder.ID, Title: "First"}
+			child := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, ParentID: &first.ID, Sort: 0}
+			second := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, ParentID: &folder.ID, Title: "Second"}
+			errs[i] = repo.CreateBatch(ctx, []*model.Block{first, child, second})
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+
+	children, err := repo.ListChildrenLite(ctx, folder.ID)
+	require.NoError(t, err)
+	require.Len(t, children, 2*workers)
+	for i, c := range children {
+		assert.Equal(t, int64(i), c.Sort)
+		// A batch's roots stay next to each other in slice order
+		if i%2 == 1 {
+			assert.Equal(t, "Second", c.Title)
+		}
+		if c.Title == "First" {
+			grandchildren, err := repo.ListChildrenLite(ctx, c.ID)
+			require.NoError(t, err)
+			require.Len(t, grandchildren, 1)
+			assert.Equal(t, model.InitialSort, grandchildren[0].Sort)
+		}
+	}
+}
+
+// TestBlockRepo_UpdatePage tests that a rename and a move are committed together
+func TestBlockRepo_UpdatePage(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	folder := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeFolder, Title: "Folder"}
+	sibling := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Sibling"}
+	page := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Old"}
+	for _, b := range []*model.Block{folder, sibling, page} {
+		require.NoError(t, repo.CreateAppend(ctx, b))
+	}
+	inFolder := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, ParentID: &folder.ID, Title: "In folder"}
+	require.NoError(t, repo.CreateAppend(ctx, inFolder))
+
+	title := "Renamed"
+	targetSort := int64(0)
+	require.NoError(t, repo.UpdatePage(ctx, page.ID, &title, &folder.ID, &targetSort))
+
+	updated, err := repo.Get(ctx, page.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", updated.Title)
+	require.NotNil(t, updated.ParentID)
+	assert.Equal(t, folder.ID, *updated.ParentID)
+	assert.Equal(t, int64(0), updated.Sort)
+
+	shifted, err := repo.Get(ctx, inFolder.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), shifted.Sort)
+
+	// A failing move rolls back the rename as well
+	missingParent := uuid.New()
+	other := "Not applied"
+	assert.Error(t, repo.UpdatePage(ctx, sibling.ID, &other, &missingParent, nil))
+	unchanged, err := repo.Get(ctx, sibling.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Sibling", unchanged.Title)
+	assert.Nil(t, unchanged.ParentID)
+}
+
+// TestBlockRepo_ListExistingIDs tests that only ids present in the space are returned
+func TestBlockRepo_ListExistingIDs(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+	otherSpace := createTestSpace(t, db)
+
+	inSpace := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "In space"}
+	elsewhere := &model.Block{ID: uuid.New(), SpaceID: otherSpace.ID, Type: model.BlockTypePage, Title: "Elsewhere"}
+	require.NoError(t, repo.CreateAppend(ctx, inSpace))
+	require.NoError(t, repo.CreateAppend(ctx, elsewhere))
+
+	found, err := repo.ListExistingIDs(ctx, space.ID, []uuid.UUID{inSpace.ID, elsewhere.ID, uuid.New()})
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{inSpace.ID}, found)
+}
+
+// TestBlockRepo_SubtreeTextStats tests aggregation over a small tree with known counts
+func TestBlockRepo_ListSubtree(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	folder := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeFolder, Title: "Docs", Sort: 0}
+	require.NoError(t, repo.Create(ctx, folder))
+	// Created out of order so the result order comes from sort alone
+	second := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, ParentID: &folder.ID, Title: "Second", Sort: 1}
+	first := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, ParentID: &folder.ID, Title: "First", Sort: 0}
+	require.NoError(t, repo.Create(ctx, second))
+	require.NoError(t, repo.Create(ctx, first))
+	textB := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &first.ID, Title: "B", Sort: 1}
+	textA := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &first.ID, Title: "A", Sort: 0}
+	archived := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &second.ID, Title: "Gone", Sort: 0, IsArchived: true}
+	for _, b := range []*model.Block{textB, textA, archived} {
+		require.NoError(t, repo.Create(ctx, b))
+	}
+
+	ids := func(list []model.Block) []uuid.UUID {
+		out := make([]uuid.UUID, len(list))
+		for i, b := range list {
+			out[i] = b.ID
+		}
+		return out
+	}
+
+	t.Run("unlimited depth", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			list, err := repo.ListSubtree(ctx, folder.ID, 0)
+			require.NoError(t, err)
+			assert.Equal(t, []uuid.UUID{first.ID, second.ID, textA.ID, textB.ID}, ids(list))
+		}
+	})
+
+	t.Run("depth limited", func(t *testing.T) {
+		list, err := repo.ListSubtree(ctx, folder.ID, 1)
+		require.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{first.ID, second.ID}, ids(list))
+
+		list, err = repo.ListSubtree(ctx, folder.ID, 2)
+		require.NoError(t, err)
+		assert.Len(t, list, 4)
+	})
+}
+
+func TestBlockRepo_SubtreeTextStats(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	page := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Guide"} // 5 chars, 1 word
+	require.NoError(t, repo.CreateAppend(ctx, page))
+
+	blocks := []*model.Block{
+		// 5 + 11 chars, 1 + 2 words
+		{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &page.ID, Title: "Intro",
+			Props: datatypes.NewJSONType(map[string]any{"text": "hello world"})},
+		// 0 + 9 chars, 0 + 2 words
+		{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &page.ID, Title: "",
+			Props: datatypes.NewJSONType(map[string]any{"text": "two  more"})},
+		// archived: ignored
+		{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &page.ID, Title: "Old", IsArchived: true,
+			Props: datatypes.NewJSONType(map[string]any{"text": "gone"})},
+	}
+	for _, b := range blocks {
+		require.NoError(t, repo.CreateAppend(ctx, b))
+	}
+
+	chars, words, count, err := repo.SubtreeTextStats(ctx, page.ID, false)
+	require.NoError(t, err)
+	assert.Equal(t, 5+5+11+9, chars)
+	assert.Equal(t, 1+3+2, words)
+	assert.Equal(t, 3, count)
+
+	chars, words, count, err = repo.SubtreeTextStats(ctx, page.ID, true)
+	require.NoError(t, err)
+	assert.Equal(t, 5+5+11+9+3+4, chars)
+	assert.Equal(t, 1+3+2+2, words)
+	assert.Equal(t, 4, count)
+}
+
+// TestBlockRepo_MoveToParentAtSort_RootTop tests that a nested page moved to root sort 0 comes first
+func TestBlockRepo_MoveToParentAtSort_RootTop(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	first := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "First"}
+	folder := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeFolder, Title: "Folder"}
+	require.NoError(t, repo.CreateAppend(ctx, first))
+	require.NoError(t, repo.CreateAppend(ctx, folder))
+	nested := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, ParentID: &folder.ID, Title: "Nested"}
+	require.NoError(t, repo.CreateAppend(ctx, nested))
+
+	require.NoError(t, repo.MoveToParentAtSort(ctx, nested.ID, nil, 0))
+
+	roots, err := repo.ListBySpace(ctx, space.ID, model.BlockTypePage, nil)
+	require.NoError(t, err)
+	require.Len(t, roots, 2)
+	assert.Equal(t, nested.ID, roots[0].ID)
+	assert.Equal(t, int64(0), roots[0].Sort)
+	assert.Equal(t, first.ID, roots[1].ID)
+}
+
+func TestBlockRepo_UpdatePropsWhere(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	page := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Page", Sort: 0}
+	require.NoError(t, repo.Create(ctx, page))
+
+	todo := func(sort int64, done bool) *model.Block {
+		b := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &page.ID, Sort: sort,
+			Props: datatypes.NewJSONType(map[string]any{"kind": "todo", "done": done, "text": "item"})}
+		require.NoError(t, repo.Create(ctx, b))
+		return b
+	}
+	open1 := todo(0, false)
+	open2 := todo(1, false)
+	closed := todo(2, true)
+	note := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &page.ID, Sort: 3,
+		Props: datatypes.NewJSONType(map[string]any{"text": "note"})}
+	require.NoError(t, repo.Create(ctx, note))
+
+	n, err := repo.UpdatePropsWhere(ctx, space.ID, page.ID,
+		map[string]any{"kind": "todo", "done": false},
+		map[string]any{"done": true})
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	for _, b := range []*model.Block{open1, open2, closed} {
+		got, err := repo.Get(ctx, b.ID)
+		require.NoError(t, err)
+		assert.Equal(t, true, got.Props.Data()["done"])
+		assert.Equal(t, "item", got.Props.Data()["text"])
+	}
+	got, err := repo.Get(ctx, note.ID)
+	require.NoError(t, err)
+	assert.NotContains(t, got.Props.Data(), "done")
+}
+
+func TestBlockRepo_Restore(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	page := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Page", Sort: 0, IsArchived: true}
+	require.NoError(t, repo.Create(ctx, page))
+	text := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &page.ID, Title: "Text", Sort: 0, IsArchived: true}
+	require.NoError(t, repo.Create(ctx, text))
+	// Takes the archived page's position in the meantime
+	other := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Other", Sort: 0}
+	require.NoError(t, repo.Create(ctx, other))
+
+	require.NoError(t, repo.Restore(ctx, []uuid.UUID{page.ID, text.ID}))
+
+	gotPage, err := repo.Get(ctx, page.ID)
+	require.NoError(t, err)
+	assert.False(t, gotPage.IsArchived)
+	assert.Equal(t, int64(0), gotPage.Sort)
+
+	gotOther, err := repo.Get(ctx, other.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), gotOther.Sort)
+
+	gotText, err := repo.Get(ctx, text.ID)
+	require.NoError(t, err)
+	assert.False(t, gotText.IsArchived)
+	assert.Equal(t, int64(0), gotText.Sort)
+}
+
+func TestBlockRepo_SoftDelete(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	page := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Page", Sort: 0}
+	require.NoError(t, repo.Create(ctx, page))
+	text := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &page.ID, Title: "Text", Sort: 0}
+	require.NoError(t, repo.Create(ctx, text))
+
+	require.NoError(t, repo.Delete(ctx, space.ID, page.ID))
+
+	// The page and its subtree are hidden from reads but still stored
+	_, err := repo.Get(ctx, page.ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, err = repo.Get(ctx, text.ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	listed, err := repo.ListBySpace(ctx, space.ID, model.BlockTypePage, nil)
+	require.NoError(t, err)
+	assert.Empty(t, listed)
+
+	var stored int64
+	require.NoError(t, db.Unscoped().Model(&model.Block{}).Where("space_id = ?", space.ID).Count(&stored).Error)
+	assert.Equal(t, int64(2), stored)
+
+	// A deleted block holds no position
+	next := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Next"}
+	require.NoError(t, repo.CreateAppend(ctx, next))
+	assert.Equal(t, model.InitialSort, next.Sort)
+
+	purged, err := repo.PurgeDeleted(ctx, space.ID, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 0, purged)
+
+	purged, err = repo.PurgeDeleted(ctx, space.ID, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Positive(t, purged)
+
+	require.NoError(t, db.Unscoped().Model(&model.Block{}).Where("space_id = ?", space.ID).Count(&stored).Error)
+	assert.Equal(t, int64(1), stored)
+}
+
+func TestBlockRepo_DeleteTree(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	page := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Page"}
+	require.NoError(t, repo.CreateAppend(ctx, page))
+	sub := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, ParentID: &page.ID, Title: "Sub"}
+	require.NoError(t, repo.CreateAppend(ctx, sub))
+	text := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &sub.ID}
+	require.NoError(t, repo.CreateAppend(ctx, text))
+	archived := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &page.ID}
+	require.NoError(t, repo.CreateAppend(ctx, archived))
+	require.NoError(t, repo.Archive(ctx, archived.ID))
+
+	// A locked descendant keeps the whole tree
+	require.NoError(t, repo.SetLocked(ctx, text.ID, true, false))
+	_, err := repo.DeleteTree(ctx, space.ID, page.ID)
+	require.ErrorIs(t, err, ErrLockedDescendant)
+	_, err = repo.Get(ctx, sub.ID)
+	require.NoError(t, err)
+
+	require.NoError(t, repo.SetLocked(ctx, text.ID, false, false))
+	n, err := repo.DeleteTree(ctx, space.ID, page.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 4, n)
+	for _, blk := range []*model.Block{page, sub, text, archived} {
+		_, err := repo.Get(ctx, blk.ID)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	}
+}
+
+func TestBlockRepo_Delete_LockedDescendant(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	page := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Page"}
+	require.NoError(t, repo.CreateAppend(ctx, page))
+	text := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &page.ID}
+	require.NoError(t, repo.CreateAppend(ctx, text))
+	require.NoError(t, repo.SetLocked(ctx, text.ID, true, false))
+
+	require.ErrorIs(t, repo.Delete(ctx, space.ID, page.ID), ErrLockedDescendant)
+	_, err := repo.Get(ctx, page.ID)
+	require.NoError(t, err)
+	_, err = repo.Get(ctx, text.ID)
+	require.NoError(t, err)
+}
+
+func TestBlockRepo_SetLocked_SkipsDeleted(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	page := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Page"}
+	require.NoError(t, repo.CreateAppend(ctx, page))
+	text := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &page.ID}
+	require.NoError(t, repo.CreateAppend(ctx, text))
+	deleted := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &page.ID}
+	require.NoError(t, repo.CreateAppend(ctx, deleted))
+	require.NoError(t, repo.Delete(ctx, space.ID, deleted.ID))
+
+	require.NoError(t, repo.SetLocked(ctx, page.ID, true, true))
+
+	got, err := repo.Get(ctx, text.ID)
+	require.NoError(t, err)
+	assert.True(t, got.IsLocked)
+
+	var stored model.Block
+	require.NoError(t, db.Unscoped().Where("id = ?", deleted.ID).First(&stored).Error)
+	assert.False(t, stored.IsLocked)
+}
+
+func TestBlockRepo_ListArchived(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	page := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Page", Sort: 0}
+	other := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Other", Sort: 1}
+	require.NoError(t, repo.Create(ctx, page))
+	require.NoError(t, repo.Create(ctx, other))
+
+	require.NoError(t, repo.Archive(ctx, page.ID))
+
+	listed, err := repo.ListBySpace(ctx, space.ID, model.BlockTypePage, nil)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, other.ID, listed[0].ID)
+
+	trash, err := repo.ListArchived(ctx, space.ID, model.BlockTypePage)
+	require.NoError(t, err)
+	require.Len(t, trash, 1)
+	assert.Equal(t, page.ID, trash[0].ID)
+
+	require.NoError(t, repo.Restore(ctx, []uuid.UUID{page.ID}))
+
+	listed, err = repo.ListBySpace(ctx, space.ID, model.BlockTypePage, nil)
+	require.NoError(t, err)
+	assert.Len(t, listed, 2)
+
+	trash, err = repo.ListArchived(ctx, space.ID, model.BlockTypePage)
+	require.NoError(t, err)
+	assert.Empty(t, trash)
+}
+
+func TestBlockRepo_ReorderChildren(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	page := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Page"}
+	require.NoError(t, repo.CreateAppend(ctx, page))
+	ids := make([]uuid.UUID, 3)
+	for i := range ids {
+		child := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &page.ID}
+		require.NoError(t, repo.CreateAppend(ctx, child))
+		ids[i] = child.ID
+	}
+
+	assertOrder := func(t *testing.T, want []uuid.UUID) {
+		children, err := repo.ListChildrenLite(ctx, page.ID)
+		require.NoError(t, err)
+		require.Len(t, children, len(want))
+		for i, child := range children {
+			assert.Equal(t, want[i], child.ID)
+			assert.Equal(t, int64(i), child.Sort)
+		}
+	}
+
+	t.Run("full reorder", func(t *testing.T) {
+		order := []uuid.UUID{ids[2], ids[0], ids[1]}
+		require.NoError(t, repo.ReorderChildren(ctx, space.ID, page.ID, order))
+		assertOrder(t, order)
+	})
+
+	t.Run("omitted child", func(t *testing.T) {
+		err := repo.ReorderChildren(ctx, space.ID, page.ID, []uuid.UUID{ids[0], ids[1]})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "missing from the order")
+		assert.Contains(t, err.Error(), ids[2].String())
+		assertOrder(t, []uuid.UUID{ids[2], ids[0], ids[1]})
+	})
+
+	t.Run("foreign ids", func(t *testing.T) {
+		foreign := []uuid.UUID{uuid.New(), page.ID}
+		err := repo.ReorderChildren(ctx, space.ID, page.ID, append([]uuid.UUID{ids[0], ids[1], ids[2]}, foreign...))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "not children of")
+		for _, id := range foreign {
+			assert.Contains(t, err.Error(), id.String())
+		}
+		assertOrder(t, []uuid.UUID{ids[2], ids[0], ids[1]})
+	})
+
+	t.Run("duplicate id", func(t *testing.T) {
+		err := repo.ReorderChildren(ctx, space.ID, page.ID, []uuid.UUID{ids[0], ids[1], ids[2], ids[0]})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "listed twice")
+	})
+}
+
+// TestBlockRepo_ReorderChildren_ConcurrentInsert tests that a reorder and an append under
+// the same parent serialize: either the reorder applies in full and the new child ends up
+// last, or the new child came first and the reorder fails as incomplete
+func TestBlockRepo_ReorderChildren_ConcurrentInsert(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	for round := 0; round < 10; round++ {
+		page := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Page"}
+		require.NoError(t, repo.CreateAppend(ctx, page))
+
+		const n = 5
+		ids := make([]uuid.UUID, n)
+		for i := range ids {
+			child := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &page.ID}
+			require.NoError(t, repo.CreateAppend(ctx, child))
+			ids[i] = child.ID
+		}
+		reversed := make([]uuid.UUID, n)
+		for i, id := range ids {
+			reversed[n-1-i] = id
+		}
+		inserted := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &page.ID}
+
+		var wg sync.WaitGroup
+		var reorderErr, insertErr error
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			reorderErr = repo.ReorderChildren(ctx, space.ID, page.ID, reversed)
+		}()
+		go func() {
+			defer wg.Done()
+			insertErr = repo.CreateAppend(ctx, inserted)
+		}()
+		wg.Wait()
+		require.NoError(t, insertErr)
+
+		want := reversed
+		if reorderErr != nil {
+			assert.Contains(t, reorderErr.Error(), inserted.ID.String())
+			want = ids
+		}
+
+		children, err := repo.ListChildrenLite(ctx, page.ID)
+		require.NoError(t, err)
+		require.Len(t, children, n+1)
+		for i, child := range children {
+			assert.Equal(t, int64(i), child.Sort)
+			if i < n {
+				assert.Equal(t, want[i], child.ID)
+			}
+		}
+		assert.Equal(t, inserted.ID, children[n].ID)
+	}
+}
+
+func TestBlockRepo_MoveToSpace(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	source := createTestSpace(t, db)
+	target := createTestSpace(t, db)
+
+	page := &model.Block{ID: uuid.New(), SpaceID: source.ID, Type: model.BlockTypePage, Title: "Page"}
+	sibling := &model.Block{ID: uuid.New(), SpaceID: source.ID, Type: model.BlockTypePage, Title: "Sibling"}
+	require.NoError(t, repo.CreateAppend(ctx, page))
+	require.NoError(t, repo.CreateAppend(ctx, sibling))
+	texts := make([]*model.Block, 2)
+	for i := range texts {
+		texts[i] = &model.Block{ID: uuid.New(), SpaceID: source.ID, Type: model.BlockTypeText, ParentID: &page.ID, Title: "Text"}
+		require.NoError(t, repo.CreateAppend(ctx, texts[i]))
+	}
+
+	folder := &model.Block{ID: uuid.New(), SpaceID: target.ID, Type: model.BlockTypeFolder, Title: "Folder"}
+	require.NoError(t, repo.CreateAppend(ctx, folder))
+	existing := &model.Block{ID: uuid.New(), SpaceID: target.ID, Type: model.BlockTypePage, ParentID: &folder.ID, Title: "Existing"}
+	require.NoError(t, repo.CreateAppend(ctx, existing))
+
+	require.NoError(t, repo.MoveToSpace(ctx, page.ID, target.ID, &folder.ID))
+
+	got, err := repo.Get(ctx, page.ID)
+	require.NoError(t, err)
+	assert.Equal(t, target.ID, got.SpaceID)
+	require.NotNil(t, got.ParentID)
+	assert.Equal(t, folder.ID, *got.ParentID)
+	assert.Equal(t, int64(1), got.Sort)
+	assert.NotNil(t, got.MovedAt)
+
+	for i, text := range texts {
+		got, err := repo.Get(ctx, text.ID)
+		require.NoError(t, err)
+		assert.Equal(t, target.ID, got.SpaceID)
+		assert.Equal(t, page.ID, *got.ParentID)
+		assert.Equal(t, int64(i), got.Sort)
+	}
+
+	// The source space is left with the sibling, moved up to close the gap
+	remaining, err := repo.ListAllBySpace(ctx, source.ID)
+	require.NoError(t, err)
+	require.Len(t, remaining, 1)
+	assert.Equal(t, sibling.ID, remaining[0].ID)
+	assert.Equal(t, int64(0), remaining[0].Sort)
+
+	// A space that does not exist is rejected before anything moves
+	err = repo.MoveToSpace(ctx, sibling.ID, uuid.New(), nil)
+	assert.ErrorIs(t, err, ErrSpaceNotFound)
+}
+
+func TestBlockRepo_ListSubtreeChangedSince(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	page := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Page", Sort: 0}
+	require.NoError(t, repo.Create(ctx, page))
+	sub := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, ParentID: &page.ID, Title: "Sub", Sort: 0}
+	require.NoError(t, repo.Create(ctx, sub))
+	text := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &page.ID, Title: "Text", Sort: 1}
+	require.NoError(t, repo.Create(ctx, text))
+
+	since := time.Now()
+	time.Sleep(10 * time.Millisecond)
+
+	require.NoError(t, repo.MoveToParentAppend(ctx, text.ID, &sub.ID))
+
+	changed, err := repo.ListSubtreeChangedSince(ctx, page.ID, since)
+	require.NoError(t, err)
+	require.Len(t, changed, 1)
+	assert.Equal(t, text.ID, changed[0].ID)
+	require.NotNil(t, changed[0].MovedAt)
+	assert.True(t, changed[0].MovedAt.After(since))
+
+	// Deleting sub soft-deletes text with it; both are still reported
+	require.NoError(t, repo.Delete(ctx, space.ID, sub.ID))
+
+	changed, err = repo.ListSubtreeChangedSince(ctx, page.ID, since)
+	require.NoError(t, err)
+	require.Len(t, changed, 2)
+	for _, b := range changed {
+		assert.True(t, b.DeletedAt.Valid)
+	}
+}
+
+// TestBlockRepo_ArchiveAppendRestore tests that appending after an archive and then
+// restoring the archived block keeps the active sorts contiguous without collisions
+func TestBlockRepo_ArchiveAppendRestore(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	page := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Page"}
+	require.NoError(t, repo.CreateAppend(ctx, page))
+	newChild := func(title string) *model.Block {
+		b := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &page.ID, Title: title}
+		require.NoError(t, repo.CreateAppend(ctx, b))
+		return b
+	}
+	a := newChild("A")
+	b := newChild("B")
+	c := newChild("C")
+
+	require.NoError(t, repo.Archive(ctx, b.ID))
+	d := newChild("D")
+	require.NoError(t, repo.Restore(ctx, []uuid.UUID{b.ID}))
+
+	children, err := repo.ListChildrenLite(ctx, page.ID)
+	require.NoError(t, err)
+	require.Len(t, children, 4)
+	for i, want := range []*model.Block{a, b, c, d} {
+		assert.Equal(t, want.ID, children[i].ID)
+		assert.Equal(t, int64(i), children[i].Sort)
+	}
+}
+
+func TestBlockRepo_ArchiveSubtree(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	page := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Page"}
+	require.NoError(t, repo.CreateAppend(ctx, page))
+	sub := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, ParentID: &page.ID, Title: "Sub"}
+	require.NoError(t, repo.CreateAppend(ctx, sub))
+	text := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &sub.ID,
+		Props: datatypes.NewJSONType(map[string]any{"text": "quarterly roadmap"})}
+	require.NoError(t, repo.CreateAppend(ctx, text))
+
+	require.NoError(t, repo.Archive(ctx, page.ID))
+
+	for _, blk := range []*model.Block{page, sub, text} {
+		got, err := repo.Get(ctx, blk.ID)
+		require.NoError(t, err)
+		assert.True(t, got.IsArchived, got.ID)
+	}
+
+	children, err := repo.ListChildrenLite(ctx, page.ID)
+	require.NoError(t, err)
+	assert.Empty(t, children)
+
+	found, err := repo.SearchBlocks(ctx, space.ID, "roadmap", SearchOptions{WithinPageID: &page.ID})
+	require.NoError(t, err)
+	assert.Empty(t, found)
+}
+
+func TestBlockRepo_ArchiveRestoreSubtree(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	newBlock := func(blockType string, parentID *uuid.UUID) *model.Block {
+		b := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: blockType, ParentID: parentID}
+		require.NoError(t, repo.CreateAppend(ctx, b))
+		return b
+	}
+	folder := newBlock(model.BlockTypeFolder, nil)
+	page := newBlock(model.BlockTypePage, &folder.ID)
+	text := newBlock(model.BlockTypeText, &page.ID)
+	earlier := newBlock(model.BlockTypePage, &folder.ID)
+	locked := newBlock(model.BlockTypePage, &folder.ID)
+	lockedText := newBlock(model.BlockTypeText, &locked.ID)
+	require.NoError(t, db.Model(&model.Block{}).Where("id = ?", locked.ID).Update("is_locked", true).Error)
+
+	// earlier is archived on its own before its parent
+	require.NoError(t, repo.Archive(ctx, earlier.ID))
+	require.NoError(t, repo.Archive(ctx, folder.ID))
+
+	isArchived := func(b *model.Block) bool {
+		got, err := repo.Get(ctx, b.ID)
+		require.NoError(t, err)
+		return got.IsArchived
+	}
+	for _, blk := range []*model.Block{folder, page, text, earlier} {
+		assert.True(t, isArchived(blk), blk.ID)
+	}
+	// Locked descendants stay active with their subtrees
+	assert.False(t, isArchived(locked))
+	assert.False(t, isArchived(lockedText))
+
+	require.NoError(t, repo.Restore(ctx, []uuid.UUID{folder.ID}))
+	for _, blk := range []*model.Block{folder, page, text} {
+		assert.False(t, isArchived(blk), blk.ID)
+	}
+	assert.True(t, isArchived(earlier))
+
+	children, err := repo.ListChildrenLite(ctx, folder.ID)
+	require.NoError(t, err)
+	require.Len(t, children, 2)
+	assert.Equal(t, page.ID, children[0].ID)
+	assert.Equal(t, locked.ID, children[1].ID)
+}
+
+func TestBlockRepo_MoveArchived(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	page := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Page"}
+	require.NoError(t, repo.CreateAppend(ctx, page))
+	other := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Other"}
+	require.NoError(t, repo.CreateAppend(ctx, other))
+	newChild := func(title string) *model.Block {
+		b := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &page.ID, Title: title}
+		require.NoError(t, repo.CreateAppend(ctx, b))
+		return b
+	}
+	a := newChild("A")
+	b := newChild("B")
+	c := newChild("C")
+	d := newChild("D")
+
+	// B's sort now equals C's, so shifting around it would collide
+	require.NoError(t, repo.Archive(ctx, b.ID))
+	require.NoError(t, repo.MoveToParentAtSort(ctx, b.ID, &page.ID, 3))
+	require.NoError(t, repo.MoveToParentAtSort(ctx, b.ID, &other.ID, 0))
+
+	children, err := repo.ListChildrenLite(ctx, page.ID)
+	require.NoError(t, err)
+	require.Len(t, children, 3)
+	for i, want := range []*model.Block{a, c, d} {
+		assert.Equal(t, want.ID, children[i].ID)
+		assert.Equal(t, int64(i), children[i].Sort)
+	}
+
+	got, err := repo.Get(ctx, b.ID)
+	require.NoError(t, err)
+	assert.True(t, got.IsArchived)
+	assert.Equal(t, &other.ID, got.ParentID)
+}
+
+func TestBlockRepo_SearchBlocks_WithinPage(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	project := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Project", Sort: 0}
+	require.NoError(t, repo.Create(ctx, project))
+	other := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Other", Sort: 1}
+	require.NoError(t, repo.Create(ctx, other))
+	sub := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, ParentID: &project.ID, Title: "Sub", Sort: 0}
+	require.NoError(t, repo.Create(ctx, sub))
+
+	inside := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &sub.ID, Sort: 0,
+		Props: datatypes.NewJSONType(map[string]any{"text": "quarterly roadmap draft"})}
+	require.NoError(t, repo.Create(ctx, inside))
+	outside := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &other.ID, Sort: 0,
+		Props: datatypes.NewJSONType(map[string]any{"text": "another roadmap"})}
+	require.NoError(t, repo.Create(ctx, outside))
+
+	all, err := repo.SearchBlocks(ctx, space.ID, "roadmap", SearchOptions{})
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	scoped, err := repo.SearchBlocks(ctx, space.ID, "roadmap", SearchOptions{WithinPageID: &project.ID})
+	require.NoError(t, err)
+	require.Len(t, scoped, 1)
+	assert.Equal(t, inside.ID, scoped[0].ID)
+}
+
+func TestBlockRepo_ConvertType(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	page := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Page"}
+	require.NoError(t, repo.CreateAppend(ctx, page))
+	a := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &page.ID, Title: "A"}
+	require.NoError(t, repo.CreateAppend(ctx, a))
+	b := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &page.ID, Title: "B"}
+	require.NoError(t, repo.CreateAppend(ctx, b))
+
+	// A becomes a page at the root, after the existing one, and B closes the gap
+	require.NoError(t, repo.ConvertType(ctx, a.ID, model.BlockTypePage, nil))
+	converted, err := repo.Get(ctx, a.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.BlockTypePage, converted.Type)
+	assert.Nil(t, converted.ParentID)
+	assert.Equal(t, int64(1), converted.Sort)
+	assert.NotNil(t, converted.MovedAt)
+
+	children, err := repo.ListChildrenLite(ctx, page.ID)
+	require.NoError(t, err)
+	require.Len(t, children, 1)
+	assert.Equal(t, b.ID, children[0].ID)
+	assert.Equal(t, int64(0), children[0].Sort)
+
+	// A page with children cannot become a text block
+	assert.Error(t, repo.ConvertType(ctx, page.ID, model.BlockTypeText, nil))
+
+	// And back: the now empty page A becomes a text block under page again
+	require.NoError(t, repo.ConvertType(ctx, a.ID, model.BlockTypeText, &page.ID))
+	converted, err = repo.Get(ctx, a.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.BlockTypeText, converted.Type)
+	require.NotNil(t, converted.ParentID)
+	assert.Equal(t, page.ID, *converted.ParentID)
+	assert.Equal(t, int64(1), converted.Sort)
+}
+
+func TestBlockRepo_CreateAt_BetweenSiblings(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	page := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Page"}
+	require.NoError(t, repo.CreateAppend(ctx, page))
+	newChild := func(title string) *model.Block {
+		return &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &page.ID, Title: title}
+	}
+
+	a, b, c := newChild("A"), newChild("B"), newChild("C")
+	for _, blk := range []*model.Block{a, b, c} {
+		require.NoError(t, repo.CreateAppend(ctx, blk))
+	}
+
+	// Insert between adjacent neighbors
+	ab := newChild("AB")
+	require.NoError(t, repo.CreateAt(ctx, ab, InsertPosition{AfterID: &a.ID, BeforeID: &b.ID}))
+	children, err := repo.ListChildrenLite(ctx, page.ID)
+	require.NoError(t, err)
+	require.Len(t, children, 4)
+	for i, want := range []*model.Block{a, ab, b, c} {
+		assert.Equal(t, want.ID, children[i].ID)
+		assert.Equal(t, int64(i), children[i].Sort)
+	}
+
+	// Anchors that are not adjacent name no single position
+	assert.Error(t, repo.CreateAt(ctx, newChild("X"), InsertPosition{AfterID: &a.ID, BeforeID: &c.ID}))
+}
+
+func TestBlockRepo_ArchiveWhere(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	newBlock := func(blockType string, parentID *uuid.UUID, props map[string]any) *model.Block {
+		b := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: blockType, ParentID: parentID,
+			Props: datatypes.NewJSONType(props)}
+		require.NoError(t, repo.CreateAppend(ctx, b))
+		return b
+	}
+	done := map[string]any{"status": "done"}
+	open := map[string]any{"status": "open"}
+
+	page := newBlock(model.BlockTypePage, nil, map[string]any{})
+	a := newBlock(model.BlockTypePage, &page.ID, done)
+	aChild := newBlock(model.BlockTypeText, &a.ID, open)
+	aDone := newBlock(model.BlockTypeText, &a.ID, done)
+	b := newBlock(model.BlockTypeText, &page.ID, open)
+	c := newBlock(model.BlockTypeText, &page.ID, done)
+	d := newBlock(model.BlockTypeText, &page.ID, open)
+
+	n, err := repo.ArchiveWhere(ctx, space.ID, done)
+	require.NoError(t, err)
+	assert.Equal(t, 4, n) // a, its two children and c
+
+	for _, blk := range []*model.Block{a, aChild, aDone, c} {
+		got, err := repo.Get(ctx, blk.ID)
+		require.NoError(t, err)
+		assert.True(t, got.IsArchived, got.ID)
+	}
+
+	// b and d remain active, renumbered without gaps
+	children, err := repo.ListChildrenLite(ctx, page.ID)
+	require.NoError(t, err)
+	require.Len(t, children, 2)
+	for i, want := range []*model.Block{b, d} {
+		assert.Equal(t, want.ID, children[i].ID)
+		assert.Equal(t, int64(i), children[i].Sort)
+	}
+}
+
+func TestBlockRepo_CreateAt(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db)
+	ctx := context.Background()
+	space := createTestSpace(t, db)
+
+	page := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Page"}
+	require.NoError(t, repo.CreateAppend(ctx, page))
+	newChild := func(title string) *model.Block {
+		return &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, ParentID: &page.ID, Title: title}
+	}
+	a := newChild("A")
+	require.NoError(t, repo.CreateAppend(ctx, a))
+	c := newChild("C")
+	require.NoError(t, repo.CreateAppend(ctx, c))
+
+	b := newChild("B")
+	require.NoError(t, repo.CreateAt(ctx, b, InsertPosition{AfterID: &a.ID}))
+	first := newChild("First")
+	require.NoError(t, repo.CreateAt(ctx, first, InsertPosition{BeforeID: &a.ID}))
+
+	children, err := repo.ListChildrenLite(ctx, page.ID)
+	require.NoError(t, err)
+	require.Len(t, children, 4)
+	for i, want := range []*model.Block{first, a, b, c} {
+		assert.Equal(t, want.ID, children[i].ID)
+		assert.Equal(t, int64(i), children[i].Sort)
+	}
+
+	// An anchor under another parent is rejected
+	other := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Other"}
+	require.NoError(t, repo.CreateAppend(ctx, other))
+	assert.Error(t, repo.CreateAt(ctx, newChild("X"), InsertPosition{AfterID: &other.ID}))
+}
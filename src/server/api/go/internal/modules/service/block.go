@@ -3,22 +3,39 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/memodb-io/Acontext/internal/modules/model"
 	"github.com/memodb-io/Acontext/internal/modules/repo"
+	"github.com/memodb-io/Acontext/internal/pkg/metrics"
+	"github.com/memodb-io/Acontext/internal/pkg/paging"
+	"github.com/memodb-io/Acontext/internal/pkg/propschema"
+	"gorm.io/datatypes"
 )
 
 type BlockService interface {
 	// Create - unified method, handles special logic for folder path
 	Create(ctx context.Context, b *model.Block) error
+	// CreateAt is Create at a given position among the siblings instead of last
+	CreateAt(ctx context.Context, b *model.Block, pos InsertPosition) error
 
 	// Delete - unified method
 	Delete(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID) error
 
+	// DeletePageRecursive deletes a page and its descendants at once, returning how many were deleted
+	DeletePageRecursive(ctx context.Context, spaceID uuid.UUID, pageID uuid.UUID) (int, error)
+
+	// PurgeDeleted permanently removes the blocks of a space deleted before olderThan, returning how many were removed
+	PurgeDeleted(ctx context.Context, spaceID uuid.UUID, olderThan time.Time) (int, error)
+
 	// Properties - unified methods
 	GetBlockProperties(ctx context.Context, blockID uuid.UUID) (*model.Block, error)
 	UpdateBlockProperties(ctx context.Context, b *model.Block) error
+	// ValidateProps checks the props of a new block of blockType against the schema of spaceID
+	ValidateProps(ctx context.Context, spaceID uuid.UUID, blockType string, props map[string]any) error
 
 	// List - unified method with optional filters
 	List(ctx context.Context, spaceID uuid.UUID, blockType string, parentID *uuid.UUID) ([]model.Block, error)
@@ -26,13 +43,207 @@ type BlockService interface {
 	// Move - unified method, handles special logic for folder path
 	Move(ctx context.Context, blockID uuid.UUID, newParentID *uuid.UUID, targetSort *int64) error
 
+	// UpdatePage renames, moves and reorders a page atomically; nil arguments are left unchanged
+	UpdatePage(ctx context.Context, spaceID uuid.UUID, pageID uuid.UUID, title *string, newParentID *uuid.UUID, targetSort *int64) error
+
+	// MovePageToTop moves a page to the first position at the space root
+	MovePageToTop(ctx context.Context, spaceID uuid.UUID, pageID uuid.UUID) error
+
+	// MovePageToSpace moves a page and its subtree into another space, as the last child of newParentID there
+	MovePageToSpace(ctx context.Context, pageID uuid.UUID, targetSpaceID uuid.UUID, newParentID *uuid.UUID) error
+
 	// Sort - unified method
 	UpdateSort(ctx context.Context, blockID uuid.UUID, sort int64) error
+
+	// InstantiateTemplate copies a template space's block tree into another space
+	InstantiateTemplate(ctx context.Context, templateSpaceID uuid.UUID, newSpaceID uuid.UUID, vars map[string]string) error
+
+	// GetBacklinks returns the blocks that link to blockID via props.links
+	GetBacklinks(ctx context.Context, blockID uuid.UUID) ([]model.Block, error)
+
+	// SerializeBlocks and PasteBlocks copy a multi-selection of subtrees under another block
+	SerializeBlocks(ctx context.Context, ids []uuid.UUID) ([]byte, error)
+	PasteBlocks(ctx context.Context, spaceID uuid.UUID, parentID uuid.UUID, data []byte) ([]model.Block, error)
+
+	// BlocksExist returns the ids that do not exist in spaceID
+	BlocksExist(ctx context.Context, spaceID uuid.UUID, ids []uuid.UUID) ([]uuid.UUID, error)
+
+	// GetSubtreeTextStats sums the text size of a page and its active descendants
+	GetSubtreeTextStats(ctx context.Context, pageID uuid.UUID) (chars int, words int, blocks int, err error)
+
+	// GetPageTree loads a page or folder with its active descendants linked through Children, down to maxDepth levels (0 for all)
+	GetPageTree(ctx context.Context, pageID uuid.UUID, maxDepth int) (*model.Block, error)
+
+	// ListChildrenLite lists the children of parentID without their props
+	ListChildrenLite(ctx context.Context, parentID uuid.UUID) ([]model.BlockLite, error)
+
+	// ListChildrenByTypes lists the children of parentID restricted to the given block types
+	ListChildrenByTypes(ctx context.Context, parentID uuid.UUID, types []string) ([]model.Block, error)
+
+	// ListChildrenPaged lists the children of parentID a page at a time, for parents too large for List
+	ListChildrenPaged(ctx context.Context, parentID uuid.UUID, limit int, cursor string) ([]model.Block, string, error)
+
+	// DuplicateBlock copies a block and its subtree directly below the original
+	DuplicateBlock(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID) (*model.Block, error)
+
+	// DuplicatePage copies a page and its subtree to the end of another parent, titled "<title> (copy)"
+	DuplicatePage(ctx context.Context, spaceID uuid.UUID, pageID uuid.UUID, newParentID *uuid.UUID) (*model.Block, error)
+
+	// LockBlock and UnlockBlock toggle read-only mode, optionally for the whole subtree
+	LockBlock(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, cascade bool) error
+	UnlockBlock(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, cascade bool) error
+
+	// GetAncestors returns the ancestor chain of a block, root first, for breadcrumbs
+	GetAncestors(ctx context.Context, blockID uuid.UUID) ([]model.Block, error)
+	// GetAncestorsBatch returns the ancestor chain, root first, of each block
+	GetAncestorsBatch(ctx context.Context, blockIDs []uuid.UUID) (map[uuid.UUID][]model.Block, error)
+
+	// ExportPageHTML renders a page and its subtree as HTML
+	ExportPageHTML(ctx context.Context, pageID uuid.UUID) (string, error)
+	// ExportPageMarkdown renders a page and its subtree as Markdown
+	ExportPageMarkdown(ctx context.Context, pageID uuid.UUID) (string, error)
+	// ImportMarkdown parses Markdown into a new page of text blocks under parentID
+	ImportMarkdown(ctx context.Context, spaceID uuid.UUID, parentID *uuid.UUID, md string) (*model.Block, error)
+
+	// UpdatePropsWhere patches the props of every descendant of parentID matching match
+	UpdatePropsWhere(ctx context.Context, spaceID uuid.UUID, parentID uuid.UUID, match map[string]any, patch map[string]any) (int, error)
+
+	// ArchiveBlock moves a block to the trash; RestoreBlock brings it back,
+	// optionally with its archived ancestors
+	ArchiveBlock(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID) error
+	RestoreBlock(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, restoreAncestors bool) error
+
+	// ListArchivedPages lists the archived pages of a space, for a trash view
+	ListArchivedPages(ctx context.Context, spaceID uuid.UUID) ([]model.Block, error)
+
+	// ArchiveWhere archives every block of a space whose props match, with its descendants
+	ArchiveWhere(ctx context.Context, spaceID uuid.UUID, match map[string]any) (int, error)
+
+	// ReorderChildren sorts the children of parentID in the given order, which must list all of them
+	ReorderChildren(ctx context.Context, spaceID uuid.UUID, parentID uuid.UUID, orderedIDs []uuid.UUID) error
+
+	// DiffTree reports the blocks of a page tree added, updated, removed or moved since a time
+	DiffTree(ctx context.Context, pageID uuid.UUID, since time.Time) (TreeDiff, error)
+
+	// SearchBlocks full-text searches the blocks of a space, optionally within one page
+	SearchBlocks(ctx context.Context, spaceID uuid.UUID, query string, opts SearchOptions) ([]model.Block, error)
+
+	// ConvertBlockType turns a content block into a page or a page into a content block
+	ConvertBlockType(ctx context.Context, spaceID uuid.UUID, id uuid.UUID, newType string) error
 }
 
-type blockService struct{ r repo.BlockRepo }
+// SearchOptions narrows SearchBlocks
+type SearchOptions = repo.SearchOptions
+
+// InsertPosition places a block created with CreateAt among its siblings
+type InsertPosition = repo.InsertPosition
+
+// DefaultMaxChildren is the default cap on children returned by a single listing
+const DefaultMaxChildren = 10000
+
+// ErrTooManyChildren is returned when a listing would exceed the children cap;
+// callers should use the paginated API instead
+var ErrTooManyChildren = errors.New("too many children, use the paginated API")
 
-func NewBlockService(r repo.BlockRepo) BlockService { return &blockService{r: r} }
+// ErrLocked is returned when a mutation targets a locked block or the children of one
+var ErrLocked = errors.New("block is locked")
+
+// ErrArchivedAncestor is returned when restoring a block whose ancestors are still archived
+var ErrArchivedAncestor = errors.New("block has archived ancestors")
+
+type blockService struct {
+	r            repo.BlockRepo
+	maxChildren  int
+	defaultProps DefaultPropsProvider
+	propsSchema  PropsSchemaProvider
+	projects     SpaceProjectProvider
+}
+
+// DefaultPropsProvider supplies the props a new block of blockType in spaceID starts
+// with, e.g. from space settings. A nil map means no defaults.
+type DefaultPropsProvider interface {
+	DefaultProps(ctx context.Context, spaceID uuid.UUID, blockType string) (map[string]any, error)
+}
+
+// PropsSchemaProvider supplies the JSON Schema the props of spaceID's blocks must
+// match. A nil schema disables validation.
+type PropsSchemaProvider interface {
+	PropsSchema(ctx context.Context, spaceID uuid.UUID) (map[string]any, error)
+}
+
+// SpaceProjectProvider supplies the project spaceID belongs to
+type SpaceProjectProvider interface {
+	SpaceProject(ctx context.Context, spaceID uuid.UUID) (uuid.UUID, error)
+}
+
+// BlockServiceOption configures optional BlockService dependencies
+type BlockServiceOption func(*blockServiceOptions)
+
+type blockServiceOptions struct {
+	recorder     metrics.Recorder
+	maxChildren  int
+	defaultProps DefaultPropsProvider
+	propsSchema  PropsSchemaProvider
+	projects     SpaceProjectProvider
+	authorizer   Authorizer
+}
+
+// WithBlockMetrics reports duration and outcome of every BlockService call to rec
+func WithBlockMetrics(rec metrics.Recorder) BlockServiceOption {
+	return func(o *blockServiceOptions) { o.recorder = rec }
+}
+
+// WithDefaultProps merges the props supplied by p into every created block,
+// keeping the keys the caller set explicitly
+func WithDefaultProps(p DefaultPropsProvider) BlockServiceOption {
+	return func(o *blockServiceOptions) { o.defaultProps = p }
+}
+
+// WithPropsSchema validates the props of created and updated blocks against the
+// schema supplied by p. Folders, whose props only hold their derived path, are
+// not validated.
+func WithPropsSchema(p PropsSchemaProvider) BlockServiceOption {
+	return func(o *blockServiceOptions) { o.propsSchema = p }
+}
+
+// WithSpaceProjects lets MovePageToSpace check with p that both spaces belong to
+// the same project. Without it pages cannot move between spaces.
+func WithSpaceProjects(p SpaceProjectProvider) BlockServiceOption {
+	return func(o *blockServiceOptions) { o.projects = p }
+}
+
+// WithAuthorizer checks every BlockService call against a. Denied calls return
+// ErrForbidden. Without it every call is allowed.
+func WithAuthorizer(a Authorizer) BlockServiceOption {
+	return func(o *blockServiceOptions) { o.authorizer = a }
+}
+
+// WithMaxChildren overrides DefaultMaxChildren. Zero or less disables the cap.
+func WithMaxChildren(n int) BlockServiceOption {
+	return func(o *blockServiceOptions) { o.maxChildren = n }
+}
+
+func NewBlockService(r repo.BlockRepo, opts ...BlockServiceOption) BlockService {
+	o := blockServiceOptions{maxChildren: DefaultMaxChildren}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var svc BlockService = &blockService{
+		r:            r,
+		maxChildren:  o.maxChildren,
+		defaultProps: o.defaultProps,
+		propsSchema:  o.propsSchema,
+		projects:     o.projects,
+	}
+	if o.authorizer != nil {
+		svc = &authorizedBlockService{next: svc, r: r, auth: o.authorizer}
+	}
+	if o.recorder != nil {
+		svc = &instrumentedBlockService{next: svc, recorder: o.recorder}
+	}
+	return svc
+}
 
 // validateAndPrepareCreate validates a block for creation and prepares its parent
 func (s *blockService) validateAndPrepareCreate(ctx context.Context, b *model.Block) (*model.Block, error) {
@@ -50,6 +261,9 @@ func (s *blockService) validateAndPrepareCreate(ctx context.Context, b *model.Bl
 		if !parent.CanHaveChildren() {
 			return nil, errors.New("parent cannot have children")
 		}
+		if parent.IsLocked {
+			return nil, ErrLocked
+		}
 	}
 
 	if err := b.ValidateParentType(parent); err != nil {
@@ -59,18 +273,32 @@ func (s *blockService) validateAndPrepareCreate(ctx context.Context, b *model.Bl
 	return parent, nil
 }
 
-// prepareBlockForCreation sets the sort order for a new block
-func (s *blockService) prepareBlockForCreation(ctx context.Context, b *model.Block) error {
-	next, err := s.r.NextSort(ctx, b.SpaceID, b.ParentID)
-	if err != nil {
+// Create - unified create method for all block types.
+// The block is always appended to its (space_id, parent_id) group so it can never
+// collide with an existing sibling; any caller-provided b.Sort is overwritten.
+func (s *blockService) Create(ctx context.Context, b *model.Block) error {
+	if err := s.prepareCreate(ctx, b); err != nil {
 		return err
 	}
-	b.Sort = next
-	return nil
+	return s.r.CreateAppend(ctx, b)
 }
 
-// Create - unified create method for all block types
-func (s *blockService) Create(ctx context.Context, b *model.Block) error {
+// CreateAt creates b at pos among its siblings, shifting the siblings after it,
+// instead of appending it. Anchors must be active siblings under the same parent;
+// an after id and a before id set together must name adjacent siblings.
+func (s *blockService) CreateAt(ctx context.Context, b *model.Block, pos InsertPosition) error {
+	if pos.AtSort != nil && (pos.AfterID != nil || pos.BeforeID != nil) {
+		return errors.New("sort cannot be combined with after id or before id")
+	}
+
+	if err := s.prepareCreate(ctx, b); err != nil {
+		return err
+	}
+	return s.r.CreateAt(ctx, b, pos)
+}
+
+// prepareCreate validates b for creation and fills in its default props and folder path
+func (s *blockService) prepareCreate(ctx context.Context, b *model.Block) error {
 	if b.Type == "" {
 		return errors.New("block type is required")
 	}
@@ -80,6 +308,13 @@ func (s *blockService) Create(ctx context.Context, b *model.Block) error {
 		return err
 	}
 
+	if err := s.applyDefaultProps(ctx, b); err != nil {
+		return err
+	}
+	if err := s.validateProps(ctx, b.SpaceID, b.Type, b.Props.Data()); err != nil {
+		return err
+	}
+
 	// Special handling for folder type - calculate and set path
 	if b.Type == model.BlockTypeFolder {
 		path := b.Title
@@ -92,11 +327,44 @@ func (s *blockService) Create(ctx context.Context, b *model.Block) error {
 		b.SetFolderPath(path)
 	}
 
-	if err := s.prepareBlockForCreation(ctx, b); err != nil {
-		return err
+	return nil
+}
+
+// applyDefaultProps fills in the provider's default props that b does not set
+func (s *blockService) applyDefaultProps(ctx context.Context, b *model.Block) error {
+	if s.defaultProps == nil {
+		return nil
+	}
+	defaults, err := s.defaultProps.DefaultProps(ctx, b.SpaceID, b.Type)
+	if err != nil {
+		return fmt.Errorf("load default props: %w", err)
+	}
+	if len(defaults) == 0 {
+		return nil
 	}
 
-	return s.r.Create(ctx, b)
+	props := make(map[string]any, len(defaults)+len(b.Props.Data()))
+	for k, v := range defaults {
+		props[k] = v
+	}
+	for k, v := range b.Props.Data() {
+		props[k] = v
+	}
+	b.Props = datatypes.NewJSONType(props)
+	return nil
+}
+
+// validateProps checks props against the space's schema, returning a
+// *propschema.ValidationError that names every offending key
+func (s *blockService) validateProps(ctx context.Context, spaceID uuid.UUID, blockType string, props map[string]any) error {
+	if s.propsSchema == nil || blockType == model.BlockTypeFolder {
+		return nil
+	}
+	schema, err := s.propsSchema.PropsSchema(ctx, spaceID)
+	if err != nil {
+		return fmt.Errorf("load props schema: %w", err)
+	}
+	return propschema.Validate(schema, props)
 }
 
 // isDescendant checks if candidateID is a descendant of ancestorID in the tree
@@ -143,6 +411,9 @@ func (s *blockService) validateAndPrepareMove(ctx context.Context, blockID uuid.
 	if err != nil {
 		return nil, nil, err
 	}
+	if err := s.checkEditable(ctx, block); err != nil {
+		return nil, nil, err
+	}
 
 	var parent *model.Block
 	if newParentID != nil {
@@ -166,6 +437,9 @@ func (s *blockService) validateAndPrepareMove(ctx context.Context, blockID uuid.
 		if !parent.CanHaveChildren() {
 			return nil, nil, errors.New("new parent cannot have children")
 		}
+		if parent.IsLocked {
+			return nil, nil, ErrLocked
+		}
 	}
 
 	if err := block.ValidateParentType(parent); err != nil {
@@ -175,12 +449,65 @@ func (s *blockService) validateAndPrepareMove(ctx context.Context, blockID uuid.
 	return block, parent, nil
 }
 
-// Delete - unified delete method for all block types
+// Delete - unified delete method for all block types. The block and its subtree
+// are soft-deleted: hidden at once, and removed for good by PurgeDeleted.
 func (s *blockService) Delete(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID) error {
 	if len(blockID) == 0 {
 		return errors.New("block id is empty")
 	}
-	return s.r.Delete(ctx, spaceID, blockID)
+
+	b, err := s.r.Get(ctx, blockID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkEditable(ctx, b); err != nil {
+		return err
+	}
+
+	err = s.r.Delete(ctx, spaceID, blockID)
+	if errors.Is(err, repo.ErrLockedDescendant) {
+		return ErrLocked
+	}
+	return err
+}
+
+// DeletePageRecursive soft-deletes pageID and all of its descendants, archived
+// ones included, in a single transaction and returns how many blocks were
+// deleted. Like the page itself, no descendant may be locked; otherwise nothing
+// is deleted and ErrLocked is returned.
+func (s *blockService) DeletePageRecursive(ctx context.Context, spaceID uuid.UUID, pageID uuid.UUID) (int, error) {
+	if len(pageID) == 0 {
+		return 0, errors.New("page id is empty")
+	}
+
+	page, err := s.r.Get(ctx, pageID)
+	if err != nil {
+		return 0, err
+	}
+	if page.SpaceID != spaceID {
+		return 0, errors.New("page not found in space")
+	}
+	if page.Type != model.BlockTypePage {
+		return 0, fmt.Errorf("block type '%s' is not a page", page.Type)
+	}
+	if err := s.checkEditable(ctx, page); err != nil {
+		return 0, err
+	}
+
+	deleted, err := s.r.DeleteTree(ctx, spaceID, pageID)
+	if errors.Is(err, repo.ErrLockedDescendant) {
+		return 0, ErrLocked
+	}
+	return deleted, err
+}
+
+// PurgeDeleted permanently removes the blocks of spaceID that were deleted before
+// olderThan, once they are past the retention window
+func (s *blockService) PurgeDeleted(ctx context.Context, spaceID uuid.UUID, olderThan time.Time) (int, error) {
+	if len(spaceID) == 0 {
+		return 0, errors.New("space id is empty")
+	}
+	return s.r.PurgeDeleted(ctx, spaceID, olderThan)
 }
 
 // GetBlockProperties - unified get properties method
@@ -196,14 +523,43 @@ func (s *blockService) UpdateBlockProperties(ctx context.Context, b *model.Block
 	if len(b.ID) == 0 {
 		return errors.New("block id is empty")
 	}
+
+	existing, err := s.r.Get(ctx, b.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkEditable(ctx, existing); err != nil {
+		return err
+	}
+	// Nil props are left unchanged, so only props that are set are validated
+	if props := b.Props.Data(); props != nil {
+		if err := s.validateProps(ctx, existing.SpaceID, existing.Type, props); err != nil {
+			return err
+		}
+	}
+
 	return s.r.Update(ctx, b)
 }
 
+// ValidateProps checks props for writers that insert blocks without this service,
+// returning a *propschema.ValidationError when they do not match
+func (s *blockService) ValidateProps(ctx context.Context, spaceID uuid.UUID, blockType string, props map[string]any) error {
+	if len(spaceID) == 0 {
+		return errors.New("space id is empty")
+	}
+	return s.validateProps(ctx, spaceID, blockType, props)
+}
+
 // List - unified list method with optional type and parent_id filters
 func (s *blockService) List(ctx context.Context, spaceID uuid.UUID, blockType string, parentID *uuid.UUID) ([]model.Block, error) {
 	if len(spaceID) == 0 {
 		return nil, errors.New("space id is empty")
 	}
+	if parentID != nil {
+		if err := s.checkChildrenCap(ctx, *parentID); err != nil {
+			return nil, err
+		}
+	}
 	return s.r.ListBySpace(ctx, spaceID, blockType, parentID)
 }
 
@@ -237,10 +593,693 @@ func (s *blockService) Move(ctx context.Context, blockID uuid.UUID, newParentID
 	return s.r.MoveToParentAtSort(ctx, blockID, newParentID, *targetSort)
 }
 
+// UpdatePage applies the provided title, parent and position changes to a page in
+// one transaction, with the same validations as Move
+func (s *blockService) UpdatePage(ctx context.Context, spaceID uuid.UUID, pageID uuid.UUID, title *string, newParentID *uuid.UUID, targetSort *int64) error {
+	if len(pageID) == 0 {
+		return errors.New("page id is empty")
+	}
+	if title != nil && strings.TrimSpace(*title) == "" {
+		return errors.New("title is empty")
+	}
+
+	page, err := s.r.Get(ctx, pageID)
+	if err != nil {
+		return err
+	}
+	if page.SpaceID != spaceID {
+		return errors.New("page not found in space")
+	}
+	if page.Type != model.BlockTypePage {
+		return fmt.Errorf("block type '%s' is not a page", page.Type)
+	}
+
+	if newParentID != nil {
+		if _, _, err := s.validateAndPrepareMove(ctx, pageID, newParentID); err != nil {
+			return err
+		}
+	} else if err := s.checkEditable(ctx, page); err != nil {
+		return err
+	}
+
+	return s.r.UpdatePage(ctx, pageID, title, newParentID, targetSort)
+}
+
+// MovePageToTop detaches a page to the root of its space at sort 0, shifting the
+// other root blocks down, in a single transaction
+func (s *blockService) MovePageToTop(ctx context.Context, spaceID uuid.UUID, pageID uuid.UUID) error {
+	if len(pageID) == 0 {
+		return errors.New("page id is empty")
+	}
+
+	page, err := s.r.Get(ctx, pageID)
+	if err != nil {
+		return err
+	}
+	if page.SpaceID != spaceID {
+		return errors.New("page not found in space")
+	}
+	if page.Type != model.BlockTypePage {
+		return fmt.Errorf("block type '%s' is not a page", page.Type)
+	}
+	if err := page.ValidateParentType(nil); err != nil {
+		return err
+	}
+	if err := s.checkEditable(ctx, page); err != nil {
+		return err
+	}
+
+	return s.r.MoveToParentAtSort(ctx, pageID, nil, model.InitialSort)
+}
+
+// MovePageToSpace moves pageID and all of its descendants to targetSpaceID, appending
+// the page under newParentID (nil for the space root) there. The parent must belong
+// to the target space, which must exist in the page's project; a space of another
+// project yields ErrForbidden. A page already in targetSpaceID is moved within it,
+// as Move would.
+func (s *blockService) MovePageToSpace(ctx context.Context, pageID uuid.UUID, targetSpaceID uuid.UUID, newParentID *uuid.UUID) error {
+	if len(pageID) == 0 {
+		return errors.New("page id is empty")
+	}
+	if len(targetSpaceID) == 0 {
+		return errors.New("target space id is empty")
+	}
+
+	page, err := s.r.Get(ctx, pageID)
+	if err != nil {
+		return err
+	}
+	if page.Type != model.BlockTypePage {
+		return fmt.Errorf("block type '%s' is not a page", page.Type)
+	}
+
+	var parent *model.Block
+	if newParentID != nil {
+		parent, err = s.r.Get(ctx, *newParentID)
+		if err != nil {
+			return err
+		}
+		if parent.SpaceID != targetSpaceID {
+			return errors.New("parent not found in target space")
+		}
+	}
+	if page.SpaceID == targetSpaceID {
+		// Already there, so it is an ordinary move within the space
+		return s.Move(ctx, pageID, newParentID, nil)
+	}
+	if err := s.checkSameProject(ctx, page.SpaceID, targetSpaceID); err != nil {
+		return err
+	}
+
+	if err := s.checkEditable(ctx, page); err != nil {
+		return err
+	}
+	if parent != nil && parent.IsLocked {
+		return ErrLocked
+	}
+	if err := page.ValidateParentType(parent); err != nil {
+		return err
+	}
+
+	return s.r.MoveToSpace(ctx, pageID, targetSpaceID, newParentID)
+}
+
+// checkSameProject returns ErrForbidden unless spaceID and otherSpaceID belong to
+// the same project, or when there is no SpaceProjectProvider to tell
+func (s *blockService) checkSameProject(ctx context.Context, spaceID uuid.UUID, otherSpaceID uuid.UUID) error {
+	if s.projects == nil {
+		return ErrForbidden
+	}
+	project, err := s.projects.SpaceProject(ctx, spaceID)
+	if err != nil {
+		return err
+	}
+	otherProject, err := s.projects.SpaceProject(ctx, otherSpaceID)
+	if err != nil {
+		return err
+	}
+	if project != otherProject {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// DuplicateBlock copies blockID and its subtree and inserts the copy right after
+// the original under the same parent
+func (s *blockService) DuplicateBlock(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID) (*model.Block, error) {
+	if len(blockID) == 0 {
+		return nil, errors.New("block id is empty")
+	}
+
+	b, err := s.r.Get(ctx, blockID)
+	if err != nil {
+		return nil, err
+	}
+	if b.SpaceID != spaceID {
+		return nil, errors.New("block not found in space")
+	}
+	if err := s.checkParentUnlocked(ctx, b.ParentID); err != nil {
+		return nil, err
+	}
+
+	return s.r.DuplicateBlock(ctx, blockID)
+}
+
+// copyTitleSuffix marks the root of a duplicated page
+const copyTitleSuffix = " (copy)"
+
+// DuplicatePage copies pageID and its subtree, with new ids, as the last child of
+// newParentID (nil for the space root). Only the copy of the page is renamed.
+func (s *blockService) DuplicatePage(ctx context.Context, spaceID uuid.UUID, pageID uuid.UUID, newParentID *uuid.UUID) (*model.Block, error) {
+	if len(pageID) == 0 {
+		return nil, errors.New("page id is empty")
+	}
+
+	page, err := s.r.Get(ctx, pageID)
+	if err != nil {
+		return nil, err
+	}
+	if page.SpaceID != spaceID {
+		return nil, errors.New("page not found in space")
+	}
+	if page.Type != model.BlockTypePage {
+		return nil, fmt.Errorf("block type '%s' is not a page", page.Type)
+	}
+
+	var parent *model.Block
+	if newParentID != nil {
+		parent, err = s.r.Get(ctx, *newParentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent.SpaceID != spaceID {
+			return nil, errors.New("parent not found in space")
+		}
+		if parent.IsLocked {
+			return nil, ErrLocked
+		}
+	}
+	if err := page.ValidateParentType(parent); err != nil {
+		return nil, err
+	}
+
+	return s.r.DuplicateTo(ctx, pageID, newParentID, page.Title+copyTitleSuffix)
+}
+
+// GetAncestors returns the ancestors of blockID, from the space root down to its
+// parent, in one recursive query. A block at the space root has none.
+func (s *blockService) GetAncestors(ctx context.Context, blockID uuid.UUID) ([]model.Block, error) {
+	if len(blockID) == 0 {
+		return nil, errors.New("block id is empty")
+	}
+	chains, err := s.r.ListAncestorsBatch(ctx, []uuid.UUID{blockID})
+	if err != nil {
+		return nil, err
+	}
+	if chain, ok := chains[blockID]; ok {
+		return chain, nil
+	}
+	return []model.Block{}, nil
+}
+
+// GetAncestorsBatch returns the ancestors of every block in blockIDs, root first, using
+// one query for all of them. Blocks at the space root map to an empty chain.
+func (s *blockService) GetAncestorsBatch(ctx context.Context, blockIDs []uuid.UUID) (map[uuid.UUID][]model.Block, error) {
+	if len(blockIDs) == 0 {
+		return map[uuid.UUID][]model.Block{}, nil
+	}
+
+	seen := make(map[uuid.UUID]struct{}, len(blockIDs))
+	unique := make([]uuid.UUID, 0, len(blockIDs))
+	for _, id := range blockIDs {
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			unique = append(unique, id)
+		}
+	}
+
+	chains, err := s.r.ListAncestorsBatch(ctx, unique)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range unique {
+		if _, ok := chains[id]; !ok {
+			chains[id] = []model.Block{}
+		}
+	}
+	return chains, nil
+}
+
+// UpdatePropsWhere shallow-merges patch into the props of every active descendant
+// of parentID whose props contain all key/values of match, and returns how many
+// blocks were updated. Locked blocks are left untouched.
+func (s *blockService) UpdatePropsWhere(ctx context.Context, spaceID uuid.UUID, parentID uuid.UUID, match map[string]any, patch map[string]any) (int, error) {
+	if len(parentID) == 0 {
+		return 0, errors.New("parent id is empty")
+	}
+	if len(patch) == 0 {
+		return 0, errors.New("patch is empty")
+	}
+
+	parent, err := s.r.Get(ctx, parentID)
+	if err != nil {
+		return 0, err
+	}
+	if parent.SpaceID != spaceID {
+		return 0, errors.New("parent not found in space")
+	}
+	if parent.IsLocked {
+		return 0, ErrLocked
+	}
+
+	return s.r.UpdatePropsWhere(ctx, spaceID, parentID, match, patch)
+}
+
+// ArchiveBlock archives blockID together with its descendants, hiding the whole
+// subtree until restored. Locked descendants are left active.
+func (s *blockService) ArchiveBlock(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID) error {
+	if len(blockID) == 0 {
+		return errors.New("block id is empty")
+	}
+
+	b, err := s.r.Get(ctx, blockID)
+	if err != nil {
+		return err
+	}
+	if b.SpaceID != spaceID {
+		return errors.New("block not found in space")
+	}
+	if err := s.checkEditable(ctx, b); err != nil {
+		return err
+	}
+	return s.r.Archive(ctx, blockID)
+}
+
+// ListArchivedPages returns the pages of spaceID archived with ArchiveBlock or
+// ArchiveWhere, most recently archived first. RestoreBlock brings one back.
+func (s *blockService) ListArchivedPages(ctx context.Context, spaceID uuid.UUID) ([]model.Block, error) {
+	if len(spaceID) == 0 {
+		return nil, errors.New("space id is empty")
+	}
+	return s.r.ListArchived(ctx, spaceID, model.BlockTypePage)
+}
+
+// ArchiveWhere archives the blocks of spaceID whose props contain match, and
+// their descendants, returning how many blocks were archived. Locked blocks, the
+// children of locked blocks and locked descendants are skipped.
+func (s *blockService) ArchiveWhere(ctx context.Context, spaceID uuid.UUID, match map[string]any) (int, error) {
+	if len(spaceID) == 0 {
+		return 0, errors.New("space id is empty")
+	}
+	// An empty predicate matches every block of the space
+	if len(match) == 0 {
+		return 0, errors.New("match is empty")
+	}
+	return s.r.ArchiveWhere(ctx, spaceID, match)
+}
+
+// RestoreBlock un-archives blockID and the descendants ArchiveBlock or ArchiveWhere
+// archived along with it; those archived on their own stay archived. A block under an
+// archived ancestor would be unreachable, so with restoreAncestors every archived
+// ancestor is restored with it; otherwise ErrArchivedAncestor names the topmost one to
+// restore first.
+func (s *blockService) RestoreBlock(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, restoreAncestors bool) error {
+	if len(blockID) == 0 {
+		return errors.New("block id is empty")
+	}
+
+	b, err := s.r.Get(ctx, blockID)
+	if err != nil {
+		return err
+	}
+	if b.SpaceID != spaceID {
+		return errors.New("block not found in space")
+	}
+	if !b.IsArchived {
+		return nil
+	}
+
+	chains, err := s.r.ListAncestorsBatch(ctx, []uuid.UUID{blockID})
+	if err != nil {
+		return err
+	}
+	ids := make([]uuid.UUID, 0, len(chains[blockID])+1)
+	parentID := b.ParentID
+	for _, ancestor := range chains[blockID] {
+		if !ancestor.IsArchived {
+			continue
+		}
+		if len(ids) == 0 {
+			parentID = ancestor.ParentID
+		}
+		ids = append(ids, ancestor.ID)
+	}
+	if len(ids) > 0 && !restoreAncestors {
+		return fmt.Errorf("%w: restore %s first", ErrArchivedAncestor, ids[0])
+	}
+	ids = append(ids, blockID)
+
+	if err := s.checkParentUnlocked(ctx, parentID); err != nil {
+		return err
+	}
+	return s.r.Restore(ctx, ids)
+}
+
+// ReorderChildren reorders the children of parentID to orderedIDs in a single
+// transaction. orderedIDs must list every active child exactly once; a child
+// inserted concurrently makes the reorder fail rather than be placed silently.
+func (s *blockService) ReorderChildren(ctx context.Context, spaceID uuid.UUID, parentID uuid.UUID, orderedIDs []uuid.UUID) error {
+	if len(parentID) == 0 {
+		return errors.New("parent id is empty")
+	}
+	if len(orderedIDs) == 0 {
+		return errors.New("ordered ids is empty")
+	}
+
+	parent, err := s.r.Get(ctx, parentID)
+	if err != nil {
+		return err
+	}
+	if parent.SpaceID != spaceID {
+		return errors.New("parent not found in space")
+	}
+	if parent.IsLocked {
+		return ErrLocked
+	}
+
+	return s.r.ReorderChildren(ctx, spaceID, parentID, orderedIDs)
+}
+
+// SearchBlocks returns the active blocks of spaceID whose title or text matches query.
+// opts.WithinPageID scopes the search to the subtree of that page.
+func (s *blockService) SearchBlocks(ctx context.Context, spaceID uuid.UUID, query string, opts SearchOptions) ([]model.Block, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, errors.New("query is empty")
+	}
+	if opts.Limit < 0 {
+		return nil, errors.New("limit must not be negative")
+	}
+	if opts.WithinPageID != nil {
+		page, err := s.r.Get(ctx, *opts.WithinPageID)
+		if err != nil {
+			return nil, err
+		}
+		if page.SpaceID != spaceID || page.Type != model.BlockTypePage {
+			return nil, errors.New("page not found in space")
+		}
+	}
+	return s.r.SearchBlocks(ctx, spaceID, query, opts)
+}
+
+// ConvertBlockType changes the type of id between page and a content type
+// (text, sop). Pages cannot live under pages, so a block converted to a page
+// is detached from its page to the root; a page converted to a content block
+// must already sit under a page and have no children, since content blocks
+// cannot have any.
+func (s *blockService) ConvertBlockType(ctx context.Context, spaceID uuid.UUID, id uuid.UUID, newType string) error {
+	if len(id) == 0 {
+		return errors.New("block id is empty")
+	}
+	if !model.IsValidBlockType(newType) {
+		return fmt.Errorf("invalid block type: %s", newType)
+	}
+
+	b, err := s.r.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if b.SpaceID != spaceID {
+		return errors.New("block not found in space")
+	}
+	if b.Type == newType {
+		return nil
+	}
+	if b.Type == model.BlockTypeFolder || newType == model.BlockTypeFolder ||
+		(b.Type != model.BlockTypePage && newType != model.BlockTypePage) {
+		return fmt.Errorf("cannot convert block type '%s' to '%s'", b.Type, newType)
+	}
+	if err := s.checkEditable(ctx, b); err != nil {
+		return err
+	}
+
+	var parent *model.Block
+	if b.ParentID != nil {
+		if parent, err = s.r.Get(ctx, *b.ParentID); err != nil {
+			return err
+		}
+	}
+
+	converted := *b
+	converted.Type = newType
+	if newType == model.BlockTypePage && parent != nil && parent.Type == model.BlockTypePage {
+		converted.ParentID = nil
+		parent = nil
+	}
+	if err := converted.Validate(); err != nil {
+		return err
+	}
+	if err := converted.ValidateParentType(parent); err != nil {
+		return err
+	}
+
+	return s.r.ConvertType(ctx, id, newType, converted.ParentID)
+}
+
+// LockBlock makes blockID read-only, along with its descendants when cascade is set
+func (s *blockService) LockBlock(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, cascade bool) error {
+	return s.setLocked(ctx, spaceID, blockID, true, cascade)
+}
+
+// UnlockBlock makes blockID editable again, along with its descendants when cascade is set
+func (s *blockService) UnlockBlock(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, cascade bool) error {
+	return s.setLocked(ctx, spaceID, blockID, false, cascade)
+}
+
+func (s *blockService) setLocked(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, locked bool, cascade bool) error {
+	if len(blockID) == 0 {
+		return errors.New("block id is empty")
+	}
+
+	b, err := s.r.Get(ctx, blockID)
+	if err != nil {
+		return err
+	}
+	if b.SpaceID != spaceID {
+		return errors.New("block not found in space")
+	}
+
+	return s.r.SetLocked(ctx, blockID, locked, cascade)
+}
+
+// checkEditable rejects mutations of b when b itself or its parent is locked
+func (s *blockService) checkEditable(ctx context.Context, b *model.Block) error {
+	if b.IsLocked {
+		return ErrLocked
+	}
+	return s.checkParentUnlocked(ctx, b.ParentID)
+}
+
+// checkParentUnlocked rejects child mutations under a locked parent
+func (s *blockService) checkParentUnlocked(ctx context.Context, parentID *uuid.UUID) error {
+	if parentID == nil {
+		return nil
+	}
+	parent, err := s.r.Get(ctx, *parentID)
+	if err != nil {
+		return err
+	}
+	if parent.IsLocked {
+		return ErrLocked
+	}
+	return nil
+}
+
+// GetBacklinks returns the blocks of blockID's space that reference it in their props.links
+func (s *blockService) GetBacklinks(ctx context.Context, blockID uuid.UUID) ([]model.Block, error) {
+	if len(blockID) == 0 {
+		return nil, errors.New("block id is empty")
+	}
+	b, err := s.r.Get(ctx, blockID)
+	if err != nil {
+		return nil, err
+	}
+	return s.r.ListBacklinks(ctx, b.SpaceID, blockID)
+}
+
+// BlocksExist checks all ids in one query and returns those not found in spaceID,
+// in input order and without duplicates
+func (s *blockService) BlocksExist(ctx context.Context, spaceID uuid.UUID, ids []uuid.UUID) ([]uuid.UUID, error) {
+	if len(spaceID) == 0 {
+		return nil, errors.New("space id is empty")
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	found, err := s.r.ListExistingIDs(ctx, spaceID, ids)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[uuid.UUID]struct{}, len(found))
+	for _, id := range found {
+		seen[id] = struct{}{}
+	}
+
+	var missing []uuid.UUID
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		missing = append(missing, id)
+	}
+	return missing, nil
+}
+
+// GetSubtreeTextStats returns the characters and words of the titles and props.text
+// of pageID and its descendants, and the number of blocks counted. Archived blocks,
+// and everything under them, are ignored.
+func (s *blockService) GetSubtreeTextStats(ctx context.Context, pageID uuid.UUID) (int, int, int, error) {
+	if len(pageID) == 0 {
+		return 0, 0, 0, errors.New("page id is empty")
+	}
+	return s.r.SubtreeTextStats(ctx, pageID, false)
+}
+
+// GetPageTree returns pageID with Children populated recursively, each level in sort
+// order, so a sidebar can be built from one call. The root may also be a folder, whose
+// tree holds its subfolders and pages. maxDepth limits the levels loaded below the
+// root; 0 loads them all. Archived blocks and their subtrees are left out.
+func (s *blockService) GetPageTree(ctx context.Context, pageID uuid.UUID, maxDepth int) (*model.Block, error) {
+	if len(pageID) == 0 {
+		return nil, errors.New("page id is empty")
+	}
+	if maxDepth < 0 {
+		return nil, errors.New("max depth must not be negative")
+	}
+
+	page, err := s.r.Get(ctx, pageID)
+	if err != nil {
+		return nil, err
+	}
+	if page.Type != model.BlockTypePage && page.Type != model.BlockTypeFolder {
+		return nil, fmt.Errorf("block type '%s' is not a page or folder", page.Type)
+	}
+
+	descendants, err := s.r.ListSubtree(ctx, pageID, maxDepth)
+	if err != nil {
+		return nil, err
+	}
+
+	// Descendants come level by level, so each parent is indexed before its children
+	page.Children = nil
+	nodes := map[uuid.UUID]*model.Block{page.ID: page}
+	for i := range descendants {
+		b := &descendants[i]
+		if b.ParentID == nil {
+			continue
+		}
+		parent, ok := nodes[*b.ParentID]
+		if !ok {
+			continue
+		}
+		parent.Children = append(parent.Children, b)
+		nodes[b.ID] = b
+	}
+	return page, nil
+}
+
+// ListChildrenLite returns the id, title, sort and parent of each child of parentID in sort order
+func (s *blockService) ListChildrenLite(ctx context.Context, parentID uuid.UUID) ([]model.BlockLite, error) {
+	if len(parentID) == 0 {
+		return nil, errors.New("parent id is empty")
+	}
+	if err := s.checkChildrenCap(ctx, parentID); err != nil {
+		return nil, err
+	}
+	return s.r.ListChildrenLite(ctx, parentID)
+}
+
+// ListChildrenPaged returns up to limit children of parentID in sort order, starting
+// after cursor (empty for the first page), and the cursor of the next page, which is
+// empty on the last one. It is not subject to the children cap.
+func (s *blockService) ListChildrenPaged(ctx context.Context, parentID uuid.UUID, limit int, cursor string) ([]model.Block, string, error) {
+	if len(parentID) == 0 {
+		return nil, "", errors.New("parent id is empty")
+	}
+	if limit <= 0 {
+		return nil, "", errors.New("limit must be positive")
+	}
+
+	var afterSort int64
+	var afterID uuid.UUID
+	if cursor != "" {
+		if err := paging.DecodeCursor(cursor, &afterSort, &afterID); err != nil {
+			return nil, "", err
+		}
+	}
+
+	// Query limit+1 to tell whether there is a next page
+	items, err := s.r.ListChildrenAfter(ctx, parentID, afterSort, afterID, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(items) <= limit {
+		return items, "", nil
+	}
+	items = items[:limit]
+	last := items[len(items)-1]
+	return items, paging.EncodeCursor(last.Sort, last.ID), nil
+}
+
+// ListChildrenByTypes returns the children of parentID whose type is one of types, in sort order
+func (s *blockService) ListChildrenByTypes(ctx context.Context, parentID uuid.UUID, types []string) ([]model.Block, error) {
+	if len(parentID) == 0 {
+		return nil, errors.New("parent id is empty")
+	}
+	if len(types) == 0 {
+		return nil, errors.New("types is empty")
+	}
+	for _, t := range types {
+		if !model.IsValidBlockType(t) {
+			return nil, fmt.Errorf("invalid block type: %s", t)
+		}
+	}
+	if err := s.checkChildrenCap(ctx, parentID); err != nil {
+		return nil, err
+	}
+	return s.r.ListChildrenByTypes(ctx, parentID, types)
+}
+
+// checkChildrenCap guards child listings against pathologically large groups
+func (s *blockService) checkChildrenCap(ctx context.Context, parentID uuid.UUID) error {
+	if s.maxChildren <= 0 {
+		return nil
+	}
+	count, err := s.r.CountChildren(ctx, parentID)
+	if err != nil {
+		return err
+	}
+	if count > int64(s.maxChildren) {
+		return fmt.Errorf("%w: %d children exceeds limit of %d", ErrTooManyChildren, count, s.maxChildren)
+	}
+	return nil
+}
+
 // UpdateSort - unified sort method for all block types
 func (s *blockService) UpdateSort(ctx context.Context, blockID uuid.UUID, sort int64) error {
 	if len(blockID) == 0 {
 		return errors.New("block id is empty")
 	}
+
+	b, err := s.r.Get(ctx, blockID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkEditable(ctx, b); err != nil {
+		return err
+	}
+
 	return s.r.ReorderWithinGroup(ctx, blockID, sort)
 }
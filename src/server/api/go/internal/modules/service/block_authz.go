@@ -0,0 +1,381 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/repo"
+)
+
+// ErrForbidden is returned when the Authorizer denies an operation
+var ErrForbidden = errors.New("forbidden")
+
+// Authorizer decides whether the caller carried by ctx may read or write a block.
+// blockID is uuid.Nil for operations on the space root.
+type Authorizer interface {
+	CanRead(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID) (bool, error)
+	CanWrite(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID) (bool, error)
+}
+
+// authorizedBlockService consults the Authorizer before every BlockService call.
+// Blocks addressed by id alone are loaded to find their space.
+type authorizedBlockService struct {
+	next BlockService
+	r    repo.BlockRepo
+	auth Authorizer
+}
+
+func (s *authorizedBlockService) check(ctx context.Context, write bool, spaceID uuid.UUID, blockID uuid.UUID) error {
+	can := s.auth.CanRead
+	if write {
+		can = s.auth.CanWrite
+	}
+	allowed, err := can(ctx, spaceID, blockID)
+	if err != nil {
+		return fmt.Errorf("authorize: %w", err)
+	}
+	if !allowed {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func (s *authorizedBlockService) checkBlock(ctx context.Context, write bool, blockID uuid.UUID) error {
+	b, err := s.r.Get(ctx, blockID)
+	if err != nil {
+		return err
+	}
+	return s.check(ctx, write, b.SpaceID, blockID)
+}
+
+func (s *authorizedBlockService) checkParent(ctx context.Context, write bool, spaceID uuid.UUID, parentID *uuid.UUID) error {
+	if parentID == nil {
+		return s.check(ctx, write, spaceID, uuid.Nil)
+	}
+	return s.check(ctx, write, spaceID, *parentID)
+}
+
+func (s *authorizedBlockService) Create(ctx context.Context, b *model.Block) error {
+	if err := s.checkParent(ctx, true, b.SpaceID, b.ParentID); err != nil {
+		return err
+	}
+	return s.next.Create(ctx, b)
+}
+
+func (s *authorizedBlockService) CreateAt(ctx context.Context, b *model.Block, pos InsertPosition) error {
+	if err := s.checkParent(ctx, true, b.SpaceID, b.ParentID); err != nil {
+		return err
+	}
+	return s.next.CreateAt(ctx, b, pos)
+}
+
+func (s *authorizedBlockService) Delete(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID) error {
+	if err := s.check(ctx, true, spaceID, blockID); err != nil {
+		return err
+	}
+	return s.next.Delete(ctx, spaceID, blockID)
+}
+
+func (s *authorizedBlockService) GetBlockProperties(ctx context.Context, blockID uuid.UUID) (*model.Block, error) {
+	if err := s.checkBlock(ctx, false, blockID); err != nil {
+		return nil, err
+	}
+	return s.next.GetBlockProperties(ctx, blockID)
+}
+
+func (s *authorizedBlockService) UpdateBlockProperties(ctx context.Context, b *model.Block) error {
+	if err := s.checkBlock(ctx, true, b.ID); err != nil {
+		return err
+	}
+	return s.next.UpdateBlockProperties(ctx, b)
+}
+
+func (s *authorizedBlockService) ValidateProps(ctx context.Context, spaceID uuid.UUID, blockType string, props map[string]any) error {
+	if err := s.check(ctx, false, spaceID, uuid.Nil); err != nil {
+		return err
+	}
+	return s.next.ValidateProps(ctx, spaceID, blockType, props)
+}
+
+func (s *authorizedBlockService) List(ctx context.Context, spaceID uuid.UUID, blockType string, parentID *uuid.UUID) ([]model.Block, error) {
+	if err := s.checkParent(ctx, false, spaceID, parentID); err != nil {
+		return nil, err
+	}
+	return s.next.List(ctx, spaceID, blockType, parentID)
+}
+
+func (s *authorizedBlockService) Move(ctx context.Context, blockID uuid.UUID, newParentID *uuid.UUID, targetSort *int64) error {
+	if err := s.checkBlock(ctx, true, blockID); err != nil {
+		return err
+	}
+	if newParentID != nil {
+		if err := s.checkBlock(ctx, true, *newParentID); err != nil {
+			return err
+		}
+	}
+	return s.next.Move(ctx, blockID, newParentID, targetSort)
+}
+
+func (s *authorizedBlockService) UpdatePage(ctx context.Context, spaceID uuid.UUID, pageID uuid.UUID, title *string, newParentID *uuid.UUID, targetSort *int64) error {
+	if err := s.check(ctx, true, spaceID, pageID); err != nil {
+		return err
+	}
+	if newParentID != nil {
+		if err := s.check(ctx, true, spaceID, *newParentID); err != nil {
+			return err
+		}
+	}
+	return s.next.UpdatePage(ctx, spaceID, pageID, title, newParentID, targetSort)
+}
+
+func (s *authorizedBlockService) MovePageToTop(ctx context.Context, spaceID uuid.UUID, pageID uuid.UUID) error {
+	if err := s.check(ctx, true, spaceID, pageID); err != nil {
+		return err
+	}
+	return s.next.MovePageToTop(ctx, spaceID, pageID)
+}
+
+func (s *authorizedBlockService) MovePageToSpace(ctx context.Context, pageID uuid.UUID, targetSpaceID uuid.UUID, newParentID *uuid.UUID) error {
+	if err := s.checkBlock(ctx, true, pageID); err != nil {
+		return err
+	}
+	if err := s.checkParent(ctx, true, targetSpaceID, newParentID); err != nil {
+		return err
+	}
+	return s.next.MovePageToSpace(ctx, pageID, targetSpaceID, newParentID)
+}
+
+func (s *authorizedBlockService) UpdateSort(ctx context.Context, blockID uuid.UUID, sort int64) error {
+	if err := s.checkBlock(ctx, true, blockID); err != nil {
+		return err
+	}
+	return s.next.UpdateSort(ctx, blockID, sort)
+}
+
+func (s *authorizedBlockService) InstantiateTemplate(ctx context.Context, templateSpaceID uuid.UUID, newSpaceID uuid.UUID, vars map[string]string) error {
+	if err := s.check(ctx, false, templateSpaceID, uuid.Nil); err != nil {
+		return err
+	}
+	if err := s.check(ctx, true, newSpaceID, uuid.Nil); err != nil {
+		return err
+	}
+	return s.next.InstantiateTemplate(ctx, templateSpaceID, newSpaceID, vars)
+}
+
+func (s *authorizedBlockService) GetBacklinks(ctx context.Context, blockID uuid.UUID) ([]model.Block, error) {
+	if err := s.checkBlock(ctx, false, blockID); err != nil {
+		return nil, err
+	}
+	return s.next.GetBacklinks(ctx, blockID)
+}
+
+func (s *authorizedBlockService) SerializeBlocks(ctx context.Context, ids []uuid.UUID) ([]byte, error) {
+	for _, id := range ids {
+		if err := s.checkBlock(ctx, false, id); err != nil {
+			return nil, err
+		}
+	}
+	return s.next.SerializeBlocks(ctx, ids)
+}
+
+func (s *authorizedBlockService) PasteBlocks(ctx context.Context, spaceID uuid.UUID, parentID uuid.UUID, data []byte) ([]model.Block, error) {
+	if err := s.check(ctx, true, spaceID, parentID); err != nil {
+		return nil, err
+	}
+	return s.next.PasteBlocks(ctx, spaceID, parentID, data)
+}
+
+func (s *authorizedBlockService) BlocksExist(ctx context.Context, spaceID uuid.UUID, ids []uuid.UUID) ([]uuid.UUID, error) {
+	if err := s.check(ctx, false, spaceID, uuid.Nil); err != nil {
+		return nil, err
+	}
+	return s.next.BlocksExist(ctx, spaceID, ids)
+}
+
+func (s *authorizedBlockService) GetSubtreeTextStats(ctx context.Context, pageID uuid.UUID) (int, int, int, error) {
+	if err := s.checkBlock(ctx, false, pageID); err != nil {
+		return 0, 0, 0, err
+	}
+	return s.next.GetSubtreeTextStats(ctx, pageID)
+}
+
+func (s *authorizedBlockService) GetPageTree(ctx context.Context, pageID uuid.UUID, maxDepth int) (*model.Block, error) {
+	if err := s.checkBlock(ctx, false, pageID); err != nil {
+		return nil, err
+	}
+	return s.next.GetPageTree(ctx, pageID, maxDepth)
+}
+
+func (s *authorizedBlockService) ListChildrenLite(ctx context.Context, parentID uuid.UUID) ([]model.BlockLite, error) {
+	if err := s.checkBlock(ctx, false, parentID); err != nil {
+		return nil, err
+	}
+	return s.next.ListChildrenLite(ctx, parentID)
+}
+
+func (s *authorizedBlockService) ListChildrenByTypes(ctx context.Context, parentID uuid.UUID, types []string) ([]model.Block, error) {
+	if err := s.checkBlock(ctx, false, parentID); err != nil {
+		return nil, err
+	}
+	return s.next.ListChildrenByTypes(ctx, parentID, types)
+}
+
+func (s *authorizedBlockService) DuplicateBlock(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID) (*model.Block, error) {
+	if err := s.check(ctx, true, spaceID, blockID); err != nil {
+		return nil, err
+	}
+	return s.next.DuplicateBlock(ctx, spaceID, blockID)
+}
+
+func (s *authorizedBlockService) LockBlock(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, cascade bool) error {
+	if err := s.check(ctx, true, spaceID, blockID); err != nil {
+		return err
+	}
+	return s.next.LockBlock(ctx, spaceID, blockID, cascade)
+}
+
+func (s *authorizedBlockService) UnlockBlock(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, cascade bool) error {
+	if err := s.check(ctx, true, spaceID, blockID); err != nil {
+		return err
+	}
+	return s.next.UnlockBlock(ctx, spaceID, blockID, cascade)
+}
+
+func (s *authorizedBlockService) GetAncestors(ctx context.Context, blockID uuid.UUID) ([]model.Block, error) {
+	if err := s.checkBlock(ctx, false, blockID); err != nil {
+		return nil, err
+	}
+	return s.next.GetAncestors(ctx, blockID)
+}
+
+func (s *authorizedBlockService) GetAncestorsBatch(ctx context.Context, blockIDs []uuid.UUID) (map[uuid.UUID][]model.Block, error) {
+	for _, id := range blockIDs {
+		if err := s.checkBlock(ctx, false, id); err != nil {
+			return nil, err
+		}
+	}
+	return s.next.GetAncestorsBatch(ctx, blockIDs)
+}
+
+func (s *authorizedBlockService) ExportPageHTML(ctx context.Context, pageID uuid.UUID) (string, error) {
+	if err := s.checkBlock(ctx, false, pageID); err != nil {
+		return "", err
+	}
+	return s.next.ExportPageHTML(ctx, pageID)
+}
+
+func (s *authorizedBlockService) ExportPageMarkdown(ctx context.Context, pageID uuid.UUID) (string, error) {
+	if err := s.checkBlock(ctx, false, pageID); err != nil {
+		return "", err
+	}
+	return s.next.ExportPageMarkdown(ctx, pageID)
+}
+
+func (s *authorizedBlockService) ImportMarkdown(ctx context.Context, spaceID uuid.UUID, parentID *uuid.UUID, md string) (*model.Block, error) {
+	if err := s.checkParent(ctx, true, spaceID, parentID); err != nil {
+		return nil, err
+	}
+	return s.next.ImportMarkdown(ctx, spaceID, parentID, md)
+}
+
+func (s *authorizedBlockService) UpdatePropsWhere(ctx context.Context, spaceID uuid.UUID, parentID uuid.UUID, match map[string]any, patch map[string]any) (int, error) {
+	if err := s.check(ctx, true, spaceID, parentID); err != nil {
+		return 0, err
+	}
+	return s.next.UpdatePropsWhere(ctx, spaceID, parentID, match, patch)
+}
+
+func (s *authorizedBlockService) RestoreBlock(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, restoreAncestors bool) error {
+	if err := s.check(ctx, true, spaceID, blockID); err != nil {
+		return err
+	}
+	return s.next.RestoreBlock(ctx, spaceID, blockID, restoreAncestors)
+}
+
+func (s *authorizedBlockService) ReorderChildren(ctx context.Context, spaceID uuid.UUID, parentID uuid.UUID, orderedIDs []uuid.UUID) error {
+	if err := s.check(ctx, true, spaceID, parentID); err != nil {
+		return err
+	}
+	return s.next.ReorderChildren(ctx, spaceID, parentID, orderedIDs)
+}
+
+func (s *authorizedBlockService) DiffTree(ctx context.Context, pageID uuid.UUID, since time.Time) (TreeDiff, error) {
+	if err := s.checkBlock(ctx, false, pageID); err != nil {
+		return TreeDiff{}, err
+	}
+	return s.next.DiffTree(ctx, pageID, since)
+}
+
+func (s *authorizedBlockService) ArchiveBlock(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID) error {
+	if err := s.check(ctx, true, spaceID, blockID); err != nil {
+		return err
+	}
+	return s.next.ArchiveBlock(ctx, spaceID, blockID)
+}
+
+func (s *authorizedBlockService) SearchBlocks(ctx context.Context, spaceID uuid.UUID, query string, opts SearchOptions) ([]model.Block, error) {
+	var blockID uuid.UUID
+	if opts.WithinPageID != nil {
+		blockID = *opts.WithinPageID
+	}
+	if err := s.check(ctx, false, spaceID, blockID); err != nil {
+		return nil, err
+	}
+	return s.next.SearchBlocks(ctx, spaceID, query, opts)
+}
+
+func (s *authorizedBlockService) ConvertBlockType(ctx context.Context, spaceID uuid.UUID, id uuid.UUID, newType string) error {
+	if err := s.check(ctx, true, spaceID, id); err != nil {
+		return err
+	}
+	return s.next.ConvertBlockType(ctx, spaceID, id, newType)
+}
+
+func (s *authorizedBlockService) ArchiveWhere(ctx context.Context, spaceID uuid.UUID, match map[string]any) (int, error) {
+	if err := s.check(ctx, true, spaceID, uuid.Nil); err != nil {
+		return 0, err
+	}
+	return s.next.ArchiveWhere(ctx, spaceID, match)
+}
+
+func (s *authorizedBlockService) DeletePageRecursive(ctx context.Context, spaceID uuid.UUID, pageID uuid.UUID) (int, error) {
+	if err := s.check(ctx, true, spaceID, pageID); err != nil {
+		return 0, err
+	}
+	return s.next.DeletePageRecursive(ctx, spaceID, pageID)
+}
+
+func (s *authorizedBlockService) DuplicatePage(ctx context.Context, spaceID uuid.UUID, pageID uuid.UUID, newParentID *uuid.UUID) (*model.Block, error) {
+	if err := s.check(ctx, false, spaceID, pageID); err != nil {
+		return nil, err
+	}
+	if err := s.checkParent(ctx, true, spaceID, newParentID); err != nil {
+		return nil, err
+	}
+	return s.next.DuplicatePage(ctx, spaceID, pageID, newParentID)
+}
+
+func (s *authorizedBlockService) ListChildrenPaged(ctx context.Context, parentID uuid.UUID, limit int, cursor string) ([]model.Block, string, error) {
+	if err := s.checkBlock(ctx, false, parentID); err != nil {
+		return nil, "", err
+	}
+	return s.next.ListChildrenPaged(ctx, parentID, limit, cursor)
+}
+
+func (s *authorizedBlockService) ListArchivedPages(ctx context.Context, spaceID uuid.UUID) ([]model.Block, error) {
+	if err := s.check(ctx, false, spaceID, uuid.Nil); err != nil {
+		return nil, err
+	}
+	return s.next.ListArchivedPages(ctx, spaceID)
+}
+
+func (s *authorizedBlockService) PurgeDeleted(ctx context.Context, spaceID uuid.UUID, olderThan time.Time) (int, error) {
+	if err := s.check(ctx, true, spaceID, uuid.Nil); err != nil {
+		return 0, err
+	}
+	return s.next.PurgeDeleted(ctx, spaceID, olderThan)
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"gorm.io/datatypes"
+)
+
+// clipboardVersion is bumped whenever the clipboard payload changes shape
+const clipboardVersion = 1
+
+// clipboard is the self-contained payload produced by SerializeBlocks
+type clipboard struct {
+	Version int             `json:"version"`
+	Blocks  []clipboardNode `json:"blocks"`
+}
+
+type clipboardNode struct {
+	Type     string          `json:"type"`
+	Title    string          `json:"title"`
+	Props    map[string]any  `json:"props,omitempty"`
+	Children []clipboardNode `json:"children,omitempty"`
+}
+
+// SerializeBlocks captures the selected blocks and their active descendants as a
+// self-contained payload for PasteBlocks. Selected blocks nested under another
+// selected block are only included once, through their ancestor.
+func (s *blockService) SerializeBlocks(ctx context.Context, ids []uuid.UUID) ([]byte, error) {
+	if len(ids) == 0 {
+		return nil, errors.New("no blocks selected")
+	}
+
+	selected := make([]*model.Block, 0, len(ids))
+	selectedIDs := make(map[uuid.UUID]struct{}, len(ids))
+	for _, id := range ids {
+		b, err := s.r.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if len(selected) > 0 && b.SpaceID != selected[0].SpaceID {
+			return nil, errors.New("selected blocks must belong to the same space")
+		}
+		selected = append(selected, b)
+		selectedIDs[id] = struct{}{}
+	}
+
+	all, err := s.r.ListAllBySpace(ctx, selected[0].SpaceID)
+	if err != nil {
+		return nil, err
+	}
+	children := make(map[uuid.UUID][]model.Block)
+	byID := make(map[uuid.UUID]model.Block, len(all))
+	for _, b := range all {
+		byID[b.ID] = b
+		if b.ParentID != nil {
+			children[*b.ParentID] = append(children[*b.ParentID], b)
+		}
+	}
+
+	noReplace := strings.NewReplacer()
+	var toNode func(b model.Block) clipboardNode
+	toNode = func(b model.Block) clipboardNode {
+		node := clipboardNode{
+			Type:  b.Type,
+			Title: b.Title,
+			Props: substituteProps(b.Props.Data(), noReplace),
+		}
+		for _, child := range children[b.ID] {
+			node.Children = append(node.Children, toNode(child))
+		}
+		return node
+	}
+
+	payload := clipboard{Version: clipboardVersion}
+	for _, b := range selected {
+		if hasSelectedAncestor(b, byID, selectedIDs) {
+			continue
+		}
+		payload.Blocks = append(payload.Blocks, toNode(*b))
+	}
+
+	return json.Marshal(payload)
+}
+
+func hasSelectedAncestor(b *model.Block, byID map[uuid.UUID]model.Block, selectedIDs map[uuid.UUID]struct{}) bool {
+	for parentID := b.ParentID; parentID != nil; {
+		if _, ok := selectedIDs[*parentID]; ok {
+			return true
+		}
+		parent, ok := byID[*parentID]
+		if !ok {
+			return false
+		}
+		parentID = parent.ParentID
+	}
+	return false
+}
+
+// PasteBlocks recreates a SerializeBlocks payload under parentID with new IDs.
+// Pasted roots are appended after the parent's existing children and keep their
+// copied order; the created blocks are returned parents first.
+func (s *blockService) PasteBlocks(ctx context.Context, spaceID uuid.UUID, parentID uuid.UUID, data []byte) ([]model.Block, error) {
+	var payload clipboard
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, fmt.Errorf("invalid clipboard data: %w", err)
+	}
+	if payload.Version != clipboardVersion {
+		return nil, fmt.Errorf("unsupported clipboard version: %d", payload.Version)
+	}
+	if len(payload.Blocks) == 0 {
+		return nil, nil
+	}
+
+	parent, err := s.r.Get(ctx, parentID)
+	if err != nil {
+		return nil, err
+	}
+	if parent.SpaceID != spaceID {
+		return nil, errors.New("parent not found in space")
+	}
+	if parent.IsLocked {
+		return nil, ErrLocked
+	}
+
+	var created []*model.Block
+	var build func(node clipboardNode, parent *model.Block, sort int64) error
+	build = func(node clipboardNode, parent *model.Block, sort int64) error {
+		b := &model.Block{
+			ID:       uuid.New(),
+			SpaceID:  spaceID,
+			Type:     node.Type,
+			ParentID: &parent.ID,
+			Title:    node.Title,
+			Props:    datatypes.NewJSONType(node.Props),
+			Sort:     sort,
+		}
+		if b.Props.Data() == nil {
+			b.Props = datatypes.NewJSONType(map[string]any{})
+		}
+		if err := b.Validate(); err != nil {
+			return err
+		}
+		if err := b.ValidateParentType(parent); err != nil {
+			return err
+		}
+		if b.Type == model.BlockTypeFolder {
+			path := b.Title
+			if parentPath := parent.GetFolderPath(); parentPath != "" {
+				path = parentPath + "/" + b.Title
+			}
+			b.SetFolderPath(path)
+		}
+		created = append(created, b)
+
+		for i, child := range node.Children {
+			if err := build(child, b, model.InitialSort+int64(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for i, node := range payload.Blocks {
+		if err := build(node, parent, int64(i)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.r.CreateBatch(ctx, created); err != nil {
+		return nil, err
+	}
+
+	out := make([]model.Block, len(created))
+	for i, b := range created {
+		out[i] = *b
+	}
+	return out, nil
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+)
+
+func TestBlockService_CopyPasteBlocks(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	sourcePageID := uuid.New()
+	targetPageID := uuid.New()
+
+	sourcePage := model.Block{ID: sourcePageID, SpaceID: spaceID, Type: model.BlockTypePage, Title: "Source"}
+	first := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeText, ParentID: &sourcePageID, Title: "First", Sort: 0,
+		Props: datatypes.NewJSONType(map[string]any{"text": "one"})}
+	second := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeText, ParentID: &sourcePageID, Title: "Second", Sort: 1,
+		Props: datatypes.NewJSONType(map[string]any{"text": "two"})}
+	unselected := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeText, ParentID: &sourcePageID, Title: "Skipped", Sort: 2}
+	targetPage := model.Block{ID: targetPageID, SpaceID: spaceID, Type: model.BlockTypePage, Title: "Target"}
+
+	repo := &MockBlockRepo{}
+	repo.On("Get", ctx, first.ID).Return(&first, nil)
+	repo.On("Get", ctx, second.ID).Return(&second, nil)
+	repo.On("Get", ctx, targetPageID).Return(&targetPage, nil)
+	repo.On("ListAllBySpace", ctx, spaceID).Return([]model.Block{sourcePage, first, second, unselected, targetPage}, nil)
+
+	var created []*model.Block
+	repo.On("CreateBatch", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		created = args.Get(1).([]*model.Block)
+	}).Return(nil)
+
+	service := NewBlockService(repo)
+
+	data, err := service.SerializeBlocks(ctx, []uuid.UUID{first.ID, second.ID})
+	require.NoError(t, err)
+
+	pasted, err := service.PasteBlocks(ctx, spaceID, targetPageID, data)
+	require.NoError(t, err)
+	require.Len(t, pasted, 2)
+	require.Len(t, created, 2)
+
+	for i, want := range []model.Block{first, second} {
+		got := pasted[i]
+		assert.NotEqual(t, want.ID, got.ID)
+		assert.Equal(t, spaceID, got.SpaceID)
+		require.NotNil(t, got.ParentID)
+		assert.Equal(t, targetPageID, *got.ParentID)
+		assert.Equal(t, want.Title, got.Title)
+		assert.Equal(t, want.Props.Data(), got.Props.Data())
+	}
+	repo.AssertExpectations(t)
+}
+
+func TestBlockService_SerializeBlocks_NestedSelection(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	folder := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeFolder, Title: "Folder"}
+	page := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypePage, ParentID: &folder.ID, Title: "Page"}
+	text := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeText, ParentID: &page.ID, Title: "Text"}
+
+	repo := &MockBlockRepo{}
+	repo.On("Get", ctx, folder.ID).Return(&folder, nil)
+	repo.On("Get", ctx, text.ID).Return(&text, nil)
+	repo.On("ListAllBySpace", ctx, spaceID).Return([]model.Block{folder, page, text}, nil)
+
+	service := NewBlockService(repo)
+	data, err := service.SerializeBlocks(ctx, []uuid.UUID{folder.ID, text.ID})
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{"version":1,"blocks":[{"type":"folder","title":"Folder","children":[
+		{"type":"page","title":"Page","children":[{"type":"text","title":"Text"}]}
+	]}]}`, string(data))
+}
+
+func TestBlockService_PasteBlocks_InvalidParentType(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	folder := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeFolder, Title: "Folder"}
+
+	repo := &MockBlockRepo{}
+	repo.On("Get", ctx, folder.ID).Return(&folder, nil)
+
+	service := NewBlockService(repo)
+	_, err := service.PasteBlocks(ctx, spaceID, folder.ID, []byte(`{"version":1,"blocks":[{"type":"text","title":"T"}]}`))
+	assert.Error(t, err)
+	repo.AssertNotCalled(t, "CreateBatch", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TreeDiff lists the blocks of a page tree that changed since a point in time.
+// Each block appears in at most one bucket.
+type TreeDiff struct {
+	Added   []uuid.UUID `json:"added"`
+	Updated []uuid.UUID `json:"updated"`
+	Removed []uuid.UUID `json:"removed"`
+	Moved   []uuid.UUID `json:"moved"`
+}
+
+// DiffTree reports how pageID's subtree changed after since, so a client holding a
+// snapshot taken at since can patch it. Blocks created after since are added, archived
+// or deleted ones removed, ones whose parent changed moved, and any other change
+// updated. A block both created and removed since is omitted. Deleted blocks already
+// purged leave no trace and are not reported.
+func (s *blockService) DiffTree(ctx context.Context, pageID uuid.UUID, since time.Time) (TreeDiff, error) {
+	diff := TreeDiff{
+		Added:   []uuid.UUID{},
+		Updated: []uuid.UUID{},
+		Removed: []uuid.UUID{},
+		Moved:   []uuid.UUID{},
+	}
+	if len(pageID) == 0 {
+		return diff, errors.New("page id is empty")
+	}
+
+	changed, err := s.r.ListSubtreeChangedSince(ctx, pageID, since)
+	if err != nil {
+		return diff, err
+	}
+
+	for _, b := range changed {
+		created := b.CreatedAt.After(since)
+		removed := b.IsArchived || b.DeletedAt.Valid
+		switch {
+		case removed && created:
+			continue
+		case removed:
+			diff.Removed = append(diff.Removed, b.ID)
+		case created:
+			diff.Added = append(diff.Added, b.ID)
+		case b.MovedAt != nil && b.MovedAt.After(since):
+			diff.Moved = append(diff.Moved, b.ID)
+		default:
+			diff.Updated = append(diff.Updated, b.ID)
+		}
+	}
+	return diff, nil
+}
@@ -0,0 +1,348 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+)
+
+// Props keys read when rendering text blocks. Props["kind"] is one of the
+// exportKind values and defaults to a paragraph. Props["indent"] is the number
+// of list items a text block is nested under among its siblings, since text
+// blocks hold no children of their own.
+const (
+	exportPropKind     = "kind"
+	exportPropLevel    = "level"
+	exportPropLanguage = "language"
+	exportPropText     = "text"
+	exportPropIndent   = "indent"
+)
+
+const (
+	exportKindHeading  = "heading"
+	exportKindList     = "list_item"
+	exportKindNumbered = "numbered_item"
+	exportKindCode     = "code"
+	exportKindQuote    = "quote"
+)
+
+// ExportPageHTML renders a page and its active descendants as semantic HTML.
+// The page becomes a <section> headed by its title; consecutive list items are
+// grouped into a <ul>, and items nested under an item, as its children or by
+// props.indent, open a <ul> inside its <li>. All text is HTML-escaped.
+func (s *blockService) ExportPageHTML(ctx context.Context, pageID uuid.UUID) (string, error) {
+	if len(pageID) == 0 {
+		return "", errors.New("page id is empty")
+	}
+	page, err := s.r.Get(ctx, pageID)
+	if err != nil {
+		return "", err
+	}
+	if page.Type != model.BlockTypePage {
+		return "", fmt.Errorf("block %s is not a page", pageID)
+	}
+
+	descendants, err := s.r.ListSubtree(ctx, pageID, 0)
+	if err != nil {
+		return "", err
+	}
+	children := make(map[uuid.UUID][]model.Block)
+	for _, b := range descendants {
+		if b.ParentID != nil {
+			children[*b.ParentID] = append(children[*b.ParentID], b)
+		}
+	}
+
+	w := &htmlWriter{children: children}
+	w.writePage(*page, 1)
+	return w.sb.String(), nil
+}
+
+// htmlWriter emits HTML elements one per line. bare is set while the text of a
+// list item is the last thing written and its <li> is still open, so nested
+// content starts on a new line and a leaf item stays on one.
+type htmlWriter struct {
+	sb       strings.Builder
+	children map[uuid.UUID][]model.Block
+	bare     bool
+}
+
+func (w *htmlWriter) write(format string, args ...any) {
+	if w.bare {
+		w.sb.WriteString("\n")
+		w.bare = false
+	}
+	fmt.Fprintf(&w.sb, format, args...)
+}
+
+// closeItem closes the innermost open <li>
+func (w *htmlWriter) closeItem() {
+	w.bare = false
+	w.sb.WriteString("</li>\n")
+}
+
+func (w *htmlWriter) writePage(page model.Block, depth int) {
+	w.write("<section>\n")
+	w.write("<h%d>%s</h%d>\n", min(depth, 6), html.EscapeString(page.Title), min(depth, 6))
+	w.writeBlocks(w.children[page.ID], depth)
+	w.write("</section>\n")
+}
+
+// writeBlocks renders a run of siblings; depth is the heading level of the page
+// that holds them
+func (w *htmlWriter) writeBlocks(blocks []model.Block, depth int) {
+	// open counts the <ul> elements left open for items nested by props.indent;
+	// each holds an open <li>
+	open := 0
+	for _, b := range blocks {
+		isItem, level := false, 0
+		if b.Type == model.BlockTypeText {
+			isItem = exportKind(b) == exportKindList
+			level = min(exportIndent(b), open)
+		}
+		want := level
+		if isItem {
+			want = level + 1
+		}
+		for ; open > want; open-- {
+			w.closeItem()
+			w.write("</ul>\n")
+		}
+		if isItem {
+			if open == want {
+				w.closeItem()
+			} else {
+				w.write("<ul>\n")
+				open++
+			}
+		}
+
+		switch b.Type {
+		case model.BlockTypePage:
+			w.writePage(b, depth+1)
+		case model.BlockTypeText:
+			w.writeText(b, depth)
+		default:
+			w.writeSection(b, depth)
+		}
+	}
+	for ; open > 0; open-- {
+		w.closeItem()
+		w.write("</ul>\n")
+	}
+}
+
+// writeText renders a text block and its children. A list item's <li> is left
+// open for writeBlocks to close.
+func (w *htmlWriter) writeText(b model.Block, depth int) {
+	text := html.EscapeString(exportText(b))
+	switch exportKind(b) {
+	case exportKindHeading:
+		level := depth + 1
+		if l, ok := b.Props.Data()[exportPropLevel].(float64); ok && l >= 1 {
+			level = int(l)
+		}
+		level = min(level, 6)
+		w.write("<h%d>%s</h%d>\n", level, text, level)
+	case exportKindList:
+		w.write("<li>%s", text)
+		w.bare = true
+	case exportKindCode:
+		if lang, _ := b.Props.Data()[exportPropLanguage].(string); lang != "" {
+			w.write("<pre><code class=\"language-%s\">%s</code></pre>\n", html.EscapeString(lang), text)
+		} else {
+			w.write("<pre><code>%s</code></pre>\n", text)
+		}
+	default:
+		w.write("<p>%s</p>\n", text)
+	}
+	w.writeBlocks(w.children[b.ID], depth)
+}
+
+// writeSection renders non-text blocks (e.g. SOPs) as a titled section
+func (w *htmlWriter) writeSection(b model.Block, depth int) {
+	level := min(depth+1, 6)
+	w.write("<section class=\"%s\">\n", html.EscapeString(b.Type))
+	if b.Title != "" {
+		w.write("<h%d>%s</h%d>\n", level, html.EscapeString(b.Title), level)
+	}
+	if text, _ := b.Props.Data()[exportPropText].(string); text != "" {
+		w.write("<p>%s</p>\n", html.EscapeString(text))
+	}
+	w.write("</section>\n")
+}
+
+// ExportPageMarkdown renders a page and its active descendants as Markdown. The
+// page title is the top-level heading and nested pages open deeper headings.
+// Bullet and numbered items are indented under the item they belong to, so list
+// nesting follows the tree and each text block's props.indent. Blocks of other
+// types fall back to their title as a paragraph.
+func (s *blockService) ExportPageMarkdown(ctx context.Context, pageID uuid.UUID) (string, error) {
+	if len(pageID) == 0 {
+		return "", errors.New("page id is empty")
+	}
+	page, err := s.r.Get(ctx, pageID)
+	if err != nil {
+		return "", err
+	}
+	if page.Type != model.BlockTypePage {
+		return "", fmt.Errorf("block %s is not a page", pageID)
+	}
+
+	descendants, err := s.r.ListSubtree(ctx, pageID, 0)
+	if err != nil {
+		return "", err
+	}
+	children := make(map[uuid.UUID][]model.Block)
+	for _, b := range descendants {
+		if b.ParentID != nil {
+			children[*b.ParentID] = append(children[*b.ParentID], b)
+		}
+	}
+
+	w := &markdownWriter{children: children}
+	w.writeHeading(1, page.Title)
+	w.writeChildren(page.ID, "", 1)
+	return w.sb.String(), nil
+}
+
+// markdownWriter emits Markdown blocks separated by blank lines, except between
+// consecutive list items
+type markdownWriter struct {
+	sb       strings.Builder
+	children map[uuid.UUID][]model.Block
+	inList   bool
+}
+
+// writeChildren renders the children of parentID. indent prefixes every line when
+// the children hang under a list item; depth is the heading level of the page
+// that holds them.
+func (w *markdownWriter) writeChildren(parentID uuid.UUID, indent string, depth int) {
+	// items holds the indent of the text of each preceding list item a sibling
+	// can nest under by its props.indent, and numbers the count of consecutive
+	// numbered items at each of those levels
+	var items []string
+	var numbers []int
+	for _, child := range w.children[parentID] {
+		kind, level := "", 0
+		if child.Type == model.BlockTypeText {
+			kind = exportKind(child)
+			level = min(exportIndent(child), len(items))
+		}
+		prefix := indent
+		if level > 0 {
+			prefix = items[level-1]
+		}
+		items = items[:level]
+		numbers = numbers[:min(level+1, len(numbers))]
+		for len(numbers) <= level {
+			numbers = append(numbers, 0)
+		}
+		if kind == exportKindNumbered {
+			numbers[level]++
+		} else {
+			numbers[level] = 0
+		}
+
+		switch {
+		case child.Type == model.BlockTypePage:
+			w.writeHeading(depth+1, child.Title)
+			w.writeChildren(child.ID, indent, depth+1)
+		case kind == exportKindList:
+			items = append(items, w.writeItem(child, prefix, "- ", depth))
+		case kind == exportKindNumbered:
+			items = append(items, w.writeItem(child, prefix, fmt.Sprintf("%d. ", numbers[level]), depth))
+		case child.Type == model.BlockTypeText:
+			w.writeText(child, prefix, depth)
+		default:
+			w.writeBlock(prefixLines(child.Title, indent))
+			w.writeChildren(child.ID, indent, depth)
+		}
+	}
+}
+
+// writeItem renders a list item and returns the indent of its text, under which
+// nested blocks align
+func (w *markdownWriter) writeItem(b model.Block, indent string, marker string, depth int) string {
+	if w.sb.Len() > 0 && !w.inList {
+		w.sb.WriteString("\n")
+	}
+	// Continuation lines and nested blocks align with the item's text
+	inner := indent + strings.Repeat(" ", len(marker))
+	lines := strings.Split(exportText(b), "\n")
+	w.sb.WriteString(indent + marker + lines[0] + "\n")
+	for _, line := range lines[1:] {
+		w.sb.WriteString(inner + line + "\n")
+	}
+	w.inList = true
+	w.writeChildren(b.ID, inner, depth)
+	return inner
+}
+
+func (w *markdownWriter) writeText(b model.Block, indent string, depth int) {
+	text := exportText(b)
+	switch exportKind(b) {
+	case exportKindHeading:
+		level := depth + 1
+		if l, ok := b.Props.Data()[exportPropLevel].(float64); ok && l >= 1 {
+			level = int(l)
+		}
+		w.writeHeading(level, text)
+	case exportKindCode:
+		lang, _ := b.Props.Data()[exportPropLanguage].(string)
+		w.writeBlock(prefixLines("```"+lang+"\n"+text+"\n```", indent))
+	case exportKindQuote:
+		w.writeBlock(prefixLines(text, indent+"> "))
+	default:
+		w.writeBlock(prefixLines(text, indent))
+	}
+	w.writeChildren(b.ID, indent, depth)
+}
+
+func (w *markdownWriter) writeHeading(level int, text string) {
+	w.writeBlock(strings.Repeat("#", min(max(level, 1), 6)) + " " + text)
+}
+
+// writeBlock writes a non-list block, separated from what precedes it by a blank line
+func (w *markdownWriter) writeBlock(text string) {
+	if w.sb.Len() > 0 {
+		w.sb.WriteString("\n")
+	}
+	w.sb.WriteString(text)
+	w.sb.WriteString("\n")
+	w.inList = false
+}
+
+// prefixLines prepends prefix to every line of text
+func prefixLines(text string, prefix string) string {
+	if prefix == "" {
+		return text
+	}
+	return prefix + strings.ReplaceAll(text, "\n", "\n"+prefix)
+}
+
+func exportKind(b model.Block) string {
+	kind, _ := b.Props.Data()[exportPropKind].(string)
+	return kind
+}
+
+// exportIndent is the block's props.indent, or 0
+func exportIndent(b model.Block) int {
+	if n, ok := b.Props.Data()[exportPropIndent].(float64); ok && n >= 1 {
+		return int(n)
+	}
+	return 0
+}
+
+// exportText is the block's props.text, falling back to its title
+func exportText(b model.Block) string {
+	if text, ok := b.Props.Data()[exportPropText].(string); ok && text != "" {
+		return text
+	}
+	return b.Title
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+)
+
+func TestBlockService_ExportPageHTML(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	pageID := uuid.New()
+
+	page := model.Block{ID: pageID, SpaceID: spaceID, Type: model.BlockTypePage, Title: "Tips & <Tricks>"}
+	para := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeText, ParentID: &pageID, Sort: 0,
+		Props: datatypes.NewJSONType(map[string]any{"text": `<script>alert("x")</script>`})}
+	item1 := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeText, ParentID: &pageID, Sort: 1,
+		Props: datatypes.NewJSONType(map[string]any{"kind": "list_item", "text": "one"})}
+	item2 := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeText, ParentID: &pageID, Sort: 2,
+		Props: datatypes.NewJSONType(map[string]any{"kind": "list_item", "text": "two"})}
+	code := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeText, ParentID: &pageID, Sort: 3,
+		Props: datatypes.NewJSONType(map[string]any{"kind": "code", "language": "go", "text": "if a < b {}"})}
+
+	repo := &MockBlockRepo{}
+	repo.On("Get", ctx, pageID).Return(&page, nil)
+	repo.On("ListSubtree", ctx, pageID, 0).Return([]model.Block{para, item1, item2, code}, nil)
+
+	service := NewBlockService(repo)
+	out, err := service.ExportPageHTML(ctx, pageID)
+	require.NoError(t, err)
+
+	want := "<section>\n" +
+		"<h1>Tips &amp; &lt;Tricks&gt;</h1>\n" +
+		"<p>&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;</p>\n" +
+		"<ul>\n<li>one</li>\n<li>two</li>\n</ul>\n" +
+		"<pre><code class=\"language-go\">if a &lt; b {}</code></pre>\n" +
+		"</section>\n"
+	assert.Equal(t, want, out)
+	assert.NotContains(t, out, "<script>")
+}
+
+func TestBlockService_ExportPageHTML_NestedList(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	page := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypePage, Title: "Setup"}
+
+	text := func(parent model.Block, sort int64, props map[string]any) model.Block {
+		return model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeText, ParentID: &parent.ID, Sort: sort,
+			Props: datatypes.NewJSONType(props)}
+	}
+	clone := text(page, 0, map[string]any{"kind": "list_item", "text": "Clone"})
+	shallow := text(page, 1, map[string]any{"kind": "list_item", "text": "shallow", "indent": float64(1)})
+	fast := text(page, 2, map[string]any{"kind": "list_item", "text": "fast", "indent": float64(2)})
+	build := text(page, 3, map[string]any{"kind": "list_item", "text": "Build"})
+	race := text(build, 0, map[string]any{"kind": "list_item", "text": "with -race"})
+	done := text(page, 4, map[string]any{"text": "Done."})
+
+	repo := &MockBlockRepo{}
+	repo.On("Get", ctx, page.ID).Return(&page, nil)
+	repo.On("ListSubtree", ctx, page.ID, 0).Return([]model.Block{clone, shallow, fast, build, done, race}, nil)
+
+	out, err := NewBlockService(repo).ExportPageHTML(ctx, page.ID)
+	require.NoError(t, err)
+
+	// Children and indented siblings both nest inside the item's <li>
+	want := "<section>\n" +
+		"<h1>Setup</h1>\n" +
+		"<ul>\n" +
+		"<li>Clone\n" +
+		"<ul>\n<li>shallow\n" +
+		"<ul>\n<li>fast</li>\n</ul>\n" +
+		"</li>\n</ul>\n" +
+		"</li>\n" +
+		"<li>Build\n" +
+		"<ul>\n<li>with -race</li>\n</ul>\n" +
+		"</li>\n" +
+		"</ul>\n" +
+		"<p>Done.</p>\n" +
+		"</section>\n"
+	assert.Equal(t, want, out)
+	repo.AssertExpectations(t)
+}
+
+func TestBlockService_ExportPageHTML_NotAPage(t *testing.T) {
+	ctx := context.Background()
+	parentID := uuid.New()
+	text := model.Block{ID: uuid.New(), SpaceID: uuid.New(), Type: model.BlockTypeText, ParentID: &parentID}
+
+	repo := &MockBlockRepo{}
+	repo.On("Get", ctx, text.ID).Return(&text, nil)
+
+	_, err := NewBlockService(repo).ExportPageHTML(ctx, text.ID)
+	assert.Error(t, err)
+}
+
+func TestBlockService_ExportPageMarkdown(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	page := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypePage, Title: "Runbook"}
+
+	text := func(parent model.Block, sort int64, props map[string]any) model.Block {
+		return model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeText, ParentID: &parent.ID, Sort: sort,
+			Props: datatypes.NewJSONType(props)}
+	}
+	intro := text(page, 0, map[string]any{"text": "Read this first."})
+	setup := text(page, 1, map[string]any{"kind": "heading", "text": "Setup"})
+	install := text(page, 2, map[string]any{"kind": "heading", "level": float64(3), "text": "Install"})
+	step1 := text(page, 3, map[string]any{"kind": "numbered_item", "text": "Clone"})
+	step2 := text(page, 4, map[string]any{"kind": "numbered_item", "text": "Build"})
+	flag := text(step2, 0, map[string]any{"kind": "list_item", "text": "with -race"})
+	detail := text(flag, 0, map[string]any{"kind": "list_item", "text": "slower"})
+	code := text(page, 5, map[string]any{"kind": "code", "language": "sh", "text": "make\nmake test"})
+	quote := text(page, 6, map[string]any{"kind": "quote", "text": "Ship it.\nTwice."})
+	sop := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeSOP, ParentID: &page.ID, Sort: 7, Title: "Deploy SOP"}
+
+	repo := &MockBlockRepo{}
+	repo.On("Get", ctx, page.ID).Return(&page, nil)
+	repo.On("ListSubtree", ctx, page.ID, 0).
+		Return([]model.Block{intro, setup, install, step1, step2, code, quote, sop, flag, detail}, nil)
+
+	out, err := NewBlockService(repo).ExportPageMarkdown(ctx, page.ID)
+	require.NoError(t, err)
+
+	want := "# Runbook\n" +
+		"\nRead this first.\n" +
+		"\n## Setup\n" +
+		"\n### Install\n" +
+		"\n1. Clone\n" +
+		"2. Build\n" +
+		"   - with -race\n" +
+		"     - slower\n" +
+		"\n```sh\nmake\nmake test\n```\n" +
+		"\n> Ship it.\n> Twice.\n" +
+		"\nDeploy SOP\n"
+	assert.Equal(t, want, out)
+	repo.AssertExpectations(t)
+}
+
+func TestBlockService_ExportPageMarkdown_NotAPage(t *testing.T) {
+	ctx := context.Background()
+	parentID := uuid.New()
+	text := model.Block{ID: uuid.New(), SpaceID: uuid.New(), Type: model.BlockTypeText, ParentID: &parentID}
+
+	repo := &MockBlockRepo{}
+	repo.On("Get", ctx, text.ID).Return(&text, nil)
+
+	_, err := NewBlockService(repo).ExportPageMarkdown(ctx, text.ID)
+	assert.Error(t, err)
+	repo.AssertNotCalled(t, "ListSubtree", mock.Anything, mock.Anything, mock.Anything)
+}
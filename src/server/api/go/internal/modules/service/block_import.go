@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"gorm.io/datatypes"
+)
+
+// importUntitled titles an imported page whose Markdown does not open with a level-1 heading
+const importUntitled = "Untitled"
+
+// markdownNode is a block parsed from Markdown. kind is one of the exportKind
+// values, empty for a paragraph; indent is the number of list items it is
+// nested under.
+type markdownNode struct {
+	kind     string
+	text     string
+	level    int
+	language string
+	indent   int
+}
+
+// ImportMarkdown parses md into a new page under parentID, the inverse of
+// ExportPageMarkdown. A leading level-1 heading becomes the page title; the
+// remaining headings, paragraphs, quotes, code fences and list items become
+// text blocks, all children of the page in document order. Text blocks hold no
+// children, so a block nested under a list item keeps its depth in props.indent
+// instead. The page is appended to its siblings and returned with its Children
+// linked.
+func (s *blockService) ImportMarkdown(ctx context.Context, spaceID uuid.UUID, parentID *uuid.UUID, md string) (*model.Block, error) {
+	if len(spaceID) == 0 {
+		return nil, errors.New("space id is empty")
+	}
+
+	title, nodes := parseMarkdown(md)
+	if title == "" {
+		title = importUntitled
+	}
+
+	page := &model.Block{
+		ID:       uuid.New(),
+		SpaceID:  spaceID,
+		Type:     model.BlockTypePage,
+		ParentID: parentID,
+		Title:    title,
+	}
+	if err := page.Validate(); err != nil {
+		return nil, err
+	}
+
+	var parent *model.Block
+	if parentID != nil {
+		var err error
+		parent, err = s.r.Get(ctx, *parentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent.SpaceID != spaceID {
+			return nil, errors.New("parent not found in space")
+		}
+		if parent.IsLocked {
+			return nil, ErrLocked
+		}
+	}
+	if err := s.prepareImported(ctx, page, parent, map[string]any{}); err != nil {
+		return nil, err
+	}
+
+	created := []*model.Block{page}
+	for i, node := range nodes {
+		b := &model.Block{
+			ID:       uuid.New(),
+			SpaceID:  spaceID,
+			Type:     model.BlockTypeText,
+			ParentID: &page.ID,
+			Sort:     model.InitialSort + int64(i),
+		}
+		if err := s.prepareImported(ctx, b, page, node.props()); err != nil {
+			return nil, err
+		}
+		created = append(created, b)
+		page.Children = append(page.Children, b)
+	}
+
+	if err := s.r.CreateBatch(ctx, created); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// prepareImported checks that b may sit under parent, then sets its props and fills in
+// defaults, checking them against the space's schema
+func (s *blockService) prepareImported(ctx context.Context, b *model.Block, parent *model.Block, props map[string]any) error {
+	if err := b.ValidateParentType(parent); err != nil {
+		return err
+	}
+	b.Props = datatypes.NewJSONType(props)
+	if err := s.applyDefaultProps(ctx, b); err != nil {
+		return err
+	}
+	return s.validateProps(ctx, b.SpaceID, b.Type, b.Props.Data())
+}
+
+func (n *markdownNode) props() map[string]any {
+	props := map[string]any{exportPropText: n.text}
+	if n.kind != "" {
+		props[exportPropKind] = n.kind
+	}
+	if n.level > 0 {
+		props[exportPropLevel] = float64(n.level)
+	}
+	if n.language != "" {
+		props[exportPropLanguage] = n.language
+	}
+	if n.indent > 0 {
+		props[exportPropIndent] = float64(n.indent)
+	}
+	return props
+}
+
+// markdownParser reads the block structure of a Markdown document line by line.
+// It understands ATX headings, fenced code, block quotes, bullet and numbered
+// lists nested by indentation, and paragraphs; inline markup is kept as text.
+type markdownParser struct {
+	lines []string
+	title string
+	nodes []*markdownNode
+	// items holds the columns where the markers of the list items enclosing the
+	// current line start, outermost first
+	items []int
+	// open is the paragraph, quote or list item the next plain line continues
+	open *markdownNode
+}
+
+func parseMarkdown(md string) (string, []*markdownNode) {
+	md = strings.ReplaceAll(md, "\r\n", "\n")
+	p := &markdownParser{lines: strings.Split(md, "\n")}
+	for i := 0; i < len(p.lines); i++ {
+		i = p.parseLine(i)
+	}
+	return p.title, p.nodes
+}
+
+// parseLine consumes the line at i and returns the index of the last line it used
+func (p *markdownParser) parseLine(i int) int {
+	line := strings.ReplaceAll(p.lines[i], "\t", "    ")
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" {
+		p.open = nil
+		return i
+	}
+	indent := len(line) - len(strings.TrimLeft(line, " "))
+
+	if marker, kind, ok := listMarker(trimmed); ok {
+		node := &markdownNode{kind: kind, text: strings.TrimSpace(trimmed[len(marker):])}
+		p.attach(node, indent)
+		p.items = append(p.items, indent)
+		p.open = node
+		return i
+	}
+
+	if fence := codeFence(trimmed); fence != "" {
+		node := &markdownNode{kind: exportKindCode, language: strings.TrimSpace(trimmed[len(fence):])}
+		if fields := strings.Fields(node.language); len(fields) > 0 {
+			node.language = fields[0]
+		}
+		var body []string
+		j := i + 1
+		for ; j < len(p.lines); j++ {
+			content := strings.ReplaceAll(p.lines[j], "\t", "    ")
+			if closing := strings.TrimSpace(content); strings.HasPrefix(closing, fence) && strings.Trim(closing, fence[:1]) == "" {
+				break
+			}
+			// Content is indented like its fence when the block sits under a list item
+			body = append(body, content[min(indent, len(content)-len(strings.TrimLeft(content, " "))):])
+		}
+		node.text = strings.Join(body, "\n")
+		p.attach(node, indent)
+		p.open = nil
+		return j
+	}
+
+	if level := headingLevel(trimmed); level > 0 {
+		text := strings.TrimSpace(trimmed[level:])
+		if level == 1 && p.title == "" && len(p.nodes) == 0 {
+			p.title = text
+			return i
+		}
+		p.attach(&markdownNode{kind: exportKindHeading, text: text, level: level}, indent)
+		p.open = nil
+		return i
+	}
+
+	if strings.HasPrefix(trimmed, ">") {
+		text := strings.TrimPrefix(strings.TrimPrefix(trimmed, ">"), " ")
+		if p.open != nil && p.open.kind == exportKindQuote {
+			p.open.text += "\n" + text
+			return i
+		}
+		node := &markdownNode{kind: exportKindQuote, text: text}
+		p.attach(node, indent)
+		p.open = node
+		return i
+	}
+
+	if p.open != nil && p.open.kind != exportKindQuote {
+		p.open.text += "\n" + trimmed
+		return i
+	}
+	node := &markdownNode{text: trimmed}
+	p.attach(node, indent)
+	p.open = node
+	return i
+}
+
+// attach adds node nested under the list items it is indented past, closing
+// the items it is not
+func (p *markdownParser) attach(node *markdownNode, indent int) {
+	for len(p.items) > 0 && indent <= p.items[len(p.items)-1] {
+		p.items = p.items[:len(p.items)-1]
+	}
+	node.indent = len(p.items)
+	p.nodes = append(p.nodes, node)
+}
+
+// listMarker returns the marker opening a bullet ("- ", "* ", "+ ") or numbered
+// ("1. ", "1) ") list item, with the item's kind
+func listMarker(line string) (string, string, bool) {
+	if len(line) >= 2 && strings.ContainsRune("-*+", rune(line[0])) && line[1] == ' ' {
+		return line[:2], exportKindList, true
+	}
+	digits := len(line) - len(strings.TrimLeft(line, "0123456789"))
+	if digits == 0 || digits > 9 || digits+1 >= len(line) {
+		return "", "", false
+	}
+	if (line[digits] == '.' || line[digits] == ')') && line[digits+1] == ' ' {
+		return line[:digits+2], exportKindNumbered, true
+	}
+	return "", "", false
+}
+
+// codeFence returns the run of at least three backticks or tildes opening line
+func codeFence(line string) string {
+	for _, c := range []string{"`", "~"} {
+		n := len(line) - len(strings.TrimLeft(line, c))
+		if n >= 3 {
+			return line[:n]
+		}
+	}
+	return ""
+}
+
+// headingLevel returns the level of an ATX heading line, or 0
+func headingLevel(line string) int {
+	n := len(line) - len(strings.TrimLeft(line, "#"))
+	if n == 0 || n > 6 || (len(line) > n && line[n] != ' ') {
+		return 0
+	}
+	return n
+}
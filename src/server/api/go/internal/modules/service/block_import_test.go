@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockService_ImportMarkdown(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	folder := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeFolder, Title: "Docs"}
+
+	md := "# Runbook\n" +
+		"\n" +
+		"Read this\nfirst.\n" +
+		"\n" +
+		"## Setup\n" +
+		"- Clone\n" +
+		"- Build\n" +
+		"  - with -race\n" +
+		"    - slower\n" +
+		"  - with -v\n" +
+		"- Test\n" +
+		"\n" +
+		"```go\n" +
+		"func main() {\n" +
+		"\tprintln(\"# not a heading\")\n" +
+		"}\n" +
+		"```\n"
+
+	repo := &MockBlockRepo{}
+	repo.On("Get", ctx, folder.ID).Return(&folder, nil)
+	var created []*model.Block
+	repo.On("CreateBatch", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		created = args.Get(1).([]*model.Block)
+	}).Return(nil)
+
+	page, err := NewBlockService(repo).ImportMarkdown(ctx, spaceID, &folder.ID, md)
+	require.NoError(t, err)
+
+	assert.Equal(t, model.BlockTypePage, page.Type)
+	assert.Equal(t, "Runbook", page.Title)
+	assert.Equal(t, &folder.ID, page.ParentID)
+	require.Len(t, created, 10)
+	assert.Same(t, page, created[0])
+
+	require.Len(t, page.Children, 9)
+	for i, b := range page.Children {
+		assert.Equal(t, model.BlockTypeText, b.Type)
+		assert.Equal(t, &page.ID, b.ParentID)
+		assert.Equal(t, int64(i), b.Sort)
+		assert.Equal(t, spaceID, b.SpaceID)
+		assert.Same(t, b, created[i+1])
+	}
+
+	// Nested items follow the item they are indented under, keeping their depth
+	want := []map[string]any{
+		{"text": "Read this\nfirst."},
+		{"kind": "heading", "level": float64(2), "text": "Setup"},
+		{"kind": "list_item", "text": "Clone"},
+		{"kind": "list_item", "text": "Build"},
+		{"kind": "list_item", "text": "with -race", "indent": float64(1)},
+		{"kind": "list_item", "text": "slower", "indent": float64(2)},
+		{"kind": "list_item", "text": "with -v", "indent": float64(1)},
+		{"kind": "list_item", "text": "Test"},
+		{"kind": "code", "language": "go", "text": "func main() {\n    println(\"# not a heading\")\n}"},
+	}
+	for i, props := range want {
+		assert.Equal(t, props, page.Children[i].Props.Data())
+	}
+}
+
+func TestBlockService_ImportMarkdown_Headings(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+
+	repo := &MockBlockRepo{}
+	repo.On("CreateBatch", ctx, mock.Anything).Return(nil)
+
+	page, err := NewBlockService(repo).ImportMarkdown(ctx, spaceID, nil, "## Intro\n# Second title\n###### Deep\n####### Too deep\n#hashtag\n")
+	require.NoError(t, err)
+
+	// Only a leading level-1 heading titles the page
+	assert.Equal(t, importUntitled, page.Title)
+	require.Len(t, page.Children, 4)
+	assert.Equal(t, map[string]any{"kind": "heading", "level": float64(2), "text": "Intro"}, page.Children[0].Props.Data())
+	assert.Equal(t, map[string]any{"kind": "heading", "level": float64(1), "text": "Second title"}, page.Children[1].Props.Data())
+	assert.Equal(t, map[string]any{"kind": "heading", "level": float64(6), "text": "Deep"}, page.Children[2].Props.Data())
+	assert.Equal(t, map[string]any{"text": "####### Too deep\n#hashtag"}, page.Children[3].Props.Data())
+}
+
+func TestBlockService_ImportMarkdown_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	md := "# Notes\n" +
+		"\n## Steps\n" +
+		"\n1. One\n" +
+		"2. Two\n" +
+		"   - nested\n" +
+		"\n```sh\nmake\n```\n" +
+		"\n> Quoted\n> twice\n" +
+		"\nDone.\n"
+
+	repo := &MockBlockRepo{}
+	var created []*model.Block
+	repo.On("CreateBatch", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		created = args.Get(1).([]*model.Block)
+	}).Return(nil)
+
+	service := NewBlockService(repo)
+	page, err := service.ImportMarkdown(ctx, spaceID, nil, md)
+	require.NoError(t, err)
+
+	var descendants []model.Block
+	for _, b := range created[1:] {
+		descendants = append(descendants, *b)
+	}
+	repo.On("Get", ctx, page.ID).Return(page, nil)
+	repo.On("ListSubtree", ctx, page.ID, 0).Return(descendants, nil)
+
+	out, err := service.ExportPageMarkdown(ctx, page.ID)
+	require.NoError(t, err)
+	assert.Equal(t, md, out)
+}
+
+func TestBlockService_ImportMarkdown_InvalidParent(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	pageParent := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypePage}
+	otherSpace := model.Block{ID: uuid.New(), SpaceID: uuid.New(), Type: model.BlockTypeFolder}
+	locked := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeFolder, IsLocked: true}
+
+	tests := []struct {
+		name   string
+		parent model.Block
+		err    string
+	}{
+		{name: "page parent", parent: pageParent, err: "cannot be a child of"},
+		{name: "parent in another space", parent: otherSpace, err: "parent not found in space"},
+		{name: "locked parent", parent: locked, err: ErrLocked.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &MockBlockRepo{}
+			repo.On("Get", ctx, tt.parent.ID).Return(&tt.parent, nil)
+
+			_, err := NewBlockService(repo).ImportMarkdown(ctx, spaceID, &tt.parent.ID, "# Page\n")
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+			repo.AssertNotCalled(t, "CreateBatch", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestBlockService_ImportMarkdown_PropsSchema(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	schema := staticPropsSchema{spaceID: {
+		"type":       "object",
+		"properties": map[string]any{"kind": map[string]any{"enum": []any{"heading"}}},
+	}}
+
+	repo := &MockBlockRepo{}
+
+	_, err := NewBlockService(repo, WithPropsSchema(schema)).ImportMarkdown(ctx, spaceID, nil, "# Page\n\n- item\n")
+	assert.Error(t, err)
+	repo.AssertNotCalled(t, "CreateBatch", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,314 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/pkg/metrics"
+)
+
+// instrumentedBlockService reports duration and outcome of every BlockService call
+type instrumentedBlockService struct {
+	next     BlockService
+	recorder metrics.Recorder
+}
+
+func (s *instrumentedBlockService) observe(name string, start time.Time, err error) {
+	s.recorder.ObserveOperation("block."+name, time.Since(start), err)
+}
+
+func (s *instrumentedBlockService) Create(ctx context.Context, b *model.Block) error {
+	start := time.Now()
+	err := s.next.Create(ctx, b)
+	s.observe("create", start, err)
+	return err
+}
+
+func (s *instrumentedBlockService) CreateAt(ctx context.Context, b *model.Block, pos InsertPosition) error {
+	start := time.Now()
+	err := s.next.CreateAt(ctx, b, pos)
+	s.observe("create_at", start, err)
+	return err
+}
+
+func (s *instrumentedBlockService) Delete(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID) error {
+	start := time.Now()
+	err := s.next.Delete(ctx, spaceID, blockID)
+	s.observe("delete", start, err)
+	return err
+}
+
+func (s *instrumentedBlockService) GetBlockProperties(ctx context.Context, blockID uuid.UUID) (*model.Block, error) {
+	start := time.Now()
+	b, err := s.next.GetBlockProperties(ctx, blockID)
+	s.observe("get_properties", start, err)
+	return b, err
+}
+
+func (s *instrumentedBlockService) UpdateBlockProperties(ctx context.Context, b *model.Block) error {
+	start := time.Now()
+	err := s.next.UpdateBlockProperties(ctx, b)
+	s.observe("update_properties", start, err)
+	return err
+}
+
+func (s *instrumentedBlockService) ValidateProps(ctx context.Context, spaceID uuid.UUID, blockType string, props map[string]any) error {
+	start := time.Now()
+	err := s.next.ValidateProps(ctx, spaceID, blockType, props)
+	s.observe("validate_props", start, err)
+	return err
+}
+
+func (s *instrumentedBlockService) List(ctx context.Context, spaceID uuid.UUID, blockType string, parentID *uuid.UUID) ([]model.Block, error) {
+	start := time.Now()
+	list, err := s.next.List(ctx, spaceID, blockType, parentID)
+	s.observe("list", start, err)
+	return list, err
+}
+
+func (s *instrumentedBlockService) Move(ctx context.Context, blockID uuid.UUID, newParentID *uuid.UUID, targetSort *int64) error {
+	start := time.Now()
+	err := s.next.Move(ctx, blockID, newParentID, targetSort)
+	s.observe("move", start, err)
+	return err
+}
+
+func (s *instrumentedBlockService) UpdateSort(ctx context.Context, blockID uuid.UUID, sort int64) error {
+	start := time.Now()
+	err := s.next.UpdateSort(ctx, blockID, sort)
+	s.observe("update_sort", start, err)
+	return err
+}
+
+func (s *instrumentedBlockService) InstantiateTemplate(ctx context.Context, templateSpaceID uuid.UUID, newSpaceID uuid.UUID, vars map[string]string) error {
+	start := time.Now()
+	err := s.next.InstantiateTemplate(ctx, templateSpaceID, newSpaceID, vars)
+	s.observe("instantiate_template", start, err)
+	return err
+}
+
+func (s *instrumentedBlockService) GetBacklinks(ctx context.Context, blockID uuid.UUID) ([]model.Block, error) {
+	start := time.Now()
+	list, err := s.next.GetBacklinks(ctx, blockID)
+	s.observe("get_backlinks", start, err)
+	return list, err
+}
+
+func (s *instrumentedBlockService) ListChildrenLite(ctx context.Context, parentID uuid.UUID) ([]model.BlockLite, error) {
+	start := time.Now()
+	list, err := s.next.ListChildrenLite(ctx, parentID)
+	s.observe("list_children_lite", start, err)
+	return list, err
+}
+
+func (s *instrumentedBlockService) UpdatePage(ctx context.Context, spaceID uuid.UUID, pageID uuid.UUID, title *string, newParentID *uuid.UUID, targetSort *int64) error {
+	start := time.Now()
+	err := s.next.UpdatePage(ctx, spaceID, pageID, title, newParentID, targetSort)
+	s.observe("update_page", start, err)
+	return err
+}
+
+func (s *instrumentedBlockService) SerializeBlocks(ctx context.Context, ids []uuid.UUID) ([]byte, error) {
+	start := time.Now()
+	data, err := s.next.SerializeBlocks(ctx, ids)
+	s.observe("serialize_blocks", start, err)
+	return data, err
+}
+
+func (s *instrumentedBlockService) PasteBlocks(ctx context.Context, spaceID uuid.UUID, parentID uuid.UUID, data []byte) ([]model.Block, error) {
+	start := time.Now()
+	list, err := s.next.PasteBlocks(ctx, spaceID, parentID, data)
+	s.observe("paste_blocks", start, err)
+	return list, err
+}
+
+func (s *instrumentedBlockService) BlocksExist(ctx context.Context, spaceID uuid.UUID, ids []uuid.UUID) ([]uuid.UUID, error) {
+	start := time.Now()
+	missing, err := s.next.BlocksExist(ctx, spaceID, ids)
+	s.observe("blocks_exist", start, err)
+	return missing, err
+}
+
+func (s *instrumentedBlockService) GetSubtreeTextStats(ctx context.Context, pageID uuid.UUID) (int, int, int, error) {
+	start := time.Now()
+	chars, words, blocks, err := s.next.GetSubtreeTextStats(ctx, pageID)
+	s.observe("get_subtree_text_stats", start, err)
+	return chars, words, blocks, err
+}
+
+func (s *instrumentedBlockService) GetPageTree(ctx context.Context, pageID uuid.UUID, maxDepth int) (*model.Block, error) {
+	start := time.Now()
+	page, err := s.next.GetPageTree(ctx, pageID, maxDepth)
+	s.observe("get_page_tree", start, err)
+	return page, err
+}
+
+func (s *instrumentedBlockService) MovePageToTop(ctx context.Context, spaceID uuid.UUID, pageID uuid.UUID) error {
+	start := time.Now()
+	err := s.next.MovePageToTop(ctx, spaceID, pageID)
+	s.observe("move_page_to_top", start, err)
+	return err
+}
+
+func (s *instrumentedBlockService) MovePageToSpace(ctx context.Context, pageID uuid.UUID, targetSpaceID uuid.UUID, newParentID *uuid.UUID) error {
+	start := time.Now()
+	err := s.next.MovePageToSpace(ctx, pageID, targetSpaceID, newParentID)
+	s.observe("move_page_to_space", start, err)
+	return err
+}
+
+func (s *instrumentedBlockService) ListChildrenByTypes(ctx context.Context, parentID uuid.UUID, types []string) ([]model.Block, error) {
+	start := time.Now()
+	list, err := s.next.ListChildrenByTypes(ctx, parentID, types)
+	s.observe("list_children_by_types", start, err)
+	return list, err
+}
+
+func (s *instrumentedBlockService) DuplicateBlock(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID) (*model.Block, error) {
+	start := time.Now()
+	b, err := s.next.DuplicateBlock(ctx, spaceID, blockID)
+	s.observe("duplicate_block", start, err)
+	return b, err
+}
+
+func (s *instrumentedBlockService) LockBlock(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, cascade bool) error {
+	start := time.Now()
+	err := s.next.LockBlock(ctx, spaceID, blockID, cascade)
+	s.observe("lock_block", start, err)
+	return err
+}
+
+func (s *instrumentedBlockService) UnlockBlock(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, cascade bool) error {
+	start := time.Now()
+	err := s.next.UnlockBlock(ctx, spaceID, blockID, cascade)
+	s.observe("unlock_block", start, err)
+	return err
+}
+
+func (s *instrumentedBlockService) GetAncestors(ctx context.Context, blockID uuid.UUID) ([]model.Block, error) {
+	start := time.Now()
+	chain, err := s.next.GetAncestors(ctx, blockID)
+	s.observe("get_ancestors", start, err)
+	return chain, err
+}
+
+func (s *instrumentedBlockService) GetAncestorsBatch(ctx context.Context, blockIDs []uuid.UUID) (map[uuid.UUID][]model.Block, error) {
+	start := time.Now()
+	chains, err := s.next.GetAncestorsBatch(ctx, blockIDs)
+	s.observe("get_ancestors_batch", start, err)
+	return chains, err
+}
+
+func (s *instrumentedBlockService) ExportPageHTML(ctx context.Context, pageID uuid.UUID) (string, error) {
+	start := time.Now()
+	out, err := s.next.ExportPageHTML(ctx, pageID)
+	s.observe("export_page_html", start, err)
+	return out, err
+}
+
+func (s *instrumentedBlockService) ExportPageMarkdown(ctx context.Context, pageID uuid.UUID) (string, error) {
+	start := time.Now()
+	out, err := s.next.ExportPageMarkdown(ctx, pageID)
+	s.observe("export_page_markdown", start, err)
+	return out, err
+}
+
+func (s *instrumentedBlockService) ImportMarkdown(ctx context.Context, spaceID uuid.UUID, parentID *uuid.UUID, md string) (*model.Block, error) {
+	start := time.Now()
+	page, err := s.next.ImportMarkdown(ctx, spaceID, parentID, md)
+	s.observe("import_markdown", start, err)
+	return page, err
+}
+
+func (s *instrumentedBlockService) UpdatePropsWhere(ctx context.Context, spaceID uuid.UUID, parentID uuid.UUID, match map[string]any, patch map[string]any) (int, error) {
+	start := time.Now()
+	n, err := s.next.UpdatePropsWhere(ctx, spaceID, parentID, match, patch)
+	s.observe("update_props_where", start, err)
+	return n, err
+}
+
+func (s *instrumentedBlockService) RestoreBlock(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, restoreAncestors bool) error {
+	start := time.Now()
+	err := s.next.RestoreBlock(ctx, spaceID, blockID, restoreAncestors)
+	s.observe("restore_block", start, err)
+	return err
+}
+
+func (s *instrumentedBlockService) ReorderChildren(ctx context.Context, spaceID uuid.UUID, parentID uuid.UUID, orderedIDs []uuid.UUID) error {
+	start := time.Now()
+	err := s.next.ReorderChildren(ctx, spaceID, parentID, orderedIDs)
+	s.observe("reorder_children", start, err)
+	return err
+}
+
+func (s *instrumentedBlockService) DiffTree(ctx context.Context, pageID uuid.UUID, since time.Time) (TreeDiff, error) {
+	start := time.Now()
+	diff, err := s.next.DiffTree(ctx, pageID, since)
+	s.observe("diff_tree", start, err)
+	return diff, err
+}
+
+func (s *instrumentedBlockService) ArchiveBlock(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID) error {
+	start := time.Now()
+	err := s.next.ArchiveBlock(ctx, spaceID, blockID)
+	s.observe("archive_block", start, err)
+	return err
+}
+
+func (s *instrumentedBlockService) SearchBlocks(ctx context.Context, spaceID uuid.UUID, query string, opts SearchOptions) ([]model.Block, error) {
+	start := time.Now()
+	list, err := s.next.SearchBlocks(ctx, spaceID, query, opts)
+	s.observe("search_blocks", start, err)
+	return list, err
+}
+
+func (s *instrumentedBlockService) ConvertBlockType(ctx context.Context, spaceID uuid.UUID, id uuid.UUID, newType string) error {
+	start := time.Now()
+	err := s.next.ConvertBlockType(ctx, spaceID, id, newType)
+	s.observe("convert_block_type", start, err)
+	return err
+}
+
+func (s *instrumentedBlockService) ArchiveWhere(ctx context.Context, spaceID uuid.UUID, match map[string]any) (int, error) {
+	start := time.Now()
+	n, err := s.next.ArchiveWhere(ctx, spaceID, match)
+	s.observe("archive_where", start, err)
+	return n, err
+}
+
+func (s *instrumentedBlockService) DeletePageRecursive(ctx context.Context, spaceID uuid.UUID, pageID uuid.UUID) (int, error) {
+	start := time.Now()
+	n, err := s.next.DeletePageRecursive(ctx, spaceID, pageID)
+	s.observe("delete_page_recursive", start, err)
+	return n, err
+}
+
+func (s *instrumentedBlockService) DuplicatePage(ctx context.Context, spaceID uuid.UUID, pageID uuid.UUID, newParentID *uuid.UUID) (*model.Block, error) {
+	start := time.Now()
+	b, err := s.next.DuplicatePage(ctx, spaceID, pageID, newParentID)
+	s.observe("duplicate_page", start, err)
+	return b, err
+}
+
+func (s *instrumentedBlockService) ListChildrenPaged(ctx context.Context, parentID uuid.UUID, limit int, cursor string) ([]model.Block, string, error) {
+	start := time.Now()
+	list, next, err := s.next.ListChildrenPaged(ctx, parentID, limit, cursor)
+	s.observe("list_children_paged", start, err)
+	return list, next, err
+}
+
+func (s *instrumentedBlockService) ListArchivedPages(ctx context.Context, spaceID uuid.UUID) ([]model.Block, error) {
+	start := time.Now()
+	list, err := s.next.ListArchivedPages(ctx, spaceID)
+	s.observe("list_archived_pages", start, err)
+	return list, err
+}
+
+func (s *instrumentedBlockService) PurgeDeleted(ctx context.Context, spaceID uuid.UUID, olderThan time.Time) (int, error) {
+	start := time.Now()
+	n, err := s.next.PurgeDeleted(ctx, spaceID, olderThan)
+	s.observe("purge_deleted", start, err)
+	return n, err
+}
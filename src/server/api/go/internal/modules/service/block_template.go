@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"gorm.io/datatypes"
+)
+
+// InstantiateTemplate copies every active block of the template space into newSpaceID,
+// replacing {{name}} placeholders in titles and string props with vars[name].
+// Root blocks are appended after any existing root blocks of the target space.
+func (s *blockService) InstantiateTemplate(ctx context.Context, templateSpaceID uuid.UUID, newSpaceID uuid.UUID, vars map[string]string) error {
+	if templateSpaceID == newSpaceID {
+		return errors.New("template space and target space must differ")
+	}
+
+	blocks, err := s.r.ListAllBySpace(ctx, templateSpaceID)
+	if err != nil {
+		return err
+	}
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	pairs := make([]string, 0, len(vars)*2)
+	for name, value := range vars {
+		pairs = append(pairs, "{{"+name+"}}", value)
+	}
+	replacer := strings.NewReplacer(pairs...)
+
+	// Group by parent so parents are always created before their children
+	children := make(map[uuid.UUID][]model.Block)
+	var roots []model.Block
+	for _, b := range blocks {
+		if b.ParentID == nil {
+			roots = append(roots, b)
+		} else {
+			children[*b.ParentID] = append(children[*b.ParentID], b)
+		}
+	}
+
+	copies := make([]*model.Block, 0, len(blocks))
+	var copyTree func(src model.Block, newParentID *uuid.UUID)
+	copyTree = func(src model.Block, newParentID *uuid.UUID) {
+		clone := &model.Block{
+			ID:       uuid.New(),
+			SpaceID:  newSpaceID,
+			Type:     src.Type,
+			ParentID: newParentID,
+			Title:    replacer.Replace(src.Title),
+			Props:    datatypes.NewJSONType(substituteProps(src.Props.Data(), replacer)),
+			Sort:     src.Sort,
+		}
+		copies = append(copies, clone)
+
+		for _, child := range children[src.ID] {
+			copyTree(child, &clone.ID)
+		}
+	}
+	for _, root := range roots {
+		copyTree(root, nil)
+	}
+
+	return s.r.CreateBatch(ctx, copies)
+}
+
+// substituteProps deep-copies props, applying the replacer to every string value.
+// Derived keys such as tool_sops are not copied.
+func substituteProps(props map[string]any, replacer *strings.Replacer) map[string]any {
+	out := make(map[string]any, len(props))
+	for k, v := range props {
+		if k == "tool_sops" {
+			continue
+		}
+		out[k] = substituteValue(v, replacer)
+	}
+	return out
+}
+
+func substituteValue(v any, replacer *strings.Replacer) any {
+	switch val := v.(type) {
+	case string:
+		return replacer.Replace(val)
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			out[k] = substituteValue(item, replacer)
+		}
+		return out
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = substituteValue(item, replacer)
+		}
+		return out
+	default:
+		return v
+	}
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+)
+
+func TestBlockService_InstantiateTemplate(t *testing.T) {
+	ctx := context.Background()
+	templateSpaceID := uuid.New()
+	newSpaceID := uuid.New()
+	pageID := uuid.New()
+
+	template := []model.Block{
+		{
+			ID:      pageID,
+			SpaceID: templateSpaceID,
+			Type:    model.BlockTypePage,
+			Title:   "{{project}} onboarding",
+			Props:   datatypes.NewJSONType(map[string]any{"owner": "{{owner}}"}),
+			Sort:    0,
+		},
+		{
+			ID:       uuid.New(),
+			SpaceID:  templateSpaceID,
+			Type:     model.BlockTypeText,
+			ParentID: &pageID,
+			Title:    "Welcome",
+			Props:    datatypes.NewJSONType(map[string]any{"text": "Welcome to {{project}}, ask {{unknown}}"}),
+			Sort:     0,
+		},
+	}
+
+	repo := &MockBlockRepo{}
+	repo.On("ListAllBySpace", ctx, templateSpaceID).Return(template, nil)
+
+	var created []*model.Block
+	repo.On("CreateBatch", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		created = args.Get(1).([]*model.Block)
+	}).Return(nil)
+
+	service := NewBlockService(repo)
+	err := service.InstantiateTemplate(ctx, templateSpaceID, newSpaceID, map[string]string{
+		"project": "Apollo",
+		"owner":   "alice",
+	})
+	require.NoError(t, err)
+	require.Len(t, created, 2)
+
+	page, text := created[0], created[1]
+	assert.NotEqual(t, pageID, page.ID)
+	assert.Equal(t, newSpaceID, page.SpaceID)
+	assert.Nil(t, page.ParentID)
+	assert.Equal(t, "Apollo onboarding", page.Title)
+	assert.Equal(t, "alice", page.Props.Data()["owner"])
+
+	require.NotNil(t, text.ParentID)
+	assert.Equal(t, page.ID, *text.ParentID)
+	assert.Equal(t, newSpaceID, text.SpaceID)
+	assert.Equal(t, "Welcome to Apollo, ask {{unknown}}", text.Props.Data()["text"])
+
+	// Template is left untouched
+	assert.Equal(t, "{{project}} onboarding", template[0].Title)
+	repo.AssertExpectations(t)
+}
+
+func TestBlockService_InstantiateTemplate_SameSpace(t *testing.T) {
+	spaceID := uuid.New()
+	service := NewBlockService(&MockBlockRepo{})
+
+	err := service.InstantiateTemplate(context.Background(), spaceID, spaceID, nil)
+	assert.Error(t, err)
+}
@@ -3,12 +3,21 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/repo"
+	"github.com/memodb-io/Acontext/internal/pkg/metrics"
+	"github.com/memodb-io/Acontext/internal/pkg/paging"
+	"github.com/memodb-io/Acontext/internal/pkg/propschema"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 // MockBlockRepo is a mock implementation of BlockRepo
@@ -39,6 +48,16 @@ func (m *MockBlockRepo) Delete(ctx context.Context, spaceID, blockID uuid.UUID)
 	return args.Error(0)
 }
 
+func (m *MockBlockRepo) DeleteTree(ctx context.Context, spaceID, blockID uuid.UUID) (int, error) {
+	args := m.Called(ctx, spaceID, blockID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockBlockRepo) PurgeDeleted(ctx context.Context, spaceID uuid.UUID, olderThan time.Time) (int, error) {
+	args := m.Called(ctx, spaceID, olderThan)
+	return args.Int(0), args.Error(1)
+}
+
 func (m *MockBlockRepo) NextSort(ctx context.Context, spaceID uuid.UUID, parentID *uuid.UUID) (int64, error) {
 	args := m.Called(ctx, spaceID, parentID)
 	return args.Get(0).(int64), args.Error(1)
@@ -54,6 +73,11 @@ func (m *MockBlockRepo) MoveToParentAtSort(ctx context.Context, blockID uuid.UUI
 	return args.Error(0)
 }
 
+func (m *MockBlockRepo) MoveToSpace(ctx context.Context, blockID uuid.UUID, spaceID uuid.UUID, newParentID *uuid.UUID) error {
+	args := m.Called(ctx, blockID, spaceID, newParentID)
+	return args.Error(0)
+}
+
 func (m *MockBlockRepo) ReorderWithinGroup(ctx context.Context, blockID uuid.UUID, sort int64) error {
 	args := m.Called(ctx, blockID, sort)
 	return args.Error(0)
@@ -67,6 +91,183 @@ func (m *MockBlockRepo) ListBySpace(ctx context.Context, spaceID uuid.UUID, bloc
 	return args.Get(0).([]model.Block), args.Error(1)
 }
 
+func (m *MockBlockRepo) ListAllBySpace(ctx context.Context, spaceID uuid.UUID) ([]model.Block, error) {
+	args := m.Called(ctx, spaceID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Block), args.Error(1)
+}
+
+func (m *MockBlockRepo) CreateBatch(ctx context.Context, blocks []*model.Block) error {
+	args := m.Called(ctx, blocks)
+	return args.Error(0)
+}
+
+func (m *MockBlockRepo) ListBacklinks(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID) ([]model.Block, error) {
+	args := m.Called(ctx, spaceID, blockID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Block), args.Error(1)
+}
+
+func (m *MockBlockRepo) ListPurgeCandidates(ctx context.Context, olderThan time.Time, limit int) ([]model.Block, error) {
+	args := m.Called(ctx, olderThan, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Block), args.Error(1)
+}
+
+func (m *MockBlockRepo) ListChildrenLite(ctx context.Context, parentID uuid.UUID) ([]model.BlockLite, error) {
+	args := m.Called(ctx, parentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.BlockLite), args.Error(1)
+}
+
+func (m *MockBlockRepo) CreateAppend(ctx context.Context, b *model.Block) error {
+	args := m.Called(ctx, b)
+	return args.Error(0)
+}
+
+func (m *MockBlockRepo) UpdatePage(ctx context.Context, id uuid.UUID, title *string, newParentID *uuid.UUID, targetSort *int64) error {
+	args := m.Called(ctx, id, title, newParentID, targetSort)
+	return args.Error(0)
+}
+
+func (m *MockBlockRepo) CountChildren(ctx context.Context, parentID uuid.UUID) (int64, error) {
+	args := m.Called(ctx, parentID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockBlockRepo) ListExistingIDs(ctx context.Context, spaceID uuid.UUID, ids []uuid.UUID) ([]uuid.UUID, error) {
+	args := m.Called(ctx, spaceID, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+func (m *MockBlockRepo) SubtreeTextStats(ctx context.Context, rootID uuid.UUID, includeArchived bool) (int, int, int, error) {
+	args := m.Called(ctx, rootID, includeArchived)
+	return args.Int(0), args.Int(1), args.Int(2), args.Error(3)
+}
+
+func (m *MockBlockRepo) ListChildrenByTypes(ctx context.Context, parentID uuid.UUID, types []string) ([]model.Block, error) {
+	args := m.Called(ctx, parentID, types)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Block), args.Error(1)
+}
+
+func (m *MockBlockRepo) DuplicateBlock(ctx context.Context, id uuid.UUID) (*model.Block, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Block), args.Error(1)
+}
+
+func (m *MockBlockRepo) SetLocked(ctx context.Context, id uuid.UUID, locked bool, cascade bool) error {
+	args := m.Called(ctx, id, locked, cascade)
+	return args.Error(0)
+}
+
+func (m *MockBlockRepo) ListAncestorsBatch(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID][]model.Block, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[uuid.UUID][]model.Block), args.Error(1)
+}
+
+func (m *MockBlockRepo) UpdatePropsWhere(ctx context.Context, spaceID uuid.UUID, parentID uuid.UUID, match map[string]any, patch map[string]any) (int, error) {
+	args := m.Called(ctx, spaceID, parentID, match, patch)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockBlockRepo) Restore(ctx context.Context, ids []uuid.UUID) error {
+	args := m.Called(ctx, ids)
+	return args.Error(0)
+}
+
+func (m *MockBlockRepo) ReorderChildren(ctx context.Context, spaceID uuid.UUID, parentID uuid.UUID, orderedIDs []uuid.UUID) error {
+	args := m.Called(ctx, spaceID, parentID, orderedIDs)
+	return args.Error(0)
+}
+
+func (m *MockBlockRepo) ListSubtreeChangedSince(ctx context.Context, rootID uuid.UUID, since time.Time) ([]model.Block, error) {
+	args := m.Called(ctx, rootID, since)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Block), args.Error(1)
+}
+
+func (m *MockBlockRepo) ListSubtree(ctx context.Context, rootID uuid.UUID, maxDepth int) ([]model.Block, error) {
+	args := m.Called(ctx, rootID, maxDepth)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Block), args.Error(1)
+}
+
+func (m *MockBlockRepo) Archive(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockBlockRepo) SearchBlocks(ctx context.Context, spaceID uuid.UUID, query string, opts SearchOptions) ([]model.Block, error) {
+	args := m.Called(ctx, spaceID, query, opts)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Block), args.Error(1)
+}
+
+func (m *MockBlockRepo) ConvertType(ctx context.Context, id uuid.UUID, newType string, newParentID *uuid.UUID) error {
+	args := m.Called(ctx, id, newType, newParentID)
+	return args.Error(0)
+}
+
+func (m *MockBlockRepo) ArchiveWhere(ctx context.Context, spaceID uuid.UUID, match map[string]any) (int, error) {
+	args := m.Called(ctx, spaceID, match)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockBlockRepo) CreateAt(ctx context.Context, b *model.Block, pos InsertPosition) error {
+	args := m.Called(ctx, b, pos)
+	return args.Error(0)
+}
+
+func (m *MockBlockRepo) DuplicateTo(ctx context.Context, id uuid.UUID, newParentID *uuid.UUID, title string) (*model.Block, error) {
+	args := m.Called(ctx, id, newParentID, title)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Block), args.Error(1)
+}
+
+func (m *MockBlockRepo) ListChildrenAfter(ctx context.Context, parentID uuid.UUID, afterSort int64, afterID uuid.UUID, limit int) ([]model.Block, error) {
+	args := m.Called(ctx, parentID, afterSort, afterID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Block), args.Error(1)
+}
+
+func (m *MockBlockRepo) ListArchived(ctx context.Context, spaceID uuid.UUID, blockType string) ([]model.Block, error) {
+	args := m.Called(ctx, spaceID, blockType)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Block), args.Error(1)
+}
+
 func TestBlockService_Create_Page(t *testing.T) {
 	ctx := context.Background()
 	spaceID := uuid.New()
@@ -87,9 +288,8 @@ func TestBlockService_Create_Page(t *testing.T) {
 				Title:   "Test Page",
 			},
 			setup: func(repo *MockBlockRepo) {
-				repo.On("NextSort", ctx, spaceID, (*uuid.UUID)(nil)).Return(int64(1), nil)
-				repo.On("Create", ctx, mock.MatchedBy(func(b *model.Block) bool {
-					return b.Type == model.BlockTypePage && b.Sort == 1
+				repo.On("CreateAppend", ctx, mock.MatchedBy(func(b *model.Block) bool {
+					return b.Type == model.BlockTypePage
 				})).Return(nil)
 			},
 			wantErr: false,
@@ -108,9 +308,8 @@ func TestBlockService_Create_Page(t *testing.T) {
 					Type: model.BlockTypeFolder,
 				}
 				repo.On("Get", ctx, parentID).Return(parentBlock, nil)
-				repo.On("NextSort", ctx, spaceID, &parentID).Return(int64(2), nil)
-				repo.On("Create", ctx, mock.MatchedBy(func(b *model.Block) bool {
-					return b.Type == model.BlockTypePage && b.Sort == 2
+				repo.On("CreateAppend", ctx, mock.MatchedBy(func(b *model.Block) bool {
+					return b.Type == model.BlockTypePage
 				})).Return(nil)
 			},
 			wantErr: false,
@@ -176,6 +375,117 @@ func TestBlockService_Create_Page(t *testing.T) {
 	}
 }
 
+type staticDefaultProps map[string]map[string]any
+
+func (p staticDefaultProps) DefaultProps(_ context.Context, _ uuid.UUID, blockType string) (map[string]any, error) {
+	return p[blockType], nil
+}
+
+func TestBlockService_Create_DefaultProps(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	parentID := uuid.New()
+
+	defaults := staticDefaultProps{
+		model.BlockTypeText: {"checked": false, "priority": "normal"},
+	}
+
+	repo := &MockBlockRepo{}
+	repo.On("Get", ctx, parentID).Return(&model.Block{ID: parentID, SpaceID: spaceID, Type: model.BlockTypePage}, nil)
+	repo.On("CreateAppend", ctx, mock.Anything).Return(nil)
+
+	service := NewBlockService(repo, WithDefaultProps(defaults))
+	b := &model.Block{
+		SpaceID:  spaceID,
+		Type:     model.BlockTypeText,
+		ParentID: &parentID,
+		Title:    "Todo",
+		Props:    datatypes.NewJSONType(map[string]any{"priority": "high"}),
+	}
+	err := service.Create(ctx, b)
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"checked": false, "priority": "high"}, b.Props.Data())
+	repo.AssertExpectations(t)
+}
+
+type staticPropsSchema map[uuid.UUID]map[string]any
+
+func (p staticPropsSchema) PropsSchema(_ context.Context, spaceID uuid.UUID) (map[string]any, error) {
+	return p[spaceID], nil
+}
+
+func TestBlockService_PropsSchema(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	parentID := uuid.New()
+	schemas := staticPropsSchema{
+		spaceID: {
+			"type":     "object",
+			"required": []any{"priority"},
+			"properties": map[string]any{
+				"priority": map[string]any{"type": "integer"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		spaceID  uuid.UUID
+		props    map[string]any
+		wantKeys []string
+	}{
+		{name: "passing doc", spaceID: spaceID, props: map[string]any{"priority": 2, "text": "Ship it"}},
+		{name: "type mismatch", spaceID: spaceID, props: map[string]any{"priority": "high"}, wantKeys: []string{"priority"}},
+		{name: "missing required field", spaceID: spaceID, props: map[string]any{"text": "Ship it"}, wantKeys: []string{"priority"}},
+		{name: "space without schema", spaceID: uuid.New(), props: map[string]any{"priority": "high"}},
+	}
+
+	for _, tt := range tests {
+		t.Run("create "+tt.name, func(t *testing.T) {
+			repo := &MockBlockRepo{}
+			repo.On("Get", ctx, parentID).Return(&model.Block{ID: parentID, SpaceID: tt.spaceID, Type: model.BlockTypePage}, nil)
+			if tt.wantKeys == nil {
+				repo.On("CreateAppend", ctx, mock.Anything).Return(nil)
+			}
+
+			err := NewBlockService(repo, WithPropsSchema(schemas)).Create(ctx, &model.Block{
+				SpaceID:  tt.spaceID,
+				Type:     model.BlockTypeText,
+				ParentID: &parentID,
+				Props:    datatypes.NewJSONType(tt.props),
+			})
+			assertPropsErr(t, err, tt.wantKeys)
+			repo.AssertExpectations(t)
+		})
+
+		t.Run("update "+tt.name, func(t *testing.T) {
+			blockID := uuid.New()
+			b := &model.Block{ID: blockID, Props: datatypes.NewJSONType(tt.props)}
+			repo := &MockBlockRepo{}
+			repo.On("Get", ctx, blockID).Return(&model.Block{ID: blockID, SpaceID: tt.spaceID, Type: model.BlockTypePage}, nil)
+			if tt.wantKeys == nil {
+				repo.On("Update", ctx, b).Return(nil)
+			}
+
+			err := NewBlockService(repo, WithPropsSchema(schemas)).UpdateBlockProperties(ctx, b)
+			assertPropsErr(t, err, tt.wantKeys)
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func assertPropsErr(t *testing.T, err error, wantKeys []string) {
+	t.Helper()
+	if wantKeys == nil {
+		assert.NoError(t, err)
+		return
+	}
+	var invalid *propschema.ValidationError
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, wantKeys, invalid.Keys())
+}
+
 func TestBlockService_Delete(t *testing.T) {
 	ctx := context.Background()
 	spaceID := uuid.New()
@@ -192,6 +502,7 @@ func TestBlockService_Delete(t *testing.T) {
 			name:    "successful block deletion",
 			blockID: blockID,
 			setup: func(repo *MockBlockRepo) {
+				repo.On("Get", ctx, blockID).Return(&model.Block{ID: blockID, SpaceID: spaceID}, nil)
 				repo.On("Delete", ctx, spaceID, blockID).Return(nil)
 			},
 			wantErr: false,
@@ -201,6 +512,7 @@ func TestBlockService_Delete(t *testing.T) {
 			blockID: uuid.UUID{},
 			setup: func(repo *MockBlockRepo) {
 				// Note: len() of uuid.UUID{} is not 0, so Delete will be called
+				repo.On("Get", ctx, uuid.UUID{}).Return(&model.Block{SpaceID: spaceID}, nil)
 				repo.On("Delete", ctx, spaceID, uuid.UUID{}).Return(nil)
 			},
 			wantErr: false, // Actually won't error, because len(uuid.UUID{}) != 0
@@ -209,10 +521,30 @@ func TestBlockService_Delete(t *testing.T) {
 			name:    "deletion failure",
 			blockID: blockID,
 			setup: func(repo *MockBlockRepo) {
+				repo.On("Get", ctx, blockID).Return(&model.Block{ID: blockID, SpaceID: spaceID}, nil)
 				repo.On("Delete", ctx, spaceID, blockID).Return(errors.New("database error"))
 			},
 			wantErr: true,
 		},
+		{
+			name:    "locked block",
+			blockID: blockID,
+			setup: func(repo *MockBlockRepo) {
+				repo.On("Get", ctx, blockID).Return(&model.Block{ID: blockID, SpaceID: spaceID, IsLocked: true}, nil)
+			},
+			wantErr: true,
+			errMsg:  "block is locked",
+		},
+		{
+			name:    "locked descendant",
+			blockID: blockID,
+			setup: func(blocks *MockBlockRepo) {
+				blocks.On("Get", ctx, blockID).Return(&model.Block{ID: blockID, SpaceID: spaceID}, nil)
+				blocks.On("Delete", ctx, spaceID, blockID).Return(repo.ErrLockedDescendant)
+			},
+			wantErr: true,
+			errMsg:  ErrLocked.Error(),
+		},
 	}
 
 	for _, tt := range tests {
@@ -237,6 +569,76 @@ func TestBlockService_Delete(t *testing.T) {
 	}
 }
 
+func TestBlockService_PurgeDeleted(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	cutoff := time.Now().Add(-30 * 24 * time.Hour)
+
+	repo := &MockBlockRepo{}
+	repo.On("PurgeDeleted", ctx, spaceID, cutoff).Return(3, nil)
+
+	n, err := NewBlockService(repo).PurgeDeleted(ctx, spaceID, cutoff)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	repo.AssertExpectations(t)
+}
+
+func TestBlockService_DeletePageRecursive(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	pageID := uuid.New()
+
+	t.Run("subtree is deleted at once", func(t *testing.T) {
+		repo := &MockBlockRepo{}
+		repo.On("Get", ctx, pageID).Return(&model.Block{ID: pageID, SpaceID: spaceID, Type: model.BlockTypePage}, nil)
+		repo.On("DeleteTree", ctx, spaceID, pageID).Return(4, nil)
+
+		n, err := NewBlockService(repo).DeletePageRecursive(ctx, spaceID, pageID)
+		require.NoError(t, err)
+		assert.Equal(t, 4, n)
+		repo.AssertExpectations(t)
+		repo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("not a page", func(t *testing.T) {
+		repo := &MockBlockRepo{}
+		repo.On("Get", ctx, pageID).Return(&model.Block{ID: pageID, SpaceID: spaceID, Type: model.BlockTypeFolder}, nil)
+
+		_, err := NewBlockService(repo).DeletePageRecursive(ctx, spaceID, pageID)
+		assert.EqualError(t, err, "block type 'folder' is not a page")
+		repo.AssertNotCalled(t, "DeleteTree", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("locked page", func(t *testing.T) {
+		repo := &MockBlockRepo{}
+		repo.On("Get", ctx, pageID).Return(&model.Block{ID: pageID, SpaceID: spaceID, Type: model.BlockTypePage, IsLocked: true}, nil)
+
+		_, err := NewBlockService(repo).DeletePageRecursive(ctx, spaceID, pageID)
+		assert.ErrorIs(t, err, ErrLocked)
+		repo.AssertNotCalled(t, "DeleteTree", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("locked descendant", func(t *testing.T) {
+		blocks := &MockBlockRepo{}
+		blocks.On("Get", ctx, pageID).Return(&model.Block{ID: pageID, SpaceID: spaceID, Type: model.BlockTypePage}, nil)
+		blocks.On("DeleteTree", ctx, spaceID, pageID).Return(0, repo.ErrLockedDescendant)
+
+		n, err := NewBlockService(blocks).DeletePageRecursive(ctx, spaceID, pageID)
+		assert.ErrorIs(t, err, ErrLocked)
+		assert.Equal(t, 0, n)
+	})
+
+	t.Run("delete failure", func(t *testing.T) {
+		repo := &MockBlockRepo{}
+		repo.On("Get", ctx, pageID).Return(&model.Block{ID: pageID, SpaceID: spaceID, Type: model.BlockTypePage}, nil)
+		repo.On("DeleteTree", ctx, spaceID, pageID).Return(0, errors.New("database error"))
+
+		n, err := NewBlockService(repo).DeletePageRecursive(ctx, spaceID, pageID)
+		assert.EqualError(t, err, "database error")
+		assert.Equal(t, 0, n)
+	})
+}
+
 func TestBlockService_Create_Text(t *testing.T) {
 	ctx := context.Background()
 	spaceID := uuid.New()
@@ -263,9 +665,8 @@ func TestBlockService_Create_Text(t *testing.T) {
 					Type: model.BlockTypePage,
 				}
 				repo.On("Get", ctx, parentID).Return(parentBlock, nil)
-				repo.On("NextSort", ctx, spaceID, &parentID).Return(int64(1), nil)
-				repo.On("Create", ctx, mock.MatchedBy(func(b *model.Block) bool {
-					return b.Type == "text" && b.Sort == 1
+				repo.On("CreateAppend", ctx, mock.MatchedBy(func(b *model.Block) bool {
+					return b.Type == "text"
 				})).Return(nil)
 			},
 			wantErr: false,
@@ -398,9 +799,8 @@ func TestBlockService_Create_Folder(t *testing.T) {
 				Title:   "RootFolder",
 			},
 			setup: func(repo *MockBlockRepo) {
-				repo.On("NextSort", ctx, spaceID, (*uuid.UUID)(nil)).Return(int64(1), nil)
-				repo.On("Create", ctx, mock.MatchedBy(func(b *model.Block) bool {
-					return b.Type == model.BlockTypeFolder && b.Sort == 1 && b.GetFolderPath() == "RootFolder"
+				repo.On("CreateAppend", ctx, mock.MatchedBy(func(b *model.Block) bool {
+					return b.Type == model.BlockTypeFolder && b.GetFolderPath() == "RootFolder"
 				})).Return(nil)
 			},
 			wantErr:      false,
@@ -421,9 +821,8 @@ func TestBlockService_Create_Folder(t *testing.T) {
 				}
 				parentBlock.SetFolderPath("RootFolder")
 				repo.On("Get", ctx, parentID).Return(parentBlock, nil)
-				repo.On("NextSort", ctx, spaceID, &parentID).Return(int64(2), nil)
-				repo.On("Create", ctx, mock.MatchedBy(func(b *model.Block) bool {
-					return b.Type == model.BlockTypeFolder && b.Sort == 2 && b.GetFolderPath() == "RootFolder/Subfolder"
+				repo.On("CreateAppend", ctx, mock.MatchedBy(func(b *model.Block) bool {
+					return b.Type == model.BlockTypeFolder && b.GetFolderPath() == "RootFolder/Subfolder"
 				})).Return(nil)
 			},
 			wantErr:      false,
@@ -444,8 +843,7 @@ func TestBlockService_Create_Folder(t *testing.T) {
 				}
 				parentBlock.SetFolderPath("Folder1/Folder2/Folder3")
 				repo.On("Get", ctx, parentID).Return(parentBlock, nil)
-				repo.On("NextSort", ctx, spaceID, &parentID).Return(int64(1), nil)
-				repo.On("Create", ctx, mock.MatchedBy(func(b *model.Block) bool {
+				repo.On("CreateAppend", ctx, mock.MatchedBy(func(b *model.Block) bool {
 					return b.Type == model.BlockTypeFolder && b.GetFolderPath() == "Folder1/Folder2/Folder3/DeepFolder"
 				})).Return(nil)
 			},
@@ -652,6 +1050,7 @@ func TestBlockService_List(t *testing.T) {
 			blockType: model.BlockTypeFolder,
 			parentID:  &parentID,
 			setup: func(repo *MockBlockRepo) {
+				repo.On("CountChildren", ctx, parentID).Return(int64(0), nil)
 				repo.On("ListBySpace", ctx, spaceID, model.BlockTypeFolder, &parentID).Return([]model.Block{}, nil)
 			},
 			wantErr: false,
@@ -672,6 +1071,7 @@ func TestBlockService_List(t *testing.T) {
 			blockType: model.BlockTypePage,
 			parentID:  &parentID,
 			setup: func(repo *MockBlockRepo) {
+				repo.On("CountChildren", ctx, parentID).Return(int64(0), nil)
 				repo.On("ListBySpace", ctx, spaceID, model.BlockTypePage, &parentID).Return([]model.Block{}, nil)
 			},
 			wantErr: false,
@@ -711,8 +1111,7 @@ func TestBlockService_ComprehensiveNesting(t *testing.T) {
 			Type:    model.BlockTypeFolder,
 			Title:   "Root",
 		}
-		repo.On("NextSort", ctx, spaceID, (*uuid.UUID)(nil)).Return(int64(1), nil)
-		repo.On("Create", ctx, mock.MatchedBy(func(b *model.Block) bool {
+		repo.On("CreateAppend", ctx, mock.MatchedBy(func(b *model.Block) bool {
 			return b.Type == model.BlockTypeFolder && b.GetFolderPath() == "Root"
 		})).Return(nil)
 
@@ -956,6 +1355,39 @@ func TestBlockService_Move_CircularReference(t *testing.T) {
 			wantErr: true,
 			errMsg:  "new parent cannot be a descendant of the block",
 		},
+		{
+			name:        "move folder under a page in its own subtree",
+			description: "FolderA -> FolderB -> Page, try to move FolderA under the page",
+			blockID:     folderAID,
+			newParentID: &folderCID,
+			setup: func(repo *MockBlockRepo) {
+				folderA := &model.Block{
+					ID:      folderAID,
+					Type:    model.BlockTypeFolder,
+					Title:   "FolderA",
+					SpaceID: spaceID,
+				}
+				repo.On("Get", ctx, folderAID).Return(folderA, nil)
+
+				page := &model.Block{
+					ID:       folderCID,
+					Type:     model.BlockTypePage,
+					Title:    "Page",
+					ParentID: &folderBID,
+				}
+				repo.On("Get", ctx, folderCID).Return(page, nil)
+
+				folderB := &model.Block{
+					ID:       folderBID,
+					Type:     model.BlockTypeFolder,
+					Title:    "FolderB",
+					ParentID: &folderAID,
+				}
+				repo.On("Get", ctx, folderBID).Return(folderB, nil)
+			},
+			wantErr: true,
+			errMsg:  "new parent cannot be a descendant of the block",
+		},
 		{
 			name:        "move sibling to sibling (valid)",
 			description: "Move FolderB to be under FolderC, where both are siblings under FolderA",
@@ -1157,3 +1589,1015 @@ func TestBlockService_isDescendant(t *testing.T) {
 		})
 	}
 }
+
+type spyOperationRecorder struct {
+	names []string
+	errs  []error
+}
+
+func (s *spyOperationRecorder) ObserveOperation(name string, _ time.Duration, err error) {
+	s.names = append(s.names, name)
+	s.errs = append(s.errs, err)
+}
+
+func (s *spyOperationRecorder) ObserveConversion(metrics.Conversion) {}
+
+func TestBlockService_Metrics(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+
+	repo := &MockBlockRepo{}
+	repo.On("CreateAppend", ctx, mock.Anything).Return(nil)
+	repo.On("Get", ctx, mock.Anything).Return(&model.Block{SpaceID: spaceID}, nil)
+	repo.On("Delete", ctx, spaceID, mock.Anything).Return(errors.New("database error"))
+
+	spy := &spyOperationRecorder{}
+	service := NewBlockService(repo, WithBlockMetrics(spy))
+
+	err := service.Create(ctx, &model.Block{SpaceID: spaceID, Type: model.BlockTypePage, Title: "Page"})
+	assert.NoError(t, err)
+
+	err = service.Delete(ctx, spaceID, uuid.New())
+	assert.Error(t, err)
+
+	assert.Equal(t, []string{"block.create", "block.delete"}, spy.names)
+	assert.NoError(t, spy.errs[0])
+	assert.EqualError(t, spy.errs[1], "database error")
+}
+
+func TestBlockService_GetBacklinks(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	pageID := uuid.New()
+	linking := model.Block{
+		ID:      uuid.New(),
+		SpaceID: spaceID,
+		Type:    model.BlockTypeText,
+		Props:   datatypes.NewJSONType(map[string]any{model.BlockPropLinks: []any{pageID.String()}}),
+	}
+
+	repo := &MockBlockRepo{}
+	repo.On("Get", ctx, pageID).Return(&model.Block{ID: pageID, SpaceID: spaceID, Type: model.BlockTypePage}, nil)
+	repo.On("ListBacklinks", ctx, spaceID, pageID).Return([]model.Block{linking}, nil)
+
+	service := NewBlockService(repo)
+	backlinks, err := service.GetBacklinks(ctx, pageID)
+
+	assert.NoError(t, err)
+	assert.Len(t, backlinks, 1)
+	assert.Equal(t, linking.ID, backlinks[0].ID)
+	repo.AssertExpectations(t)
+}
+
+func TestBlockService_Create_IgnoresExplicitSort(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+
+	repo := &MockBlockRepo{}
+	// The repo assigns the tail position of the group
+	repo.On("CreateAppend", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(1).(*model.Block).Sort = 3
+	}).Return(nil)
+
+	// Sort 0 is already taken by an existing sibling
+	block := &model.Block{
+		SpaceID: spaceID,
+		Type:    model.BlockTypePage,
+		Title:   "Explicit sort",
+		Sort:    0,
+	}
+
+	service := NewBlockService(repo)
+	err := service.Create(ctx, block)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), block.Sort)
+	repo.AssertExpectations(t)
+}
+
+func TestBlockService_ListChildrenLite(t *testing.T) {
+	ctx := context.Background()
+	parentID := uuid.New()
+	children := []model.BlockLite{
+		{ID: uuid.New(), Title: "First", Sort: 0, ParentID: &parentID},
+		{ID: uuid.New(), Title: "Second", Sort: 1, ParentID: &parentID},
+	}
+
+	repo := &MockBlockRepo{}
+	repo.On("CountChildren", ctx, parentID).Return(int64(len(children)), nil)
+	repo.On("ListChildrenLite", ctx, parentID).Return(children, nil)
+
+	service := NewBlockService(repo)
+	result, err := service.ListChildrenLite(ctx, parentID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, children, result)
+	repo.AssertExpectations(t)
+}
+
+func TestBlockService_ListChildrenByTypes(t *testing.T) {
+	ctx := context.Background()
+	parentID := uuid.New()
+	pages := []model.Block{
+		{ID: uuid.New(), Type: model.BlockTypePage, ParentID: &parentID, Title: "Page", Sort: 1},
+	}
+
+	t.Run("filters to the requested types", func(t *testing.T) {
+		repo := &MockBlockRepo{}
+		repo.On("CountChildren", ctx, parentID).Return(int64(2), nil)
+		repo.On("ListChildrenByTypes", ctx, parentID, []string{model.BlockTypePage}).Return(pages, nil)
+
+		service := NewBlockService(repo)
+		result, err := service.ListChildrenByTypes(ctx, parentID, []string{model.BlockTypePage})
+
+		assert.NoError(t, err)
+		assert.Equal(t, pages, result)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("rejects unknown types", func(t *testing.T) {
+		repo := &MockBlockRepo{}
+		service := NewBlockService(repo)
+
+		_, err := service.ListChildrenByTypes(ctx, parentID, []string{model.BlockTypePage, "widget"})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid block type: widget")
+		repo.AssertNotCalled(t, "ListChildrenByTypes", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestBlockService_DuplicateBlock(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	parentID := uuid.New()
+	original := &model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeText, ParentID: &parentID, Title: "Note", Sort: 2}
+
+	t.Run("copy lands right after the original", func(t *testing.T) {
+		clone := &model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeText, ParentID: &parentID, Title: "Note", Sort: 3}
+
+		repo := &MockBlockRepo{}
+		repo.On("Get", ctx, original.ID).Return(original, nil)
+		repo.On("Get", ctx, parentID).Return(&model.Block{ID: parentID, SpaceID: spaceID, Type: model.BlockTypePage}, nil)
+		repo.On("DuplicateBlock", ctx, original.ID).Return(clone, nil)
+
+		service := NewBlockService(repo)
+		result, err := service.DuplicateBlock(ctx, spaceID, original.ID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, original.Sort+1, result.Sort)
+		assert.Equal(t, original.ParentID, result.ParentID)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("block from another space", func(t *testing.T) {
+		repo := &MockBlockRepo{}
+		repo.On("Get", ctx, original.ID).Return(original, nil)
+
+		service := NewBlockService(repo)
+		_, err := service.DuplicateBlock(ctx, uuid.New(), original.ID)
+
+		assert.EqualError(t, err, "block not found in space")
+		repo.AssertNotCalled(t, "DuplicateBlock", mock.Anything, mock.Anything)
+	})
+}
+
+func TestBlockService_DuplicatePage(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	folderID := uuid.New()
+	page := &model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypePage, Title: "Plan"}
+
+	t.Run("copy is appended under the new parent", func(t *testing.T) {
+		clone := &model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypePage, ParentID: &folderID, Title: "Plan (copy)"}
+
+		repo := &MockBlockRepo{}
+		repo.On("Get", ctx, page.ID).Return(page, nil)
+		repo.On("Get", ctx, folderID).Return(&model.Block{ID: folderID, SpaceID: spaceID, Type: model.BlockTypeFolder}, nil)
+		repo.On("DuplicateTo", ctx, page.ID, &folderID, "Plan (copy)").Return(clone, nil)
+
+		result, err := NewBlockService(repo).DuplicatePage(ctx, spaceID, page.ID, &folderID)
+		require.NoError(t, err)
+		assert.Equal(t, clone, result)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("copy to the space root", func(t *testing.T) {
+		repo := &MockBlockRepo{}
+		repo.On("Get", ctx, page.ID).Return(page, nil)
+		repo.On("DuplicateTo", ctx, page.ID, (*uuid.UUID)(nil), "Plan (copy)").Return(&model.Block{}, nil)
+
+		_, err := NewBlockService(repo).DuplicatePage(ctx, spaceID, page.ID, nil)
+		require.NoError(t, err)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("parent that cannot hold pages", func(t *testing.T) {
+		otherPageID := uuid.New()
+		repo := &MockBlockRepo{}
+		repo.On("Get", ctx, page.ID).Return(page, nil)
+		repo.On("Get", ctx, otherPageID).Return(&model.Block{ID: otherPageID, SpaceID: spaceID, Type: model.BlockTypePage}, nil)
+
+		_, err := NewBlockService(repo).DuplicatePage(ctx, spaceID, page.ID, &otherPageID)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot be a child of")
+		repo.AssertNotCalled(t, "DuplicateTo", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("not a page", func(t *testing.T) {
+		repo := &MockBlockRepo{}
+		repo.On("Get", ctx, folderID).Return(&model.Block{ID: folderID, SpaceID: spaceID, Type: model.BlockTypeFolder}, nil)
+
+		_, err := NewBlockService(repo).DuplicatePage(ctx, spaceID, folderID, nil)
+		assert.EqualError(t, err, "block type 'folder' is not a page")
+	})
+}
+
+func TestBlockService_ListChildrenPaged(t *testing.T) {
+	ctx := context.Background()
+	parentID := uuid.New()
+
+	children := make([]model.Block, 5)
+	for i := range children {
+		children[i] = model.Block{ID: uuid.New(), ParentID: &parentID, Type: model.BlockTypeText, Sort: int64(i)}
+	}
+
+	repo := &MockBlockRepo{}
+	repo.On("ListChildrenAfter", ctx, parentID, int64(0), uuid.Nil, 3).Return(children[0:3], nil)
+	repo.On("ListChildrenAfter", ctx, parentID, int64(1), children[1].ID, 3).Return(children[2:5], nil)
+	repo.On("ListChildrenAfter", ctx, parentID, int64(3), children[3].ID, 3).Return(children[4:5], nil)
+	service := NewBlockService(repo)
+
+	// First page
+	items, cursor, err := service.ListChildrenPaged(ctx, parentID, 2, "")
+	require.NoError(t, err)
+	assert.Equal(t, children[0:2], items)
+	require.NotEmpty(t, cursor)
+
+	// Middle page
+	items, cursor, err = service.ListChildrenPaged(ctx, parentID, 2, cursor)
+	require.NoError(t, err)
+	assert.Equal(t, children[2:4], items)
+	require.NotEmpty(t, cursor)
+
+	// Final page
+	items, cursor, err = service.ListChildrenPaged(ctx, parentID, 2, cursor)
+	require.NoError(t, err)
+	assert.Equal(t, children[4:5], items)
+	assert.Empty(t, cursor)
+	repo.AssertExpectations(t)
+
+	t.Run("invalid input", func(t *testing.T) {
+		_, _, err := service.ListChildrenPaged(ctx, parentID, 0, "")
+		assert.EqualError(t, err, "limit must be positive")
+
+		_, _, err = service.ListChildrenPaged(ctx, parentID, 2, "not-a-cursor")
+		assert.ErrorIs(t, err, paging.ErrBadCursor)
+	})
+}
+
+func TestBlockService_UpdatePage(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	pageID := uuid.New()
+	folderID := uuid.New()
+	title := "Renamed"
+	sort := int64(0)
+
+	page := &model.Block{ID: pageID, SpaceID: spaceID, Type: model.BlockTypePage, Title: "Old"}
+
+	tests := []struct {
+		name        string
+		title       *string
+		newParentID *uuid.UUID
+		setup       func(*MockBlockRepo)
+		wantErr     bool
+		errMsg      string
+	}{
+		{
+			name:        "rename and move in one call",
+			title:       &title,
+			newParentID: &folderID,
+			setup: func(repo *MockBlockRepo) {
+				folder := &model.Block{ID: folderID, SpaceID: spaceID, Type: model.BlockTypeFolder}
+				repo.On("Get", ctx, pageID).Return(page, nil)
+				repo.On("Get", ctx, folderID).Return(folder, nil)
+				repo.On("UpdatePage", ctx, pageID, &title, &folderID, &sort).Return(nil)
+			},
+		},
+		{
+			name:        "invalid parent type rejects the rename too",
+			title:       &title,
+			newParentID: &folderID,
+			setup: func(repo *MockBlockRepo) {
+				otherPage := &model.Block{ID: folderID, SpaceID: spaceID, Type: model.BlockTypePage}
+				repo.On("Get", ctx, pageID).Return(page, nil)
+				repo.On("Get", ctx, folderID).Return(otherPage, nil)
+			},
+			wantErr: true,
+			errMsg:  "cannot be a child of",
+		},
+		{
+			name:    "empty title",
+			title:   new(string),
+			setup:   func(repo *MockBlockRepo) {},
+			wantErr: true,
+			errMsg:  "title is empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &MockBlockRepo{}
+			tt.setup(repo)
+
+			service := NewBlockService(repo)
+			err := service.UpdatePage(ctx, spaceID, pageID, tt.title, tt.newParentID, &sort)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+				repo.AssertNotCalled(t, "UpdatePage", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			} else {
+				assert.NoError(t, err)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBlockService_ListChildren_Cap(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	parentID := uuid.New()
+
+	t.Run("over the cap", func(t *testing.T) {
+		repo := &MockBlockRepo{}
+		repo.On("CountChildren", ctx, parentID).Return(int64(3), nil)
+
+		service := NewBlockService(repo, WithMaxChildren(2))
+		_, err := service.List(ctx, spaceID, "", &parentID)
+		assert.ErrorIs(t, err, ErrTooManyChildren)
+
+		_, err = service.ListChildrenLite(ctx, parentID)
+		assert.ErrorIs(t, err, ErrTooManyChildren)
+
+		repo.AssertNotCalled(t, "ListBySpace", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		repo.AssertNotCalled(t, "ListChildrenLite", mock.Anything, mock.Anything)
+	})
+
+	t.Run("at the cap", func(t *testing.T) {
+		children := []model.Block{{ID: uuid.New()}, {ID: uuid.New()}}
+		repo := &MockBlockRepo{}
+		repo.On("CountChildren", ctx, parentID).Return(int64(2), nil)
+		repo.On("ListBySpace", ctx, spaceID, "", &parentID).Return(children, nil)
+
+		service := NewBlockService(repo, WithMaxChildren(2))
+		list, err := service.List(ctx, spaceID, "", &parentID)
+		assert.NoError(t, err)
+		assert.Len(t, list, 2)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		repo := &MockBlockRepo{}
+		repo.On("ListBySpace", ctx, spaceID, "", &parentID).Return([]model.Block{}, nil)
+
+		service := NewBlockService(repo, WithMaxChildren(0))
+		_, err := service.List(ctx, spaceID, "", &parentID)
+		assert.NoError(t, err)
+		repo.AssertNotCalled(t, "CountChildren", mock.Anything, mock.Anything)
+	})
+}
+
+func TestBlockService_BlocksExist(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	valid1, valid2 := uuid.New(), uuid.New()
+	missing1, missing2 := uuid.New(), uuid.New()
+	ids := []uuid.UUID{valid1, missing1, valid2, missing2, missing1}
+
+	repo := &MockBlockRepo{}
+	repo.On("ListExistingIDs", ctx, spaceID, ids).Return([]uuid.UUID{valid2, valid1}, nil)
+
+	service := NewBlockService(repo)
+	missing, err := service.BlocksExist(ctx, spaceID, ids)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{missing1, missing2}, missing)
+	repo.AssertExpectations(t)
+}
+
+func TestBlockService_GetPageTree(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	folder := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeFolder, Title: "Docs"}
+	first := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypePage, ParentID: &folder.ID, Sort: 0}
+	second := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypePage, ParentID: &folder.ID, Sort: 1}
+	textA := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeText, ParentID: &first.ID, Sort: 0}
+	textB := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeText, ParentID: &first.ID, Sort: 1}
+
+	t.Run("links descendants level by level", func(t *testing.T) {
+		root := folder
+		repo := &MockBlockRepo{}
+		repo.On("Get", ctx, folder.ID).Return(&root, nil)
+		repo.On("ListSubtree", ctx, folder.ID, 0).Return([]model.Block{first, second, textA, textB}, nil)
+
+		tree, err := NewBlockService(repo).GetPageTree(ctx, folder.ID, 0)
+		require.NoError(t, err)
+		require.Len(t, tree.Children, 2)
+		assert.Equal(t, first.ID, tree.Children[0].ID)
+		assert.Equal(t, second.ID, tree.Children[1].ID)
+		require.Len(t, tree.Children[0].Children, 2)
+		assert.Equal(t, textA.ID, tree.Children[0].Children[0].ID)
+		assert.Equal(t, textB.ID, tree.Children[0].Children[1].ID)
+		assert.Empty(t, tree.Children[1].Children)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("negative depth", func(t *testing.T) {
+		repo := &MockBlockRepo{}
+		_, err := NewBlockService(repo).GetPageTree(ctx, folder.ID, -1)
+		assert.Error(t, err)
+		repo.AssertNotCalled(t, "ListSubtree", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("leaf block", func(t *testing.T) {
+		leaf := textA
+		repo := &MockBlockRepo{}
+		repo.On("Get", ctx, textA.ID).Return(&leaf, nil)
+		_, err := NewBlockService(repo).GetPageTree(ctx, textA.ID, 0)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "is not a page or folder")
+	})
+}
+
+func TestBlockService_GetSubtreeTextStats(t *testing.T) {
+	ctx := context.Background()
+	pageID := uuid.New()
+
+	repo := &MockBlockRepo{}
+	repo.On("SubtreeTextStats", ctx, pageID, false).Return(120, 20, 3, nil)
+
+	service := NewBlockService(repo)
+	chars, words, blocks, err := service.GetSubtreeTextStats(ctx, pageID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 120, chars)
+	assert.Equal(t, 20, words)
+	assert.Equal(t, 3, blocks)
+	repo.AssertExpectations(t)
+}
+
+func TestBlockService_LockBlock(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	page := &model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypePage, Title: "Published"}
+
+	repo := &MockBlockRepo{}
+	repo.On("Get", ctx, page.ID).Return(page, nil)
+	repo.On("SetLocked", ctx, page.ID, mock.Anything, false).Run(func(args mock.Arguments) {
+		page.IsLocked = args.Bool(2)
+	}).Return(nil)
+	repo.On("Update", ctx, mock.Anything).Return(nil)
+
+	service := NewBlockService(repo)
+	update := &model.Block{ID: page.ID, Title: "Edited"}
+
+	require.NoError(t, service.LockBlock(ctx, spaceID, page.ID, false))
+	assert.ErrorIs(t, service.UpdateBlockProperties(ctx, update), ErrLocked)
+	assert.ErrorIs(t, service.Create(ctx, &model.Block{SpaceID: spaceID, Type: model.BlockTypeText, ParentID: &page.ID}), ErrLocked)
+	repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+
+	require.NoError(t, service.UnlockBlock(ctx, spaceID, page.ID, false))
+	assert.NoError(t, service.UpdateBlockProperties(ctx, update))
+	repo.AssertCalled(t, "Update", ctx, update)
+}
+
+type stubAuthorizer struct{ allow bool }
+
+func (a stubAuthorizer) CanRead(context.Context, uuid.UUID, uuid.UUID) (bool, error) {
+	return a.allow, nil
+}
+
+func (a stubAuthorizer) CanWrite(context.Context, uuid.UUID, uuid.UUID) (bool, error) {
+	return a.allow, nil
+}
+
+func TestBlockService_Authorizer(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	block := &model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypePage, Title: "Page"}
+	update := &model.Block{ID: block.ID, Title: "Renamed"}
+
+	t.Run("denying authorizer blocks the update", func(t *testing.T) {
+		repo := &MockBlockRepo{}
+		repo.On("Get", ctx, block.ID).Return(block, nil)
+
+		service := NewBlockService(repo, WithAuthorizer(stubAuthorizer{allow: false}))
+		err := service.UpdateBlockProperties(ctx, update)
+
+		assert.ErrorIs(t, err, ErrForbidden)
+		repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("allowing authorizer permits the update", func(t *testing.T) {
+		repo := &MockBlockRepo{}
+		repo.On("Get", ctx, block.ID).Return(block, nil)
+		repo.On("Update", ctx, update).Return(nil)
+
+		service := NewBlockService(repo, WithAuthorizer(stubAuthorizer{allow: true}))
+		err := service.UpdateBlockProperties(ctx, update)
+
+		assert.NoError(t, err)
+		repo.AssertExpectations(t)
+	})
+}
+
+func TestBlockService_GetAncestors(t *testing.T) {
+	ctx := context.Background()
+	outer := model.Block{ID: uuid.New(), Type: model.BlockTypeFolder, Title: "Outer"}
+	inner := model.Block{ID: uuid.New(), Type: model.BlockTypeFolder, ParentID: &outer.ID, Title: "Inner"}
+	page := model.Block{ID: uuid.New(), Type: model.BlockTypePage, ParentID: &inner.ID, Title: "Page"}
+	text := model.Block{ID: uuid.New(), Type: model.BlockTypeText, ParentID: &page.ID}
+
+	t.Run("deep chain", func(t *testing.T) {
+		repo := &MockBlockRepo{}
+		repo.On("ListAncestorsBatch", ctx, []uuid.UUID{text.ID}).Return(map[uuid.UUID][]model.Block{
+			text.ID: {outer, inner, page},
+		}, nil)
+
+		chain, err := NewBlockService(repo).GetAncestors(ctx, text.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []model.Block{outer, inner, page}, chain)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("root node", func(t *testing.T) {
+		repo := &MockBlockRepo{}
+		repo.On("ListAncestorsBatch", ctx, []uuid.UUID{outer.ID}).Return(map[uuid.UUID][]model.Block{}, nil)
+
+		chain, err := NewBlockService(repo).GetAncestors(ctx, outer.ID)
+		require.NoError(t, err)
+		assert.NotNil(t, chain)
+		assert.Empty(t, chain)
+		repo.AssertExpectations(t)
+	})
+}
+
+func TestBlockService_GetAncestorsBatch(t *testing.T) {
+	ctx := context.Background()
+	folder := model.Block{ID: uuid.New(), Type: model.BlockTypeFolder, Title: "Folder"}
+	page := model.Block{ID: uuid.New(), Type: model.BlockTypePage, ParentID: &folder.ID, Title: "Page"}
+	text := model.Block{ID: uuid.New(), Type: model.BlockTypeText, ParentID: &page.ID}
+
+	repo := &MockBlockRepo{}
+	repo.On("ListAncestorsBatch", ctx, []uuid.UUID{text.ID, page.ID, folder.ID}).Return(map[uuid.UUID][]model.Block{
+		text.ID: {folder, page},
+		page.ID: {folder},
+	}, nil)
+
+	service := NewBlockService(repo)
+	chains, err := service.GetAncestorsBatch(ctx, []uuid.UUID{text.ID, page.ID, folder.ID, text.ID})
+
+	require.NoError(t, err)
+	assert.Equal(t, map[uuid.UUID][]model.Block{
+		text.ID:   {folder, page},
+		page.ID:   {folder},
+		folder.ID: {},
+	}, chains)
+	repo.AssertExpectations(t)
+}
+
+func TestBlockService_MovePageToTop(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	folderID := uuid.New()
+	pageID := uuid.New()
+
+	t.Run("nested page moved to root top", func(t *testing.T) {
+		page := &model.Block{ID: pageID, SpaceID: spaceID, Type: model.BlockTypePage, ParentID: &folderID, Sort: 5}
+		repo := &MockBlockRepo{}
+		repo.On("Get", ctx, pageID).Return(page, nil)
+		repo.On("Get", ctx, folderID).Return(&model.Block{ID: folderID, SpaceID: spaceID, Type: model.BlockTypeFolder}, nil)
+		repo.On("MoveToParentAtSort", ctx, pageID, (*uuid.UUID)(nil), int64(0)).Return(nil)
+
+		service := NewBlockService(repo)
+		assert.NoError(t, service.MovePageToTop(ctx, spaceID, pageID))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("not a page", func(t *testing.T) {
+		text := &model.Block{ID: pageID, SpaceID: spaceID, Type: model.BlockTypeText, ParentID: &folderID}
+		repo := &MockBlockRepo{}
+		repo.On("Get", ctx, pageID).Return(text, nil)
+
+		service := NewBlockService(repo)
+		assert.Error(t, service.MovePageToTop(ctx, spaceID, pageID))
+		repo.AssertNotCalled(t, "MoveToParentAtSort", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+type staticSpaceProjects map[uuid.UUID]uuid.UUID
+
+func (p staticSpaceProjects) SpaceProject(_ context.Context, spaceID uuid.UUID) (uuid.UUID, error) {
+	return p[spaceID], nil
+}
+
+func TestBlockService_MovePageToSpace(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	targetSpaceID := uuid.New()
+	otherProjectSpaceID := uuid.New()
+	pageID := uuid.New()
+	folderID := uuid.New()
+	projectID := uuid.New()
+	projects := staticSpaceProjects{spaceID: projectID, targetSpaceID: projectID, otherProjectSpaceID: uuid.New()}
+
+	tests := []struct {
+		name        string
+		targetSpace uuid.UUID
+		parentID    *uuid.UUID
+		setup       func(*MockBlockRepo)
+		wantErr     string
+	}{
+		{
+			name:        "move under a folder in another space",
+			targetSpace: targetSpaceID,
+			parentID:    &folderID,
+			setup: func(repo *MockBlockRepo) {
+				repo.On("Get", ctx, pageID).Return(&model.Block{ID: pageID, SpaceID: spaceID, Type: model.BlockTypePage}, nil)
+				repo.On("Get", ctx, folderID).Return(&model.Block{ID: folderID, SpaceID: targetSpaceID, Type: model.BlockTypeFolder}, nil)
+				repo.On("MoveToSpace", ctx, pageID, targetSpaceID, &folderID).Return(nil)
+			},
+		},
+		{
+			name:        "move to the root of another space",
+			targetSpace: targetSpaceID,
+			setup: func(repo *MockBlockRepo) {
+				repo.On("Get", ctx, pageID).Return(&model.Block{ID: pageID, SpaceID: spaceID, Type: model.BlockTypePage}, nil)
+				repo.On("MoveToSpace", ctx, pageID, targetSpaceID, (*uuid.UUID)(nil)).Return(nil)
+			},
+		},
+		{
+			name:        "same space moves within it",
+			targetSpace: spaceID,
+			setup: func(repo *MockBlockRepo) {
+				repo.On("Get", ctx, pageID).Return(&model.Block{ID: pageID, SpaceID: spaceID, Type: model.BlockTypePage}, nil)
+				repo.On("MoveToParentAppend", ctx, pageID, (*uuid.UUID)(nil)).Return(nil)
+			},
+		},
+		{
+			name:        "parent in another space",
+			targetSpace: targetSpaceID,
+			parentID:    &folderID,
+			setup: func(repo *MockBlockRepo) {
+				repo.On("Get", ctx, pageID).Return(&model.Block{ID: pageID, SpaceID: spaceID, Type: model.BlockTypePage}, nil)
+				repo.On("Get", ctx, folderID).Return(&model.Block{ID: folderID, SpaceID: spaceID, Type: model.BlockTypeFolder}, nil)
+			},
+			wantErr: "parent not found in target space",
+		},
+		{
+			name:        "not a page",
+			targetSpace: targetSpaceID,
+			setup: func(repo *MockBlockRepo) {
+				repo.On("Get", ctx, pageID).Return(&model.Block{ID: pageID, SpaceID: spaceID, Type: model.BlockTypeFolder}, nil)
+			},
+			wantErr: "is not a page",
+		},
+		{
+			name:        "locked page",
+			targetSpace: targetSpaceID,
+			setup: func(repo *MockBlockRepo) {
+				repo.On("Get", ctx, pageID).Return(&model.Block{ID: pageID, SpaceID: spaceID, Type: model.BlockTypePage, IsLocked: true}, nil)
+			},
+			wantErr: ErrLocked.Error(),
+		},
+		{
+			name:        "space of another project",
+			targetSpace: otherProjectSpaceID,
+			setup: func(repo *MockBlockRepo) {
+				repo.On("Get", ctx, pageID).Return(&model.Block{ID: pageID, SpaceID: spaceID, Type: model.BlockTypePage}, nil)
+			},
+			wantErr: ErrForbidden.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &MockBlockRepo{}
+			tt.setup(repo)
+
+			err := NewBlockService(repo, WithSpaceProjects(projects)).MovePageToSpace(ctx, pageID, tt.targetSpace, tt.parentID)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				repo.AssertNotCalled(t, "MoveToSpace", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			} else {
+				assert.NoError(t, err)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBlockService_UpdatePropsWhere(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	page := &model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypePage, Title: "Todos"}
+	match := map[string]any{"kind": "todo", "done": false}
+	patch := map[string]any{"done": true}
+
+	repo := &MockBlockRepo{}
+	repo.On("Get", ctx, page.ID).Return(page, nil)
+	repo.On("UpdatePropsWhere", ctx, spaceID, page.ID, match, patch).Return(3, nil)
+
+	service := NewBlockService(repo)
+
+	n, err := service.UpdatePropsWhere(ctx, spaceID, page.ID, match, patch)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	_, err = service.UpdatePropsWhere(ctx, spaceID, page.ID, match, nil)
+	assert.Error(t, err)
+
+	_, err = service.UpdatePropsWhere(ctx, uuid.New(), page.ID, match, patch)
+	assert.Error(t, err)
+
+	page.IsLocked = true
+	_, err = service.UpdatePropsWhere(ctx, spaceID, page.ID, match, patch)
+	assert.ErrorIs(t, err, ErrLocked)
+	repo.AssertNumberOfCalls(t, "UpdatePropsWhere", 1)
+}
+
+func TestBlockService_ReorderChildren(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	parentID := uuid.New()
+	order := []uuid.UUID{uuid.New(), uuid.New()}
+
+	tests := []struct {
+		name    string
+		order   []uuid.UUID
+		setup   func(*MockBlockRepo)
+		wantErr string
+	}{
+		{
+			name:  "full reorder",
+			order: order,
+			setup: func(repo *MockBlockRepo) {
+				repo.On("Get", ctx, parentID).Return(&model.Block{ID: parentID, SpaceID: spaceID}, nil)
+				repo.On("ReorderChildren", ctx, spaceID, parentID, order).Return(nil)
+			},
+		},
+		{
+			name:  "foreign ids are reported",
+			order: order,
+			setup: func(repo *MockBlockRepo) {
+				repo.On("Get", ctx, parentID).Return(&model.Block{ID: parentID, SpaceID: spaceID}, nil)
+				repo.On("ReorderChildren", ctx, spaceID, parentID, order).
+					Return(fmt.Errorf("blocks are not children of %s or are listed twice: %s", parentID, order[1]))
+			},
+			wantErr: order[1].String(),
+		},
+		{
+			name:    "empty order",
+			setup:   func(repo *MockBlockRepo) {},
+			wantErr: "ordered ids is empty",
+		},
+		{
+			name:  "parent in another space",
+			order: order,
+			setup: func(repo *MockBlockRepo) {
+				repo.On("Get", ctx, parentID).Return(&model.Block{ID: parentID, SpaceID: uuid.New()}, nil)
+			},
+			wantErr: "parent not found in space",
+		},
+		{
+			name:  "locked parent",
+			order: order,
+			setup: func(repo *MockBlockRepo) {
+				repo.On("Get", ctx, parentID).Return(&model.Block{ID: parentID, SpaceID: spaceID, IsLocked: true}, nil)
+			},
+			wantErr: ErrLocked.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &MockBlockRepo{}
+			tt.setup(repo)
+
+			err := NewBlockService(repo).ReorderChildren(ctx, spaceID, parentID, tt.order)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBlockService_RestoreBlock_ArchivedAncestors(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	folder := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeFolder, Title: "Folder"}
+	page := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypePage, ParentID: &folder.ID, Title: "Page", IsArchived: true}
+	sub := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypePage, ParentID: &page.ID, Title: "Sub", IsArchived: true}
+	text := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeText, ParentID: &sub.ID, IsArchived: true}
+
+	repo := &MockBlockRepo{}
+	repo.On("Get", ctx, text.ID).Return(&text, nil)
+	repo.On("Get", ctx, folder.ID).Return(&folder, nil)
+	repo.On("ListAncestorsBatch", ctx, []uuid.UUID{text.ID}).Return(map[uuid.UUID][]model.Block{
+		text.ID: {folder, page, sub},
+	}, nil)
+	repo.On("Restore", ctx, []uuid.UUID{page.ID, sub.ID, text.ID}).Return(nil)
+
+	service := NewBlockService(repo)
+
+	err := service.RestoreBlock(ctx, spaceID, text.ID, false)
+	assert.ErrorIs(t, err, ErrArchivedAncestor)
+	assert.Contains(t, err.Error(), page.ID.String())
+	repo.AssertNotCalled(t, "Restore", mock.Anything, mock.Anything)
+
+	require.NoError(t, service.RestoreBlock(ctx, spaceID, text.ID, true))
+	repo.AssertCalled(t, "Restore", ctx, []uuid.UUID{page.ID, sub.ID, text.ID})
+}
+
+func TestBlockService_DiffTree(t *testing.T) {
+	ctx := context.Background()
+	since := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	before := since.Add(-time.Hour)
+	after := since.Add(time.Hour)
+	pageID := uuid.New()
+
+	added := model.Block{ID: uuid.New(), CreatedAt: after, UpdatedAt: after}
+	updated := model.Block{ID: uuid.New(), CreatedAt: before, UpdatedAt: after}
+	moved := model.Block{ID: uuid.New(), CreatedAt: before, UpdatedAt: after, MovedAt: &after}
+	movedLongAgo := model.Block{ID: uuid.New(), CreatedAt: before, UpdatedAt: after, MovedAt: &before}
+	removed := model.Block{ID: uuid.New(), CreatedAt: before, UpdatedAt: after, IsArchived: true}
+	transient := model.Block{ID: uuid.New(), CreatedAt: after, UpdatedAt: after, IsArchived: true}
+	deleted := model.Block{ID: uuid.New(), CreatedAt: before, UpdatedAt: before, DeletedAt: gorm.DeletedAt{Time: after, Valid: true}}
+	deletedNew := model.Block{ID: uuid.New(), CreatedAt: after, UpdatedAt: after, DeletedAt: gorm.DeletedAt{Time: after, Valid: true}}
+
+	repo := &MockBlockRepo{}
+	repo.On("ListSubtreeChangedSince", ctx, pageID, since).
+		Return([]model.Block{added, updated, moved, movedLongAgo, removed, transient, deleted, deletedNew}, nil)
+
+	diff, err := NewBlockService(repo).DiffTree(ctx, pageID, since)
+	require.NoError(t, err)
+	assert.Equal(t, TreeDiff{
+		Added:   []uuid.UUID{added.ID},
+		Updated: []uuid.UUID{updated.ID, movedLongAgo.ID},
+		Removed: []uuid.UUID{removed.ID, deleted.ID},
+		Moved:   []uuid.UUID{moved.ID},
+	}, diff)
+}
+
+func TestBlockService_ArchiveBlock(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	page := &model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypePage, Title: "Page"}
+
+	repo := &MockBlockRepo{}
+	repo.On("Get", ctx, page.ID).Return(page, nil)
+	repo.On("Archive", ctx, page.ID).Return(nil)
+
+	service := NewBlockService(repo)
+	require.NoError(t, service.ArchiveBlock(ctx, spaceID, page.ID))
+
+	page.IsLocked = true
+	assert.ErrorIs(t, service.ArchiveBlock(ctx, spaceID, page.ID), ErrLocked)
+	repo.AssertNumberOfCalls(t, "Archive", 1)
+}
+
+func TestBlockService_ListArchivedPages(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	archived := []model.Block{{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypePage, Title: "Old", IsArchived: true}}
+
+	repo := &MockBlockRepo{}
+	repo.On("ListArchived", ctx, spaceID, model.BlockTypePage).Return(archived, nil)
+
+	list, err := NewBlockService(repo).ListArchivedPages(ctx, spaceID)
+	require.NoError(t, err)
+	assert.Equal(t, archived, list)
+	repo.AssertExpectations(t)
+}
+
+func TestBlockService_SearchBlocks_WithinPage(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	page := &model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypePage, Title: "Project"}
+	match := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeText, ParentID: &page.ID, Title: "roadmap"}
+	opts := SearchOptions{WithinPageID: &page.ID}
+
+	repo := &MockBlockRepo{}
+	repo.On("Get", ctx, page.ID).Return(page, nil)
+	repo.On("SearchBlocks", ctx, spaceID, "roadmap", opts).Return([]model.Block{match}, nil)
+
+	service := NewBlockService(repo)
+
+	list, err := service.SearchBlocks(ctx, spaceID, "  roadmap ", opts)
+	require.NoError(t, err)
+	assert.Equal(t, []model.Block{match}, list)
+
+	_, err = service.SearchBlocks(ctx, uuid.New(), "roadmap", opts)
+	assert.Error(t, err)
+
+	_, err = service.SearchBlocks(ctx, spaceID, " ", SearchOptions{})
+	assert.Error(t, err)
+	repo.AssertNumberOfCalls(t, "SearchBlocks", 1)
+}
+
+func TestBlockService_ConvertBlockType(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	folder := &model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeFolder, Title: "Folder"}
+	page := &model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypePage, ParentID: &folder.ID, Title: "Page"}
+	text := &model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeText, ParentID: &page.ID, Title: "Notes"}
+	nested := &model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypePage, ParentID: &page.ID, Title: "Nested"}
+
+	repo := &MockBlockRepo{}
+	for _, b := range []*model.Block{folder, page, text, nested} {
+		repo.On("Get", ctx, b.ID).Return(b, nil)
+	}
+	repo.On("ConvertType", ctx, text.ID, model.BlockTypePage, (*uuid.UUID)(nil)).Return(nil)
+	repo.On("ConvertType", ctx, nested.ID, model.BlockTypeText, &page.ID).Return(nil)
+
+	service := NewBlockService(repo)
+
+	t.Run("block to page detaches to root", func(t *testing.T) {
+		require.NoError(t, service.ConvertBlockType(ctx, spaceID, text.ID, model.BlockTypePage))
+		repo.AssertCalled(t, "ConvertType", ctx, text.ID, model.BlockTypePage, (*uuid.UUID)(nil))
+	})
+
+	t.Run("page under a page to block keeps its parent", func(t *testing.T) {
+		require.NoError(t, service.ConvertBlockType(ctx, spaceID, nested.ID, model.BlockTypeText))
+		repo.AssertCalled(t, "ConvertType", ctx, nested.ID, model.BlockTypeText, &page.ID)
+	})
+
+	t.Run("page under a folder cannot become a block", func(t *testing.T) {
+		assert.Error(t, service.ConvertBlockType(ctx, spaceID, page.ID, model.BlockTypeText))
+	})
+
+	t.Run("folders are not convertible", func(t *testing.T) {
+		assert.Error(t, service.ConvertBlockType(ctx, spaceID, folder.ID, model.BlockTypePage))
+		assert.Error(t, service.ConvertBlockType(ctx, spaceID, text.ID, model.BlockTypeFolder))
+	})
+
+	t.Run("locked parent", func(t *testing.T) {
+		page.IsLocked = true
+		defer func() { page.IsLocked = false }()
+		assert.ErrorIs(t, service.ConvertBlockType(ctx, spaceID, text.ID, model.BlockTypePage), ErrLocked)
+	})
+
+	repo.AssertNumberOfCalls(t, "ConvertType", 2)
+}
+
+func TestBlockService_ArchiveWhere(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	match := map[string]any{"status": "done"}
+
+	repo := &MockBlockRepo{}
+	repo.On("ArchiveWhere", ctx, spaceID, match).Return(3, nil)
+
+	service := NewBlockService(repo)
+
+	n, err := service.ArchiveWhere(ctx, spaceID, match)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	_, err = service.ArchiveWhere(ctx, spaceID, map[string]any{})
+	assert.Error(t, err)
+	repo.AssertNumberOfCalls(t, "ArchiveWhere", 1)
+}
+
+func TestBlockService_CreateAt(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	page := &model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypePage, Title: "Page"}
+	anchorID := uuid.New()
+	pos := InsertPosition{AfterID: &anchorID}
+
+	repo := &MockBlockRepo{}
+	repo.On("Get", ctx, page.ID).Return(page, nil)
+	repo.On("CreateAt", ctx, mock.AnythingOfType("*model.Block"), pos).Return(nil)
+
+	service := NewBlockService(repo)
+
+	b := &model.Block{SpaceID: spaceID, Type: model.BlockTypeText, ParentID: &page.ID}
+	require.NoError(t, service.CreateAt(ctx, b, pos))
+
+	sort := int64(0)
+	err := service.CreateAt(ctx, b, InsertPosition{AfterID: &anchorID, AtSort: &sort})
+	assert.Error(t, err)
+
+	page.IsLocked = true
+	assert.ErrorIs(t, service.CreateAt(ctx, b, pos), ErrLocked)
+	repo.AssertNumberOfCalls(t, "CreateAt", 1)
+}
@@ -62,7 +62,7 @@ func (s *diskService) List(ctx context.Context, in ListDisksInput) (*ListDisksOu
 	var afterID uuid.UUID
 	var err error
 	if in.Cursor != "" {
-		afterT, afterID, err = paging.DecodeCursor(in.Cursor)
+		err = paging.DecodeCursor(in.Cursor, &afterT, &afterID)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+)
+
+// ExtractInlineAssets moves inline data: URIs found in part meta "url" into assets.
+// Each rewritten part references an asset keyed by the SHA256 of the decoded bytes
+// and loses its "url" meta. The returned assets are unique by SHA256, carry the
+// decoded bytes in Data and have no S3 location yet; uploading them is up to the
+// caller. Input messages are not modified.
+func ExtractInlineAssets(messages []model.Message) ([]model.Message, []model.Asset, error) {
+	result := make([]model.Message, len(messages))
+	var assets []model.Asset
+	seen := make(map[string]int)
+
+	for i, msg := range messages {
+		result[i] = msg
+
+		var parts []model.Part
+		for j, part := range msg.Parts {
+			raw, ok := part.Meta["url"].(string)
+			if !ok || !strings.HasPrefix(raw, "data:") {
+				continue
+			}
+
+			mime, data, err := decodeDataURI(raw)
+			if err != nil {
+				return nil, nil, fmt.Errorf("messages[%d].parts[%d]: %w", i, j, err)
+			}
+
+			sum := sha256.Sum256(data)
+			sumHex := hex.EncodeToString(sum[:])
+			idx, ok := seen[sumHex]
+			if !ok {
+				idx = len(assets)
+				seen[sumHex] = idx
+				assets = append(assets, model.Asset{
+					SHA256: sumHex,
+					MIME:   mime,
+					SizeB:  int64(len(data)),
+					Data:   data,
+				})
+			}
+
+			if parts == nil {
+				parts = append([]model.Part(nil), msg.Parts...)
+			}
+			meta := make(map[string]any, len(part.Meta))
+			for k, v := range part.Meta {
+				if k != "url" {
+					meta[k] = v
+				}
+			}
+			asset := assets[idx]
+			asset.Data = nil
+			parts[j].Asset = &asset
+			parts[j].Meta = meta
+		}
+
+		if parts != nil {
+			result[i].Parts = parts
+		}
+	}
+
+	return result, assets, nil
+}
+
+// decodeDataURI parses data:[<mime>][;base64],<data>
+func decodeDataURI(uri string) (string, []byte, error) {
+	header, payload, ok := strings.Cut(strings.TrimPrefix(uri, "data:"), ",")
+	if !ok {
+		return "", nil, fmt.Errorf("malformed data URI")
+	}
+
+	mime, isBase64 := strings.CutSuffix(header, ";base64")
+	if i := strings.Index(mime, ";"); i >= 0 {
+		mime = mime[:i]
+	}
+	if mime == "" {
+		mime = "text/plain"
+	}
+
+	if isBase64 {
+		data, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid base64 data URI: %w", err)
+		}
+		return mime, data, nil
+	}
+
+	text, err := url.PathUnescape(payload)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid data URI: %w", err)
+	}
+	return mime, []byte(text), nil
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractInlineAssets(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\nfake")
+	sum := sha256.Sum256(png)
+	sumHex := hex.EncodeToString(sum[:])
+	dataURI := "data:image/png;base64," + base64.StdEncoding.EncodeToString(png)
+
+	messages := []model.Message{
+		{Role: "user", Parts: []model.Part{
+			{Type: "text", Text: "What is this?"},
+			{Type: "image", Meta: map[string]any{"url": dataURI, "detail": "high"}},
+		}},
+		{Role: "user", Parts: []model.Part{
+			{Type: "image", Meta: map[string]any{"url": dataURI}},
+			{Type: "image", Meta: map[string]any{"url": "https://example.com/a.png"}},
+		}},
+	}
+
+	out, assets, err := ExtractInlineAssets(messages)
+	require.NoError(t, err)
+
+	require.Len(t, assets, 1)
+	assert.Equal(t, sumHex, assets[0].SHA256)
+	assert.Equal(t, "image/png", assets[0].MIME)
+	assert.Equal(t, int64(len(png)), assets[0].SizeB)
+	assert.Equal(t, png, assets[0].Data)
+
+	image := out[0].Parts[1]
+	require.NotNil(t, image.Asset)
+	assert.Equal(t, sumHex, image.Asset.SHA256)
+	assert.Nil(t, image.Asset.Data)
+	assert.Equal(t, map[string]any{"detail": "high"}, image.Meta)
+
+	require.NotNil(t, out[1].Parts[0].Asset)
+	assert.Equal(t, sumHex, out[1].Parts[0].Asset.SHA256)
+	assert.Nil(t, out[1].Parts[1].Asset)
+	assert.Equal(t, "https://example.com/a.png", out[1].Parts[1].Meta["url"])
+
+	// Input is left untouched
+	assert.Nil(t, messages[0].Parts[1].Asset)
+	assert.Equal(t, dataURI, messages[0].Parts[1].Meta["url"])
+}
+
+func TestExtractInlineAssets_InvalidBase64(t *testing.T) {
+	messages := []model.Message{
+		{Role: "user", Parts: []model.Part{
+			{Type: "image", Meta: map[string]any{"url": "data:image/png;base64,!!!"}},
+		}},
+	}
+
+	_, _, err := ExtractInlineAssets(messages)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "messages[0].parts[0]")
+}
@@ -111,7 +111,7 @@ func (s *sessionService) List(ctx context.Context, in ListSessionsInput) (*ListS
 	var afterID uuid.UUID
 	var err error
 	if in.Cursor != "" {
-		afterT, afterID, err = paging.DecodeCursor(in.Cursor)
+		err = paging.DecodeCursor(in.Cursor, &afterT, &afterID)
 		if err != nil {
 			return nil, err
 		}
@@ -153,10 +153,10 @@ type SendMQPublishJSON struct {
 }
 
 type PartIn struct {
-	Type      string                 `json:"type" validate:"required,oneof=text image audio video file tool-call tool-result data"` // "text" | "image" | ...
-	Text      string                 `json:"text,omitempty"`                                                                        // Text sharding
-	FileField string                 `json:"file_field,omitempty"`                                                                  // File field name in the form
-	Meta      map[string]interface{} `json:"meta,omitempty"`                                                                        // [Optional] metadata
+	Type      string                 `json:"type" validate:"required,oneof=text image audio video file tool-call tool-result data refusal reasoning output-audio"` // "text" | "image" | ...
+	Text      string                 `json:"text,omitempty"`                                                                                                       // Text sharding
+	FileField string                 `json:"file_field,omitempty"`                                                                                                 // File field name in the form
+	Meta      map[string]interface{} `json:"meta,omitempty"`                                                                                                       // [Optional] metadata
 }
 
 func (p *PartIn) Validate() error {
@@ -173,6 +173,18 @@ func (p *PartIn) Validate() error {
 		if p.Text == "" {
 			return errors.New("text part requires non-empty text field")
 		}
+	case "refusal":
+		if p.Text == "" {
+			return errors.New("refusal part requires non-empty text field")
+		}
+	case "reasoning":
+		if p.Text == "" {
+			return errors.New("reasoning part requires non-empty text field")
+		}
+	case "output-audio":
+		if id, _ := p.Meta["id"].(string); id == "" {
+			return errors.New("output-audio part requires 'id' in meta")
+		}
 	case "tool-call":
 		// UNIFIED FORMAT: only "tool-call" is accepted (no more "tool-use")
 		if p.Meta == nil {
@@ -335,7 +347,7 @@ func (s *sessionService) GetMessages(ctx context.Context, in GetMessagesInput) (
 		var afterT time.Time
 		var afterID uuid.UUID
 		if in.Cursor != "" {
-			afterT, afterID, err = paging.DecodeCursor(in.Cursor)
+			err = paging.DecodeCursor(in.Cursor, &afterT, &afterID)
 			if err != nil {
 				return nil, err
 			}
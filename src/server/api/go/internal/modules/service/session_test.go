@@ -602,6 +602,24 @@ func TestPartIn_Validate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "text part requires non-empty text field",
 		},
+		{
+			name: "valid output-audio part",
+			part: PartIn{
+				Type: "output-audio",
+				Text: "Hello there",
+				Meta: map[string]interface{}{"id": "audio_abc123"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "output-audio part missing id",
+			part: PartIn{
+				Type: "output-audio",
+				Text: "Hello there",
+			},
+			wantErr: true,
+			errMsg:  "output-audio part requires 'id' in meta",
+		},
 		{
 			name: "valid tool-call part",
 			part: PartIn{
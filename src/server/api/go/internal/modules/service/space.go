@@ -41,6 +41,38 @@ func NewSpaceService(r repo.SpaceRepo, publisher *mq.Publisher, cfg *config.Conf
 	}
 }
 
+// spacePropsSchemas reads the props schema stored on each space
+type spacePropsSchemas struct{ r repo.SpaceRepo }
+
+// NewSpacePropsSchemas returns a PropsSchemaProvider backed by model.Space.PropsSchema
+func NewSpacePropsSchemas(r repo.SpaceRepo) PropsSchemaProvider {
+	return &spacePropsSchemas{r: r}
+}
+
+func (p *spacePropsSchemas) PropsSchema(ctx context.Context, spaceID uuid.UUID) (map[string]any, error) {
+	space, err := p.r.Get(ctx, &model.Space{ID: spaceID})
+	if err != nil {
+		return nil, err
+	}
+	return space.PropsSchema, nil
+}
+
+// spaceProjects reads the project of each space
+type spaceProjects struct{ r repo.SpaceRepo }
+
+// NewSpaceProjects returns a SpaceProjectProvider backed by model.Space.ProjectID
+func NewSpaceProjects(r repo.SpaceRepo) SpaceProjectProvider {
+	return &spaceProjects{r: r}
+}
+
+func (p *spaceProjects) SpaceProject(ctx context.Context, spaceID uuid.UUID) (uuid.UUID, error) {
+	space, err := p.r.Get(ctx, &model.Space{ID: spaceID})
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return space.ProjectID, nil
+}
+
 func (s *spaceService) Create(ctx context.Context, m *model.Space) error {
 	return s.r.Create(ctx, m)
 }
@@ -85,7 +117,7 @@ func (s *spaceService) List(ctx context.Context, in ListSpacesInput) (*ListSpace
 	var afterID uuid.UUID
 	var err error
 	if in.Cursor != "" {
-		afterT, afterID, err = paging.DecodeCursor(in.Cursor)
+		err = paging.DecodeCursor(in.Cursor, &afterT, &afterID)
 		if err != nil {
 			return nil, err
 		}
@@ -130,7 +162,7 @@ func (s *spaceService) ListExperienceConfirmations(ctx context.Context, in ListE
 	var afterID uuid.UUID
 	var err error
 	if in.Cursor != "" {
-		afterT, afterID, err = paging.DecodeCursor(in.Cursor)
+		err = paging.DecodeCursor(in.Cursor, &afterT, &afterID)
 		if err != nil {
 			return nil, err
 		}
@@ -46,7 +46,7 @@ func (s *taskService) GetTasks(ctx context.Context, in GetTasksInput) (*GetTasks
 	var afterID uuid.UUID
 	var err error
 	if in.Cursor != "" {
-		afterT, afterID, err = paging.DecodeCursor(in.Cursor)
+		err = paging.DecodeCursor(in.Cursor, &afterT, &afterID)
 		if err != nil {
 			return nil, err
 		}
@@ -1,6 +1,7 @@
 package converter
 
 import (
+	"context"
 	"github.com/memodb-io/Acontext/internal/modules/model"
 	"github.com/memodb-io/Acontext/internal/modules/service"
 )
@@ -25,10 +26,14 @@ type AcontextMessage struct {
 }
 
 // Convert converts internal model.Message to Acontext format
-func (c *AcontextConverter) Convert(messages []model.Message, publicURLs map[string]service.PublicURL) (interface{}, error) {
+func (c *AcontextConverter) Convert(ctx context.Context, messages []model.Message, publicURLs map[string]service.PublicURL) (interface{}, error) {
 	result := make([]AcontextMessage, len(messages))
 
 	for i, msg := range messages {
+		// Parts is always a list, even for a message stored without any
+		if msg.Parts == nil {
+			msg.Parts = []model.Part{}
+		}
 		acontextMsg := AcontextMessage{
 			ID:                       msg.ID.String(),
 			SessionID:                msg.SessionID.String(),
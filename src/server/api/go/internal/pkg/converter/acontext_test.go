@@ -1,6 +1,7 @@
 package converter
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -21,7 +22,7 @@ func TestAcontextConverter_Convert_TextMessage(t *testing.T) {
 		}, nil),
 	}
 
-	result, err := converter.Convert(messages, nil)
+	result, err := converter.Convert(context.Background(), messages, nil)
 	require.NoError(t, err)
 
 	acontextMessages, ok := result.([]AcontextMessage)
@@ -56,7 +57,7 @@ func TestAcontextConverter_Convert_WithAsset(t *testing.T) {
 		"assets/test.jpg": {URL: "https://example.com/test.jpg"},
 	}
 
-	result, err := converter.Convert(messages, publicURLs)
+	result, err := converter.Convert(context.Background(), messages, publicURLs)
 	require.NoError(t, err)
 
 	acontextMessages := result.([]AcontextMessage)
@@ -89,7 +90,7 @@ func TestAcontextConverter_Convert_WithCacheControl(t *testing.T) {
 		}, nil),
 	}
 
-	result, err := converter.Convert(messages, nil)
+	result, err := converter.Convert(context.Background(), messages, nil)
 	require.NoError(t, err)
 
 	acontextMessages := result.([]AcontextMessage)
@@ -115,7 +116,7 @@ func TestAcontextConverter_Convert_MessageMeta(t *testing.T) {
 		}),
 	}
 
-	result, err := converter.Convert(messages, nil)
+	result, err := converter.Convert(context.Background(), messages, nil)
 	require.NoError(t, err)
 
 	acontextMessages := result.([]AcontextMessage)
@@ -144,7 +145,7 @@ func TestAcontextConverter_Convert_MultipleParts(t *testing.T) {
 		}, nil),
 	}
 
-	result, err := converter.Convert(messages, nil)
+	result, err := converter.Convert(context.Background(), messages, nil)
 	require.NoError(t, err)
 
 	acontextMessages := result.([]AcontextMessage)
@@ -169,7 +170,7 @@ func TestAcontextConverter_Convert_EmptyMeta(t *testing.T) {
 		}, nil),
 	}
 
-	result, err := converter.Convert(messages, nil)
+	result, err := converter.Convert(context.Background(), messages, nil)
 	require.NoError(t, err)
 
 	acontextMessages := result.([]AcontextMessage)
@@ -197,7 +198,7 @@ func TestAcontextConverter_Convert_MultipleMessages(t *testing.T) {
 		}, nil),
 	}
 
-	result, err := converter.Convert(messages, nil)
+	result, err := converter.Convert(context.Background(), messages, nil)
 	require.NoError(t, err)
 
 	acontextMessages := result.([]AcontextMessage)
@@ -226,7 +227,7 @@ func TestAcontextConverter_Convert_Timestamps(t *testing.T) {
 
 	messages := []model.Message{msg}
 
-	result, err := converter.Convert(messages, nil)
+	result, err := converter.Convert(context.Background(), messages, nil)
 	require.NoError(t, err)
 
 	acontextMessages := result.([]AcontextMessage)
@@ -268,7 +269,7 @@ func TestAcontextConverter_Convert_ParentID(t *testing.T) {
 	}
 	msg.Meta = datatypes.NewJSONType(map[string]any{})
 
-	result, err := converter.Convert([]model.Message{msg}, nil)
+	result, err := converter.Convert(context.Background(), []model.Message{msg}, nil)
 	require.NoError(t, err)
 
 	acontextMessages := result.([]AcontextMessage)
@@ -297,7 +298,7 @@ func TestAcontextConverter_Convert_NoParentID(t *testing.T) {
 	}
 	msg.Meta = datatypes.NewJSONType(map[string]any{})
 
-	result, err := converter.Convert([]model.Message{msg}, nil)
+	result, err := converter.Convert(context.Background(), []model.Message{msg}, nil)
 	require.NoError(t, err)
 
 	acontextMessages := result.([]AcontextMessage)
@@ -337,7 +338,7 @@ func TestAcontextConverter_Convert_SessionTaskProcessStatus(t *testing.T) {
 			}
 			msg.Meta = datatypes.NewJSONType(map[string]any{})
 
-			result, err := converter.Convert([]model.Message{msg}, nil)
+			result, err := converter.Convert(context.Background(), []model.Message{msg}, nil)
 			require.NoError(t, err)
 
 			acontextMessages := result.([]AcontextMessage)
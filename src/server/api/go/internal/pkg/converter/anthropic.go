@@ -1,6 +1,7 @@
 package converter
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"io"
@@ -14,33 +15,123 @@ import (
 	"github.com/memodb-io/Acontext/internal/pkg/normalizer"
 )
 
-// AnthropicConverter converts messages to Anthropic Claude-compatible format using official SDK types
-type AnthropicConverter struct{}
+// AnthropicMessages is the output of FormatAnthropic: the messages and the
+// system prompt, which the Messages API takes as a separate top-level field
+type AnthropicMessages struct {
+	System   string                   `json:"system,omitempty"`
+	Messages []anthropic.MessageParam `json:"messages"`
+}
+
+// AnthropicConverter converts messages to Anthropic Claude-compatible format using official SDK types.
+// System messages are joined into the system prompt.
+type AnthropicConverter struct {
+	// CoalesceToolResults merges a user turn into the preceding user turn when
+	// the preceding one carries tool_result blocks, since the Messages API
+	// rejects two consecutive user turns
+	CoalesceToolResults bool
+
+	// ImageFirst moves a message's image blocks ahead of its first text block,
+	// as Anthropic recommends placing images before the text that refers to them
+	ImageFirst bool
+}
 
-func (c *AnthropicConverter) Convert(messages []model.Message, publicURLs map[string]service.PublicURL) (interface{}, error) {
+func (c *AnthropicConverter) Convert(ctx context.Context, messages []model.Message, publicURLs map[string]service.PublicURL) (interface{}, error) {
 	result := make([]anthropic.MessageParam, 0, len(messages))
+	var system []string
 
 	for _, msg := range messages {
-		anthropicMsg := c.convertMessage(msg, publicURLs)
+		if msg.Role == "system" {
+			for _, part := range msg.Parts {
+				if part.Type == "text" && part.Text != "" {
+					system = append(system, part.Text)
+				}
+			}
+			continue
+		}
+
+		anthropicMsg, err := c.convertMessage(ctx, msg, publicURLs)
+		if err != nil {
+			return nil, err
+		}
+
+		// A reasoning-only turn without a signature has nothing Anthropic accepts
+		if len(anthropicMsg.Content) == 0 && isReasoningOnly(msg) {
+			continue
+		}
+
+		if c.CoalesceToolResults && len(result) > 0 {
+			last := &result[len(result)-1]
+			if last.Role == anthropic.MessageParamRoleUser &&
+				anthropicMsg.Role == anthropic.MessageParamRoleUser &&
+				c.hasToolResult(last.Content) {
+				last.Content = append(last.Content, anthropicMsg.Content...)
+				continue
+			}
+		}
+
 		result = append(result, anthropicMsg)
 	}
 
-	return result, nil
+	return AnthropicMessages{System: strings.Join(system, systemSeparator), Messages: result}, nil
+}
+
+func (c *AnthropicConverter) hasToolResult(blocks []anthropic.ContentBlockParamUnion) bool {
+	for _, block := range blocks {
+		if block.OfToolResult != nil {
+			return true
+		}
+	}
+	return false
 }
 
-func (c *AnthropicConverter) convertMessage(msg model.Message, publicURLs map[string]service.PublicURL) anthropic.MessageParam {
+func (c *AnthropicConverter) convertMessage(ctx context.Context, msg model.Message, publicURLs map[string]service.PublicURL) (anthropic.MessageParam, error) {
 	role := c.convertRole(msg.Role)
 
 	// Convert parts to content blocks
-	contentBlocks := c.convertParts(msg.Parts, publicURLs)
+	contentBlocks, err := c.convertParts(ctx, msg.Parts, publicURLs)
+	if err != nil {
+		return anthropic.MessageParam{}, err
+	}
+	if c.ImageFirst {
+		contentBlocks = c.imagesFirst(contentBlocks)
+	}
 
 	if role == "user" {
-		return anthropic.NewUserMessage(contentBlocks...)
+		return anthropic.NewUserMessage(contentBlocks...), nil
 	} else {
-		return anthropic.NewAssistantMessage(contentBlocks...)
+		return anthropic.NewAssistantMessage(contentBlocks...), nil
 	}
 }
 
+// imagesFirst moves every image block to the position of the first text block,
+// keeping the relative order of images and of all other blocks
+func (c *AnthropicConverter) imagesFirst(blocks []anthropic.ContentBlockParamUnion) []anthropic.ContentBlockParamUnion {
+	firstText := -1
+	for i, block := range blocks {
+		if block.OfText != nil {
+			firstText = i
+			break
+		}
+	}
+	if firstText < 0 {
+		return blocks
+	}
+
+	result := make([]anthropic.ContentBlockParamUnion, 0, len(blocks))
+	result = append(result, blocks[:firstText]...)
+	for _, block := range blocks[firstText:] {
+		if block.OfImage != nil {
+			result = append(result, block)
+		}
+	}
+	for _, block := range blocks[firstText:] {
+		if block.OfImage == nil {
+			result = append(result, block)
+		}
+	}
+	return result
+}
+
 func (c *AnthropicConverter) convertRole(role string) string {
 	// Anthropic roles: "user", "assistant"
 	switch role {
@@ -53,7 +144,7 @@ func (c *AnthropicConverter) convertRole(role string) string {
 	}
 }
 
-func (c *AnthropicConverter) convertParts(parts []model.Part, publicURLs map[string]service.PublicURL) []anthropic.ContentBlockParamUnion {
+func (c *AnthropicConverter) convertParts(ctx context.Context, parts []model.Part, publicURLs map[string]service.PublicURL) ([]anthropic.ContentBlockParamUnion, error) {
 	contentBlocks := make([]anthropic.ContentBlockParamUnion, 0, len(parts))
 
 	for _, part := range parts {
@@ -74,8 +165,23 @@ func (c *AnthropicConverter) convertParts(parts []model.Part, publicURLs map[str
 				}
 			}
 
+		case PartTypeReasoning:
+			// Thinking blocks are only accepted back with their signature
+			if signature, _ := part.Meta[PartMetaSignature].(string); signature != "" && part.Text != "" {
+				contentBlocks = append(contentBlocks, anthropic.NewThinkingBlock(signature, part.Text))
+			}
+
+		case "refusal":
+			// Anthropic has no refusal field, keep it as plain text
+			if part.Text != "" {
+				contentBlocks = append(contentBlocks, anthropic.NewTextBlock(part.Text))
+			}
+
 		case "image":
-			imageBlock := c.convertImagePart(part, publicURLs)
+			imageBlock, err := c.convertImagePart(ctx, part, publicURLs)
+			if err != nil {
+				return nil, err
+			}
 			if imageBlock != nil {
 				contentBlocks = append(contentBlocks, *imageBlock)
 			}
@@ -106,10 +212,12 @@ func (c *AnthropicConverter) convertParts(parts []model.Part, publicURLs map[str
 		}
 	}
 
-	return contentBlocks
+	return contentBlocks, nil
 }
 
-func (c *AnthropicConverter) convertImagePart(part model.Part, publicURLs map[string]service.PublicURL) *anthropic.ContentBlockParamUnion {
+// convertImagePart returns an error only when the context ends the image download,
+// which would otherwise be indistinguishable from an unreachable image
+func (c *AnthropicConverter) convertImagePart(ctx context.Context, part model.Part, publicURLs map[string]service.PublicURL) (*anthropic.ContentBlockParamUnion, error) {
 	// Try to get image URL from asset
 	imageURL := c.getAssetURL(part.Asset, publicURLs)
 	if imageURL == "" && part.Meta != nil {
@@ -119,7 +227,7 @@ func (c *AnthropicConverter) convertImagePart(part model.Part, publicURLs map[st
 	}
 
 	if imageURL == "" {
-		return nil
+		return nil, nil
 	}
 
 	// Check if it's a base64 data URL or regular URL
@@ -127,7 +235,7 @@ func (c *AnthropicConverter) convertImagePart(part model.Part, publicURLs map[st
 		// Extract base64 data and media type
 		parts := strings.SplitN(imageURL, ",", 2)
 		if len(parts) != 2 {
-			return nil
+			return nil, nil
 		}
 
 		// Parse media type from data URL (e.g., "data:image/png;base64")
@@ -138,18 +246,21 @@ func (c *AnthropicConverter) convertImagePart(part model.Part, publicURLs map[st
 		}
 
 		block := anthropic.NewImageBlockBase64(mediaType, parts[1])
-		return &block
+		return &block, nil
 	}
 
 	// Try to download and convert to base64
-	if base64Data, mediaType := c.downloadImageAsBase64(imageURL); base64Data != "" {
+	if base64Data, mediaType := c.downloadImageAsBase64(ctx, imageURL); base64Data != "" {
 		block := anthropic.NewImageBlockBase64(mediaType, base64Data)
-		return &block
+		return &block, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	// Fall back to URL if available (note: Anthropic might not support URL directly for images in some contexts)
-	// In practice, we convert to base64
-	return nil
+	// Let Anthropic fetch the image itself when it could not be downloaded here
+	block := anthropic.NewImageBlock(anthropic.URLImageSourceParam{URL: imageURL})
+	return &block, nil
 }
 
 func (c *AnthropicConverter) convertToolCallPart(part model.Part) *anthropic.ContentBlockParamUnion {
@@ -165,16 +276,19 @@ func (c *AnthropicConverter) convertToolCallPart(part model.Part) *anthropic.Con
 		return nil
 	}
 
-	// Parse arguments (unified field name)
-	var input interface{}
+	// Parse arguments (unified field name). Arguments are passed through as raw
+	// JSON so large numbers are not re-encoded in scientific notation.
+	input := json.RawMessage(`{}`)
 	if argsStr, ok := part.Meta["arguments"].(string); ok {
-		// Arguments is JSON string, unmarshal it
-		if err := json.Unmarshal([]byte(argsStr), &input); err != nil {
-			input = map[string]interface{}{}
+		// Arguments is JSON string, keep it verbatim when valid
+		if json.Valid([]byte(argsStr)) {
+			input = json.RawMessage(argsStr)
 		}
-	} else {
+	} else if argsObj, ok := part.Meta["arguments"]; ok && argsObj != nil {
 		// Arguments is already an object
-		input = part.Meta["arguments"]
+		if encoded, err := encodeArguments(argsObj); err == nil {
+			input = json.RawMessage(encoded)
+		}
 	}
 
 	block := anthropic.NewToolUseBlock(id, input, name)
@@ -199,7 +313,7 @@ func (c *AnthropicConverter) convertToolResultPart(part model.Part) *anthropic.C
 		return nil
 	}
 
-	block := anthropic.NewToolResultBlock(toolUseID, part.Text, isError)
+	block := anthropic.NewToolResultBlock(toolUseID, toolResultContent(part), isError)
 	return &block
 }
 
@@ -238,8 +352,15 @@ func (c *AnthropicConverter) convertDocumentPart(part model.Part, publicURLs map
 	return nil
 }
 
-func (c *AnthropicConverter) downloadImageAsBase64(imageURL string) (string, string) {
-	resp, err := http.Get(imageURL)
+func (c *AnthropicConverter) downloadImageAsBase64(ctx context.Context, imageURL string) (string, string) {
+	if ctx.Err() != nil {
+		return "", ""
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+	if err != nil {
+		return "", ""
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return "", ""
 	}
@@ -267,12 +388,5 @@ func (c *AnthropicConverter) downloadImageAsBase64(imageURL string) (string, str
 }
 
 func (c *AnthropicConverter) getAssetURL(asset *model.Asset, publicURLs map[string]service.PublicURL) string {
-	if asset == nil {
-		return ""
-	}
-	assetKey := asset.S3Key
-	if publicURL, ok := publicURLs[assetKey]; ok {
-		return publicURL.URL
-	}
-	return ""
+	return assetPublicURL(asset, publicURLs)
 }
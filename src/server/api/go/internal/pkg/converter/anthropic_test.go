@@ -1,8 +1,13 @@
 package converter
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/memodb-io/Acontext/internal/modules/model"
 	"github.com/memodb-io/Acontext/internal/modules/service"
 	"github.com/stretchr/testify/assert"
@@ -18,14 +23,32 @@ func TestAnthropicConverter_Convert_TextMessage(t *testing.T) {
 		}, nil),
 	}
 
-	result, err := converter.Convert(messages, nil)
+	result, err := converter.Convert(context.Background(), messages, nil)
 	require.NoError(t, err)
 
-	// Anthropic converter returns []anthropic.MessageParam
+	// Anthropic converter returns AnthropicMessages
 	// For testing, we just verify it doesn't error
 	assert.NotNil(t, result)
 }
 
+func TestAnthropicConverter_Convert_System(t *testing.T) {
+	converter := &AnthropicConverter{}
+
+	messages := []model.Message{
+		createTestMessage("system", []model.Part{{Type: "text", Text: "You are helpful."}}, nil),
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Hi"}}, nil),
+		createTestMessage("system", []model.Part{{Type: "text", Text: "Be concise."}}, nil),
+	}
+
+	result, err := converter.Convert(context.Background(), messages, nil)
+	require.NoError(t, err)
+
+	out := result.(AnthropicMessages)
+	assert.Equal(t, "You are helpful.\n\nBe concise.", out.System)
+	require.Len(t, out.Messages, 1)
+	assert.Equal(t, anthropic.MessageParamRoleUser, out.Messages[0].Role)
+}
+
 func TestAnthropicConverter_Convert_WithCacheControl(t *testing.T) {
 	converter := &AnthropicConverter{}
 
@@ -43,7 +66,7 @@ func TestAnthropicConverter_Convert_WithCacheControl(t *testing.T) {
 		}, nil),
 	}
 
-	result, err := converter.Convert(messages, nil)
+	result, err := converter.Convert(context.Background(), messages, nil)
 	require.NoError(t, err)
 	assert.NotNil(t, result)
 }
@@ -66,7 +89,7 @@ func TestAnthropicConverter_Convert_ToolCall(t *testing.T) {
 		}, nil),
 	}
 
-	result, err := converter.Convert(messages, nil)
+	result, err := converter.Convert(context.Background(), messages, nil)
 	require.NoError(t, err)
 	assert.NotNil(t, result)
 }
@@ -87,11 +110,30 @@ func TestAnthropicConverter_Convert_ToolResult(t *testing.T) {
 		}, nil),
 	}
 
-	result, err := converter.Convert(messages, nil)
+	result, err := converter.Convert(context.Background(), messages, nil)
 	require.NoError(t, err)
 	assert.NotNil(t, result)
 }
 
+func TestAnthropicConverter_Convert_ToolResultMetaContent(t *testing.T) {
+	converter := &AnthropicConverter{}
+
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{
+			{Type: "tool-result", Meta: map[string]any{"tool_call_id": "toolu_1", "content": "72°F"}},
+			{Type: "tool-result", Meta: map[string]any{"tool_call_id": "toolu_2", "content": map[string]any{"temp": 72}}},
+		}, nil),
+	}
+
+	result, err := converter.Convert(context.Background(), messages, nil)
+	require.NoError(t, err)
+
+	blocks := result.(AnthropicMessages).Messages[0].Content
+	require.Len(t, blocks, 2)
+	assert.Equal(t, "72°F", blocks[0].OfToolResult.Content[0].OfText.Text)
+	assert.Equal(t, `{"temp":72}`, blocks[1].OfToolResult.Content[0].OfText.Text)
+}
+
 func TestAnthropicConverter_Convert_Image(t *testing.T) {
 	converter := &AnthropicConverter{}
 
@@ -113,7 +155,192 @@ func TestAnthropicConverter_Convert_Image(t *testing.T) {
 		"assets/image.jpg": {URL: "https://example.com/image.jpg"},
 	}
 
-	result, err := converter.Convert(messages, publicURLs)
+	result, err := converter.Convert(context.Background(), messages, publicURLs)
 	require.NoError(t, err)
 	assert.NotNil(t, result)
 }
+
+func TestAnthropicConverter_Convert_CoalesceToolResults(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("assistant", []model.Part{
+			{
+				Type: "tool-call",
+				Meta: map[string]any{
+					"id":        "toolu_123",
+					"name":      "get_weather",
+					"arguments": "{\"city\":\"Boston\"}",
+				},
+			},
+		}, nil),
+		createTestMessage("user", []model.Part{
+			{
+				Type: "tool-result",
+				Text: "Weather: 72°F",
+				Meta: map[string]any{
+					"tool_call_id": "toolu_123",
+				},
+			},
+		}, nil),
+		createTestMessage("user", []model.Part{
+			{Type: "text", Text: "Should I bring a jacket?"},
+		}, nil),
+	}
+
+	t.Run("merges tool result and following user turn", func(t *testing.T) {
+		converter := &AnthropicConverter{CoalesceToolResults: true}
+
+		result, err := converter.Convert(context.Background(), messages, nil)
+		require.NoError(t, err)
+
+		msgs := result.(AnthropicMessages).Messages
+		require.Len(t, msgs, 2)
+		assert.Equal(t, anthropic.MessageParamRoleAssistant, msgs[0].Role)
+		assert.Equal(t, anthropic.MessageParamRoleUser, msgs[1].Role)
+		require.Len(t, msgs[1].Content, 2)
+		require.NotNil(t, msgs[1].Content[0].OfToolResult)
+		assert.Equal(t, "toolu_123", msgs[1].Content[0].OfToolResult.ToolUseID)
+		require.NotNil(t, msgs[1].Content[1].OfText)
+		assert.Equal(t, "Should I bring a jacket?", msgs[1].Content[1].OfText.Text)
+	})
+
+	t.Run("keeps turns separate when disabled", func(t *testing.T) {
+		converter := &AnthropicConverter{}
+
+		result, err := converter.Convert(context.Background(), messages, nil)
+		require.NoError(t, err)
+
+		msgs := result.(AnthropicMessages).Messages
+		assert.Len(t, msgs, 3)
+	})
+}
+
+func TestAnthropicConverter_Convert_Refusal(t *testing.T) {
+	converter := &AnthropicConverter{}
+
+	messages := []model.Message{
+		createTestMessage("assistant", []model.Part{
+			{Type: "refusal", Text: "I cannot help with that request."},
+		}, nil),
+	}
+
+	result, err := converter.Convert(context.Background(), messages, nil)
+	require.NoError(t, err)
+
+	msgs := result.(AnthropicMessages).Messages
+	require.Len(t, msgs, 1)
+	require.Len(t, msgs[0].Content, 1)
+	require.NotNil(t, msgs[0].Content[0].OfText)
+	assert.Equal(t, "I cannot help with that request.", msgs[0].Content[0].OfText.Text)
+}
+
+func TestAnthropicConverter_Convert_ImageFirst(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{
+			{Type: "text", Text: "What is in this image?"},
+			{Type: "image", Meta: map[string]any{"url": "data:image/png;base64,iVBORw0KGgo="}},
+		}, nil),
+	}
+
+	t.Run("reordered", func(t *testing.T) {
+		converter := &AnthropicConverter{ImageFirst: true}
+		result, err := converter.Convert(context.Background(), messages, nil)
+		require.NoError(t, err)
+
+		msgs := result.(AnthropicMessages).Messages
+		require.Len(t, msgs, 1)
+		require.Len(t, msgs[0].Content, 2)
+		assert.NotNil(t, msgs[0].Content[0].OfImage)
+		require.NotNil(t, msgs[0].Content[1].OfText)
+		assert.Equal(t, "What is in this image?", msgs[0].Content[1].OfText.Text)
+	})
+
+	t.Run("original order when disabled", func(t *testing.T) {
+		converter := &AnthropicConverter{}
+		result, err := converter.Convert(context.Background(), messages, nil)
+		require.NoError(t, err)
+
+		msgs := result.(AnthropicMessages).Messages
+		require.Len(t, msgs[0].Content, 2)
+		assert.NotNil(t, msgs[0].Content[0].OfText)
+		assert.NotNil(t, msgs[0].Content[1].OfImage)
+	})
+}
+
+func TestConvertMessages_AnthropicImageFetchHonorsDeadline(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{
+			{Type: "text", Text: "What is in this image?"},
+			{Type: "image", Meta: map[string]any{"url": server.URL + "/slow.png"}},
+		}, nil),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := ConvertMessages(ctx, ConvertMessagesInput{
+		Messages: messages,
+		Format:   model.FormatAnthropic,
+	})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestAnthropicConverter_Convert_CancelledWithoutDownloads(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Hello"}}, nil),
+		createTestMessage("assistant", []model.Part{{Type: "text", Text: "Hi"}}, nil),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Only an image download consults the context
+	result, err := (&AnthropicConverter{}).Convert(ctx, messages, nil)
+	require.NoError(t, err)
+	assert.Len(t, result.(AnthropicMessages).Messages, 2)
+}
+
+func TestAnthropicConverter_Convert_ImageURLFallback(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{
+			{
+				Type: "image",
+				Asset: &model.Asset{
+					S3Key:  "assets/image.png",
+					SHA256: "abc123",
+					MIME:   "image/png",
+				},
+			},
+		}, nil),
+	}
+
+	// The session service keys public URLs by the asset's SHA256
+	publicURLs := map[string]service.PublicURL{
+		"abc123": {URL: server.URL + "/image.png"},
+	}
+
+	result, err := (&AnthropicConverter{}).Convert(context.Background(), messages, publicURLs)
+	require.NoError(t, err)
+
+	msgs := result.(AnthropicMessages).Messages
+	require.Len(t, msgs, 1)
+	require.Len(t, msgs[0].Content, 1)
+	image := msgs[0].Content[0].OfImage
+	require.NotNil(t, image)
+	require.NotNil(t, image.Source.OfURL)
+	assert.Equal(t, server.URL+"/image.png", image.Source.OfURL.URL)
+}
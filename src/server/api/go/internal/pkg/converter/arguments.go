@@ -0,0 +1,59 @@
+package converter
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+)
+
+// encodeArguments serializes tool-call arguments that are stored as an object.
+// Whole float64 values (how jsonb numbers come back from the database) are
+// written in plain decimal form instead of scientific notation.
+func encodeArguments(v interface{}) (string, error) {
+	b, err := json.Marshal(preciseNumbers(v))
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// decodeArguments returns tool-call arguments as an object, decoding them when
+// stored as a JSON string
+func decodeArguments(arguments any) map[string]any {
+	switch args := arguments.(type) {
+	case map[string]any:
+		return args
+	case string:
+		var decoded map[string]any
+		if err := json.Unmarshal([]byte(args), &decoded); err == nil {
+			return decoded
+		}
+	}
+	return nil
+}
+
+// preciseNumbers walks decoded JSON and replaces whole float64 values with an
+// equivalent json.Number in plain decimal notation
+func preciseNumbers(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[k] = preciseNumbers(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = preciseNumbers(item)
+		}
+		return out
+	case float64:
+		if val == math.Trunc(val) && !math.IsInf(val, 0) {
+			return json.Number(strconv.FormatFloat(val, 'f', -1, 64))
+		}
+		return val
+	default:
+		return v
+	}
+}
@@ -0,0 +1,76 @@
+package converter
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	openai "github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAIConverter_Convert_LargeIntegerArguments(t *testing.T) {
+	converter := &OpenAIConverter{}
+
+	// Arguments stored as an object come back from jsonb as float64
+	messages := []model.Message{
+		createTestMessage("assistant", []model.Part{
+			{
+				Type: "tool-call",
+				Meta: map[string]any{
+					"id":   "call_123",
+					"name": "bulk_insert",
+					"arguments": map[string]any{
+						"count": float64(10000000000),
+						"total": float64(1e22),
+						"ratio": 0.5,
+					},
+				},
+			},
+		}, nil),
+	}
+
+	result, err := converter.Convert(context.Background(), messages, nil)
+	require.NoError(t, err)
+
+	msgs := result.([]openai.ChatCompletionMessageParamUnion)
+	require.Len(t, msgs, 1)
+	require.Len(t, msgs[0].OfAssistant.ToolCalls, 1)
+
+	arguments := msgs[0].OfAssistant.ToolCalls[0].OfFunction.Function.Arguments
+	assert.Contains(t, arguments, `"count":10000000000`)
+	assert.Contains(t, arguments, `"total":10000000000000000000000`)
+	assert.Contains(t, arguments, `"ratio":0.5`)
+	assert.NotContains(t, arguments, "e+")
+}
+
+func TestAnthropicConverter_Convert_LargeIntegerArguments(t *testing.T) {
+	converter := &AnthropicConverter{}
+
+	messages := []model.Message{
+		createTestMessage("assistant", []model.Part{
+			{
+				Type: "tool-call",
+				Meta: map[string]any{
+					"id":        "toolu_123",
+					"name":      "lookup",
+					"arguments": `{"id":12345678901234567890}`,
+				},
+			},
+		}, nil),
+	}
+
+	result, err := converter.Convert(context.Background(), messages, nil)
+	require.NoError(t, err)
+
+	msgs := result.(AnthropicMessages).Messages
+	require.Len(t, msgs, 1)
+	require.NotNil(t, msgs[0].Content[0].OfToolUse)
+
+	encoded, err := json.Marshal(msgs[0].Content[0].OfToolUse.Input)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":12345678901234567890}`, string(encoded))
+	assert.NotContains(t, string(encoded), "e+")
+}
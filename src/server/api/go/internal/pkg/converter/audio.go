@@ -0,0 +1,9 @@
+package converter
+
+// PartTypeOutputAudio is audio produced by the model in an assistant turn. Its
+// Meta[PartMetaAudioID] holds the provider's id for the audio and Text, if
+// set, its transcript.
+const PartTypeOutputAudio = "output-audio"
+
+// PartMetaAudioID is the part meta key holding the id of a model's audio output
+const PartMetaAudioID = "id"
@@ -0,0 +1,38 @@
+package converter
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+)
+
+func TestConvertMessages_OutputAudioReference(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Say hello"}}, nil),
+		createTestMessage("assistant", []model.Part{{
+			Type: PartTypeOutputAudio,
+			Text: "Hello!",
+			Meta: map[string]any{PartMetaAudioID: "audio_abc123"},
+		}}, nil),
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Again"}}, nil),
+	}
+
+	result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+		Messages: messages,
+		Format:   model.FormatOpenAI,
+	})
+	require.NoError(t, err)
+
+	out, err := json.Marshal(result)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"role": "user", "content": "Say hello"},
+		{"role": "assistant", "audio": {"id": "audio_abc123"}},
+		{"role": "user", "content": "Again"}
+	]`, string(out))
+}
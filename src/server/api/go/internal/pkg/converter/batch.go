@@ -0,0 +1,37 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+)
+
+// batchCustomIDPattern is what the Message Batches API accepts as a custom_id
+var batchCustomIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// BuildAnthropicBatchItem builds one request of an Anthropic Message Batch:
+// {"custom_id": ..., "params": {...}} where params is what BuildRequest
+// produces for the Anthropic format. customID identifies the result and must
+// be unique within the batch. input.Format must be empty or anthropic.
+func BuildAnthropicBatchItem(ctx context.Context, customID string, input ConvertMessagesInput) (map[string]any, error) {
+	if !batchCustomIDPattern.MatchString(customID) {
+		return nil, fmt.Errorf("invalid batch custom_id %q: must be 1-64 letters, digits, '_' or '-'", customID)
+	}
+	if input.Format == "" {
+		input.Format = model.FormatAnthropic
+	}
+	if input.Format != model.FormatAnthropic {
+		return nil, fmt.Errorf("anthropic batch items cannot be built for format: %s", input.Format)
+	}
+
+	params, err := BuildRequest(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"custom_id": customID,
+		"params":    params,
+	}, nil
+}
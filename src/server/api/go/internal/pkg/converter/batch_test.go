@@ -0,0 +1,57 @@
+package converter
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+)
+
+func TestBuildAnthropicBatchItem(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Summarize this"}}, nil),
+		createTestMessage("assistant", []model.Part{{Type: "text", Text: "Sure"}}, nil),
+	}
+
+	item, err := BuildAnthropicBatchItem(context.Background(), "req-001", ConvertMessagesInput{
+		Messages:   messages,
+		MaxTokens:  1024,
+		ToolChoice: &ToolChoice{Mode: ToolChoiceNone},
+	})
+	require.NoError(t, err)
+
+	out, err := json.Marshal(item)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"custom_id": "req-001",
+		"params": {
+			"messages": [
+				{"role": "user", "content": [{"type": "text", "text": "Summarize this"}]},
+				{"role": "assistant", "content": [{"type": "text", "text": "Sure"}]}
+			],
+			"tool_choice": {"type": "none"},
+			"max_tokens": 1024
+		}
+	}`, string(out))
+}
+
+func TestBuildAnthropicBatchItem_Invalid(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Hi"}}, nil),
+	}
+
+	for _, customID := range []string{"", "has space", string(make([]byte, 65))} {
+		_, err := BuildAnthropicBatchItem(context.Background(), customID, ConvertMessagesInput{Messages: messages, MaxTokens: 1024})
+		assert.Error(t, err, "custom_id %q", customID)
+	}
+
+	_, err := BuildAnthropicBatchItem(context.Background(), "req-1", ConvertMessagesInput{
+		Messages: messages,
+		Format:   model.FormatOpenAI,
+	})
+	assert.Error(t, err)
+}
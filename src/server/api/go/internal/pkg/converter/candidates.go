@@ -0,0 +1,66 @@
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+)
+
+// MessageMetaCandidates is the message meta key holding alternative assistant
+// replies. Each candidate is either a string (a single text part) or a list of
+// parts in the stored part shape.
+const MessageMetaCandidates = "candidates"
+
+// selectCandidates replaces the parts of every assistant message that carries
+// candidates with the parts of candidate index. Input messages are not modified.
+func selectCandidates(messages []model.Message, index int) ([]model.Message, error) {
+	var result []model.Message
+	for i, msg := range messages {
+		if msg.Role != "assistant" {
+			continue
+		}
+		raw, ok := msg.Meta.Data()[MessageMetaCandidates]
+		if !ok {
+			continue
+		}
+		candidates, ok := raw.([]any)
+		if !ok {
+			return nil, fmt.Errorf("message %d: candidates must be a list", i)
+		}
+		if index < 0 || index >= len(candidates) {
+			return nil, fmt.Errorf("message %d: candidate %d out of range, %d available", i, index, len(candidates))
+		}
+
+		parts, err := candidateParts(candidates[index])
+		if err != nil {
+			return nil, fmt.Errorf("message %d: candidate %d: %w", i, index, err)
+		}
+
+		if result == nil {
+			result = append([]model.Message(nil), messages...)
+		}
+		result[i].Parts = parts
+	}
+
+	if result == nil {
+		return messages, nil
+	}
+	return result, nil
+}
+
+func candidateParts(candidate any) ([]model.Part, error) {
+	if text, ok := candidate.(string); ok {
+		return []model.Part{{Type: "text", Text: text}}, nil
+	}
+
+	encoded, err := json.Marshal(candidate)
+	if err != nil {
+		return nil, err
+	}
+	var parts []model.Part
+	if err := json.Unmarshal(encoded, &parts); err != nil {
+		return nil, fmt.Errorf("invalid parts: %w", err)
+	}
+	return parts, nil
+}
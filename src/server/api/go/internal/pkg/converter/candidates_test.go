@@ -0,0 +1,47 @@
+package converter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	openai "github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertMessages_SelectCandidate(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Name a color"}}, nil),
+		createTestMessage("assistant", []model.Part{{Type: "text", Text: "Red"}}, map[string]any{
+			MessageMetaCandidates: []any{
+				"Red",
+				[]any{map[string]any{"type": "text", "text": "Blue"}},
+			},
+		}),
+	}
+
+	t.Run("selected candidate replaces the reply", func(t *testing.T) {
+		result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages:        messages,
+			Format:          model.FormatOpenAI,
+			SelectCandidate: 1,
+		})
+		require.NoError(t, err)
+
+		msgs := result.([]openai.ChatCompletionMessageParamUnion)
+		require.Len(t, msgs, 2)
+		assert.Equal(t, "Blue", msgs[1].OfAssistant.Content.OfString.Value)
+		assert.Equal(t, "Red", messages[1].Parts[0].Text)
+	})
+
+	t.Run("out of range errors", func(t *testing.T) {
+		_, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages:        messages,
+			Format:          model.FormatOpenAI,
+			SelectCandidate: 2,
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "candidate 2 out of range")
+	})
+}
@@ -0,0 +1,145 @@
+package converter
+
+import (
+	"context"
+	"strings"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+)
+
+// Cohere chat roles
+const (
+	CohereRoleUser    = "USER"
+	CohereRoleChatbot = "CHATBOT"
+	CohereRoleSystem  = "SYSTEM"
+	CohereRoleTool    = "TOOL"
+)
+
+// CohereMessages is the output of FormatCohere. Cohere takes the final user
+// turn as message (with any tool results it carries as tool_results) and the
+// turns before it as chat_history.
+type CohereMessages struct {
+	Message     string              `json:"message"`
+	ChatHistory []CohereChatMessage `json:"chat_history,omitempty"`
+	ToolResults []CohereToolResult  `json:"tool_results,omitempty"`
+}
+
+// CohereChatMessage is one turn of chat_history. TOOL turns carry only
+// ToolResults; CHATBOT turns may carry ToolCalls.
+type CohereChatMessage struct {
+	Role        string             `json:"role"`
+	Message     string             `json:"message,omitempty"`
+	ToolCalls   []CohereToolCall   `json:"tool_calls,omitempty"`
+	ToolResults []CohereToolResult `json:"tool_results,omitempty"`
+}
+
+type CohereToolCall struct {
+	Name       string         `json:"name"`
+	Parameters map[string]any `json:"parameters"`
+}
+
+// CohereToolResult pairs a tool call with the outputs it produced
+type CohereToolResult struct {
+	Call    CohereToolCall   `json:"call"`
+	Outputs []map[string]any `json:"outputs"`
+}
+
+// CohereConverter converts messages to Cohere's chat shape. System messages
+// stay in the history as SYSTEM turns. Cohere matches tool results to the
+// call itself rather than an id, so each result repeats the call its
+// tool_call_id refers to. Cohere chat takes no images; they are left out.
+type CohereConverter struct{}
+
+func (c *CohereConverter) Convert(ctx context.Context, messages []model.Message, publicURLs map[string]service.PublicURL) (interface{}, error) {
+	out := CohereMessages{}
+	calls := make(map[string]CohereToolCall)
+
+	// The final user turn is sent apart from the history; a conversation ending
+	// on another role leaves message empty
+	last := len(messages)
+	if len(messages) > 0 && messages[len(messages)-1].Role == "user" {
+		last = len(messages) - 1
+	}
+
+	for i, msg := range messages {
+		text, toolCalls, toolResults := c.convertParts(msg, calls)
+		if i == last {
+			out.Message = text
+			out.ToolResults = toolResults
+			break
+		}
+
+		switch msg.Role {
+		case "assistant":
+			if text != "" || len(toolCalls) > 0 {
+				out.ChatHistory = append(out.ChatHistory, CohereChatMessage{Role: CohereRoleChatbot, Message: text, ToolCalls: toolCalls})
+			}
+		case "system":
+			if text != "" {
+				out.ChatHistory = append(out.ChatHistory, CohereChatMessage{Role: CohereRoleSystem, Message: text})
+			}
+		default:
+			// Tool results answer the preceding CHATBOT turn, so they come first
+			if len(toolResults) > 0 {
+				out.ChatHistory = append(out.ChatHistory, CohereChatMessage{Role: CohereRoleTool, ToolResults: toolResults})
+			}
+			if text != "" {
+				out.ChatHistory = append(out.ChatHistory, CohereChatMessage{Role: CohereRoleUser, Message: text})
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// convertParts returns the message text, joined by newlines, and its tool calls
+// and results. Tool calls are recorded in calls by id for later results.
+func (c *CohereConverter) convertParts(msg model.Message, calls map[string]CohereToolCall) (string, []CohereToolCall, []CohereToolResult) {
+	var texts []string
+	var toolCalls []CohereToolCall
+	var toolResults []CohereToolResult
+
+	for _, part := range msg.Parts {
+		switch part.Type {
+		case "text":
+			if part.Text != "" {
+				texts = append(texts, part.Text)
+			}
+		case "tool-call":
+			id, _ := part.Meta["id"].(string)
+			name, _ := part.Meta["name"].(string)
+			if name == "" {
+				continue
+			}
+			call := CohereToolCall{Name: name, Parameters: decodeArguments(part.Meta["arguments"])}
+			if call.Parameters == nil {
+				call.Parameters = map[string]any{}
+			}
+			if id != "" {
+				calls[id] = call
+			}
+			toolCalls = append(toolCalls, call)
+		case "tool-result":
+			id, _ := part.Meta["tool_call_id"].(string)
+			call, ok := calls[id]
+			if !ok {
+				continue
+			}
+			toolResults = append(toolResults, CohereToolResult{Call: call, Outputs: c.toolOutputs(part)})
+		}
+	}
+
+	return strings.Join(texts, "\n"), toolCalls, toolResults
+}
+
+// toolOutputs returns a tool result as Cohere outputs: an object stored in
+// Meta["content"] is sent as-is, anything else as {"result": text}
+func (c *CohereConverter) toolOutputs(part model.Part) []map[string]any {
+	if part.Text == "" {
+		if content, ok := part.Meta["content"].(map[string]any); ok {
+			return []map[string]any{content}
+		}
+	}
+	return []map[string]any{{"result": toolResultContent(part)}}
+}
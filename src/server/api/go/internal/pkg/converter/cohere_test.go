@@ -0,0 +1,108 @@
+package converter
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertMessages_Cohere(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("system", []model.Part{{Type: "text", Text: "You are a travel agent."}}, nil),
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Weather in Paris?"}}, nil),
+		createTestMessage("assistant", []model.Part{
+			{Type: "text", Text: "Let me check."},
+			{Type: "tool-call", Meta: map[string]any{"id": "call_1", "name": "get_weather", "arguments": `{"city":"Paris"}`}},
+		}, nil),
+		createTestMessage("user", []model.Part{
+			{Type: "tool-result", Meta: map[string]any{"tool_call_id": "call_1", "content": map[string]any{"temp": 21}}},
+		}, nil),
+		createTestMessage("assistant", []model.Part{{Type: "text", Text: "It is 21°C."}}, nil),
+		createTestMessage("user", []model.Part{{Type: "text", Text: "And tomorrow?"}}, nil),
+	}
+
+	result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+		Messages: messages,
+		Format:   model.FormatCohere,
+	})
+	require.NoError(t, err)
+
+	out, err := json.Marshal(result)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"message": "And tomorrow?",
+		"chat_history": [
+			{"role": "SYSTEM", "message": "You are a travel agent."},
+			{"role": "USER", "message": "Weather in Paris?"},
+			{"role": "CHATBOT", "message": "Let me check.", "tool_calls": [{"name": "get_weather", "parameters": {"city": "Paris"}}]},
+			{"role": "TOOL", "tool_results": [{"call": {"name": "get_weather", "parameters": {"city": "Paris"}}, "outputs": [{"temp": 21}]}]},
+			{"role": "CHATBOT", "message": "It is 21°C."}
+		]
+	}`, string(out))
+}
+
+func TestCohereConverter_FinalToolResults(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Weather in Rome?"}}, nil),
+		createTestMessage("assistant", []model.Part{
+			{Type: "tool-call", Meta: map[string]any{"id": "call_1", "name": "get_weather", "arguments": map[string]any{"city": "Rome"}}},
+		}, nil),
+		createTestMessage("user", []model.Part{
+			{Type: "tool-result", Text: "Sunny", Meta: map[string]any{"tool_call_id": "call_1"}},
+		}, nil),
+	}
+
+	result, err := (&CohereConverter{}).Convert(context.Background(), messages, nil)
+	require.NoError(t, err)
+
+	cohere := result.(CohereMessages)
+	assert.Empty(t, cohere.Message)
+	require.Len(t, cohere.ToolResults, 1)
+	assert.Equal(t, "get_weather", cohere.ToolResults[0].Call.Name)
+	assert.Equal(t, []map[string]any{{"result": "Sunny"}}, cohere.ToolResults[0].Outputs)
+	require.Len(t, cohere.ChatHistory, 2)
+	assert.Equal(t, CohereRoleUser, cohere.ChatHistory[0].Role)
+	assert.Equal(t, CohereRoleChatbot, cohere.ChatHistory[1].Role)
+}
+
+func TestCohereConverter_EndsWithAssistant(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Hi"}}, nil),
+		createTestMessage("assistant", []model.Part{{Type: "text", Text: "Hello"}}, nil),
+	}
+
+	result, err := (&CohereConverter{}).Convert(context.Background(), messages, nil)
+	require.NoError(t, err)
+
+	cohere := result.(CohereMessages)
+	assert.Empty(t, cohere.Message)
+	assert.Equal(t, []CohereChatMessage{
+		{Role: CohereRoleUser, Message: "Hi"},
+		{Role: CohereRoleChatbot, Message: "Hello"},
+	}, cohere.ChatHistory)
+}
+
+func TestBuildRequest_Cohere(t *testing.T) {
+	request, err := BuildRequest(context.Background(), ConvertMessagesInput{
+		Messages: []model.Message{
+			createTestMessage("user", []model.Part{{Type: "text", Text: "Hi"}}, nil),
+			createTestMessage("assistant", []model.Part{{Type: "text", Text: "Hello"}}, nil),
+			createTestMessage("user", []model.Part{{Type: "text", Text: "Tell me a joke"}}, nil),
+		},
+		Format:    model.FormatCohere,
+		MaxTokens: 256,
+		Stop:      []string{"END"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Tell me a joke", request["message"])
+	assert.Len(t, request["chat_history"], 2)
+	assert.NotContains(t, request, "messages")
+	assert.NotContains(t, request, "tool_results")
+	assert.Equal(t, 256, request["max_tokens"])
+	assert.Equal(t, []string{"END"}, request["stop_sequences"])
+}
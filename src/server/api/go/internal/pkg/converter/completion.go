@@ -0,0 +1,117 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+)
+
+// CompletionTemplate describes how messages are rendered into a single prompt
+type CompletionTemplate struct {
+	SystemPrefix     string
+	UserPrefix       string
+	AssistantPrefix  string
+	Separator        string // between turns
+	ImagePlaceholder string // replaces image parts
+}
+
+// DefaultCompletionTemplate is used when no template is provided
+var DefaultCompletionTemplate = CompletionTemplate{
+	SystemPrefix:     "### System:",
+	UserPrefix:       "### Human:",
+	AssistantPrefix:  "### Assistant:",
+	Separator:        "\n\n",
+	ImagePlaceholder: "[image]",
+}
+
+// CompletionConverter renders messages into one prompt string ending with an
+// assistant cue, for legacy text-completion endpoints
+type CompletionConverter struct {
+	Template *CompletionTemplate
+}
+
+func (c *CompletionConverter) Convert(ctx context.Context, messages []model.Message, publicURLs map[string]service.PublicURL) (interface{}, error) {
+	tpl := DefaultCompletionTemplate
+	if c.Template != nil {
+		tpl = *c.Template
+	}
+
+	turns := make([]string, 0, len(messages)+1)
+	for _, msg := range messages {
+		if isReasoningOnly(msg) {
+			continue
+		}
+		content := c.renderParts(msg.Parts, tpl)
+		turns = append(turns, strings.TrimRight(c.prefix(msg.Role, tpl)+" "+content, " "))
+	}
+
+	// Trailing cue so the model continues as the assistant
+	turns = append(turns, tpl.AssistantPrefix)
+
+	return strings.Join(turns, tpl.Separator), nil
+}
+
+func (c *CompletionConverter) prefix(role string, tpl CompletionTemplate) string {
+	switch role {
+	case "assistant":
+		return tpl.AssistantPrefix
+	case "system":
+		return tpl.SystemPrefix
+	default:
+		return tpl.UserPrefix
+	}
+}
+
+func (c *CompletionConverter) renderParts(parts []model.Part, tpl CompletionTemplate) string {
+	rendered := make([]string, 0, len(parts))
+	for _, part := range parts {
+		switch part.Type {
+		case "text", "tool-result", "refusal":
+			if part.Text != "" {
+				rendered = append(rendered, part.Text)
+			}
+		case "image":
+			rendered = append(rendered, tpl.ImagePlaceholder)
+		case "tool-call":
+			if part.Meta != nil {
+				name, _ := part.Meta["name"].(string)
+				arguments, _ := part.Meta["arguments"].(string)
+				rendered = append(rendered, fmt.Sprintf("[tool call: %s(%s)]", name, arguments))
+			}
+		}
+	}
+	return strings.Join(rendered, "\n")
+}
+
+// appendStopToken appends token to the last text part of the final message,
+// adding a text part when it has none. A message already ending with token is
+// left as is. Input messages are not modified.
+func appendStopToken(messages []model.Message, token string) []model.Message {
+	if len(messages) == 0 {
+		return messages
+	}
+
+	last := len(messages) - 1
+	parts := append([]model.Part(nil), messages[last].Parts...)
+
+	textIdx := -1
+	for i, part := range parts {
+		if part.Type == "text" {
+			textIdx = i
+		}
+	}
+	if textIdx < 0 {
+		parts = append(parts, model.Part{Type: "text", Text: token})
+	} else if strings.HasSuffix(parts[textIdx].Text, token) {
+		return messages
+	} else {
+		parts[textIdx].Text += token
+	}
+
+	result := append([]model.Message(nil), messages...)
+	result[last].Parts = parts
+	return result
+}
@@ -0,0 +1,80 @@
+package converter
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompletionConverter_Convert(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{
+			{Type: "text", Text: "What is in this picture?"},
+			{Type: "image", Asset: &model.Asset{S3Key: "assets/cat.png"}},
+		}, nil),
+		createTestMessage("assistant", []model.Part{{Type: "text", Text: "A cat."}}, nil),
+	}
+
+	t.Run("default template", func(t *testing.T) {
+		result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages: messages,
+			Format:   model.FormatCompletion,
+		})
+		require.NoError(t, err)
+
+		expected := "### Human: What is in this picture?\n[image]\n\n" +
+			"### Assistant: A cat.\n\n" +
+			"### Assistant:"
+		assert.Equal(t, expected, result)
+	})
+
+	t.Run("custom template", func(t *testing.T) {
+		result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages: messages,
+			Format:   model.FormatCompletion,
+			CompletionTemplate: &CompletionTemplate{
+				UserPrefix:       "User:",
+				AssistantPrefix:  "Bot:",
+				Separator:        "\n",
+				ImagePlaceholder: "<image>",
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "User: What is in this picture?\n<image>\nBot: A cat.\nBot:", result)
+	})
+}
+
+func TestConvertMessages_AppendStopToken(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Hi"}}, nil),
+		createTestMessage("assistant", []model.Part{{Type: "text", Text: "Hello"}}, nil),
+	}
+
+	input := ConvertMessagesInput{
+		Messages:           messages,
+		Format:             model.FormatCompletion,
+		CompletionTemplate: &CompletionTemplate{UserPrefix: "U:", AssistantPrefix: "A:", Separator: "\n"},
+		AppendStopToken:    "</s>",
+	}
+
+	result, err := ConvertMessages(context.Background(), input)
+	require.NoError(t, err)
+	assert.Equal(t, "U: Hi\nA: Hello</s>\nA:", result)
+	assert.Equal(t, "Hello", messages[1].Parts[0].Text)
+
+	// A history that already carries the token is not given a second one
+	input.Messages = []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Hi</s>"}}, nil),
+	}
+	result, err = ConvertMessages(context.Background(), input)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(result.(string), "</s>"))
+
+	input.Format = model.FormatOpenAI
+	_, err = ConvertMessages(context.Background(), input)
+	assert.Error(t, err)
+}
@@ -1,10 +1,25 @@
 package converter
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
+	"time"
 
 	"github.com/memodb-io/Acontext/internal/modules/model"
 	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/memodb-io/Acontext/internal/pkg/metrics"
+)
+
+var (
+	// ErrOutputTooLarge is returned when the serialized conversion result exceeds MaxOutputBytes
+	ErrOutputTooLarge = errors.New("converted output too large")
+	// ErrNoMessages is returned for an empty input when ErrorOnEmpty is set
+	ErrNoMessages = errors.New("no messages to convert")
+	// ErrDuplicateMessageID is returned when RejectDuplicateIDs is set and two messages share an ID
+	ErrDuplicateMessageID = errors.New("duplicate message id")
 )
 
 // ConvertMessagesInput represents the input for converting messages
@@ -12,16 +27,181 @@ type ConvertMessagesInput struct {
 	Messages   []model.Message
 	Format     model.MessageFormat
 	PublicURLs map[string]service.PublicURL
+
+	// RoleMap renames message roles before conversion, on top of DefaultRoleMap
+	// (e.g. {"persona": "system"}). Targets must be user, assistant or system;
+	// a role that maps to none of them fails the conversion with ErrUnknownRole.
+	RoleMap map[string]string
+
+	// CoalesceToolResults merges tool results and a following user turn into a
+	// single user message (Anthropic only)
+	CoalesceToolResults bool
+
+	// EmptyAssistant selects how OpenAI output handles assistant messages with
+	// neither content nor tool calls. Defaults to EmptyAssistantDrop.
+	EmptyAssistant EmptyAssistantPolicy
+
+	// ImageFirst places images before the text of the same message (Anthropic only)
+	ImageFirst bool
+
+	// DedupeImages sends each image asset (by SHA256) only once per payload.
+	// Later occurrences become a short text reference, or are removed when
+	// DropDuplicateImages is set.
+	DedupeImages        bool
+	DropDuplicateImages bool
+
+	// RejectDuplicateIDs fails conversion when two messages share an ID.
+	// DedupeByID instead keeps only the last message with a given ID.
+	RejectDuplicateIDs bool
+	DedupeByID         bool
+
+	// SelectCandidate picks which entry of Meta["candidates"] replaces the parts
+	// of an assistant message that carries candidates. Out of range is an error.
+	SelectCandidate int
+
+	// TrimText trims surrounding whitespace from every text part, or only
+	// trailing whitespace when TrimTrailingOnly is set
+	TrimText         bool
+	TrimTrailingOnly bool
+
+	// MaxOutputBytes caps the JSON-serialized size of the converted messages.
+	// Zero means unlimited.
+	MaxOutputBytes int
+
+	// CompletionTemplate controls role prefixes for FormatCompletion.
+	// Nil uses DefaultCompletionTemplate.
+	CompletionTemplate *CompletionTemplate
+
+	// PlainTextOptions controls transcript rendering for FormatPlainText.
+	// Nil uses DefaultPlainTextOptions.
+	PlainTextOptions *PlainTextOptions
+
+	// MemoryMaxChars caps the summary length of each FormatMemory record.
+	// Zero uses DefaultMemoryMaxChars.
+	MemoryMaxChars int
+
+	// AppendStopToken is appended to the text of the final message, for chat
+	// templates that expect an explicit end-of-turn marker (FormatCompletion only)
+	AppendStopToken string
+
+	// ConsolidateSystem merges every system message, in order, into a single
+	// leading system message
+	ConsolidateSystem bool
+
+	// SystemPrompt and DeveloperPrompt are injected ahead of the conversation
+	// as a system and a developer message, in that order, following OpenAI's
+	// guidance for reasoning models to keep the system message minimal and put
+	// high-level instructions in the developer message (OpenAI formats only)
+	SystemPrompt    string
+	DeveloperPrompt string
+
+	// SystemAsUser sends the consolidated system prompt as the leading user
+	// message, for endpoints without a system role. SystemAsUserPrefix (e.g.
+	// "System: ") is prepended to its text.
+	SystemAsUser       bool
+	SystemAsUserPrefix string
+
+	// FewShotExamples is a pool of example messages placed before the live
+	// conversation (after its leading system messages). Examples are taken in
+	// order while their estimated tokens fit FewShotBudget; zero includes none.
+	FewShotExamples []model.Message
+	FewShotBudget   int
+
+	// EmptyPlaceholder, when set, is sent as a single user message if there are
+	// no messages, since some providers reject an empty messages array.
+	// ErrorOnEmpty returns ErrNoMessages instead.
+	EmptyPlaceholder string
+	ErrorOnEmpty     bool
+
+	// RejectInvalidToolNames fails conversion when a tool name does not match
+	// ^[a-zA-Z0-9_-]{1,64}$ instead of sanitizing it (OpenAI and Anthropic only)
+	RejectInvalidToolNames bool
+
+	// StrictTurns splits a stored message whose parts cannot share one provider
+	// turn into several consecutive messages (see splitMixedTurns)
+	StrictTurns bool
+
+	// ToolChoice forces, allows or disables tool calls. It only applies to
+	// BuildRequest.
+	ToolChoice *ToolChoice
+
+	// ResponseFormat requests structured output. It only applies to
+	// BuildRequest; bare message conversion ignores it.
+	ResponseFormat *ResponseFormat
+
+	// DataSources is passed through as data_sources by BuildRequest for
+	// FormatAzureOpenAI ("on your data" extensions)
+	DataSources []map[string]any
+
+	// PromptCacheKey is sent by BuildRequest as OpenAI's prompt_cache_key and
+	// user, so calls from the same session are routed to the same prompt cache
+	PromptCacheKey string
+
+	// ExtraParams are merged into the top level of the request built by
+	// BuildRequest for OpenAI formats, for vendor knobs such as
+	// reasoning_effort or service_tier. "messages" and "model" are reserved.
+	ExtraParams map[string]any
+
+	// MaxTokens and Stop make BuildRequest send the provider's output token
+	// limit and stop sequences. Unset, the RequestDefaults for the format apply.
+	MaxTokens int
+	Stop      []string
+
+	// RequestDefaults holds per-format fallbacks for MaxTokens and Stop.
+	// Anthropic requires max_tokens, so BuildRequest fails for it when neither
+	// MaxTokens nor a default is set.
+	RequestDefaults map[model.MessageFormat]RequestDefaults
+
+	// DisableParallelToolCalls makes BuildRequest send parallel_tool_calls: false
+	// for OpenAI formats, so the model issues at most one tool call per turn.
+	// Stored turns with several tool calls are still converted as they are.
+	DisableParallelToolCalls bool
+
+	// URLTransform, when set, rewrites asset URLs from PublicURLs before they
+	// are placed in the output. It is called once per distinct asset.
+	URLTransform URLTransform
+
+	// PreferredVariant names the asset variant (e.g. "medium") to send for
+	// images instead of the original, to cut vision costs. Images without
+	// that variant, or without a public URL for it, keep the original.
+	PreferredVariant string
+
+	// InlineAssetResolver supplies the bytes of images that have no public
+	// URL, which are then sent inline as base64 (OpenAI formats and Gemini).
+	// Images with a public URL always use it.
+	InlineAssetResolver InlineAssetResolver
+
+	// Clock tells which PublicURLs have passed their ExpireAt; expired ones are
+	// treated as missing. An image whose only URL expired fails the conversion
+	// with ErrPublicURLExpired for image-sending formats, unless it can be sent
+	// through InlineAssetResolver. Nil uses time.Now.
+	Clock ClockFunc
+
+	// Metrics, when set, observes the conversion: format, message count,
+	// duration, outcome and the JSON size of the result
+	Metrics metrics.Recorder
 }
 
-// MessageConverter interface for extensible message conversion
+// MessageConverter interface for extensible message conversion. ctx bounds any
+// asset fetching the converter does.
 type MessageConverter interface {
-	Convert(messages []model.Message, publicURLs map[string]service.PublicURL) (interface{}, error)
+	Convert(ctx context.Context, messages []model.Message, publicURLs map[string]service.PublicURL) (interface{}, error)
 }
 
-// ConvertMessages converts messages to the specified format
-func ConvertMessages(input ConvertMessagesInput) (interface{}, error) {
-	var converter MessageConverter
+// ConvertMessages converts messages to the specified format. ctx bounds any
+// asset fetching done during conversion; when it ends mid-fetch, ctx.Err() is
+// returned. Conversions that fetch nothing ignore it.
+func ConvertMessages(ctx context.Context, input ConvertMessagesInput) (interface{}, error) {
+	result, _, _, err := observedConvert(ctx, input)
+	return result, err
+}
+
+// observedConvert validates the input, resolves the default format, converts and reports the call to
+// input.Metrics. It also returns the preprocessed messages and the format used.
+func observedConvert(ctx context.Context, input ConvertMessagesInput) (interface{}, []model.Message, model.MessageFormat, error) {
+	if err := input.Validate(); err != nil {
+		return nil, nil, input.Format, err
+	}
 
 	// Default to Acontext format if not specified
 	format := input.Format
@@ -29,40 +209,247 @@ func ConvertMessages(input ConvertMessagesInput) (interface{}, error) {
 		format = model.FormatAcontext
 	}
 
+	if input.Metrics == nil {
+		result, messages, err := convertMessages(ctx, input, format)
+		return result, messages, format, err
+	}
+
+	start := time.Now()
+	result, messages, err := convertMessages(ctx, input, format)
+	observation := metrics.Conversion{
+		Format:       string(format),
+		MessageCount: len(input.Messages),
+		Duration:     time.Since(start),
+		Err:          err,
+	}
+	if err == nil {
+		if size, sErr := outputSize(result); sErr == nil {
+			observation.BytesOut = size
+		}
+	}
+	input.Metrics.ObserveConversion(observation)
+
+	return result, messages, format, err
+}
+
+// convertMessages returns the converted result along with the preprocessed
+// messages that were handed to the format converter
+func convertMessages(ctx context.Context, input ConvertMessagesInput, format model.MessageFormat) (interface{}, []model.Message, error) {
+	var converter MessageConverter
+
+	messages := input.Messages
+	if len(messages) == 0 {
+		if input.ErrorOnEmpty {
+			return nil, nil, ErrNoMessages
+		}
+		if input.EmptyPlaceholder != "" {
+			messages = []model.Message{{
+				Role:  "user",
+				Parts: []model.Part{{Type: "text", Text: input.EmptyPlaceholder}},
+			}}
+		}
+	}
+	messages, err := mapRoles(messages, input.RoleMap)
+	if err != nil {
+		return nil, nil, err
+	}
+	if input.RejectDuplicateIDs {
+		if err := checkDuplicateIDs(messages); err != nil {
+			return nil, nil, err
+		}
+	} else if input.DedupeByID {
+		messages = dedupeByID(messages)
+	}
+	messages, err = selectCandidates(messages, input.SelectCandidate)
+	if err != nil {
+		return nil, nil, err
+	}
+	if input.TrimText {
+		messages = trimTextParts(messages, input.TrimTrailingOnly)
+	}
+	if len(input.FewShotExamples) > 0 && input.FewShotBudget > 0 {
+		examples, err := mapRoles(input.FewShotExamples, input.RoleMap)
+		if err != nil {
+			return nil, nil, fmt.Errorf("few-shot examples: %w", err)
+		}
+		if messages, err = withFewShot(messages, examples, input.FewShotBudget); err != nil {
+			return nil, nil, err
+		}
+	}
+	if input.SystemAsUser {
+		messages = systemAsUser(messages, input.SystemAsUserPrefix)
+	} else if input.ConsolidateSystem {
+		messages = consolidateSystemMessages(messages)
+	}
+	if input.DedupeImages {
+		messages = dedupeImages(messages, input.DropDuplicateImages)
+	}
+	switch format {
+	case model.FormatOpenAI, model.FormatAzureOpenAI, model.FormatAnthropic:
+		// Providers require an id on every tool call, in their own format
+		messages = assignToolCallIDs(messages)
+		messages = normalizeForeignToolCallIDs(messages, format)
+		if messages, err = sanitizeToolNames(messages, input.RejectInvalidToolNames); err != nil {
+			return nil, nil, err
+		}
+	}
+	if input.StrictTurns {
+		messages = splitMixedTurns(messages, format)
+	}
+	if input.AppendStopToken != "" {
+		messages = appendStopToken(messages, input.AppendStopToken)
+	}
+
 	switch format {
 	case model.FormatAcontext:
 		converter = &AcontextConverter{}
-	case model.FormatOpenAI:
-		converter = &OpenAIConverter{}
+	case model.FormatOpenAI, model.FormatAzureOpenAI:
+		converter = &OpenAIConverter{
+			EmptyAssistant:      input.EmptyAssistant,
+			SystemPrompt:        input.SystemPrompt,
+			DeveloperPrompt:     input.DeveloperPrompt,
+			InlineAssetResolver: input.InlineAssetResolver,
+		}
 	case model.FormatAnthropic:
-		converter = &AnthropicConverter{}
+		converter = &AnthropicConverter{
+			CoalesceToolResults: input.CoalesceToolResults,
+			ImageFirst:          input.ImageFirst,
+		}
+	case model.FormatCompletion:
+		converter = &CompletionConverter{Template: input.CompletionTemplate}
+	case model.FormatPlainText:
+		converter = &PlainTextConverter{Options: input.PlainTextOptions}
+	case model.FormatMemory:
+		converter = &MemoryConverter{MaxChars: input.MemoryMaxChars}
+	case model.FormatGemini:
+		converter = &GeminiConverter{InlineAssetResolver: input.InlineAssetResolver}
+	case model.FormatCohere:
+		converter = &CohereConverter{}
 	default:
-		return nil, fmt.Errorf("unsupported format: %s", format)
+		return nil, nil, fmt.Errorf("unsupported format: %s", format)
+	}
+
+	clock := input.Clock
+	if clock == nil {
+		clock = time.Now
+	}
+	publicURLs, expired := dropExpiredURLs(input.PublicURLs, clock())
+
+	if input.PreferredVariant != "" {
+		messages = selectImageVariants(messages, publicURLs, input.PreferredVariant)
+	}
+	if expired {
+		// Formats that send images need a fresh URL, or inline data where supported
+		check := false
+		switch format {
+		case model.FormatOpenAI, model.FormatAzureOpenAI, model.FormatGemini:
+			check = input.InlineAssetResolver == nil
+		case model.FormatAnthropic:
+			check = true
+		}
+		if check {
+			if err := checkExpiredImages(messages, input.PublicURLs, publicURLs); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	publicURLs = transformPublicURLs(messages, publicURLs, input.URLTransform)
+	result, err := converter.Convert(ctx, messages, publicURLs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if input.MaxOutputBytes > 0 {
+		if err := checkOutputSize(result, input.MaxOutputBytes); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return result, messages, nil
+}
+
+// checkOutputSize serializes the converted items one by one and stops as soon
+// as the running JSON array size exceeds limit
+func checkOutputSize(result interface{}, limit int) error {
+	if prompt, ok := result.(string); ok {
+		if len(prompt) > limit {
+			return fmt.Errorf("%w: exceeds %d bytes", ErrOutputTooLarge, limit)
+		}
+		return nil
+	}
+
+	items := reflect.ValueOf(result)
+	if items.Kind() != reflect.Slice {
+		// A single object such as AnthropicMessages or GeminiMessages is measured whole
+		encoded, err := json.Marshal(result)
+		if err != nil {
+			return fmt.Errorf("failed to measure converted messages: %w", err)
+		}
+		if len(encoded) > limit {
+			return fmt.Errorf("%w: exceeds %d bytes", ErrOutputTooLarge, limit)
+		}
+		return nil
+	}
+
+	size := 2 // enclosing brackets
+	for i := 0; i < items.Len(); i++ {
+		encoded, err := json.Marshal(items.Index(i).Interface())
+		if err != nil {
+			return fmt.Errorf("failed to measure converted message %d: %w", i, err)
+		}
+		if i > 0 {
+			size++ // separating comma
+		}
+		size += len(encoded)
+		if size > limit {
+			return fmt.Errorf("%w: exceeds %d bytes at message %d", ErrOutputTooLarge, limit, i)
+		}
 	}
+	return nil
+}
+
+// byteCounter is an io.Writer that only counts what is written to it
+type byteCounter int
 
-	return converter.Convert(input.Messages, input.PublicURLs)
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
+	return len(p), nil
+}
+
+// outputSize returns the length of the JSON encoding of result without holding
+// the encoded bytes
+func outputSize(result interface{}) (int, error) {
+	var counter byteCounter
+	if err := json.NewEncoder(&counter).Encode(result); err != nil {
+		return 0, err
+	}
+	// Encode terminates the value with a newline that json.Marshal would not write
+	return int(counter) - 1, nil
 }
 
 // ValidateFormat checks if the format is valid
 func ValidateFormat(format string) (model.MessageFormat, error) {
 	mf := model.MessageFormat(format)
 	switch mf {
-	case model.FormatAcontext, model.FormatOpenAI, model.FormatAnthropic:
+	case model.FormatAcontext, model.FormatOpenAI, model.FormatAnthropic, model.FormatCompletion, model.FormatAzureOpenAI, model.FormatPlainText, model.FormatMemory, model.FormatGemini, model.FormatCohere:
 		return mf, nil
 	default:
-		return "", fmt.Errorf("invalid format: %s, supported formats: acontext, openai, anthropic", format)
+		return "", fmt.Errorf("invalid format: %s, supported formats: acontext, openai, anthropic, completion, azure_openai, plain_text, memory, gemini, cohere", format)
 	}
 }
 
-// GetConvertedMessagesOutput wraps the converted messages with metadata
+// GetConvertedMessagesOutput wraps the converted messages with metadata. A
+// non-nil tokenEstimate adds a "token_estimate" of the converted messages.
 func GetConvertedMessagesOutput(
+	ctx context.Context,
 	messages []model.Message,
 	format model.MessageFormat,
 	publicURLs map[string]service.PublicURL,
 	nextCursor string,
 	hasMore bool,
+	tokenEstimate *TokenEstimateOptions,
 ) (map[string]interface{}, error) {
-	convertedData, err := ConvertMessages(ConvertMessagesInput{
+	convertedData, err := ConvertMessages(ctx, ConvertMessagesInput{
 		Messages:   messages,
 		Format:     format,
 		PublicURLs: publicURLs,
@@ -75,11 +462,26 @@ func GetConvertedMessagesOutput(
 		"items":    convertedData,
 		"has_more": hasMore,
 	}
+	if anthropic, ok := convertedData.(AnthropicMessages); ok {
+		// Items stay a list; the system prompt goes beside it, as Anthropic takes it
+		result["items"] = anthropic.Messages
+		if anthropic.System != "" {
+			result["system"] = anthropic.System
+		}
+	}
 
 	if nextCursor != "" {
 		result["next_cursor"] = nextCursor
 	}
 
+	if tokenEstimate != nil {
+		tokens, err := EstimateTokens(convertedData, *tokenEstimate)
+		if err != nil {
+			return nil, err
+		}
+		result["token_estimate"] = tokens
+	}
+
 	// Include public_urls only if format is None (original format)
 	if format == model.FormatAcontext && len(publicURLs) > 0 {
 		result["public_urls"] = publicURLs
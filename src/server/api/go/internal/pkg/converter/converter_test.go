@@ -1,12 +1,18 @@
 package converter
 
 import (
+	"context"
+	"encoding/json"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/google/uuid"
 	"github.com/memodb-io/Acontext/internal/modules/model"
 	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/memodb-io/Acontext/internal/pkg/metrics"
+	openai "github.com/openai/openai-go/v3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gorm.io/datatypes"
@@ -38,7 +44,7 @@ func TestConvertMessages_InvalidFormat(t *testing.T) {
 		}, nil),
 	}
 
-	_, err := ConvertMessages(ConvertMessagesInput{
+	_, err := ConvertMessages(context.Background(), ConvertMessagesInput{
 		Messages:   messages,
 		Format:     "invalid_format",
 		PublicURLs: nil,
@@ -56,7 +62,7 @@ func TestConvertMessages_DefaultFormat(t *testing.T) {
 	}
 
 	// Empty format should default to Acontext
-	result, err := ConvertMessages(ConvertMessagesInput{
+	result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
 		Messages:   messages,
 		Format:     "",
 		PublicURLs: nil,
@@ -78,11 +84,14 @@ func TestConvertMessages_AllFormats(t *testing.T) {
 		model.FormatAcontext,
 		model.FormatOpenAI,
 		model.FormatAnthropic,
+		model.FormatCompletion,
+		model.FormatAzureOpenAI,
+		model.FormatPlainText,
 	}
 
 	for _, format := range formats {
 		t.Run(string(format), func(t *testing.T) {
-			result, err := ConvertMessages(ConvertMessagesInput{
+			result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
 				Messages:   messages,
 				Format:     format,
 				PublicURLs: nil,
@@ -119,6 +128,36 @@ func TestValidateFormat(t *testing.T) {
 			want:    model.FormatAnthropic,
 			wantErr: false,
 		},
+		{
+			name:    "valid completion",
+			format:  "completion",
+			want:    model.FormatCompletion,
+			wantErr: false,
+		},
+		{
+			name:    "valid azure_openai",
+			format:  "azure_openai",
+			want:    model.FormatAzureOpenAI,
+			wantErr: false,
+		},
+		{
+			name:    "valid plain_text",
+			format:  "plain_text",
+			want:    model.FormatPlainText,
+			wantErr: false,
+		},
+		{
+			name:    "valid gemini",
+			format:  "gemini",
+			want:    model.FormatGemini,
+			wantErr: false,
+		},
+		{
+			name:    "valid cohere",
+			format:  "cohere",
+			want:    model.FormatCohere,
+			wantErr: false,
+		},
 		{
 			name:    "invalid format",
 			format:  "invalid",
@@ -158,11 +197,13 @@ func TestGetConvertedMessagesOutput(t *testing.T) {
 	}
 
 	result, err := GetConvertedMessagesOutput(
+		context.Background(),
 		messages,
 		model.FormatAcontext,
 		publicURLs,
 		"next_cursor_123",
 		true,
+		nil,
 	)
 
 	require.NoError(t, err)
@@ -187,11 +228,13 @@ func TestGetConvertedMessagesOutput_NonAcontextFormat(t *testing.T) {
 	}
 
 	result, err := GetConvertedMessagesOutput(
+		context.Background(),
 		messages,
 		model.FormatOpenAI,
 		publicURLs,
 		"",
 		false,
+		nil,
 	)
 
 	require.NoError(t, err)
@@ -201,4 +244,113 @@ func TestGetConvertedMessagesOutput_NonAcontextFormat(t *testing.T) {
 
 	// Non-Acontext formats should NOT include public_urls
 	assert.Nil(t, result["public_urls"])
+	assert.NotContains(t, result, "token_estimate")
+}
+
+func TestGetConvertedMessagesOutput_AnthropicSystem(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("system", []model.Part{{Type: "text", Text: "Be brief."}}, nil),
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Hi"}}, nil),
+	}
+
+	result, err := GetConvertedMessagesOutput(context.Background(), messages, model.FormatAnthropic, nil, "", false, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Be brief.", result["system"])
+	items := result["items"].([]anthropic.MessageParam)
+	require.Len(t, items, 1)
+	assert.Equal(t, anthropic.MessageParamRoleUser, items[0].Role)
+}
+
+type spyRecorder struct {
+	conversions []metrics.Conversion
+}
+
+func (s *spyRecorder) ObserveOperation(string, time.Duration, error) {}
+
+func (s *spyRecorder) ObserveConversion(c metrics.Conversion) {
+	s.conversions = append(s.conversions, c)
+}
+
+func TestConvertMessages_Metrics(t *testing.T) {
+	spy := &spyRecorder{}
+
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Hello"}}, nil),
+		createTestMessage("assistant", []model.Part{{Type: "text", Text: "Hi there"}}, nil),
+	}
+
+	result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+		Messages: messages,
+		Format:   model.FormatOpenAI,
+		Metrics:  spy,
+	})
+	require.NoError(t, err)
+
+	require.Len(t, spy.conversions, 1)
+	observed := spy.conversions[0]
+	assert.Equal(t, "openai", observed.Format)
+	assert.Equal(t, 2, observed.MessageCount)
+	encoded, err := json.Marshal(result)
+	require.NoError(t, err)
+	assert.Equal(t, len(encoded), observed.BytesOut)
+	assert.GreaterOrEqual(t, observed.Duration, time.Duration(0))
+	assert.NoError(t, observed.Err)
+}
+
+func TestConvertMessages_MaxOutputBytes(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: strings.Repeat("a", 200)}}, nil),
+		createTestMessage("assistant", []model.Part{{Type: "text", Text: strings.Repeat("b", 200)}}, nil),
+	}
+
+	t.Run("under the limit succeeds", func(t *testing.T) {
+		result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages:       messages,
+			Format:         model.FormatOpenAI,
+			MaxOutputBytes: 10_000,
+		})
+		require.NoError(t, err)
+		assert.NotNil(t, result)
+	})
+
+	t.Run("over the limit fails", func(t *testing.T) {
+		_, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages:       messages,
+			Format:         model.FormatOpenAI,
+			MaxOutputBytes: 300,
+		})
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrOutputTooLarge)
+		assert.Contains(t, err.Error(), "at message 1")
+	})
+}
+
+func TestConvertMessages_EmptyInput(t *testing.T) {
+	t.Run("default returns an empty list", func(t *testing.T) {
+		result, err := ConvertMessages(context.Background(), ConvertMessagesInput{Format: model.FormatOpenAI})
+		require.NoError(t, err)
+		assert.Empty(t, result)
+	})
+
+	t.Run("placeholder emits a single user message", func(t *testing.T) {
+		result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Format:           model.FormatOpenAI,
+			EmptyPlaceholder: "Hello",
+		})
+		require.NoError(t, err)
+
+		msgs := result.([]openai.ChatCompletionMessageParamUnion)
+		require.Len(t, msgs, 1)
+		require.NotNil(t, msgs[0].OfUser)
+		assert.Equal(t, "Hello", msgs[0].OfUser.Content.OfString.Value)
+	})
+
+	t.Run("error on empty", func(t *testing.T) {
+		_, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Format:       model.FormatAnthropic,
+			ErrorOnEmpty: true,
+		})
+		assert.ErrorIs(t, err, ErrNoMessages)
+	})
 }
@@ -0,0 +1,45 @@
+package converter
+
+import "github.com/memodb-io/Acontext/internal/modules/model"
+
+// duplicateImageReference replaces repeated images when DedupeImages is enabled
+const duplicateImageReference = "(see image above)"
+
+// dedupeImages keeps the first occurrence of every image asset (keyed by SHA256)
+// and replaces later occurrences with a short text reference, or drops them when
+// drop is true. Input messages are not modified.
+func dedupeImages(messages []model.Message, drop bool) []model.Message {
+	seen := make(map[string]struct{})
+	result := make([]model.Message, len(messages))
+
+	for i, msg := range messages {
+		result[i] = msg
+
+		var parts []model.Part
+		changed := false
+		for j, part := range msg.Parts {
+			if part.Type == "image" && part.Asset != nil && part.Asset.SHA256 != "" {
+				if _, ok := seen[part.Asset.SHA256]; ok {
+					if !changed {
+						parts = append(make([]model.Part, 0, len(msg.Parts)), msg.Parts[:j]...)
+						changed = true
+					}
+					if !drop {
+						parts = append(parts, model.Part{Type: "text", Text: duplicateImageReference})
+					}
+					continue
+				}
+				seen[part.Asset.SHA256] = struct{}{}
+			}
+			if changed {
+				parts = append(parts, part)
+			}
+		}
+
+		if changed {
+			result[i].Parts = parts
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,81 @@
+package converter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+	openai "github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertMessages_DedupeImages(t *testing.T) {
+	asset := &model.Asset{S3Key: "assets/cat.png", SHA256: "abc123", MIME: "image/png"}
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{
+			{Type: "text", Text: "Look at this"},
+			{Type: "image", Asset: asset},
+		}, nil),
+		createTestMessage("assistant", []model.Part{{Type: "text", Text: "A cat"}}, nil),
+		createTestMessage("user", []model.Part{
+			{Type: "text", Text: "Same image again"},
+			{Type: "image", Asset: asset},
+		}, nil),
+	}
+	publicURLs := map[string]service.PublicURL{
+		"assets/cat.png": {URL: "https://example.com/cat.png"},
+	}
+
+	countImages := func(result interface{}) int {
+		count := 0
+		for _, msg := range result.([]openai.ChatCompletionMessageParamUnion) {
+			if msg.OfUser == nil {
+				continue
+			}
+			for _, part := range msg.OfUser.Content.OfArrayOfContentParts {
+				if part.OfImageURL != nil {
+					count++
+				}
+			}
+		}
+		return count
+	}
+
+	t.Run("without dedupe every occurrence is sent", func(t *testing.T) {
+		result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages:   messages,
+			Format:     model.FormatOpenAI,
+			PublicURLs: publicURLs,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 2, countImages(result))
+	})
+
+	t.Run("dedupe replaces repeat with a reference", func(t *testing.T) {
+		result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages:     messages,
+			Format:       model.FormatOpenAI,
+			PublicURLs:   publicURLs,
+			DedupeImages: true,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, countImages(result))
+
+		last := result.([]openai.ChatCompletionMessageParamUnion)[2].OfUser
+		require.NotNil(t, last)
+		parts := last.Content.OfArrayOfContentParts
+		require.Len(t, parts, 2)
+		assert.Equal(t, duplicateImageReference, parts[1].OfText.Text)
+
+		// Original messages are untouched
+		assert.Equal(t, "image", messages[2].Parts[1].Type)
+	})
+
+	t.Run("dedupe can drop repeats", func(t *testing.T) {
+		deduped := dedupeImages(messages, true)
+		require.Len(t, deduped[2].Parts, 1)
+		assert.Equal(t, "Same image again", deduped[2].Parts[0].Text)
+	})
+}
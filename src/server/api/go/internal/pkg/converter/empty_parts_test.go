@@ -0,0 +1,68 @@
+package converter
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+)
+
+func TestConvertMessages_EmptyParts(t *testing.T) {
+	tests := []struct {
+		format model.MessageFormat
+		want   string
+	}{
+		{format: model.FormatOpenAI, want: `[{"role":"user","content":""},{"role":"user","content":""}]`},
+		{format: model.FormatAzureOpenAI, want: `[{"role":"user","content":""},{"role":"user","content":""}]`},
+		{format: model.FormatAnthropic, want: `{"messages":[{"role":"user","content":[]},{"role":"user","content":[]}]}`},
+		{format: model.FormatCompletion, want: `"### Human:\n\n### Human:\n\n### Assistant:"`},
+		{format: model.FormatPlainText, want: `""`},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			messages := []model.Message{
+				createTestMessage("user", nil, nil),
+				createTestMessage("user", []model.Part{}, nil),
+			}
+			result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+				Messages: messages,
+				Format:   tt.format,
+			})
+			require.NoError(t, err)
+
+			out, err := json.Marshal(result)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(out))
+		})
+	}
+
+	t.Run(string(model.FormatAcontext), func(t *testing.T) {
+		result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages: []model.Message{createTestMessage("user", nil, nil)},
+			Format:   model.FormatAcontext,
+		})
+		require.NoError(t, err)
+
+		msgs := result.([]AcontextMessage)
+		require.Len(t, msgs, 1)
+		assert.NotNil(t, msgs[0].Parts)
+		assert.Empty(t, msgs[0].Parts)
+	})
+
+	t.Run(string(model.FormatMemory), func(t *testing.T) {
+		result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages: []model.Message{createTestMessage("user", nil, nil)},
+			Format:   model.FormatMemory,
+		})
+		require.NoError(t, err)
+
+		records := result.([]MemoryRecord)
+		require.Len(t, records, 1)
+		assert.Empty(t, records[0].Summary)
+	})
+}
@@ -0,0 +1,98 @@
+package converter
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/memodb-io/Acontext/internal/pkg/tokenizer"
+)
+
+// Defaults used for zero TokenEstimateOptions fields
+const (
+	DefaultCharsPerToken   = 4
+	DefaultMessageOverhead = 4
+)
+
+// TokenEstimateOptions tunes EstimateTokens. Zero fields use the defaults:
+// DefaultCharsPerToken, DefaultMessageOverhead and tokenizer.DefaultImageTokens.
+type TokenEstimateOptions struct {
+	CharsPerToken   int
+	MessageOverhead int
+	ImageTokens     int
+}
+
+// EstimateTokens roughly estimates the prompt tokens of converted messages, so
+// the estimate reflects the output format: the characters of every string in
+// the payload divided by CharsPerToken, plus MessageOverhead per message and
+// ImageTokens per image.
+func EstimateTokens(converted interface{}, opts TokenEstimateOptions) (int, error) {
+	if opts.CharsPerToken <= 0 {
+		opts.CharsPerToken = DefaultCharsPerToken
+	}
+	if opts.MessageOverhead <= 0 {
+		opts.MessageOverhead = DefaultMessageOverhead
+	}
+	if opts.ImageTokens <= 0 {
+		opts.ImageTokens = tokenizer.DefaultImageTokens
+	}
+
+	encoded, err := json.Marshal(converted)
+	if err != nil {
+		return 0, err
+	}
+	var payload any
+	if err := json.Unmarshal(encoded, &payload); err != nil {
+		return 0, err
+	}
+
+	messages := 1
+	if anthropic, ok := converted.(AnthropicMessages); ok {
+		messages = len(anthropic.Messages)
+	} else if gemini, ok := converted.(GeminiMessages); ok {
+		messages = len(gemini.Contents)
+	} else if cohere, ok := converted.(CohereMessages); ok {
+		messages = len(cohere.ChatHistory) + 1
+	} else if items := reflect.ValueOf(converted); items.Kind() == reflect.Slice {
+		messages = items.Len()
+	}
+
+	var chars, images int
+	countPayload(payload, &chars, &images)
+
+	textTokens := (chars + opts.CharsPerToken - 1) / opts.CharsPerToken
+	return textTokens + messages*opts.MessageOverhead + images*opts.ImageTokens, nil
+}
+
+// countPayload adds up the string lengths in decoded JSON, counting each image
+// block as one image instead of by the length of its URL or data
+func countPayload(v any, chars, images *int) {
+	switch val := v.(type) {
+	case string:
+		*chars += len([]rune(val))
+	case []any:
+		for _, item := range val {
+			countPayload(item, chars, images)
+		}
+	case map[string]any:
+		if isImageBlock(val) {
+			*images++
+			return
+		}
+		for _, item := range val {
+			countPayload(item, chars, images)
+		}
+	}
+}
+
+// isImageBlock recognizes the image blocks of the supported formats: typed
+// "image" (Acontext, Anthropic) and "image_url" (OpenAI) parts, and Gemini
+// inline or file data
+func isImageBlock(block map[string]any) bool {
+	switch block["type"] {
+	case "image", "image_url":
+		return true
+	}
+	_, inline := block["inlineData"]
+	_, file := block["fileData"]
+	return inline || file
+}
@@ -0,0 +1,73 @@
+package converter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	t.Run("text", func(t *testing.T) {
+		// {"role":"user","content":"abcdefgh"}: "user" + "abcdefgh" is 12 chars
+		converted := []map[string]any{{"role": "user", "content": "abcdefgh"}}
+		tokens, err := EstimateTokens(converted, TokenEstimateOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, 3+DefaultMessageOverhead, tokens)
+	})
+
+	t.Run("images use the flat cost", func(t *testing.T) {
+		messages := []model.Message{
+			createTestMessage("user", []model.Part{
+				{Type: "image", Asset: &model.Asset{S3Key: "assets/a.png", SHA256: "sha-a", MIME: "image/png"}},
+			}, nil),
+		}
+		publicURLs := map[string]service.PublicURL{"sha-a": {URL: "https://cdn.example.com/a.png"}}
+
+		for _, format := range []model.MessageFormat{model.FormatOpenAI, model.FormatGemini} {
+			t.Run(string(format), func(t *testing.T) {
+				converted, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+					Messages:   messages,
+					Format:     format,
+					PublicURLs: publicURLs,
+				})
+				require.NoError(t, err)
+
+				small, err := EstimateTokens(converted, TokenEstimateOptions{ImageTokens: 100})
+				require.NoError(t, err)
+				large, err := EstimateTokens(converted, TokenEstimateOptions{ImageTokens: 1000})
+				require.NoError(t, err)
+				assert.Equal(t, 900, large-small)
+				assert.Less(t, small, 110, "the image URL is not counted as text")
+			})
+		}
+	})
+}
+
+func TestGetConvertedMessagesOutput_TokenEstimate(t *testing.T) {
+	turn := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "What is the capital of France?"}}, nil),
+		createTestMessage("assistant", []model.Part{{Type: "text", Text: "Paris."}}, nil),
+	}
+
+	estimate := func(messages []model.Message) int {
+		result, err := GetConvertedMessagesOutput(
+			context.Background(), messages, model.FormatOpenAI, nil, "", false, &TokenEstimateOptions{},
+		)
+		require.NoError(t, err)
+		require.Contains(t, result, "token_estimate")
+		return result["token_estimate"].(int)
+	}
+
+	one := estimate(turn)
+	three := estimate(append(append(append([]model.Message{}, turn...), turn...), turn...))
+	assert.Greater(t, one, 2*DefaultMessageOverhead)
+	assert.InDelta(t, 3*one, three, 2, "the estimate scales with message count")
+
+	result, err := GetConvertedMessagesOutput(context.Background(), turn, model.FormatOpenAI, nil, "", false, nil)
+	require.NoError(t, err)
+	assert.NotContains(t, result, "token_estimate")
+}
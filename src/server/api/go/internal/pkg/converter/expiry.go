@@ -0,0 +1,62 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+)
+
+// ErrPublicURLExpired is returned when an image's only public URL has expired
+// and it cannot be sent inline instead
+var ErrPublicURLExpired = errors.New("public URL expired")
+
+// ClockFunc returns the current time
+type ClockFunc func() time.Time
+
+// dropExpiredURLs returns publicURLs without the entries whose ExpireAt is
+// before now, and whether any were dropped. A zero ExpireAt never expires.
+func dropExpiredURLs(publicURLs map[string]service.PublicURL, now time.Time) (map[string]service.PublicURL, bool) {
+	var result map[string]service.PublicURL
+	for key, publicURL := range publicURLs {
+		if publicURL.ExpireAt.IsZero() || !publicURL.ExpireAt.Before(now) {
+			continue
+		}
+		if result == nil {
+			result = make(map[string]service.PublicURL, len(publicURLs))
+			for k, v := range publicURLs {
+				result[k] = v
+			}
+		}
+		delete(result, key)
+	}
+	if result == nil {
+		return publicURLs, false
+	}
+	return result, true
+}
+
+// checkExpiredImages fails for the first image that had a public URL in all
+// but has none in fresh, unless it carries its own Meta["url"]
+func checkExpiredImages(messages []model.Message, all, fresh map[string]service.PublicURL) error {
+	for i, msg := range messages {
+		for j, part := range msg.Parts {
+			if part.Type != "image" || part.Asset == nil {
+				continue
+			}
+			if _, ok := publicURLKey(part.Asset, fresh); ok {
+				continue
+			}
+			if url, _ := part.Meta["url"].(string); url != "" {
+				continue
+			}
+			if key, ok := publicURLKey(part.Asset, all); ok {
+				return fmt.Errorf("%w: message %d part %d, asset %s expired at %s",
+					ErrPublicURLExpired, i, j, part.Asset.SHA256, all[key].ExpireAt.Format(time.RFC3339))
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package converter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+	openai "github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertMessages_ExpiredPublicURLs(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{
+			{Type: "text", Text: "Describe"},
+			{Type: "image", Asset: &model.Asset{S3Key: "assets/a.png", SHA256: "sha-a", MIME: "image/png"}},
+		}, nil),
+	}
+	fresh := map[string]service.PublicURL{
+		"sha-a": {URL: "https://cdn.example.com/a.png", ExpireAt: now.Add(time.Hour)},
+	}
+	expired := map[string]service.PublicURL{
+		"sha-a": {URL: "https://cdn.example.com/a.png", ExpireAt: now.Add(-time.Minute)},
+	}
+
+	imageURL := func(t *testing.T, result interface{}) string {
+		msgs := result.([]openai.ChatCompletionMessageParamUnion)
+		require.Len(t, msgs, 1)
+		for _, part := range msgs[0].OfUser.Content.OfArrayOfContentParts {
+			if part.OfImageURL != nil {
+				return part.OfImageURL.ImageURL.URL
+			}
+		}
+		return ""
+	}
+
+	t.Run("fresh url", func(t *testing.T) {
+		result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages:   messages,
+			Format:     model.FormatOpenAI,
+			PublicURLs: fresh,
+			Clock:      clock,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "https://cdn.example.com/a.png", imageURL(t, result))
+	})
+
+	t.Run("expired url", func(t *testing.T) {
+		for _, format := range []model.MessageFormat{model.FormatOpenAI, model.FormatAnthropic, model.FormatGemini} {
+			_, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+				Messages:   messages,
+				Format:     format,
+				PublicURLs: expired,
+				Clock:      clock,
+			})
+			require.ErrorIs(t, err, ErrPublicURLExpired, format)
+			assert.Contains(t, err.Error(), "sha-a")
+		}
+	})
+
+	t.Run("expired url with resolver", func(t *testing.T) {
+		result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages:   messages,
+			Format:     model.FormatOpenAI,
+			PublicURLs: expired,
+			Clock:      clock,
+			InlineAssetResolver: func(ctx context.Context, asset *model.Asset) ([]byte, error) {
+				return []byte("png"), nil
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "data:image/png;base64,cG5n", imageURL(t, result))
+	})
+
+	t.Run("expired url in a text format", func(t *testing.T) {
+		_, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages:   messages,
+			Format:     model.FormatPlainText,
+			PublicURLs: expired,
+			Clock:      clock,
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("zero expiry never expires", func(t *testing.T) {
+		result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages:   messages,
+			Format:     model.FormatOpenAI,
+			PublicURLs: map[string]service.PublicURL{"sha-a": {URL: "https://cdn.example.com/a.png"}},
+			Clock:      clock,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "https://cdn.example.com/a.png", imageURL(t, result))
+	})
+}
@@ -0,0 +1,63 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/pkg/tokenizer"
+)
+
+// selectFewShot returns the longest prefix of examples whose token estimate fits budget
+func selectFewShot(examples []model.Message, budget int) ([]model.Message, error) {
+	used := 0
+	for i, example := range examples {
+		tokens, err := estimateTokens(example)
+		if err != nil {
+			return nil, fmt.Errorf("estimate few-shot example %d: %w", i, err)
+		}
+		if used+tokens > budget {
+			return examples[:i], nil
+		}
+		used += tokens
+	}
+	return examples, nil
+}
+
+// estimateTokens counts the text and tool-call tokens of msg plus the estimated
+// cost of its images, which depends on their dimensions
+func estimateTokens(msg model.Message) (int, error) {
+	tokens, err := tokenizer.CountSingleMessageTokens(context.Background(), msg)
+	if err != nil {
+		return 0, err
+	}
+	for _, part := range msg.Parts {
+		if part.Type == "image" {
+			tokens += tokenizer.ImageTokens(part)
+		}
+	}
+	return tokens, nil
+}
+
+// withFewShot inserts the examples that fit budget after the leading system
+// messages of the conversation
+func withFewShot(messages []model.Message, examples []model.Message, budget int) ([]model.Message, error) {
+	selected, err := selectFewShot(examples, budget)
+	if err != nil {
+		return nil, err
+	}
+	if len(selected) == 0 {
+		return messages, nil
+	}
+
+	head := 0
+	for head < len(messages) && messages[head].Role == "system" {
+		head++
+	}
+
+	result := make([]model.Message, 0, len(messages)+len(selected))
+	result = append(result, messages[:head]...)
+	result = append(result, selected...)
+	result = append(result, messages[head:]...)
+	return result, nil
+}
@@ -0,0 +1,79 @@
+package converter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/pkg/tokenizer"
+	openai "github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestConvertMessages_FewShotBudget(t *testing.T) {
+	require.NoError(t, tokenizer.Init(zaptest.NewLogger(t)))
+
+	examples := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Translate: cat"}}, nil),
+		createTestMessage("assistant", []model.Part{{Type: "text", Text: "chat"}}, nil),
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Translate: a considerably longer sentence about dogs"}}, nil),
+		createTestMessage("assistant", []model.Part{{Type: "text", Text: "x"}}, nil),
+	}
+	messages := []model.Message{
+		createTestMessage("system", []model.Part{{Type: "text", Text: "You translate to French."}}, nil),
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Translate: house"}}, nil),
+	}
+
+	budget := 0
+	for _, example := range examples[:2] {
+		tokens, err := tokenizer.CountSingleMessageTokens(context.Background(), example)
+		require.NoError(t, err)
+		budget += tokens
+	}
+
+	result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+		Messages:        messages,
+		Format:          model.FormatOpenAI,
+		FewShotExamples: examples,
+		FewShotBudget:   budget,
+	})
+	require.NoError(t, err)
+
+	msgs := result.([]openai.ChatCompletionMessageParamUnion)
+	require.Len(t, msgs, 4)
+	require.NotNil(t, msgs[0].OfSystem)
+	assert.Equal(t, "Translate: cat", msgs[1].OfUser.Content.OfString.Value)
+	assert.Equal(t, "chat", msgs[2].OfAssistant.Content.OfString.Value)
+	assert.Equal(t, "Translate: house", msgs[3].OfUser.Content.OfString.Value)
+}
+
+func TestSelectFewShot(t *testing.T) {
+	require.NoError(t, tokenizer.Init(zaptest.NewLogger(t)))
+
+	examples := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "one two three four five six"}}, nil),
+		createTestMessage("assistant", []model.Part{{Type: "text", Text: "ok"}}, nil),
+	}
+
+	selected, err := selectFewShot(examples, 1)
+	require.NoError(t, err)
+	assert.Empty(t, selected, "a later example that fits must not be picked after one that does not")
+
+	selected, err = selectFewShot(examples, 1000)
+	require.NoError(t, err)
+	assert.Equal(t, examples, selected)
+}
+
+func TestEstimateTokens_ImageDimensions(t *testing.T) {
+	require.NoError(t, tokenizer.Init(zaptest.NewLogger(t)))
+
+	msg := createTestMessage("user", []model.Part{
+		{Type: "image", Asset: &model.Asset{S3Key: "assets/big.png", Width: 2048, Height: 2048}, Meta: map[string]any{"detail": "high"}},
+	}, nil)
+
+	tokens, err := estimateTokens(msg)
+	require.NoError(t, err)
+	assert.Equal(t, 85+170*4, tokens)
+}
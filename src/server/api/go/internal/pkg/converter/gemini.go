@@ -0,0 +1,167 @@
+package converter
+
+import (
+	"context"
+	"strings"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+)
+
+// GeminiMessages is the output of FormatGemini: the contents array and the
+// system instruction, which Gemini takes as a separate request field
+type GeminiMessages struct {
+	Contents          []GeminiContent `json:"contents"`
+	SystemInstruction *GeminiContent  `json:"systemInstruction,omitempty"`
+}
+
+// GeminiContent is one turn of a Gemini conversation. Role is "user" or
+// "model", and is left empty on the system instruction.
+type GeminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []GeminiPart `json:"parts"`
+}
+
+// GeminiPart holds exactly one of its fields
+type GeminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	InlineData       *GeminiBlob             `json:"inlineData,omitempty"`
+	FileData         *GeminiFileData         `json:"fileData,omitempty"`
+	FunctionCall     *GeminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *GeminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+// GeminiBlob is media sent inline as base64
+type GeminiBlob struct {
+	MIMEType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+// GeminiFileData is media referenced by URI
+type GeminiFileData struct {
+	MIMEType string `json:"mimeType,omitempty"`
+	FileURI  string `json:"fileUri"`
+}
+
+type GeminiFunctionCall struct {
+	Name string         `json:"name"`
+	Args map[string]any `json:"args,omitempty"`
+}
+
+type GeminiFunctionResponse struct {
+	Name     string         `json:"name"`
+	Response map[string]any `json:"response"`
+}
+
+// GeminiConverter converts messages to Gemini contents. Assistant messages
+// take the "model" role and system messages become the system instruction.
+// Gemini matches function responses to calls by name, so tool results are
+// named after the tool call their tool_call_id refers to.
+type GeminiConverter struct {
+	// InlineAssetResolver, when set, supplies the bytes of images that have no
+	// public URL so they are sent as inline data instead of being dropped
+	InlineAssetResolver InlineAssetResolver
+}
+
+func (c *GeminiConverter) Convert(ctx context.Context, messages []model.Message, publicURLs map[string]service.PublicURL) (interface{}, error) {
+	out := GeminiMessages{Contents: make([]GeminiContent, 0, len(messages))}
+	var system []string
+	toolNames := make(map[string]string)
+
+	for _, msg := range messages {
+		if msg.Role == "system" {
+			for _, part := range msg.Parts {
+				if part.Type == "text" && part.Text != "" {
+					system = append(system, part.Text)
+				}
+			}
+			continue
+		}
+
+		role := "user"
+		if msg.Role == "assistant" {
+			role = "model"
+		}
+
+		parts := make([]GeminiPart, 0, len(msg.Parts))
+		for _, part := range msg.Parts {
+			converted, err := c.convertPart(ctx, part, publicURLs, toolNames)
+			if err != nil {
+				return nil, err
+			}
+			if converted != nil {
+				parts = append(parts, *converted)
+			}
+		}
+		if len(parts) == 0 {
+			continue
+		}
+		out.Contents = append(out.Contents, GeminiContent{Role: role, Parts: parts})
+	}
+
+	if len(system) > 0 {
+		out.SystemInstruction = &GeminiContent{
+			Parts: []GeminiPart{{Text: strings.Join(system, systemSeparator)}},
+		}
+	}
+	return out, nil
+}
+
+func (c *GeminiConverter) convertPart(ctx context.Context, part model.Part, publicURLs map[string]service.PublicURL, toolNames map[string]string) (*GeminiPart, error) {
+	switch part.Type {
+	case "text":
+		if part.Text == "" {
+			return nil, nil
+		}
+		return &GeminiPart{Text: part.Text}, nil
+	case "image":
+		return c.convertImagePart(ctx, part, publicURLs)
+	case "tool-call":
+		id, _ := part.Meta["id"].(string)
+		name, _ := part.Meta["name"].(string)
+		if name == "" {
+			return nil, nil
+		}
+		if id != "" {
+			toolNames[id] = name
+		}
+		return &GeminiPart{FunctionCall: &GeminiFunctionCall{Name: name, Args: decodeArguments(part.Meta["arguments"])}}, nil
+	case "tool-result":
+		id, _ := part.Meta["tool_call_id"].(string)
+		name := toolNames[id]
+		if name == "" {
+			return nil, nil
+		}
+		return &GeminiPart{FunctionResponse: &GeminiFunctionResponse{
+			Name:     name,
+			Response: map[string]any{"content": part.Text},
+		}}, nil
+	}
+	return nil, nil
+}
+
+// convertImagePart references the image by its public URL (or Meta["url"]),
+// falling back to inline data from the resolver
+func (c *GeminiConverter) convertImagePart(ctx context.Context, part model.Part, publicURLs map[string]service.PublicURL) (*GeminiPart, error) {
+	mime := ""
+	if part.Asset != nil {
+		mime = part.Asset.MIME
+	}
+
+	url := assetPublicURL(part.Asset, publicURLs)
+	if url == "" && part.Meta != nil {
+		url, _ = part.Meta["url"].(string)
+	}
+	if url != "" {
+		return &GeminiPart{FileData: &GeminiFileData{MIMEType: mime, FileURI: url}}, nil
+	}
+
+	if part.Asset == nil || c.InlineAssetResolver == nil {
+		return nil, nil
+	}
+	mime, data, err := inlineData(ctx, c.InlineAssetResolver, part.Asset)
+	if err != nil {
+		return nil, err
+	}
+	return &GeminiPart{InlineData: &GeminiBlob{MIMEType: mime, Data: data}}, nil
+}
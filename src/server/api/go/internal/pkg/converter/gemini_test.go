@@ -0,0 +1,96 @@
+package converter
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertMessages_Gemini(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("system", []model.Part{{Type: "text", Text: "You describe images."}}, nil),
+		createTestMessage("user", []model.Part{
+			{Type: "text", Text: "What is in this image?"},
+			{Type: "image", Asset: &model.Asset{S3Key: "assets/cat.png", SHA256: "sha-cat", MIME: "image/png"}},
+		}, nil),
+		createTestMessage("assistant", []model.Part{
+			{Type: "tool-call", Meta: map[string]any{"id": "call_1", "name": "classify", "arguments": `{"label":"cat"}`}},
+		}, nil),
+		createTestMessage("user", []model.Part{
+			{Type: "tool-result", Text: "confirmed", Meta: map[string]any{"tool_call_id": "call_1"}},
+		}, nil),
+		createTestMessage("assistant", []model.Part{{Type: "text", Text: "A cat."}}, nil),
+	}
+
+	result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+		Messages: messages,
+		Format:   model.FormatGemini,
+		PublicURLs: map[string]service.PublicURL{
+			"sha-cat": {URL: "https://cdn.example.com/cat.png"},
+		},
+	})
+	require.NoError(t, err)
+
+	out, err := json.Marshal(result)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"systemInstruction": {"parts": [{"text": "You describe images."}]},
+		"contents": [
+			{"role": "user", "parts": [
+				{"text": "What is in this image?"},
+				{"fileData": {"mimeType": "image/png", "fileUri": "https://cdn.example.com/cat.png"}}
+			]},
+			{"role": "model", "parts": [{"functionCall": {"name": "classify", "args": {"label": "cat"}}}]},
+			{"role": "user", "parts": [{"functionResponse": {"name": "classify", "response": {"content": "confirmed"}}}]},
+			{"role": "model", "parts": [{"text": "A cat."}]}
+		]
+	}`, string(out))
+}
+
+func TestGeminiConverter_InlineImage(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{
+			{Type: "image", Asset: &model.Asset{S3Key: "assets/cat.png", SHA256: "sha-cat", MIME: "image/png"}},
+		}, nil),
+	}
+
+	t.Run("without resolver", func(t *testing.T) {
+		result, err := (&GeminiConverter{}).Convert(context.Background(), messages, nil)
+		require.NoError(t, err)
+		assert.Empty(t, result.(GeminiMessages).Contents)
+	})
+
+	t.Run("with resolver", func(t *testing.T) {
+		converter := &GeminiConverter{InlineAssetResolver: func(ctx context.Context, asset *model.Asset) ([]byte, error) {
+			return []byte("png"), nil
+		}}
+		result, err := converter.Convert(context.Background(), messages, nil)
+		require.NoError(t, err)
+
+		contents := result.(GeminiMessages).Contents
+		require.Len(t, contents, 1)
+		assert.Equal(t, []GeminiPart{{InlineData: &GeminiBlob{MIMEType: "image/png", Data: "cG5n"}}}, contents[0].Parts)
+	})
+}
+
+func TestBuildRequest_Gemini(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("system", []model.Part{{Type: "text", Text: "Be brief."}}, nil),
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Hi"}}, nil),
+	}
+
+	request, err := BuildRequest(context.Background(), ConvertMessagesInput{
+		Messages: messages,
+		Format:   model.FormatGemini,
+	})
+	require.NoError(t, err)
+
+	assert.NotContains(t, request, "messages")
+	assert.Equal(t, []GeminiContent{{Role: "user", Parts: []GeminiPart{{Text: "Hi"}}}}, request["contents"])
+	assert.Equal(t, &GeminiContent{Parts: []GeminiPart{{Text: "Be brief."}}}, request["systemInstruction"])
+}
@@ -0,0 +1,39 @@
+package converter
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+)
+
+// InlineAssetResolver returns the raw bytes of an asset, so an image without a
+// public URL can still be sent inline as a data URL. ctx is the conversion's.
+type InlineAssetResolver func(ctx context.Context, asset *model.Asset) ([]byte, error)
+
+// inlineData resolves asset and returns its MIME type and base64 data. Once ctx
+// is done no further asset is resolved and its error is returned.
+func inlineData(ctx context.Context, resolve InlineAssetResolver, asset *model.Asset) (string, string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", "", err
+	}
+	data, err := resolve(ctx, asset)
+	if err != nil {
+		return "", "", fmt.Errorf("resolve asset %s: %w", asset.SHA256, err)
+	}
+	mime := asset.MIME
+	if mime == "" {
+		mime = "application/octet-stream"
+	}
+	return mime, base64.StdEncoding.EncodeToString(data), nil
+}
+
+// inlineDataURL resolves asset and encodes it as a data:<mime>;base64 URL
+func inlineDataURL(ctx context.Context, resolve InlineAssetResolver, asset *model.Asset) (string, error) {
+	mime, data, err := inlineData(ctx, resolve, asset)
+	if err != nil {
+		return "", err
+	}
+	return "data:" + mime + ";base64," + data, nil
+}
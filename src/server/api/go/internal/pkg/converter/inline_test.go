@@ -0,0 +1,115 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+	openai "github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertMessages_OpenAIInlineAssetResolver(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{
+			{Type: "text", Text: "Compare these"},
+			{Type: "image", Asset: &model.Asset{S3Key: "assets/a.png", SHA256: "sha-a", MIME: "image/png"}},
+			{Type: "image", Asset: &model.Asset{S3Key: "assets/b.png", SHA256: "sha-b", MIME: "image/png"}},
+		}, nil),
+	}
+	publicURLs := map[string]service.PublicURL{
+		"sha-a": {URL: "https://cdn.example.com/a.png"},
+	}
+
+	imageURLs := func(t *testing.T, result interface{}) []string {
+		msgs := result.([]openai.ChatCompletionMessageParamUnion)
+		require.Len(t, msgs, 1)
+		var urls []string
+		for _, part := range msgs[0].OfUser.Content.OfArrayOfContentParts {
+			if part.OfImageURL != nil {
+				urls = append(urls, part.OfImageURL.ImageURL.URL)
+			}
+		}
+		return urls
+	}
+
+	t.Run("without resolver", func(t *testing.T) {
+		result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages:   messages,
+			Format:     model.FormatOpenAI,
+			PublicURLs: publicURLs,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"https://cdn.example.com/a.png"}, imageURLs(t, result))
+	})
+
+	t.Run("resolver fills missing urls", func(t *testing.T) {
+		var resolved []string
+		result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages:   messages,
+			Format:     model.FormatOpenAI,
+			PublicURLs: publicURLs,
+			InlineAssetResolver: func(ctx context.Context, asset *model.Asset) ([]byte, error) {
+				resolved = append(resolved, asset.SHA256)
+				return []byte("png"), nil
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"https://cdn.example.com/a.png",
+			"data:image/png;base64,cG5n",
+		}, imageURLs(t, result))
+		assert.Equal(t, []string{"sha-b"}, resolved)
+	})
+
+	t.Run("resolver error", func(t *testing.T) {
+		errNotFound := errors.New("object not found")
+		_, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages:   messages,
+			Format:     model.FormatOpenAI,
+			PublicURLs: publicURLs,
+			InlineAssetResolver: func(ctx context.Context, asset *model.Asset) ([]byte, error) {
+				return nil, errNotFound
+			},
+		})
+		require.ErrorIs(t, err, errNotFound)
+		assert.Contains(t, err.Error(), "sha-b")
+	})
+
+	t.Run("resolver gets the conversion context", func(t *testing.T) {
+		type ctxKey struct{}
+		ctx := context.WithValue(context.Background(), ctxKey{}, "request")
+		var got any
+		_, err := ConvertMessages(ctx, ConvertMessagesInput{
+			Messages:   messages,
+			Format:     model.FormatOpenAI,
+			PublicURLs: publicURLs,
+			InlineAssetResolver: func(ctx context.Context, asset *model.Asset) ([]byte, error) {
+				got = ctx.Value(ctxKey{})
+				return []byte("png"), nil
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "request", got)
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		called := false
+		_, err := ConvertMessages(ctx, ConvertMessagesInput{
+			Messages:   messages,
+			Format:     model.FormatGemini,
+			PublicURLs: publicURLs,
+			InlineAssetResolver: func(ctx context.Context, asset *model.Asset) ([]byte, error) {
+				called = true
+				return []byte("png"), nil
+			},
+		})
+		require.ErrorIs(t, err, context.Canceled)
+		assert.False(t, called)
+	})
+}
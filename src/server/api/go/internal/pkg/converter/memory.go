@@ -0,0 +1,85 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+)
+
+// DefaultMemoryMaxChars is the summary length used when MemoryConverter.MaxChars is zero
+const DefaultMemoryMaxChars = 200
+
+// MemoryRecord is the condensed form of one message produced by FormatMemory
+type MemoryRecord struct {
+	Role      string    `json:"role"`
+	Summary   string    `json:"summary"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// MemoryConverter condenses each message into a MemoryRecord: its text cut to
+// MaxChars characters, followed by a short note per attachment or tool call.
+// The conversion is lossy by design.
+type MemoryConverter struct {
+	MaxChars int
+}
+
+func (c *MemoryConverter) Convert(ctx context.Context, messages []model.Message, publicURLs map[string]service.PublicURL) (interface{}, error) {
+	maxChars := c.MaxChars
+	if maxChars <= 0 {
+		maxChars = DefaultMemoryMaxChars
+	}
+
+	records := make([]MemoryRecord, 0, len(messages))
+	for _, msg := range messages {
+		var texts, notes []string
+		for _, part := range msg.Parts {
+			switch part.Type {
+			case "text", "refusal", "tool-result":
+				if part.Text != "" {
+					texts = append(texts, part.Text)
+				}
+			case "tool-call":
+				name, _ := part.Meta["name"].(string)
+				notes = append(notes, fmt.Sprintf("[tool call: %s]", name))
+			case "image", "audio", "video", "file":
+				notes = append(notes, c.attachmentNote(part))
+			}
+		}
+
+		summary := truncateRunes(strings.Join(texts, " "), maxChars)
+		if len(notes) > 0 {
+			summary = strings.TrimSpace(summary + " " + strings.Join(notes, " "))
+		}
+		records = append(records, MemoryRecord{
+			Role:      msg.Role,
+			Summary:   summary,
+			Timestamp: msg.CreatedAt,
+		})
+	}
+	return records, nil
+}
+
+// attachmentNote names an attachment part, e.g. "[image: chart.png]"
+func (c *MemoryConverter) attachmentNote(part model.Part) string {
+	name := part.Filename
+	if name == "" {
+		name, _ = part.Meta["filename"].(string)
+	}
+	if name == "" {
+		return "[" + part.Type + "]"
+	}
+	return "[" + part.Type + ": " + name + "]"
+}
+
+// truncateRunes cuts s to at most n characters, marking the cut with an ellipsis
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "…"
+}
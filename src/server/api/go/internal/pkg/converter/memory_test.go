@@ -0,0 +1,51 @@
+package converter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryConverter_Convert(t *testing.T) {
+	user := createTestMessage("user", []model.Part{
+		{Type: "text", Text: "Please review the quarterly report"},
+		{Type: "file", Filename: "q3.pdf"},
+		{Type: "image"},
+	}, nil)
+	assistant := createTestMessage("assistant", []model.Part{
+		{Type: "text", Text: "Revenue grew."},
+		{Type: "tool-call", Meta: map[string]any{"id": "call_1", "name": "chart", "arguments": "{}"}},
+	}, nil)
+	user.CreatedAt = time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	assistant.CreatedAt = user.CreatedAt.Add(time.Minute)
+
+	result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+		Messages:       []model.Message{user, assistant},
+		Format:         model.FormatMemory,
+		MemoryMaxChars: 10,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []MemoryRecord{
+		{Role: "user", Summary: "Please rev… [file: q3.pdf] [image]", Timestamp: user.CreatedAt},
+		{Role: "assistant", Summary: "Revenue gr… [tool call: chart]", Timestamp: assistant.CreatedAt},
+	}, result)
+}
+
+func TestMemoryConverter_DefaultLength(t *testing.T) {
+	msg := createTestMessage("user", []model.Part{{Type: "text", Text: "short note"}}, nil)
+
+	result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+		Messages: []model.Message{msg},
+		Format:   model.FormatMemory,
+	})
+	require.NoError(t, err)
+
+	records := result.([]MemoryRecord)
+	require.Len(t, records, 1)
+	assert.Equal(t, "short note", records[0].Summary)
+}
@@ -0,0 +1,48 @@
+package converter
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+)
+
+// checkDuplicateIDs returns ErrDuplicateMessageID naming the first message ID
+// that appears more than once. Messages without an ID are ignored.
+func checkDuplicateIDs(messages []model.Message) error {
+	seen := make(map[uuid.UUID]struct{}, len(messages))
+	for _, msg := range messages {
+		if msg.ID == uuid.Nil {
+			continue
+		}
+		if _, ok := seen[msg.ID]; ok {
+			return fmt.Errorf("%w: %s", ErrDuplicateMessageID, msg.ID)
+		}
+		seen[msg.ID] = struct{}{}
+	}
+	return nil
+}
+
+// dedupeByID drops every message whose ID appears again later, keeping the last
+// occurrence at its own position. Messages without an ID are kept. Input
+// messages are not modified.
+func dedupeByID(messages []model.Message) []model.Message {
+	last := make(map[uuid.UUID]int, len(messages))
+	for i, msg := range messages {
+		if msg.ID != uuid.Nil {
+			last[msg.ID] = i
+		}
+	}
+	if len(last) == len(messages) {
+		return messages
+	}
+
+	result := make([]model.Message, 0, len(messages))
+	for i, msg := range messages {
+		if msg.ID != uuid.Nil && last[msg.ID] != i {
+			continue
+		}
+		result = append(result, msg)
+	}
+	return result
+}
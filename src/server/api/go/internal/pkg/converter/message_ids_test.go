@@ -0,0 +1,48 @@
+package converter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+)
+
+func TestConvertMessages_DuplicateIDs(t *testing.T) {
+	first := createTestMessage("user", []model.Part{{Type: "text", Text: "Hello"}}, nil)
+	reply := createTestMessage("assistant", []model.Part{{Type: "text", Text: "Hi there"}}, nil)
+	replayed := first
+	replayed.Parts = []model.Part{{Type: "text", Text: "Hello again"}}
+	messages := []model.Message{first, reply, replayed}
+
+	t.Run("reject", func(t *testing.T) {
+		_, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages:           messages,
+			Format:             model.FormatPlainText,
+			RejectDuplicateIDs: true,
+		})
+		assert.ErrorIs(t, err, ErrDuplicateMessageID)
+		assert.Contains(t, err.Error(), first.ID.String())
+	})
+
+	t.Run("dedupe keeps last", func(t *testing.T) {
+		result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages:   messages,
+			Format:     model.FormatPlainText,
+			DedupeByID: true,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "Assistant: Hi there\nUser: Hello again", result)
+	})
+
+	t.Run("no duplicates", func(t *testing.T) {
+		_, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages:           []model.Message{first, reply},
+			Format:             model.FormatPlainText,
+			RejectDuplicateIDs: true,
+		})
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,58 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+)
+
+// ErrUnknownModel is returned by FormatForModel for a model name it cannot map
+var ErrUnknownModel = errors.New("unknown model")
+
+// modelFormats maps model name prefixes to the format they expect. An empty
+// format marks a known model family that has no converter yet.
+var modelFormats = []struct {
+	prefix string
+	format model.MessageFormat
+}{
+	{"gpt-", model.FormatOpenAI},
+	{"chatgpt-", model.FormatOpenAI},
+	{"o1", model.FormatOpenAI},
+	{"o3", model.FormatOpenAI},
+	{"o4", model.FormatOpenAI},
+	{"claude-", model.FormatAnthropic},
+	{"gemini-", model.FormatGemini},
+	{"command", model.FormatCohere},
+}
+
+// FormatForModel returns the message format expected by the named model,
+// matched case-insensitively by prefix. A provider prefix such as
+// "openai/" or "anthropic/" is ignored.
+func FormatForModel(modelName string) (model.MessageFormat, error) {
+	name := strings.ToLower(strings.TrimSpace(modelName))
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	for _, m := range modelFormats {
+		if !strings.HasPrefix(name, m.prefix) {
+			continue
+		}
+		if m.format == "" {
+			return "", fmt.Errorf("model %q has no supported message format", modelName)
+		}
+		return m.format, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownModel, modelName)
+}
+
+// FormatForModelOr is FormatForModel with fallback returned for models that
+// cannot be mapped
+func FormatForModelOr(modelName string, fallback model.MessageFormat) model.MessageFormat {
+	format, err := FormatForModel(modelName)
+	if err != nil {
+		return fallback
+	}
+	return format
+}
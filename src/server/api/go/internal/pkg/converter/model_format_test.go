@@ -0,0 +1,48 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatForModel(t *testing.T) {
+	tests := []struct {
+		model  string
+		format model.MessageFormat
+	}{
+		{"gpt-4o", model.FormatOpenAI},
+		{"gpt-4.1-mini", model.FormatOpenAI},
+		{"o1-preview", model.FormatOpenAI},
+		{"o3-mini", model.FormatOpenAI},
+		{"claude-3-5-sonnet-20241022", model.FormatAnthropic},
+		{"Claude-Opus-4", model.FormatAnthropic},
+		{"anthropic/claude-3-haiku", model.FormatAnthropic},
+		{"openai/gpt-4o", model.FormatOpenAI},
+		{"gemini-1.5-pro", model.FormatGemini},
+		{"google/gemini-2.0-flash", model.FormatGemini},
+		{"command-r-plus", model.FormatCohere},
+		{"cohere/command-a-03-2025", model.FormatCohere},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			format, err := FormatForModel(tt.model)
+			require.NoError(t, err)
+			assert.Equal(t, tt.format, format)
+		})
+	}
+}
+
+func TestFormatForModel_Unknown(t *testing.T) {
+	_, err := FormatForModel("llama-3-70b")
+	assert.ErrorIs(t, err, ErrUnknownModel)
+
+	_, err = FormatForModel("")
+	assert.ErrorIs(t, err, ErrUnknownModel)
+
+	assert.Equal(t, model.FormatAcontext, FormatForModelOr("llama-3-70b", model.FormatAcontext))
+	assert.Equal(t, model.FormatAnthropic, FormatForModelOr("claude-3-haiku", model.FormatAcontext))
+}
@@ -1,7 +1,8 @@
 package converter
 
 import (
-	"encoding/json"
+	"context"
+	"fmt"
 
 	openai "github.com/openai/openai-go/v3"
 	"github.com/openai/openai-go/v3/packages/param"
@@ -10,58 +11,108 @@ import (
 	"github.com/memodb-io/Acontext/internal/modules/service"
 )
 
+// EmptyAssistantPolicy decides what happens to an assistant message left with
+// neither content nor tool calls, which OpenAI rejects
+type EmptyAssistantPolicy string
+
+const (
+	// EmptyAssistantDrop omits the message (default)
+	EmptyAssistantDrop EmptyAssistantPolicy = "drop"
+	// EmptyAssistantEmptyContent sends the message with content ""
+	EmptyAssistantEmptyContent EmptyAssistantPolicy = "empty_content"
+)
+
 // OpenAIConverter converts messages to OpenAI-compatible format using official SDK types
-type OpenAIConverter struct{}
+type OpenAIConverter struct {
+	EmptyAssistant EmptyAssistantPolicy
+
+	// SystemPrompt and DeveloperPrompt, when set, are emitted before the
+	// messages as a system message followed by a developer message
+	SystemPrompt    string
+	DeveloperPrompt string
+
+	// InlineAssetResolver, when set, supplies the bytes of images that have no
+	// public URL so they are sent as data URLs instead of being dropped
+	InlineAssetResolver InlineAssetResolver
+}
 
-func (c *OpenAIConverter) Convert(messages []model.Message, publicURLs map[string]service.PublicURL) (interface{}, error) {
-	result := make([]openai.ChatCompletionMessageParamUnion, 0, len(messages))
+func (c *OpenAIConverter) Convert(ctx context.Context, messages []model.Message, publicURLs map[string]service.PublicURL) (interface{}, error) {
+	result := make([]openai.ChatCompletionMessageParamUnion, 0, len(messages)+2)
+
+	if c.SystemPrompt != "" {
+		result = append(result, openai.SystemMessage(c.SystemPrompt))
+	}
+	if c.DeveloperPrompt != "" {
+		result = append(result, openai.DeveloperMessage(c.DeveloperPrompt))
+	}
 
 	for _, msg := range messages {
-		// Special handling: if user role contains only tool-result parts,
-		// convert to OpenAI's tool role
-		if msg.Role == "user" && c.isToolResultOnly(msg.Parts) {
-			toolMsg := c.convertToToolMessage(msg)
-			result = append(result, toolMsg)
-		} else {
-			// Normal message conversion
-			switch msg.Role {
-			case "user":
-				userMsg := c.convertToUserMessage(msg, publicURLs)
-				result = append(result, userMsg)
-			case "assistant":
-				assistantMsg := c.convertToAssistantMessage(msg)
-				result = append(result, assistantMsg)
-			default:
-				// Default to user message
-				userMsg := c.convertToUserMessage(msg, publicURLs)
-				result = append(result, userMsg)
+		switch msg.Role {
+		case "user":
+			// Tool results become standalone tool-role messages, placed before any
+			// other content so they directly follow the assistant turn that called them
+			toolResults, rest := c.splitToolResults(msg.Parts)
+			for _, part := range toolResults {
+				result = append(result, c.convertToToolMessage(part))
+			}
+			if len(toolResults) > 0 && len(rest) == 0 {
+				continue
+			}
+			msg.Parts = rest
+			userMsg, err := c.convertToUserMessage(ctx, msg, publicURLs)
+			if err != nil {
+				return nil, err
 			}
+			result = append(result, userMsg)
+		case "assistant":
+			// Reasoning is not resent to OpenAI; a turn with nothing else is omitted
+			if isReasoningOnly(msg) {
+				continue
+			}
+			assistantMsg := c.convertToAssistantMessage(msg)
+			if c.isEmptyAssistant(assistantMsg.OfAssistant) {
+				if c.EmptyAssistant != EmptyAssistantEmptyContent {
+					continue
+				}
+				assistantMsg.OfAssistant.Content.OfString = param.NewOpt("")
+			}
+			result = append(result, assistantMsg)
+		case "system":
+			systemMsg := openai.SystemMessage(c.joinText(msg.Parts))
+			systemMsg.OfSystem.Name = messageName(msg)
+			result = append(result, systemMsg)
+		default:
+			// Default to user message
+			userMsg, err := c.convertToUserMessage(ctx, msg, publicURLs)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, userMsg)
 		}
 	}
 
 	return result, nil
 }
 
-func (c *OpenAIConverter) convertToUserMessage(msg model.Message, publicURLs map[string]service.PublicURL) openai.ChatCompletionMessageParamUnion {
+func (c *OpenAIConverter) convertToUserMessage(ctx context.Context, msg model.Message, publicURLs map[string]service.PublicURL) (openai.ChatCompletionMessageParamUnion, error) {
 	// Check if content should be string or array
-	if len(msg.Parts) == 1 && msg.Parts[0].Type == "text" {
-		// Single text part - use string content
+	if len(msg.Parts) == 0 || (len(msg.Parts) == 1 && msg.Parts[0].Type == "text") {
+		// Single text part - use string content; no parts at all is empty content
+		text := ""
+		if len(msg.Parts) == 1 {
+			text = msg.Parts[0].Text
+		}
 		userParam := openai.ChatCompletionUserMessageParam{
 			Content: openai.ChatCompletionUserMessageParamContentUnion{
-				OfString: param.NewOpt(msg.Parts[0].Text),
+				OfString: param.NewOpt(text),
 			},
 		}
 
-		// Add name field from message meta if present
-		if metaData := msg.Meta.Data(); len(metaData) > 0 {
-			if name, ok := metaData["name"].(string); ok && name != "" {
-				userParam.Name = param.NewOpt(name)
-			}
-		}
+		userParam.Name = messageName(msg)
 
 		return openai.ChatCompletionMessageParamUnion{
 			OfUser: &userParam,
-		}
+		}, nil
 	}
 
 	// Multiple parts or non-text parts - use array content
@@ -72,6 +123,13 @@ func (c *OpenAIConverter) convertToUserMessage(msg model.Message, publicURLs map
 			contentParts = append(contentParts, openai.TextContentPart(part.Text))
 		case "image":
 			imageURL := c.getAssetURL(part.Asset, publicURLs)
+			if imageURL == "" && part.Asset != nil && c.InlineAssetResolver != nil {
+				dataURL, err := inlineDataURL(ctx, c.InlineAssetResolver, part.Asset)
+				if err != nil {
+					return openai.ChatCompletionMessageParamUnion{}, err
+				}
+				imageURL = dataURL
+			}
 			if imageURL != "" {
 				detail := ""
 				if part.Meta != nil {
@@ -137,27 +195,26 @@ func (c *OpenAIConverter) convertToUserMessage(msg model.Message, publicURLs map
 		},
 	}
 
-	// Add name field from message meta if present
-	if metaData := msg.Meta.Data(); len(metaData) > 0 {
-		if name, ok := metaData["name"].(string); ok && name != "" {
-			userParam.Name = param.NewOpt(name)
-		}
-	}
+	userParam.Name = messageName(msg)
 
 	return openai.ChatCompletionMessageParamUnion{
 		OfUser: &userParam,
-	}
+	}, nil
 }
 
 func (c *OpenAIConverter) convertToAssistantMessage(msg model.Message) openai.ChatCompletionMessageParamUnion {
-	// Separate text content and tool calls
+	// Separate text content, refusal and tool calls
 	var textContent string
+	var refusal string
 	var toolCalls []openai.ChatCompletionMessageToolCallUnionParam
+	var audioID string
 
 	for _, part := range msg.Parts {
 		switch part.Type {
 		case "text":
 			textContent += part.Text
+		case "refusal":
+			refusal += part.Text
 		case "tool-call":
 			if part.Meta != nil {
 				toolCall := c.convertToToolCall(part)
@@ -165,6 +222,11 @@ func (c *OpenAIConverter) convertToAssistantMessage(msg model.Message) openai.Ch
 					toolCalls = append(toolCalls, *toolCall)
 				}
 			}
+		case PartTypeOutputAudio:
+			// Earlier audio is referenced by id rather than uploaded again
+			if id, ok := part.Meta[PartMetaAudioID].(string); ok && id != "" && audioID == "" {
+				audioID = id
+			}
 		}
 	}
 
@@ -177,31 +239,35 @@ func (c *OpenAIConverter) convertToAssistantMessage(msg model.Message) openai.Ch
 		}
 	}
 
+	if refusal != "" {
+		assistantParam.Refusal = param.NewOpt(refusal)
+	}
+
 	if len(toolCalls) > 0 {
 		assistantParam.ToolCalls = toolCalls
 	}
 
-	// Add name field from message meta if present
-	if metaData := msg.Meta.Data(); len(metaData) > 0 {
-		if name, ok := metaData["name"].(string); ok && name != "" {
-			assistantParam.Name = param.NewOpt(name)
-		}
+	if audioID != "" {
+		assistantParam.Audio = openai.ChatCompletionAssistantMessageParamAudio{ID: audioID}
 	}
 
+	assistantParam.Name = messageName(msg)
+
 	return openai.ChatCompletionMessageParamUnion{
 		OfAssistant: &assistantParam,
 	}
 }
 
-func (c *OpenAIConverter) convertToToolMessage(msg model.Message) openai.ChatCompletionMessageParamUnion {
-	// Extract tool result information
-	toolCallID := c.extractToolCallID(msg.Parts)
-	content := c.extractToolResultContent(msg.Parts)
+func (c *OpenAIConverter) convertToToolMessage(part model.Part) openai.ChatCompletionMessageParamUnion {
+	toolCallID := ""
+	if part.Meta != nil {
+		toolCallID, _ = part.Meta["tool_call_id"].(string)
+	}
 
 	toolParam := openai.ChatCompletionToolMessageParam{
 		ToolCallID: toolCallID,
 		Content: openai.ChatCompletionToolMessageParamContentUnion{
-			OfString: param.NewOpt(content),
+			OfString: param.NewOpt(toolResultContent(part)),
 		},
 	}
 
@@ -220,11 +286,11 @@ func (c *OpenAIConverter) convertToToolCall(part model.Part) *openai.ChatComplet
 	name, _ := part.Meta["name"].(string) // Unified: was "tool_name", now "name"
 	arguments, _ := part.Meta["arguments"].(string)
 
-	// If arguments is not a string, marshal it
+	// If arguments is not a string, marshal it with precise number handling
 	if arguments == "" {
 		if argsObj, ok := part.Meta["arguments"]; ok {
-			if argsBytes, err := json.Marshal(argsObj); err == nil {
-				arguments = string(argsBytes)
+			if encoded, err := encodeArguments(argsObj); err == nil {
+				arguments = encoded
 			}
 		}
 	}
@@ -246,33 +312,61 @@ func (c *OpenAIConverter) convertToToolCall(part model.Part) *openai.ChatComplet
 	}
 }
 
-func (c *OpenAIConverter) isToolResultOnly(parts []model.Part) bool {
-	if len(parts) == 0 {
-		return false
-	}
+func (c *OpenAIConverter) isEmptyAssistant(msg *openai.ChatCompletionAssistantMessageParam) bool {
+	return !msg.Content.OfString.Valid() &&
+		len(msg.Content.OfArrayOfContentParts) == 0 &&
+		!msg.Refusal.Valid() &&
+		len(msg.ToolCalls) == 0 &&
+		msg.Audio.ID == ""
+}
+
+// splitToolResults separates tool-result parts from the rest, keeping the
+// order within each group
+func (c *OpenAIConverter) splitToolResults(parts []model.Part) ([]model.Part, []model.Part) {
+	var toolResults, rest []model.Part
 	for _, part := range parts {
-		if part.Type != "tool-result" {
-			return false
+		if part.Type == "tool-result" {
+			toolResults = append(toolResults, part)
+		} else {
+			rest = append(rest, part)
 		}
 	}
-	return true
+	return toolResults, rest
 }
 
-func (c *OpenAIConverter) extractToolCallID(parts []model.Part) string {
-	for _, part := range parts {
-		if part.Type == "tool-result" && part.Meta != nil {
-			if toolCallID, ok := part.Meta["tool_call_id"].(string); ok {
-				return toolCallID
-			}
+// toolResultContent returns the part text, or else Meta["content"] as-is when
+// it is a string and JSON-encoded otherwise
+func toolResultContent(part model.Part) string {
+	if part.Text != "" || part.Meta == nil {
+		return part.Text
+	}
+	switch content := part.Meta["content"].(type) {
+	case nil:
+		return ""
+	case string:
+		return content
+	default:
+		encoded, err := encodeArguments(content)
+		if err != nil {
+			return fmt.Sprint(content)
 		}
+		return encoded
 	}
-	return ""
 }
 
-func (c *OpenAIConverter) extractToolResultContent(parts []model.Part) string {
+// messageName is the participant name stored in Meta["name"], which OpenAI
+// uses to tell apart agents or functions sharing a role. Unset, it is omitted.
+func messageName(msg model.Message) param.Opt[string] {
+	if name, ok := msg.Meta.Data()["name"].(string); ok && name != "" {
+		return param.NewOpt(name)
+	}
+	return param.Opt[string]{}
+}
+
+func (c *OpenAIConverter) joinText(parts []model.Part) string {
 	content := ""
 	for _, part := range parts {
-		if part.Type == "tool-result" {
+		if part.Type == "text" {
 			content += part.Text
 		}
 	}
@@ -280,12 +374,5 @@ func (c *OpenAIConverter) extractToolResultContent(parts []model.Part) string {
 }
 
 func (c *OpenAIConverter) getAssetURL(asset *model.Asset, publicURLs map[string]service.PublicURL) string {
-	if asset == nil {
-		return ""
-	}
-	assetKey := asset.S3Key
-	if publicURL, ok := publicURLs[assetKey]; ok {
-		return publicURL.URL
-	}
-	return ""
+	return assetPublicURL(asset, publicURLs)
 }
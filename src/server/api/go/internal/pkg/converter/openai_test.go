@@ -1,9 +1,13 @@
 package converter
 
 import (
+	"context"
+	"encoding/json"
 	"testing"
 
 	"github.com/memodb-io/Acontext/internal/modules/model"
+	openai "github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/packages/param"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -17,7 +21,7 @@ func TestOpenAIConverter_Convert_TextMessage(t *testing.T) {
 		}, nil),
 	}
 
-	result, err := converter.Convert(messages, nil)
+	result, err := converter.Convert(context.Background(), messages, nil)
 	require.NoError(t, err)
 
 	// OpenAI converter returns []openai.ChatCompletionMessageParamUnion
@@ -43,7 +47,7 @@ func TestOpenAIConverter_Convert_AssistantWithToolCalls(t *testing.T) {
 		}, nil),
 	}
 
-	result, err := converter.Convert(messages, nil)
+	result, err := converter.Convert(context.Background(), messages, nil)
 	require.NoError(t, err)
 	assert.NotNil(t, result)
 }
@@ -63,7 +67,151 @@ func TestOpenAIConverter_Convert_ToolResult(t *testing.T) {
 		}, nil),
 	}
 
-	result, err := converter.Convert(messages, nil)
+	result, err := converter.Convert(context.Background(), messages, nil)
 	require.NoError(t, err)
 	assert.NotNil(t, result)
 }
+
+func TestOpenAIConverter_Convert_Refusal(t *testing.T) {
+	converter := &OpenAIConverter{}
+
+	messages := []model.Message{
+		createTestMessage("assistant", []model.Part{
+			{Type: "refusal", Text: "I cannot help with that request."},
+		}, nil),
+	}
+
+	result, err := converter.Convert(context.Background(), messages, nil)
+	require.NoError(t, err)
+
+	msgs := result.([]openai.ChatCompletionMessageParamUnion)
+	require.Len(t, msgs, 1)
+	require.NotNil(t, msgs[0].OfAssistant)
+	assert.Equal(t, "I cannot help with that request.", msgs[0].OfAssistant.Refusal.Value)
+	assert.True(t, param.IsOmitted(msgs[0].OfAssistant.Content.OfString))
+}
+
+func TestOpenAIConverter_Convert_EmptyAssistant(t *testing.T) {
+	// The only part is one the OpenAI converter cannot send
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Hi"}}, nil),
+		createTestMessage("assistant", []model.Part{
+			{Type: "tool-call", Meta: map[string]any{"arguments": "{}"}},
+		}, nil),
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Hello?"}}, nil),
+	}
+
+	t.Run("dropped by default", func(t *testing.T) {
+		result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages: messages,
+			Format:   model.FormatOpenAI,
+		})
+		require.NoError(t, err)
+
+		msgs := result.([]openai.ChatCompletionMessageParamUnion)
+		require.Len(t, msgs, 2)
+		assert.NotNil(t, msgs[0].OfUser)
+		assert.NotNil(t, msgs[1].OfUser)
+	})
+
+	t.Run("empty content", func(t *testing.T) {
+		result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages:       messages,
+			Format:         model.FormatOpenAI,
+			EmptyAssistant: EmptyAssistantEmptyContent,
+		})
+		require.NoError(t, err)
+
+		msgs := result.([]openai.ChatCompletionMessageParamUnion)
+		require.Len(t, msgs, 3)
+		require.NotNil(t, msgs[1].OfAssistant)
+		require.True(t, msgs[1].OfAssistant.Content.OfString.Valid())
+		assert.Equal(t, "", msgs[1].OfAssistant.Content.OfString.Value)
+
+		out, err := json.Marshal(msgs[1])
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"role":"assistant","content":""}`, string(out))
+	})
+}
+
+func TestOpenAIConverter_Convert_SystemAndDeveloperPrompts(t *testing.T) {
+	result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+		Messages: []model.Message{
+			createTestMessage("user", []model.Part{{Type: "text", Text: "Solve it"}}, nil),
+		},
+		Format:          model.FormatOpenAI,
+		SystemPrompt:    "You are a helpful assistant.",
+		DeveloperPrompt: "Think step by step and answer in JSON.",
+	})
+	require.NoError(t, err)
+
+	out, err := json.Marshal(result)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"role":"system","content":"You are a helpful assistant."},
+		{"role":"developer","content":"Think step by step and answer in JSON."},
+		{"role":"user","content":"Solve it"}
+	]`, string(out))
+
+	_, err = ConvertMessages(context.Background(), ConvertMessagesInput{
+		Format:          model.FormatAnthropic,
+		DeveloperPrompt: "Think step by step.",
+	})
+	assert.Error(t, err)
+}
+
+func TestOpenAIConverter_Convert_ToolResultRoundTrip(t *testing.T) {
+	converter := &OpenAIConverter{}
+
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "What's the weather in Paris and Rome?"}}, nil),
+		createTestMessage("assistant", []model.Part{
+			{Type: "tool-call", Meta: map[string]any{"id": "call_1", "name": "get_weather", "arguments": `{"city":"Paris"}`}},
+			{Type: "tool-call", Meta: map[string]any{"id": "call_2", "name": "get_weather", "arguments": `{"city":"Rome"}`}},
+		}, nil),
+		createTestMessage("user", []model.Part{
+			{Type: "tool-result", Meta: map[string]any{"tool_call_id": "call_1", "content": "Sunny"}},
+			{Type: "tool-result", Meta: map[string]any{"tool_call_id": "call_2", "content": map[string]any{"temp": 21}}},
+			{Type: "text", Text: "Which one is warmer?"},
+		}, nil),
+	}
+
+	result, err := converter.Convert(context.Background(), messages, nil)
+	require.NoError(t, err)
+
+	out, err := json.Marshal(result)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"role":"user","content":"What's the weather in Paris and Rome?"},
+		{"role":"assistant","tool_calls":[
+			{"type":"function","id":"call_1","function":{"name":"get_weather","arguments":"{\"city\":\"Paris\"}"}},
+			{"type":"function","id":"call_2","function":{"name":"get_weather","arguments":"{\"city\":\"Rome\"}"}}
+		]},
+		{"role":"tool","tool_call_id":"call_1","content":"Sunny"},
+		{"role":"tool","tool_call_id":"call_2","content":"{\"temp\":21}"},
+		{"role":"user","content":"Which one is warmer?"}
+	]`, string(out))
+}
+
+func TestOpenAIConverter_Convert_MessageName(t *testing.T) {
+	converter := &OpenAIConverter{}
+
+	messages := []model.Message{
+		createTestMessage("system", []model.Part{{Type: "text", Text: "Coordinate the agents."}}, map[string]any{"name": "orchestrator"}),
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Plan a trip"}}, map[string]any{"name": "alice"}),
+		createTestMessage("assistant", []model.Part{{Type: "text", Text: "Day 1: Paris"}}, map[string]any{"name": "planner"}),
+		createTestMessage("assistant", []model.Part{{Type: "text", Text: "Looks good"}}, nil),
+	}
+
+	result, err := converter.Convert(context.Background(), messages, nil)
+	require.NoError(t, err)
+
+	out, err := json.Marshal(result)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"role":"system","name":"orchestrator","content":"Coordinate the agents."},
+		{"role":"user","name":"alice","content":"Plan a trip"},
+		{"role":"assistant","name":"planner","content":"Day 1: Paris"},
+		{"role":"assistant","content":"Looks good"}
+	]`, string(out))
+}
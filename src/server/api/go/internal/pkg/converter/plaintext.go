@@ -0,0 +1,102 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+)
+
+// PlainTextOptions controls how FormatPlainText renders a transcript. Empty
+// labels and separator fall back to DefaultPlainTextOptions; the zero value
+// gives a minimal transcript suited to embeddings.
+type PlainTextOptions struct {
+	// RoleLabels maps a message role to the label written before its content
+	RoleLabels map[string]string
+	// Separator is written between messages
+	Separator string
+	// IncludeTimestamps prefixes each message with its creation time (RFC 3339)
+	IncludeTimestamps bool
+	// IncludeToolCalls renders tool calls and tool results instead of omitting them
+	IncludeToolCalls bool
+}
+
+// DefaultPlainTextOptions is used when no options are provided
+var DefaultPlainTextOptions = PlainTextOptions{
+	RoleLabels: map[string]string{
+		"user":      "User",
+		"assistant": "Assistant",
+		"system":    "System",
+	},
+	Separator: "\n",
+}
+
+// PlainTextConverter renders messages into a human-readable transcript with one
+// "Label: content" entry per message
+type PlainTextConverter struct {
+	Options *PlainTextOptions
+}
+
+func (c *PlainTextConverter) Convert(ctx context.Context, messages []model.Message, publicURLs map[string]service.PublicURL) (interface{}, error) {
+	opts := DefaultPlainTextOptions
+	if c.Options != nil {
+		opts = *c.Options
+		if opts.Separator == "" {
+			opts.Separator = DefaultPlainTextOptions.Separator
+		}
+	}
+
+	entries := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		content := c.renderParts(msg.Parts, opts)
+		if content == "" {
+			continue
+		}
+
+		entry := c.label(msg.Role, opts) + ": " + content
+		if opts.IncludeTimestamps && !msg.CreatedAt.IsZero() {
+			entry = "[" + msg.CreatedAt.UTC().Format(time.RFC3339) + "] " + entry
+		}
+		entries = append(entries, entry)
+	}
+
+	return strings.Join(entries, opts.Separator), nil
+}
+
+func (c *PlainTextConverter) label(role string, opts PlainTextOptions) string {
+	if label, ok := opts.RoleLabels[role]; ok && label != "" {
+		return label
+	}
+	if label, ok := DefaultPlainTextOptions.RoleLabels[role]; ok {
+		return label
+	}
+	return role
+}
+
+func (c *PlainTextConverter) renderParts(parts []model.Part, opts PlainTextOptions) string {
+	rendered := make([]string, 0, len(parts))
+	for _, part := range parts {
+		switch part.Type {
+		case "text", "refusal":
+			if part.Text != "" {
+				rendered = append(rendered, part.Text)
+			}
+		case "image":
+			rendered = append(rendered, "[image]")
+		case "tool-call":
+			if opts.IncludeToolCalls && part.Meta != nil {
+				name, _ := part.Meta["name"].(string)
+				arguments, _ := part.Meta["arguments"].(string)
+				rendered = append(rendered, fmt.Sprintf("[tool call: %s(%s)]", name, arguments))
+			}
+		case "tool-result":
+			if opts.IncludeToolCalls {
+				rendered = append(rendered, fmt.Sprintf("[tool result: %s]", part.Text))
+			}
+		}
+	}
+	return strings.Join(rendered, "\n")
+}
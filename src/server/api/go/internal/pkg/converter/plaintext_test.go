@@ -0,0 +1,65 @@
+package converter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlainTextConverter_Convert(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "What's the weather?"}}, nil),
+		createTestMessage("assistant", []model.Part{
+			{Type: "tool-call", Meta: map[string]any{"id": "call_1", "name": "weather", "arguments": `{"city":"Paris"}`}},
+		}, nil),
+		createTestMessage("user", []model.Part{
+			{Type: "tool-result", Text: "sunny", Meta: map[string]any{"tool_call_id": "call_1"}},
+		}, nil),
+		createTestMessage("assistant", []model.Part{{Type: "text", Text: "It is sunny."}}, nil),
+	}
+
+	t.Run("defaults omit tool traffic", func(t *testing.T) {
+		result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages: messages,
+			Format:   model.FormatPlainText,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "User: What's the weather?\nAssistant: It is sunny.", result)
+	})
+
+	t.Run("custom labels and separator", func(t *testing.T) {
+		result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages: messages,
+			Format:   model.FormatPlainText,
+			PlainTextOptions: &PlainTextOptions{
+				RoleLabels:       map[string]string{"user": "Q", "assistant": "A"},
+				Separator:        "\n---\n",
+				IncludeToolCalls: true,
+			},
+		})
+		require.NoError(t, err)
+
+		expected := "Q: What's the weather?\n---\n" +
+			`A: [tool call: weather({"city":"Paris"})]` + "\n---\n" +
+			"Q: [tool result: sunny]\n---\n" +
+			"A: It is sunny."
+		assert.Equal(t, expected, result)
+	})
+
+	t.Run("timestamps", func(t *testing.T) {
+		msg := createTestMessage("user", []model.Part{{Type: "text", Text: "Hi"}}, nil)
+		msg.CreatedAt = time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+		result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages:         []model.Message{msg},
+			Format:           model.FormatPlainText,
+			PlainTextOptions: &PlainTextOptions{IncludeTimestamps: true},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "[2025-01-02T03:04:05Z] User: Hi", result)
+	})
+}
@@ -0,0 +1,105 @@
+package converter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+)
+
+// MessageMetaProvider is the message meta key naming the provider (a MessageFormat)
+// that produced the message. Messages produced by the target provider are rendered
+// natively: their tool-call ids are sent exactly as stored.
+const MessageMetaProvider = "provider"
+
+const openAIMaxToolCallIDLength = 40
+
+var anthropicToolCallIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// normalizeForeignToolCallIDs rewrites tool-call ids the target provider would
+// reject, for messages that were not produced by that provider, and rewrites the
+// tool results that reference them to match. Input messages are not modified.
+func normalizeForeignToolCallIDs(messages []model.Message, format model.MessageFormat) []model.Message {
+	var valid func(id string) bool
+	var prefix string
+	switch format {
+	case model.FormatOpenAI, model.FormatAzureOpenAI:
+		valid = func(id string) bool { return len(id) <= openAIMaxToolCallIDLength }
+		prefix = "call_"
+	case model.FormatAnthropic:
+		valid = anthropicToolCallIDPattern.MatchString
+		prefix = "toolu_"
+	default:
+		return messages
+	}
+
+	renamed := make(map[string]string)
+	for _, msg := range messages {
+		if isNativeMessage(msg, format) {
+			continue
+		}
+		for _, part := range msg.Parts {
+			if part.Type != "tool-call" {
+				continue
+			}
+			if id, _ := part.Meta["id"].(string); id != "" && !valid(id) {
+				sum := sha256.Sum256([]byte(id))
+				renamed[id] = prefix + hex.EncodeToString(sum[:])[:generatedToolCallIDLength]
+			}
+		}
+	}
+	if len(renamed) == 0 {
+		return messages
+	}
+
+	result := make([]model.Message, len(messages))
+	for i, msg := range messages {
+		result[i] = msg
+		native := isNativeMessage(msg, format)
+
+		var parts []model.Part
+		for j, part := range msg.Parts {
+			var key string
+			switch {
+			case part.Type == "tool-call" && !native:
+				key = "id"
+			case part.Type == "tool-result":
+				key = "tool_call_id"
+			default:
+				continue
+			}
+			id, _ := part.Meta[key].(string)
+			newID, ok := renamed[id]
+			if !ok {
+				continue
+			}
+
+			if parts == nil {
+				parts = append([]model.Part(nil), msg.Parts...)
+			}
+			meta := make(map[string]any, len(part.Meta))
+			for k, v := range part.Meta {
+				meta[k] = v
+			}
+			meta[key] = newID
+			parts[j].Meta = meta
+		}
+
+		if parts != nil {
+			result[i].Parts = parts
+		}
+	}
+	return result
+}
+
+func isNativeMessage(msg model.Message, format model.MessageFormat) bool {
+	provider, _ := msg.Meta.Data()[MessageMetaProvider].(string)
+	if provider == "" {
+		return false
+	}
+	if format == model.FormatAzureOpenAI {
+		return provider == string(model.FormatOpenAI) || provider == string(model.FormatAzureOpenAI)
+	}
+	return provider == string(format)
+}
@@ -0,0 +1,69 @@
+package converter
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	openai "github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertMessages_NativeProviderKeepsToolCallID(t *testing.T) {
+	longID := "call_" + strings.Repeat("a", 60)
+	toolCall := func(id string) model.Part {
+		return model.Part{Type: "tool-call", Meta: map[string]any{"id": id, "name": "lookup", "arguments": "{}"}}
+	}
+	toolResult := func(id string) model.Part {
+		return model.Part{Type: "tool-result", Text: "ok", Meta: map[string]any{"tool_call_id": id}}
+	}
+
+	messages := []model.Message{
+		createTestMessage("assistant", []model.Part{toolCall(longID)}, map[string]any{MessageMetaProvider: "openai"}),
+		createTestMessage("user", []model.Part{toolResult(longID)}, nil),
+		createTestMessage("assistant", []model.Part{toolCall(longID + "x")}, map[string]any{MessageMetaProvider: "anthropic"}),
+		createTestMessage("user", []model.Part{toolResult(longID + "x")}, nil),
+	}
+
+	result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+		Messages: messages,
+		Format:   model.FormatOpenAI,
+	})
+	require.NoError(t, err)
+	msgs := result.([]openai.ChatCompletionMessageParamUnion)
+	require.Len(t, msgs, 4)
+
+	// Produced by OpenAI: kept verbatim
+	assert.Equal(t, longID, msgs[0].OfAssistant.ToolCalls[0].OfFunction.ID)
+	assert.Equal(t, longID, msgs[1].OfTool.ToolCallID)
+
+	// Produced elsewhere: rewritten to an OpenAI-acceptable id, result follows
+	foreign := msgs[2].OfAssistant.ToolCalls[0].OfFunction.ID
+	assert.True(t, strings.HasPrefix(foreign, "call_"))
+	assert.LessOrEqual(t, len(foreign), openAIMaxToolCallIDLength)
+	assert.Equal(t, foreign, msgs[3].OfTool.ToolCallID)
+}
+
+func TestConvertMessages_AnthropicForeignToolCallID(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("assistant", []model.Part{
+			{Type: "tool-call", Meta: map[string]any{"id": "fc.123:abc", "name": "lookup", "arguments": "{}"}},
+		}, map[string]any{MessageMetaProvider: "openai"}),
+		createTestMessage("user", []model.Part{
+			{Type: "tool-result", Text: "ok", Meta: map[string]any{"tool_call_id": "fc.123:abc"}},
+		}, nil),
+	}
+
+	result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+		Messages: messages,
+		Format:   model.FormatAnthropic,
+	})
+	require.NoError(t, err)
+	msgs := result.(AnthropicMessages).Messages
+
+	id := msgs[0].Content[0].OfToolUse.ID
+	assert.True(t, anthropicToolCallIDPattern.MatchString(id))
+	assert.Equal(t, id, msgs[1].Content[0].OfToolResult.ToolUseID)
+}
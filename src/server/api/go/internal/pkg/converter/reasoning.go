@@ -0,0 +1,24 @@
+package converter
+
+import "github.com/memodb-io/Acontext/internal/modules/model"
+
+// PartTypeReasoning is a model's reasoning trace. Its Text is the reasoning
+// and Meta[PartMetaSignature] holds the provider signature, if any.
+const PartTypeReasoning = "reasoning"
+
+// PartMetaSignature is the part meta key holding an Anthropic thinking signature
+const PartMetaSignature = "signature"
+
+// isReasoningOnly reports whether msg carries reasoning and nothing else, a
+// turn that has no user-visible content to resend
+func isReasoningOnly(msg model.Message) bool {
+	if len(msg.Parts) == 0 {
+		return false
+	}
+	for _, part := range msg.Parts {
+		if part.Type != PartTypeReasoning {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,83 @@
+package converter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	openai "github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+)
+
+func reasoningOnlyConversation(signature string) []model.Message {
+	meta := map[string]any{}
+	if signature != "" {
+		meta[PartMetaSignature] = signature
+	}
+	return []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "What is 2+2?"}}, nil),
+		createTestMessage("assistant", []model.Part{{Type: PartTypeReasoning, Text: "Adding two and two.", Meta: meta}}, nil),
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Go on"}}, nil),
+	}
+}
+
+func TestConvertMessages_ReasoningOnlyTurn(t *testing.T) {
+	t.Run("openai drops the turn", func(t *testing.T) {
+		for _, policy := range []EmptyAssistantPolicy{EmptyAssistantDrop, EmptyAssistantEmptyContent} {
+			result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+				Messages:       reasoningOnlyConversation("sig"),
+				Format:         model.FormatOpenAI,
+				EmptyAssistant: policy,
+			})
+			require.NoError(t, err)
+
+			msgs := result.([]openai.ChatCompletionMessageParamUnion)
+			require.Len(t, msgs, 2)
+			assert.NotNil(t, msgs[0].OfUser)
+			assert.NotNil(t, msgs[1].OfUser)
+		}
+	})
+
+	t.Run("anthropic keeps a signed turn as thinking", func(t *testing.T) {
+		result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages: reasoningOnlyConversation("sig-123"),
+			Format:   model.FormatAnthropic,
+		})
+		require.NoError(t, err)
+
+		msgs := result.(AnthropicMessages).Messages
+		require.Len(t, msgs, 3)
+		assert.Equal(t, anthropic.MessageParamRoleAssistant, msgs[1].Role)
+		require.Len(t, msgs[1].Content, 1)
+		thinking := msgs[1].Content[0].OfThinking
+		require.NotNil(t, thinking)
+		assert.Equal(t, "Adding two and two.", thinking.Thinking)
+		assert.Equal(t, "sig-123", thinking.Signature)
+	})
+
+	t.Run("anthropic drops an unsigned turn", func(t *testing.T) {
+		result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages: reasoningOnlyConversation(""),
+			Format:   model.FormatAnthropic,
+		})
+		require.NoError(t, err)
+
+		msgs := result.(AnthropicMessages).Messages
+		require.Len(t, msgs, 2)
+		for _, msg := range msgs {
+			assert.NotEmpty(t, msg.Content)
+		}
+	})
+
+	t.Run("completion omits the turn", func(t *testing.T) {
+		result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages: reasoningOnlyConversation("sig"),
+			Format:   model.FormatCompletion,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "### Human: What is 2+2?\n\n### Human: Go on\n\n### Assistant:", result)
+	})
+}
@@ -0,0 +1,290 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+)
+
+const (
+	ResponseFormatJSONSchema = "json_schema"
+	ResponseFormatJSONObject = "json_object"
+
+	// defaultResponseFormatName names the forced tool when ResponseFormat.Name is empty
+	defaultResponseFormatName = "structured_output"
+)
+
+// Tool choice modes
+const (
+	ToolChoiceAuto     = "auto"
+	ToolChoiceNone     = "none"
+	ToolChoiceRequired = "required"
+	// ToolChoiceFunction forces the tool named by ToolChoice.Name
+	ToolChoiceFunction = "function"
+)
+
+// ToolChoice controls whether and which tool the model must call
+type ToolChoice struct {
+	Mode string
+	Name string
+}
+
+// RequestDefaults are values BuildRequest uses for a format when the input and
+// ExtraParams leave them unset
+type RequestDefaults struct {
+	MaxTokens int
+	Stop      []string
+}
+
+// ErrMaxTokensRequired is returned by BuildRequest for Anthropic, which rejects
+// requests without max_tokens, when neither MaxTokens nor a default is set
+var ErrMaxTokensRequired = errors.New("max_tokens is required for anthropic and has no default")
+
+// generationKeys names the output limit and stop sequence fields of each
+// provider's request
+var generationKeys = map[model.MessageFormat]struct{ maxTokens, stop string }{
+	model.FormatOpenAI:      {maxTokens: "max_completion_tokens", stop: "stop"},
+	model.FormatAzureOpenAI: {maxTokens: "max_completion_tokens", stop: "stop"},
+	model.FormatAnthropic:   {maxTokens: "max_tokens", stop: "stop_sequences"},
+	model.FormatCohere:      {maxTokens: "max_tokens", stop: "stop_sequences"},
+}
+
+// ResponseFormat describes the structured output expected from the model
+type ResponseFormat struct {
+	// Type is ResponseFormatJSONSchema (default) or ResponseFormatJSONObject
+	Type        string
+	Name        string
+	Description string
+	Schema      map[string]any
+	Strict      bool
+}
+
+// BuildRequest converts the messages and places request-level options where
+// the target provider expects them. The result is a request body fragment
+// holding "messages" (with "system" for Anthropic; for Gemini, "contents" and
+// "systemInstruction"; for Cohere, "message", "chat_history" and "tool_results")
+// plus any provider fields; model and sampling parameters other than the output
+// limit and stop sequences are left to the caller.
+func BuildRequest(ctx context.Context, input ConvertMessagesInput) (map[string]any, error) {
+	format := input.Format
+	if format == "" {
+		format = model.FormatAcontext
+	}
+	input.Format = format
+
+	messages, err := ConvertMessages(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	request := map[string]any{
+		"messages": messages,
+	}
+	if gemini, ok := messages.(GeminiMessages); ok {
+		// Gemini names the conversation contents, with the system prompt beside it
+		request = map[string]any{"contents": gemini.Contents}
+		if gemini.SystemInstruction != nil {
+			request["systemInstruction"] = gemini.SystemInstruction
+		}
+	}
+	if anthropic, ok := messages.(AnthropicMessages); ok {
+		// Anthropic takes the system prompt as a top-level field rather than a message
+		request = map[string]any{"messages": anthropic.Messages}
+		if anthropic.System != "" {
+			request["system"] = anthropic.System
+		}
+	}
+	if cohere, ok := messages.(CohereMessages); ok {
+		// Cohere takes the final user turn apart from the history
+		request = map[string]any{"message": cohere.Message}
+		if len(cohere.ChatHistory) > 0 {
+			request["chat_history"] = cohere.ChatHistory
+		}
+		if len(cohere.ToolResults) > 0 {
+			request["tool_results"] = cohere.ToolResults
+		}
+	}
+
+	if format == model.FormatOpenAI && input.PromptCacheKey != "" {
+		request["prompt_cache_key"] = input.PromptCacheKey
+		request["user"] = input.PromptCacheKey
+	}
+
+	if input.DisableParallelToolCalls && (format == model.FormatOpenAI || format == model.FormatAzureOpenAI) {
+		request["parallel_tool_calls"] = false
+	}
+
+	if format == model.FormatAzureOpenAI && len(input.DataSources) > 0 {
+		request["data_sources"] = input.DataSources
+	}
+
+	if input.ToolChoice != nil {
+		if input.ResponseFormat != nil && format == model.FormatAnthropic {
+			return nil, fmt.Errorf("ToolChoice cannot be combined with ResponseFormat for %s, which forces a tool itself", format)
+		}
+		if err := applyToolChoice(request, format, input.ToolChoice); err != nil {
+			return nil, err
+		}
+	}
+
+	if input.ResponseFormat != nil {
+		if err := applyResponseFormat(request, format, input.ResponseFormat); err != nil {
+			return nil, err
+		}
+	}
+
+	if input.MaxTokens > 0 || len(input.Stop) > 0 {
+		if err := applyGeneration(request, format, RequestDefaults{MaxTokens: input.MaxTokens, Stop: input.Stop}); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(input.ExtraParams) > 0 {
+		if err := applyExtraParams(request, format, input.ExtraParams); err != nil {
+			return nil, err
+		}
+	}
+
+	if defaults, ok := input.RequestDefaults[format]; ok {
+		if err := applyGeneration(request, format, defaults); err != nil {
+			return nil, err
+		}
+	}
+	if format == model.FormatAnthropic {
+		if _, ok := request[generationKeys[format].maxTokens]; !ok {
+			return nil, ErrMaxTokensRequired
+		}
+	}
+
+	return request, nil
+}
+
+// applyGeneration sets the output limit and stop sequences of a provider
+// request, leaving any already set alone
+func applyGeneration(request map[string]any, format model.MessageFormat, values RequestDefaults) error {
+	keys, ok := generationKeys[format]
+	if !ok {
+		return fmt.Errorf("max tokens and stop sequences are not supported for format: %s", format)
+	}
+	if _, set := request[keys.maxTokens]; !set && values.MaxTokens > 0 {
+		request[keys.maxTokens] = values.MaxTokens
+	}
+	if _, set := request[keys.stop]; !set && len(values.Stop) > 0 {
+		request[keys.stop] = values.Stop
+	}
+	return nil
+}
+
+// reservedRequestKeys may never be set through ExtraParams
+var reservedRequestKeys = map[string]struct{}{
+	"messages": {},
+	"model":    {},
+}
+
+// applyExtraParams merges vendor-specific top-level parameters into an
+// OpenAI-compatible request. Reserved keys and keys already set by the builder
+// are rejected rather than overwritten.
+func applyExtraParams(request map[string]any, format model.MessageFormat, params map[string]any) error {
+	if format != model.FormatOpenAI && format != model.FormatAzureOpenAI {
+		return fmt.Errorf("extra params are not supported for format: %s", format)
+	}
+	for key := range params {
+		if _, ok := reservedRequestKeys[key]; ok {
+			return fmt.Errorf("extra param %q is reserved", key)
+		}
+		if _, ok := request[key]; ok {
+			return fmt.Errorf("extra param %q conflicts with a built request field", key)
+		}
+	}
+	for key, value := range params {
+		request[key] = value
+	}
+	return nil
+}
+
+// applyResponseFormat maps rf onto the provider's structured-output mechanism.
+// OpenAI takes response_format directly; Anthropic has none, so a single tool
+// carrying the schema is forced through tool_choice.
+func applyResponseFormat(request map[string]any, format model.MessageFormat, rf *ResponseFormat) error {
+	name := rf.Name
+	if name == "" {
+		name = defaultResponseFormatName
+	}
+
+	switch format {
+	case model.FormatOpenAI, model.FormatAzureOpenAI:
+		if rf.Type == ResponseFormatJSONObject {
+			request["response_format"] = map[string]any{"type": ResponseFormatJSONObject}
+			return nil
+		}
+		jsonSchema := map[string]any{
+			"name":   name,
+			"schema": rf.Schema,
+			"strict": rf.Strict,
+		}
+		if rf.Description != "" {
+			jsonSchema["description"] = rf.Description
+		}
+		request["response_format"] = map[string]any{
+			"type":        ResponseFormatJSONSchema,
+			"json_schema": jsonSchema,
+		}
+	case model.FormatAnthropic:
+		schema := rf.Schema
+		if schema == nil {
+			schema = map[string]any{"type": "object"}
+		}
+		tool := map[string]any{
+			"name":         name,
+			"input_schema": schema,
+		}
+		if rf.Description != "" {
+			tool["description"] = rf.Description
+		}
+		request["tools"] = []any{tool}
+		request["tool_choice"] = map[string]any{"type": "tool", "name": name}
+	default:
+		return fmt.Errorf("response format is not supported for format: %s", format)
+	}
+	return nil
+}
+
+// applyToolChoice maps tc onto the provider's tool_choice shape. Anthropic names
+// "required" as "any" and a forced function as a "tool".
+func applyToolChoice(request map[string]any, format model.MessageFormat, tc *ToolChoice) error {
+	switch tc.Mode {
+	case ToolChoiceAuto, ToolChoiceNone, ToolChoiceRequired:
+	case ToolChoiceFunction:
+		if tc.Name == "" {
+			return errors.New("tool choice function requires a name")
+		}
+	default:
+		return fmt.Errorf("unknown tool choice mode: %s", tc.Mode)
+	}
+
+	switch format {
+	case model.FormatOpenAI, model.FormatAzureOpenAI:
+		if tc.Mode == ToolChoiceFunction {
+			request["tool_choice"] = map[string]any{
+				"type":     "function",
+				"function": map[string]any{"name": tc.Name},
+			}
+		} else {
+			request["tool_choice"] = tc.Mode
+		}
+	case model.FormatAnthropic:
+		switch tc.Mode {
+		case ToolChoiceFunction:
+			request["tool_choice"] = map[string]any{"type": "tool", "name": tc.Name}
+		case ToolChoiceRequired:
+			request["tool_choice"] = map[string]any{"type": "any"}
+		default:
+			request["tool_choice"] = map[string]any{"type": tc.Mode}
+		}
+	default:
+		return fmt.Errorf("tool choice is not supported for format: %s", format)
+	}
+	return nil
+}
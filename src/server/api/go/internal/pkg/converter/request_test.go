@@ -0,0 +1,392 @@
+package converter
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	openai "github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"answer": map[string]any{"type": "string"},
+	},
+	"required": []any{"answer"},
+}
+
+func TestBuildRequest_OpenAIResponseFormat(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Hi"}}, nil),
+	}
+
+	request, err := BuildRequest(context.Background(), ConvertMessagesInput{
+		Messages: messages,
+		Format:   model.FormatOpenAI,
+		ResponseFormat: &ResponseFormat{
+			Name:   "answer",
+			Schema: testSchema,
+			Strict: true,
+		},
+	})
+	require.NoError(t, err)
+
+	out, err := json.Marshal(request)
+	require.NoError(t, err)
+
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(out, &decoded))
+
+	assert.Len(t, decoded["messages"], 1)
+	responseFormat := decoded["response_format"].(map[string]any)
+	assert.Equal(t, "json_schema", responseFormat["type"])
+	jsonSchema := responseFormat["json_schema"].(map[string]any)
+	assert.Equal(t, "answer", jsonSchema["name"])
+	assert.Equal(t, true, jsonSchema["strict"])
+	assert.Equal(t, "object", jsonSchema["schema"].(map[string]any)["type"])
+	assert.Contains(t, jsonSchema["schema"].(map[string]any)["properties"], "answer")
+}
+
+func TestBuildRequest_AnthropicToolForcing(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Hi"}}, nil),
+	}
+
+	request, err := BuildRequest(context.Background(), ConvertMessagesInput{
+		Messages:       messages,
+		Format:         model.FormatAnthropic,
+		MaxTokens:      1024,
+		ResponseFormat: &ResponseFormat{Schema: testSchema},
+	})
+	require.NoError(t, err)
+
+	tools := request["tools"].([]any)
+	require.Len(t, tools, 1)
+	tool := tools[0].(map[string]any)
+	assert.Equal(t, "structured_output", tool["name"])
+	assert.Equal(t, testSchema, tool["input_schema"])
+	assert.Equal(t, map[string]any{"type": "tool", "name": "structured_output"}, request["tool_choice"])
+	assert.NotContains(t, request, "response_format")
+}
+
+func TestBuildRequest_NoResponseFormat(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Hi"}}, nil),
+	}
+
+	request, err := BuildRequest(context.Background(), ConvertMessagesInput{
+		Messages: messages,
+		Format:   model.FormatOpenAI,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"messages"}, keys(request))
+}
+
+func TestBuildRequest_UnsupportedResponseFormat(t *testing.T) {
+	_, err := BuildRequest(context.Background(), ConvertMessagesInput{
+		Messages:       []model.Message{createTestMessage("user", []model.Part{{Type: "text", Text: "Hi"}}, nil)},
+		Format:         model.FormatCompletion,
+		ResponseFormat: &ResponseFormat{Schema: testSchema},
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "response format is not supported")
+}
+
+func keys(m map[string]any) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
+
+func TestBuildRequest_AzureDataSources(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "What does the handbook say?"}}, nil),
+	}
+	dataSources := []map[string]any{{
+		"type": "azure_search",
+		"parameters": map[string]any{
+			"endpoint":   "https://search.example.com",
+			"index_name": "handbook",
+		},
+	}}
+
+	request, err := BuildRequest(context.Background(), ConvertMessagesInput{
+		Messages:    messages,
+		Format:      model.FormatAzureOpenAI,
+		DataSources: dataSources,
+	})
+	require.NoError(t, err)
+
+	out, err := json.Marshal(request)
+	require.NoError(t, err)
+
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(out, &decoded))
+
+	msgs := decoded["messages"].([]any)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "user", msgs[0].(map[string]any)["role"])
+
+	sources := decoded["data_sources"].([]any)
+	require.Len(t, sources, 1)
+	assert.Equal(t, "azure_search", sources[0].(map[string]any)["type"])
+}
+
+func TestBuildRequest_DataSourcesIgnoredForOpenAI(t *testing.T) {
+	request, err := BuildRequest(context.Background(), ConvertMessagesInput{
+		Messages:    []model.Message{createTestMessage("user", []model.Part{{Type: "text", Text: "Hi"}}, nil)},
+		Format:      model.FormatOpenAI,
+		DataSources: []map[string]any{{"type": "azure_search"}},
+	})
+	require.NoError(t, err)
+	assert.NotContains(t, request, "data_sources")
+}
+
+func TestBuildRequest_PromptCacheKey(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Hi"}}, nil),
+	}
+
+	request, err := BuildRequest(context.Background(), ConvertMessagesInput{
+		Messages:       messages,
+		Format:         model.FormatOpenAI,
+		PromptCacheKey: "session-123",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "session-123", request["prompt_cache_key"])
+	assert.Equal(t, "session-123", request["user"])
+
+	request, err = BuildRequest(context.Background(), ConvertMessagesInput{
+		Messages:       messages,
+		Format:         model.FormatAnthropic,
+		MaxTokens:      1024,
+		PromptCacheKey: "session-123",
+	})
+	require.NoError(t, err)
+	assert.NotContains(t, request, "prompt_cache_key")
+	assert.NotContains(t, request, "user")
+}
+
+func TestBuildRequest_DisableParallelToolCalls(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Check both"}}, nil),
+		createTestMessage("assistant", []model.Part{
+			{Type: "tool-call", Meta: map[string]any{"id": "call_1", "name": "first", "arguments": "{}"}},
+			{Type: "tool-call", Meta: map[string]any{"id": "call_2", "name": "second", "arguments": "{}"}},
+		}, nil),
+	}
+
+	request, err := BuildRequest(context.Background(), ConvertMessagesInput{
+		Messages:                 messages,
+		Format:                   model.FormatOpenAI,
+		DisableParallelToolCalls: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, false, request["parallel_tool_calls"])
+
+	// Stored parallel calls are kept as separate calls
+	msgs := request["messages"].([]openai.ChatCompletionMessageParamUnion)
+	assert.Len(t, msgs[1].OfAssistant.ToolCalls, 2)
+
+	request, err = BuildRequest(context.Background(), ConvertMessagesInput{Messages: messages, Format: model.FormatOpenAI})
+	require.NoError(t, err)
+	assert.NotContains(t, request, "parallel_tool_calls")
+
+	request, err = BuildRequest(context.Background(), ConvertMessagesInput{
+		Messages:                 messages,
+		Format:                   model.FormatAnthropic,
+		MaxTokens:                1024,
+		DisableParallelToolCalls: true,
+	})
+	require.NoError(t, err)
+	assert.NotContains(t, request, "parallel_tool_calls")
+}
+
+func TestBuildRequest_ToolChoice(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "What's the weather?"}}, nil),
+	}
+	forced := &ToolChoice{Mode: ToolChoiceFunction, Name: "get_weather"}
+
+	t.Run("openai specific function", func(t *testing.T) {
+		request, err := BuildRequest(context.Background(), ConvertMessagesInput{Messages: messages, Format: model.FormatOpenAI, ToolChoice: forced})
+		require.NoError(t, err)
+
+		out, err := json.Marshal(request["tool_choice"])
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"type":"function","function":{"name":"get_weather"}}`, string(out))
+	})
+
+	t.Run("anthropic specific function", func(t *testing.T) {
+		request, err := BuildRequest(context.Background(), ConvertMessagesInput{Messages: messages, Format: model.FormatAnthropic, MaxTokens: 1024, ToolChoice: forced})
+		require.NoError(t, err)
+
+		out, err := json.Marshal(request["tool_choice"])
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"type":"tool","name":"get_weather"}`, string(out))
+	})
+
+	t.Run("modes", func(t *testing.T) {
+		request, err := BuildRequest(context.Background(), ConvertMessagesInput{Messages: messages, Format: model.FormatOpenAI, ToolChoice: &ToolChoice{Mode: ToolChoiceNone}})
+		require.NoError(t, err)
+		assert.Equal(t, "none", request["tool_choice"])
+
+		request, err = BuildRequest(context.Background(), ConvertMessagesInput{Messages: messages, Format: model.FormatAnthropic, MaxTokens: 1024, ToolChoice: &ToolChoice{Mode: ToolChoiceRequired}})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"type": "any"}, request["tool_choice"])
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := BuildRequest(context.Background(), ConvertMessagesInput{Messages: messages, Format: model.FormatOpenAI, ToolChoice: &ToolChoice{Mode: ToolChoiceFunction}})
+		assert.Error(t, err)
+
+		_, err = BuildRequest(context.Background(), ConvertMessagesInput{Messages: messages, Format: model.FormatCompletion, ToolChoice: &ToolChoice{Mode: ToolChoiceAuto}})
+		assert.Error(t, err)
+
+		_, err = BuildRequest(context.Background(), ConvertMessagesInput{
+			Messages:       messages,
+			Format:         model.FormatAnthropic,
+			ToolChoice:     forced,
+			ResponseFormat: &ResponseFormat{Schema: testSchema},
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestBuildRequest_ExtraParams(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Hi"}}, nil),
+	}
+
+	request, err := BuildRequest(context.Background(), ConvertMessagesInput{
+		Messages: messages,
+		Format:   model.FormatOpenAI,
+		ExtraParams: map[string]any{
+			"reasoning_effort":      "low",
+			"service_tier":          "flex",
+			"max_completion_tokens": 256,
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "low", request["reasoning_effort"])
+	assert.Equal(t, "flex", request["service_tier"])
+	assert.Equal(t, 256, request["max_completion_tokens"])
+	assert.Len(t, request["messages"], 1)
+
+	for _, key := range []string{"messages", "model"} {
+		_, err := BuildRequest(context.Background(), ConvertMessagesInput{
+			Messages:    messages,
+			Format:      model.FormatOpenAI,
+			ExtraParams: map[string]any{key: "x"},
+		})
+		assert.ErrorContains(t, err, "reserved", key)
+	}
+
+	_, err = BuildRequest(context.Background(), ConvertMessagesInput{
+		Messages:                 messages,
+		Format:                   model.FormatOpenAI,
+		DisableParallelToolCalls: true,
+		ExtraParams:              map[string]any{"parallel_tool_calls": true},
+	})
+	assert.Error(t, err)
+
+	_, err = BuildRequest(context.Background(), ConvertMessagesInput{
+		Messages:    messages,
+		Format:      model.FormatAnthropic,
+		ExtraParams: map[string]any{"service_tier": "auto"},
+	})
+	assert.Error(t, err)
+}
+
+func TestBuildRequest_GenerationDefaults(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Hi"}}, nil),
+	}
+	defaults := map[model.MessageFormat]RequestDefaults{
+		model.FormatAnthropic: {MaxTokens: 4096, Stop: []string{"END"}},
+		model.FormatOpenAI:    {Stop: []string{"END"}},
+	}
+
+	t.Run("anthropic default", func(t *testing.T) {
+		request, err := BuildRequest(context.Background(), ConvertMessagesInput{
+			Messages:        messages,
+			Format:          model.FormatAnthropic,
+			RequestDefaults: defaults,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 4096, request["max_tokens"])
+		assert.Equal(t, []string{"END"}, request["stop_sequences"])
+	})
+
+	t.Run("explicit values win", func(t *testing.T) {
+		request, err := BuildRequest(context.Background(), ConvertMessagesInput{
+			Messages:        messages,
+			Format:          model.FormatAnthropic,
+			MaxTokens:       256,
+			Stop:            []string{"STOP"},
+			RequestDefaults: defaults,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 256, request["max_tokens"])
+		assert.Equal(t, []string{"STOP"}, request["stop_sequences"])
+	})
+
+	t.Run("anthropic without max tokens", func(t *testing.T) {
+		_, err := BuildRequest(context.Background(), ConvertMessagesInput{
+			Messages:        messages,
+			Format:          model.FormatAnthropic,
+			RequestDefaults: map[model.MessageFormat]RequestDefaults{model.FormatOpenAI: {MaxTokens: 4096}},
+		})
+		assert.ErrorIs(t, err, ErrMaxTokensRequired)
+	})
+
+	t.Run("openai needs no max tokens", func(t *testing.T) {
+		request, err := BuildRequest(context.Background(), ConvertMessagesInput{
+			Messages:        messages,
+			Format:          model.FormatOpenAI,
+			RequestDefaults: defaults,
+			ExtraParams:     map[string]any{"stop": []string{"EXTRA"}},
+		})
+		require.NoError(t, err)
+		assert.NotContains(t, request, "max_completion_tokens")
+		assert.Equal(t, []string{"EXTRA"}, request["stop"])
+	})
+}
+
+func TestBuildRequest_AnthropicSystem(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("system", []model.Part{{Type: "text", Text: "You are helpful."}}, nil),
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Hi"}}, nil),
+		createTestMessage("system", []model.Part{{Type: "text", Text: "Be concise."}}, nil),
+	}
+
+	request, err := BuildRequest(context.Background(), ConvertMessagesInput{
+		Messages:  messages,
+		Format:    model.FormatAnthropic,
+		MaxTokens: 1024,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "You are helpful.\n\nBe concise.", request["system"])
+	msgs := request["messages"].([]anthropic.MessageParam)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, anthropic.MessageParamRoleUser, msgs[0].Role)
+
+	t.Run("system as user", func(t *testing.T) {
+		request, err := BuildRequest(context.Background(), ConvertMessagesInput{
+			Messages:     messages,
+			Format:       model.FormatAnthropic,
+			MaxTokens:    1024,
+			SystemAsUser: true,
+		})
+		require.NoError(t, err)
+		assert.NotContains(t, request, "system")
+	})
+}
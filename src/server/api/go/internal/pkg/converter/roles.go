@@ -0,0 +1,70 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+)
+
+// ErrUnknownRole is returned when a message role, after RoleMap is applied, is
+// not one the converters understand
+var ErrUnknownRole = errors.New("unknown message role")
+
+// knownRoles are the roles every converter handles
+var knownRoles = map[string]struct{}{
+	"user":      {},
+	"assistant": {},
+	"system":    {},
+}
+
+// DefaultRoleMap renames the nonstandard roles some upstreams send (OpenAI's
+// developer, LangChain's human and ai, tool and function results) before
+// conversion. Entries of ConvertMessagesInput.RoleMap take precedence.
+var DefaultRoleMap = map[string]string{
+	"developer": "system",
+	"human":     "user",
+	"ai":        "assistant",
+	"tool":      "user",
+	"function":  "user",
+}
+
+// validateRoleMap checks that every role is mapped to a known one
+func validateRoleMap(roleMap map[string]string) error {
+	for from, to := range roleMap {
+		if _, ok := knownRoles[to]; !ok {
+			return fmt.Errorf("RoleMap maps %q to %q, which is not one of user, assistant, system", from, to)
+		}
+	}
+	return nil
+}
+
+// mapRoles renames message roles through roleMap, falling back to
+// DefaultRoleMap, and fails on any role left unknown. messages is not modified.
+func mapRoles(messages []model.Message, roleMap map[string]string) ([]model.Message, error) {
+	var out []model.Message
+	for i, msg := range messages {
+		role, ok := roleMap[msg.Role]
+		if !ok {
+			role, ok = DefaultRoleMap[msg.Role]
+		}
+		if !ok {
+			role = msg.Role
+		}
+		if _, known := knownRoles[role]; !known {
+			return nil, fmt.Errorf("%w %q at message %d", ErrUnknownRole, msg.Role, i)
+		}
+		if role == msg.Role {
+			continue
+		}
+		if out == nil {
+			out = make([]model.Message, len(messages))
+			copy(out, messages)
+		}
+		out[i].Role = role
+	}
+	if out == nil {
+		return messages, nil
+	}
+	return out, nil
+}
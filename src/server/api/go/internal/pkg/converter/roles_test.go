@@ -0,0 +1,89 @@
+package converter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	openai "github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertMessages_RoleMap(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("developer", []model.Part{{Type: "text", Text: "Answer in French."}}, nil),
+		createTestMessage("human", []model.Part{{Type: "text", Text: "Hello"}}, nil),
+		createTestMessage("bot", []model.Part{{Type: "text", Text: "Bonjour"}}, nil),
+	}
+
+	result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+		Messages: messages,
+		Format:   model.FormatOpenAI,
+		RoleMap:  map[string]string{"bot": "assistant"},
+	})
+	require.NoError(t, err)
+
+	msgs := result.([]openai.ChatCompletionMessageParamUnion)
+	require.Len(t, msgs, 3)
+	require.NotNil(t, msgs[0].OfSystem, "developer maps to system by default")
+	assert.Equal(t, "Answer in French.", msgs[0].OfSystem.Content.OfString.Value)
+	require.NotNil(t, msgs[1].OfUser)
+	require.NotNil(t, msgs[2].OfAssistant)
+
+	// The caller's messages keep their roles
+	assert.Equal(t, "developer", messages[0].Role)
+}
+
+func TestConvertMessages_RoleMapOverridesDefault(t *testing.T) {
+	result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+		Messages: []model.Message{createTestMessage("developer", []model.Part{{Type: "text", Text: "Hi"}}, nil)},
+		Format:   model.FormatOpenAI,
+		RoleMap:  map[string]string{"developer": "user"},
+	})
+	require.NoError(t, err)
+
+	msgs := result.([]openai.ChatCompletionMessageParamUnion)
+	require.Len(t, msgs, 1)
+	assert.NotNil(t, msgs[0].OfUser)
+}
+
+func TestConvertMessages_UnknownRole(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Hi"}}, nil),
+		createTestMessage("narrator", []model.Part{{Type: "text", Text: "Meanwhile"}}, nil),
+	}
+
+	for _, format := range []model.MessageFormat{model.FormatOpenAI, model.FormatAnthropic, model.FormatGemini} {
+		t.Run(string(format), func(t *testing.T) {
+			_, err := ConvertMessages(context.Background(), ConvertMessagesInput{Messages: messages, Format: format})
+			require.ErrorIs(t, err, ErrUnknownRole)
+			assert.Contains(t, err.Error(), `"narrator" at message 1`)
+		})
+	}
+}
+
+func TestConvertMessages_InvalidRoleMap(t *testing.T) {
+	_, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+		Messages: []model.Message{createTestMessage("user", []model.Part{{Type: "text", Text: "Hi"}}, nil)},
+		Format:   model.FormatOpenAI,
+		RoleMap:  map[string]string{"persona": "character"},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `"persona" to "character"`)
+}
+
+func TestBuildRequest_RoleMapAnthropicSystem(t *testing.T) {
+	request, err := BuildRequest(context.Background(), ConvertMessagesInput{
+		Messages: []model.Message{
+			createTestMessage("developer", []model.Part{{Type: "text", Text: "Be brief."}}, nil),
+			createTestMessage("human", []model.Part{{Type: "text", Text: "Hi"}}, nil),
+		},
+		Format:    model.FormatAnthropic,
+		MaxTokens: 100,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Be brief.", request["system"])
+	assert.Len(t, request["messages"], 1)
+}
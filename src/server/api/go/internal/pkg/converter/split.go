@@ -0,0 +1,53 @@
+package converter
+
+import "github.com/memodb-io/Acontext/internal/modules/model"
+
+// splitMixedTurns breaks up messages whose parts the target provider rejects in
+// a single turn, keeping part order within each piece:
+//   - OpenAI: an assistant message with tool calls and other content becomes an
+//     assistant message with the content followed by one with only the tool calls
+//   - Anthropic: a user message with tool results and other content becomes a
+//     message with only the tool results followed by one with the rest
+//
+// Other formats and messages are returned unchanged. Input messages are not modified.
+func splitMixedTurns(messages []model.Message, format model.MessageFormat) []model.Message {
+	var role, partType string
+	switch format {
+	case model.FormatOpenAI, model.FormatAzureOpenAI:
+		role, partType = "assistant", "tool-call"
+	case model.FormatAnthropic:
+		role, partType = "user", "tool-result"
+	default:
+		return messages
+	}
+
+	result := make([]model.Message, 0, len(messages))
+	for _, msg := range messages {
+		if msg.Role != role {
+			result = append(result, msg)
+			continue
+		}
+
+		var matched, rest []model.Part
+		for _, part := range msg.Parts {
+			if part.Type == partType {
+				matched = append(matched, part)
+			} else {
+				rest = append(rest, part)
+			}
+		}
+		if len(matched) == 0 || len(rest) == 0 {
+			result = append(result, msg)
+			continue
+		}
+
+		first, second := msg, msg
+		if role == "assistant" {
+			first.Parts, second.Parts = rest, matched
+		} else {
+			first.Parts, second.Parts = matched, rest
+		}
+		result = append(result, first, second)
+	}
+	return result
+}
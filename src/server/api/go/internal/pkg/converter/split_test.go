@@ -0,0 +1,84 @@
+package converter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	openai "github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertMessages_StrictTurns_OpenAIAssistantSplit(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Weather?"}}, nil),
+		createTestMessage("assistant", []model.Part{
+			{Type: "text", Text: "Let me check."},
+			{Type: "tool-call", Meta: map[string]any{
+				"id":        "call_1",
+				"name":      "get_weather",
+				"arguments": `{"city":"Paris"}`,
+			}},
+		}, nil),
+	}
+
+	t.Run("split", func(t *testing.T) {
+		result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages:    messages,
+			Format:      model.FormatOpenAI,
+			StrictTurns: true,
+		})
+		require.NoError(t, err)
+
+		msgs := result.([]openai.ChatCompletionMessageParamUnion)
+		require.Len(t, msgs, 3)
+
+		text := msgs[1].OfAssistant
+		require.NotNil(t, text)
+		assert.Empty(t, text.ToolCalls)
+		assert.Equal(t, "Let me check.", text.Content.OfString.Value)
+
+		calls := msgs[2].OfAssistant
+		require.NotNil(t, calls)
+		require.Len(t, calls.ToolCalls, 1)
+		assert.Equal(t, "call_1", calls.ToolCalls[0].OfFunction.ID)
+		assert.False(t, calls.Content.OfString.Valid())
+	})
+
+	t.Run("kept together when disabled", func(t *testing.T) {
+		result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages: messages,
+			Format:   model.FormatOpenAI,
+		})
+		require.NoError(t, err)
+		assert.Len(t, result.([]openai.ChatCompletionMessageParamUnion), 2)
+	})
+}
+
+func TestSplitMixedTurns_AnthropicToolResultsFirst(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{
+			{Type: "text", Text: "Also, thanks!"},
+			{Type: "tool-result", Text: "72F", Meta: map[string]any{"tool_call_id": "call_1"}},
+		}, nil),
+	}
+
+	result := splitMixedTurns(messages, model.FormatAnthropic)
+	require.Len(t, result, 2)
+	assert.Equal(t, []model.Part{messages[0].Parts[1]}, result[0].Parts)
+	assert.Equal(t, []model.Part{messages[0].Parts[0]}, result[1].Parts)
+	assert.Len(t, messages[0].Parts, 2)
+}
+
+func TestSplitMixedTurns_Unchanged(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("assistant", []model.Part{
+			{Type: "tool-call", Meta: map[string]any{"id": "call_1", "name": "f"}},
+		}, nil),
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Hi"}}, nil),
+	}
+
+	assert.Equal(t, messages, splitMixedTurns(messages, model.FormatOpenAI))
+	assert.Equal(t, messages, splitMixedTurns(messages, model.FormatAcontext))
+}
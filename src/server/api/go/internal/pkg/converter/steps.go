@@ -0,0 +1,42 @@
+package converter
+
+import "github.com/memodb-io/Acontext/internal/modules/model"
+
+// SplitAtToolSteps returns, for every assistant message that issues tool calls,
+// the conversation prefix ending right before it, so each tool step can be
+// replayed on its own. A prefix in which some tool call has no result yet is
+// skipped, as providers reject it. The prefixes share the input's backing array
+// and must not be modified.
+func SplitAtToolSteps(messages []model.Message) [][]model.Message {
+	var steps [][]model.Message
+	pending := make(map[string]struct{})
+
+	for i, msg := range messages {
+		if msg.Role == "assistant" && hasToolCall(msg) && len(pending) == 0 {
+			steps = append(steps, messages[:i:i])
+		}
+
+		for _, part := range msg.Parts {
+			switch part.Type {
+			case "tool-call":
+				if id, _ := part.Meta["id"].(string); id != "" {
+					pending[id] = struct{}{}
+				}
+			case "tool-result":
+				if id, _ := part.Meta["tool_call_id"].(string); id != "" {
+					delete(pending, id)
+				}
+			}
+		}
+	}
+	return steps
+}
+
+func hasToolCall(msg model.Message) bool {
+	for _, part := range msg.Parts {
+		if part.Type == "tool-call" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitAtToolSteps(t *testing.T) {
+	toolCall := func(id string) model.Part {
+		return model.Part{Type: "tool-call", Meta: map[string]any{"id": id, "name": "search", "arguments": "{}"}}
+	}
+	toolResult := func(id string) model.Part {
+		return model.Part{Type: "tool-result", Text: "found", Meta: map[string]any{"tool_call_id": id}}
+	}
+
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Find two things"}}, nil),
+		createTestMessage("assistant", []model.Part{toolCall("call_1")}, nil),
+		createTestMessage("user", []model.Part{toolResult("call_1")}, nil),
+		createTestMessage("assistant", []model.Part{{Type: "text", Text: "One more"}, toolCall("call_2")}, nil),
+		createTestMessage("user", []model.Part{toolResult("call_2")}, nil),
+		createTestMessage("assistant", []model.Part{{Type: "text", Text: "Done"}}, nil),
+	}
+
+	steps := SplitAtToolSteps(messages)
+	require.Len(t, steps, 2)
+	assert.Equal(t, messages[:1], steps[0])
+	assert.Equal(t, messages[:3], steps[1])
+
+	t.Run("unbalanced prefix is skipped", func(t *testing.T) {
+		unanswered := []model.Message{
+			createTestMessage("user", []model.Part{{Type: "text", Text: "Go"}}, nil),
+			createTestMessage("assistant", []model.Part{toolCall("call_1")}, nil),
+			createTestMessage("assistant", []model.Part{toolCall("call_2")}, nil),
+		}
+
+		steps := SplitAtToolSteps(unanswered)
+		require.Len(t, steps, 1)
+		assert.Equal(t, unanswered[:1], steps[0])
+	})
+}
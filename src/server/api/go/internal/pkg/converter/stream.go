@@ -0,0 +1,67 @@
+package converter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+)
+
+// StreamConvertedMessages converts input and writes the result to w as JSON,
+// one encoded message at a time, calling flush after each message so clients can
+// render a long history incrementally. When flush is nil and w is an
+// http.Flusher, w.Flush is used. The bytes written equal json.Marshal of the
+// ConvertMessages result.
+func StreamConvertedMessages(ctx context.Context, w io.Writer, input ConvertMessagesInput, flush func()) error {
+	if flush == nil {
+		if f, ok := w.(http.Flusher); ok {
+			flush = f.Flush
+		}
+	}
+	if flush == nil {
+		flush = func() {}
+	}
+
+	result, err := ConvertMessages(ctx, input)
+	if err != nil {
+		return err
+	}
+
+	items := reflect.ValueOf(result)
+	if items.Kind() != reflect.Slice || items.IsNil() {
+		// A prompt string, an object such as AnthropicMessages or an empty
+		// result is a single value
+		encoded, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(encoded); err != nil {
+			return err
+		}
+		flush()
+		return nil
+	}
+
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	for i := 0; i < items.Len(); i++ {
+		encoded, err := json.Marshal(items.Index(i).Interface())
+		if err != nil {
+			return fmt.Errorf("failed to encode converted message %d: %w", i, err)
+		}
+		if i > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(encoded); err != nil {
+			return err
+		}
+		flush()
+	}
+	_, err = io.WriteString(w, "]")
+	return err
+}
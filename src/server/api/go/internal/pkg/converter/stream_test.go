@@ -0,0 +1,59 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamConvertedMessages(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("system", []model.Part{{Type: "text", Text: "Be brief."}}, nil),
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Hello"}}, nil),
+		createTestMessage("assistant", []model.Part{{Type: "text", Text: "Hi there"}}, nil),
+	}
+	input := ConvertMessagesInput{Messages: messages, Format: model.FormatOpenAI}
+
+	batch, err := ConvertMessages(context.Background(), input)
+	require.NoError(t, err)
+	expected, err := json.Marshal(batch)
+	require.NoError(t, err)
+
+	t.Run("flush after every message", func(t *testing.T) {
+		var buf bytes.Buffer
+		flushes := 0
+
+		require.NoError(t, StreamConvertedMessages(context.Background(), &buf, input, func() { flushes++ }))
+		assert.Equal(t, 3, flushes)
+		assert.Equal(t, string(expected), buf.String())
+	})
+
+	t.Run("http.Flusher writer", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+
+		require.NoError(t, StreamConvertedMessages(context.Background(), rec, input, nil))
+		assert.True(t, rec.Flushed)
+		assert.Equal(t, string(expected), rec.Body.String())
+	})
+
+	t.Run("completion prompt is one value", func(t *testing.T) {
+		var buf bytes.Buffer
+		flushes := 0
+
+		input := ConvertMessagesInput{Messages: messages, Format: model.FormatCompletion}
+		require.NoError(t, StreamConvertedMessages(context.Background(), &buf, input, func() { flushes++ }))
+
+		prompt, err := ConvertMessages(context.Background(), input)
+		require.NoError(t, err)
+		encoded, err := json.Marshal(prompt)
+		require.NoError(t, err)
+		assert.Equal(t, 1, flushes)
+		assert.Equal(t, string(encoded), buf.String())
+	})
+}
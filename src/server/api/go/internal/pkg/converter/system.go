@@ -0,0 +1,57 @@
+package converter
+
+import (
+	"strings"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+)
+
+// systemSeparator joins consolidated system prompts
+const systemSeparator = "\n\n"
+
+// consolidateSystemMessages moves every system message into one leading system
+// message whose text is the in-order concatenation of their text parts.
+// Non-system messages keep their relative order.
+func consolidateSystemMessages(messages []model.Message) []model.Message {
+	var texts []string
+	var first *model.Message
+	rest := make([]model.Message, 0, len(messages))
+
+	for i, msg := range messages {
+		if msg.Role != "system" {
+			rest = append(rest, msg)
+			continue
+		}
+		if first == nil {
+			first = &messages[i]
+		}
+		for _, part := range msg.Parts {
+			if part.Type == "text" && part.Text != "" {
+				texts = append(texts, part.Text)
+			}
+		}
+	}
+
+	if first == nil {
+		return messages
+	}
+
+	system := *first
+	system.Parts = []model.Part{{Type: "text", Text: strings.Join(texts, systemSeparator)}}
+
+	return append([]model.Message{system}, rest...)
+}
+
+// systemAsUser consolidates the system messages and turns the result into a
+// leading user message whose text starts with prefix
+func systemAsUser(messages []model.Message, prefix string) []model.Message {
+	result := consolidateSystemMessages(messages)
+	if len(result) == 0 || result[0].Role != "system" {
+		return result
+	}
+
+	// consolidateSystemMessages built a new slice, so it is safe to modify
+	result[0].Role = "user"
+	result[0].Parts = []model.Part{{Type: "text", Text: prefix + result[0].Parts[0].Text}}
+	return result
+}
@@ -0,0 +1,87 @@
+package converter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	openai "github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertMessages_ConsolidateSystem(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("system", []model.Part{{Type: "text", Text: "You are helpful."}}, nil),
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Hi"}}, nil),
+		createTestMessage("system", []model.Part{{Type: "text", Text: "Be concise."}}, nil),
+		createTestMessage("assistant", []model.Part{{Type: "text", Text: "Hello"}}, nil),
+		createTestMessage("system", []model.Part{{Type: "text", Text: "Answer in English."}}, nil),
+	}
+
+	t.Run("consolidated", func(t *testing.T) {
+		result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages:          messages,
+			Format:            model.FormatOpenAI,
+			ConsolidateSystem: true,
+		})
+		require.NoError(t, err)
+
+		msgs := result.([]openai.ChatCompletionMessageParamUnion)
+		require.Len(t, msgs, 3)
+		require.NotNil(t, msgs[0].OfSystem)
+		assert.Equal(t, "You are helpful.\n\nBe concise.\n\nAnswer in English.", msgs[0].OfSystem.Content.OfString.Value)
+		assert.NotNil(t, msgs[1].OfUser)
+		assert.NotNil(t, msgs[2].OfAssistant)
+	})
+
+	t.Run("kept separate when disabled", func(t *testing.T) {
+		result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages: messages,
+			Format:   model.FormatOpenAI,
+		})
+		require.NoError(t, err)
+
+		systemCount := 0
+		for _, msg := range result.([]openai.ChatCompletionMessageParamUnion) {
+			if msg.OfSystem != nil {
+				systemCount++
+			}
+		}
+		assert.Equal(t, 3, systemCount)
+	})
+}
+
+func TestConvertMessages_SystemAsUser(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Hi"}}, nil),
+		createTestMessage("system", []model.Part{{Type: "text", Text: "You are helpful."}}, nil),
+		createTestMessage("assistant", []model.Part{{Type: "text", Text: "Hello"}}, nil),
+	}
+
+	result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+		Messages:           messages,
+		Format:             model.FormatOpenAI,
+		SystemAsUser:       true,
+		SystemAsUserPrefix: "System: ",
+	})
+	require.NoError(t, err)
+
+	msgs := result.([]openai.ChatCompletionMessageParamUnion)
+	require.Len(t, msgs, 3)
+	require.NotNil(t, msgs[0].OfUser)
+	assert.Equal(t, "System: You are helpful.", msgs[0].OfUser.Content.OfString.Value)
+	require.NotNil(t, msgs[1].OfUser)
+	assert.Equal(t, "Hi", msgs[1].OfUser.Content.OfString.Value)
+	assert.NotNil(t, msgs[2].OfAssistant)
+
+	// The stored system message is left untouched
+	assert.Equal(t, "system", messages[1].Role)
+}
+
+func TestSystemAsUser_NoSystemMessage(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Hi"}}, nil),
+	}
+	assert.Equal(t, messages, systemAsUser(messages, "System: "))
+}
@@ -0,0 +1,92 @@
+package converter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+)
+
+// generatedToolCallIDLength is the number of hex characters kept from the hash
+const generatedToolCallIDLength = 24
+
+// assignToolCallIDs gives every tool-call part without an id a deterministic one,
+// "call_" followed by a hash of its name and arguments, and hands these ids to the
+// following tool-result parts that lack a tool_call_id, in call order. Repeated
+// identical calls get a numeric suffix so ids stay unique. Input messages are not modified.
+func assignToolCallIDs(messages []model.Message) []model.Message {
+	used := make(map[string]int)
+	for _, msg := range messages {
+		for _, part := range msg.Parts {
+			if part.Type == "tool-call" {
+				if id, _ := part.Meta["id"].(string); id != "" {
+					used[id]++
+				}
+			}
+		}
+	}
+
+	var pending []string
+	result := make([]model.Message, len(messages))
+	for i, msg := range messages {
+		result[i] = msg
+
+		var parts []model.Part
+		for j, part := range msg.Parts {
+			var key, value string
+			switch part.Type {
+			case "tool-call":
+				if id, _ := part.Meta["id"].(string); id != "" || part.Meta == nil {
+					continue
+				}
+				value = generateToolCallID(part, used)
+				pending = append(pending, value)
+				key = "id"
+			case "tool-result":
+				if id, _ := part.Meta["tool_call_id"].(string); id != "" || len(pending) == 0 {
+					continue
+				}
+				value, pending = pending[0], pending[1:]
+				key = "tool_call_id"
+			default:
+				continue
+			}
+
+			if parts == nil {
+				parts = append([]model.Part(nil), msg.Parts...)
+			}
+			meta := make(map[string]any, len(part.Meta)+1)
+			for k, v := range part.Meta {
+				meta[k] = v
+			}
+			meta[key] = value
+			parts[j].Meta = meta
+		}
+
+		if parts != nil {
+			result[i].Parts = parts
+		}
+	}
+
+	return result
+}
+
+func generateToolCallID(part model.Part, used map[string]int) string {
+	name, _ := part.Meta["name"].(string)
+	arguments, ok := part.Meta["arguments"].(string)
+	if !ok {
+		if encoded, err := encodeArguments(part.Meta["arguments"]); err == nil {
+			arguments = encoded
+		}
+	}
+
+	sum := sha256.Sum256([]byte(name + "\x00" + arguments))
+	id := "call_" + hex.EncodeToString(sum[:])[:generatedToolCallIDLength]
+
+	used[id]++
+	if n := used[id]; n > 1 {
+		id += "_" + strconv.Itoa(n)
+	}
+	return id
+}
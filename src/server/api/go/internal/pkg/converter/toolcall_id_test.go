@@ -0,0 +1,70 @@
+package converter
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	openai "github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertMessages_GeneratedToolCallID(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Weather?"}}, nil),
+		createTestMessage("assistant", []model.Part{
+			{Type: "tool-call", Meta: map[string]any{
+				"name":      "get_weather",
+				"arguments": `{"city":"Paris"}`,
+			}},
+		}, nil),
+		createTestMessage("user", []model.Part{
+			{Type: "tool-result", Text: "18C", Meta: map[string]any{}},
+		}, nil),
+	}
+
+	convert := func() []openai.ChatCompletionMessageParamUnion {
+		result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages: messages,
+			Format:   model.FormatOpenAI,
+		})
+		require.NoError(t, err)
+		return result.([]openai.ChatCompletionMessageParamUnion)
+	}
+
+	msgs := convert()
+	require.Len(t, msgs, 3)
+	require.NotNil(t, msgs[1].OfAssistant)
+	require.Len(t, msgs[1].OfAssistant.ToolCalls, 1)
+	id := msgs[1].OfAssistant.ToolCalls[0].OfFunction.ID
+	assert.True(t, strings.HasPrefix(id, "call_"))
+
+	require.NotNil(t, msgs[2].OfTool)
+	assert.Equal(t, id, msgs[2].OfTool.ToolCallID)
+
+	// Stable across conversions, and the stored messages are untouched
+	assert.Equal(t, id, convert()[1].OfAssistant.ToolCalls[0].OfFunction.ID)
+	assert.NotContains(t, messages[1].Parts[0].Meta, "id")
+}
+
+func TestAssignToolCallIDs_RepeatedCalls(t *testing.T) {
+	call := model.Part{Type: "tool-call", Meta: map[string]any{"name": "roll", "arguments": "{}"}}
+	result := model.Part{Type: "tool-result", Text: "4", Meta: map[string]any{}}
+	explicit := model.Part{Type: "tool-call", Meta: map[string]any{"id": "call_explicit", "name": "roll", "arguments": "{}"}}
+
+	messages := []model.Message{
+		createTestMessage("assistant", []model.Part{call, call, explicit}, nil),
+		createTestMessage("user", []model.Part{result, result}, nil),
+	}
+
+	out := assignToolCallIDs(messages)
+	first := out[0].Parts[0].Meta["id"].(string)
+	second := out[0].Parts[1].Meta["id"].(string)
+	assert.NotEqual(t, first, second)
+	assert.Equal(t, first+"_2", second)
+	assert.Equal(t, "call_explicit", out[0].Parts[2].Meta["id"])
+	assert.Equal(t, first, out[1].Parts[0].Meta["tool_call_id"])
+	assert.Equal(t, second, out[1].Parts[1].Meta["tool_call_id"])
+}
@@ -0,0 +1,65 @@
+package converter
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+)
+
+const maxToolNameLength = 64
+
+var (
+	validToolName       = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+	invalidToolNameChar = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+)
+
+// sanitizeToolName maps a stored tool name to one OpenAI and Anthropic accept:
+// characters outside [a-zA-Z0-9_-] become underscores and the result is cut to
+// 64 characters. The mapping is deterministic, so calls and results stay paired.
+func sanitizeToolName(name string) string {
+	name = invalidToolNameChar.ReplaceAllString(name, "_")
+	if len(name) > maxToolNameLength {
+		name = name[:maxToolNameLength]
+	}
+	return name
+}
+
+// sanitizeToolNames rewrites the name of every tool call and tool result that
+// providers would reject, or fails on the first one when reject is set. Input
+// messages are not modified.
+func sanitizeToolNames(messages []model.Message, reject bool) ([]model.Message, error) {
+	result := make([]model.Message, len(messages))
+	for i, msg := range messages {
+		result[i] = msg
+
+		var parts []model.Part
+		for j, part := range msg.Parts {
+			if part.Type != "tool-call" && part.Type != "tool-result" {
+				continue
+			}
+			name, _ := part.Meta["name"].(string)
+			if name == "" || validToolName.MatchString(name) {
+				continue
+			}
+			if reject {
+				return nil, fmt.Errorf("message %d part %d: invalid tool name %q", i, j, name)
+			}
+
+			if parts == nil {
+				parts = append([]model.Part(nil), msg.Parts...)
+			}
+			meta := make(map[string]any, len(part.Meta))
+			for k, v := range part.Meta {
+				meta[k] = v
+			}
+			meta["name"] = sanitizeToolName(name)
+			parts[j].Meta = meta
+		}
+
+		if parts != nil {
+			result[i].Parts = parts
+		}
+	}
+	return result, nil
+}
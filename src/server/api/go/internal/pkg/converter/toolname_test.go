@@ -0,0 +1,51 @@
+package converter
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	openai "github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertMessages_SanitizeToolNames(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("assistant", []model.Part{
+			{Type: "tool-call", Meta: map[string]any{"id": "call_1", "name": "files.read file", "arguments": "{}"}},
+		}, nil),
+		createTestMessage("user", []model.Part{
+			{Type: "tool-result", Text: "contents", Meta: map[string]any{"tool_call_id": "call_1", "name": "files.read file"}},
+		}, nil),
+	}
+
+	t.Run("invalid characters become underscores", func(t *testing.T) {
+		converted, err := ConvertMessages(context.Background(), ConvertMessagesInput{Messages: messages, Format: model.FormatOpenAI})
+		require.NoError(t, err)
+
+		msgs := converted.([]openai.ChatCompletionMessageParamUnion)
+		assert.Equal(t, "files_read_file", msgs[0].OfAssistant.ToolCalls[0].OfFunction.Function.Name)
+
+		sanitized, err := sanitizeToolNames(messages, false)
+		require.NoError(t, err)
+		assert.Equal(t, "files_read_file", sanitized[0].Parts[0].Meta["name"])
+		assert.Equal(t, "files_read_file", sanitized[1].Parts[0].Meta["name"])
+		assert.Equal(t, "files.read file", messages[0].Parts[0].Meta["name"])
+	})
+
+	t.Run("long names are truncated", func(t *testing.T) {
+		assert.Equal(t, strings.Repeat("a", 64), sanitizeToolName(strings.Repeat("a", 80)))
+	})
+
+	t.Run("reject invalid names", func(t *testing.T) {
+		_, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages:               messages,
+			Format:                 model.FormatOpenAI,
+			RejectInvalidToolNames: true,
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `invalid tool name "files.read file"`)
+	})
+}
@@ -0,0 +1,42 @@
+package converter
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+)
+
+// trimTextParts strips whitespace around text parts, or only after them when
+// trailingOnly is set. Input messages are not modified.
+func trimTextParts(messages []model.Message, trailingOnly bool) []model.Message {
+	trim := strings.TrimSpace
+	if trailingOnly {
+		trim = func(s string) string { return strings.TrimRightFunc(s, unicode.IsSpace) }
+	}
+
+	result := make([]model.Message, len(messages))
+	for i, msg := range messages {
+		result[i] = msg
+
+		var parts []model.Part
+		for j, part := range msg.Parts {
+			if part.Type != "text" {
+				continue
+			}
+			trimmed := trim(part.Text)
+			if trimmed == part.Text {
+				continue
+			}
+			if parts == nil {
+				parts = append([]model.Part(nil), msg.Parts...)
+			}
+			parts[j].Text = trimmed
+		}
+
+		if parts != nil {
+			result[i].Parts = parts
+		}
+	}
+	return result
+}
@@ -0,0 +1,32 @@
+package converter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	openai "github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertMessages_TrimText(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "\n  Hello there \n\n"}}, nil),
+	}
+
+	convert := func(input ConvertMessagesInput) string {
+		input.Messages = messages
+		input.Format = model.FormatOpenAI
+		result, err := ConvertMessages(context.Background(), input)
+		require.NoError(t, err)
+		return result.([]openai.ChatCompletionMessageParamUnion)[0].OfUser.Content.OfString.Value
+	}
+
+	assert.Equal(t, "Hello there", convert(ConvertMessagesInput{TrimText: true}))
+	assert.Equal(t, "\n  Hello there", convert(ConvertMessagesInput{TrimText: true, TrimTrailingOnly: true}))
+	assert.Equal(t, "\n  Hello there \n\n", convert(ConvertMessagesInput{}))
+
+	// The stored message keeps its whitespace
+	assert.Equal(t, "\n  Hello there \n\n", messages[0].Parts[0].Text)
+}
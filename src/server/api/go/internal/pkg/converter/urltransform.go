@@ -0,0 +1,74 @@
+package converter
+
+import (
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+)
+
+// URLTransform rewrites the public URL of an asset before it is sent, e.g. to
+// sign it or route it through a CDN
+type URLTransform func(asset *model.Asset, url string) string
+
+// publicURLKey returns the key of asset in publicURLs: its SHA256, which the
+// session service keys public URLs by, or else its S3 key
+func publicURLKey(asset *model.Asset, publicURLs map[string]service.PublicURL) (string, bool) {
+	if asset == nil {
+		return "", false
+	}
+	if _, ok := publicURLs[asset.SHA256]; ok && asset.SHA256 != "" {
+		return asset.SHA256, true
+	}
+	if _, ok := publicURLs[asset.S3Key]; ok && asset.S3Key != "" {
+		return asset.S3Key, true
+	}
+	return "", false
+}
+
+// assetPublicURL returns the public URL of asset, or "" when it has none
+func assetPublicURL(asset *model.Asset, publicURLs map[string]service.PublicURL) string {
+	key, ok := publicURLKey(asset, publicURLs)
+	if !ok {
+		return ""
+	}
+	return publicURLs[key].URL
+}
+
+// transformPublicURLs returns a copy of publicURLs with the URL of every asset
+// referenced by messages passed through transform. The transform runs once per
+// distinct asset (by SHA256, or S3 key when the hash is unknown), however many
+// parts reference it. publicURLs is not modified.
+func transformPublicURLs(messages []model.Message, publicURLs map[string]service.PublicURL, transform URLTransform) map[string]service.PublicURL {
+	if transform == nil || len(publicURLs) == 0 {
+		return publicURLs
+	}
+
+	result := make(map[string]service.PublicURL, len(publicURLs))
+	for key, publicURL := range publicURLs {
+		result[key] = publicURL
+	}
+
+	memo := make(map[string]string)
+	for _, msg := range messages {
+		for _, part := range msg.Parts {
+			asset := part.Asset
+			key, ok := publicURLKey(asset, publicURLs)
+			if !ok {
+				continue
+			}
+			publicURL := publicURLs[key]
+
+			id := asset.SHA256
+			if id == "" {
+				id = asset.S3Key
+			}
+			url, seen := memo[id]
+			if !seen {
+				url = transform(asset, publicURL.URL)
+				memo[id] = url
+			}
+			publicURL.URL = url
+			result[key] = publicURL
+		}
+	}
+	return result
+}
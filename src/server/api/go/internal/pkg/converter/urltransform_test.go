@@ -0,0 +1,52 @@
+package converter
+
+import (
+	"context"
+	"testing"
+
+	openai "github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+)
+
+func TestConvertMessages_URLTransformOncePerAsset(t *testing.T) {
+	cat := &model.Asset{S3Key: "assets/cat.png", SHA256: "aaa", MIME: "image/png"}
+	dog := &model.Asset{S3Key: "assets/dog.png", SHA256: "bbb", MIME: "image/png"}
+	image := func(asset *model.Asset) model.Part {
+		return model.Part{Type: "image", Asset: asset}
+	}
+
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Look"}, image(cat), image(dog)}, nil),
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Again"}, image(cat), image(cat)}, nil),
+	}
+	publicURLs := map[string]service.PublicURL{
+		cat.S3Key: {URL: "https://cdn.example.com/cat.png"},
+		dog.S3Key: {URL: "https://cdn.example.com/dog.png"},
+	}
+
+	calls := map[string]int{}
+	result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+		Messages:   messages,
+		Format:     model.FormatOpenAI,
+		PublicURLs: publicURLs,
+		URLTransform: func(asset *model.Asset, url string) string {
+			calls[asset.SHA256]++
+			return url + "?sig=" + asset.SHA256
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]int{"aaa": 1, "bbb": 1}, calls)
+	assert.Equal(t, "https://cdn.example.com/cat.png", publicURLs[cat.S3Key].URL, "input map must not be modified")
+
+	msgs := result.([]openai.ChatCompletionMessageParamUnion)
+	require.Len(t, msgs, 2)
+	parts := msgs[1].OfUser.Content.OfArrayOfContentParts
+	require.Len(t, parts, 3)
+	assert.Equal(t, "https://cdn.example.com/cat.png?sig=aaa", parts[1].OfImageURL.ImageURL.URL)
+	assert.Equal(t, "https://cdn.example.com/cat.png?sig=aaa", parts[2].OfImageURL.ImageURL.URL)
+}
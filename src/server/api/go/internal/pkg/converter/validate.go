@@ -0,0 +1,77 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+)
+
+// Validate reports option combinations that cannot be honoured, before any
+// conversion work is done
+func (input ConvertMessagesInput) Validate() error {
+	if input.ErrorOnEmpty && input.EmptyPlaceholder != "" {
+		return errors.New("ErrorOnEmpty and EmptyPlaceholder are mutually exclusive")
+	}
+	if input.RejectDuplicateIDs && input.DedupeByID {
+		return errors.New("RejectDuplicateIDs and DedupeByID are mutually exclusive")
+	}
+	if input.MaxOutputBytes < 0 {
+		return fmt.Errorf("MaxOutputBytes must not be negative, got %d", input.MaxOutputBytes)
+	}
+	if input.FewShotBudget < 0 {
+		return fmt.Errorf("FewShotBudget must not be negative, got %d", input.FewShotBudget)
+	}
+	if input.MemoryMaxChars < 0 {
+		return fmt.Errorf("MemoryMaxChars must not be negative, got %d", input.MemoryMaxChars)
+	}
+	if input.MaxTokens < 0 {
+		return fmt.Errorf("MaxTokens must not be negative, got %d", input.MaxTokens)
+	}
+	for format, defaults := range input.RequestDefaults {
+		if defaults.MaxTokens < 0 {
+			return fmt.Errorf("default MaxTokens for %s must not be negative, got %d", format, defaults.MaxTokens)
+		}
+	}
+	if input.SelectCandidate < 0 {
+		return fmt.Errorf("SelectCandidate must not be negative, got %d", input.SelectCandidate)
+	}
+	if input.TrimTrailingOnly && !input.TrimText {
+		return errors.New("TrimTrailingOnly requires TrimText")
+	}
+	if input.DropDuplicateImages && !input.DedupeImages {
+		return errors.New("DropDuplicateImages requires DedupeImages")
+	}
+	if input.SystemAsUserPrefix != "" && !input.SystemAsUser {
+		return errors.New("SystemAsUserPrefix requires SystemAsUser")
+	}
+	if err := validateRoleMap(input.RoleMap); err != nil {
+		return err
+	}
+
+	switch input.EmptyAssistant {
+	case "", EmptyAssistantDrop, EmptyAssistantEmptyContent:
+	default:
+		return fmt.Errorf("unknown EmptyAssistant policy: %s", input.EmptyAssistant)
+	}
+
+	if input.AppendStopToken != "" && input.Format != model.FormatCompletion {
+		return fmt.Errorf("AppendStopToken is only supported for %s, got format %q", model.FormatCompletion, input.Format)
+	}
+
+	if input.Format != model.FormatAnthropic {
+		if input.CoalesceToolResults {
+			return fmt.Errorf("CoalesceToolResults is only supported for %s, got format %q", model.FormatAnthropic, input.Format)
+		}
+		if input.ImageFirst {
+			return fmt.Errorf("ImageFirst is only supported for %s, got format %q", model.FormatAnthropic, input.Format)
+		}
+	}
+
+	if input.Format != model.FormatOpenAI && input.Format != model.FormatAzureOpenAI &&
+		(input.SystemPrompt != "" || input.DeveloperPrompt != "") {
+		return fmt.Errorf("SystemPrompt and DeveloperPrompt are only supported for %s, got format %q", model.FormatOpenAI, input.Format)
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package converter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertMessagesInput_Validate(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Hello"}}, nil),
+	}
+
+	tests := []struct {
+		name   string
+		input  ConvertMessagesInput
+		errMsg string
+	}{
+		{
+			name:   "empty placeholder and error on empty",
+			input:  ConvertMessagesInput{Format: model.FormatOpenAI, EmptyPlaceholder: "Hi", ErrorOnEmpty: true},
+			errMsg: "ErrorOnEmpty and EmptyPlaceholder are mutually exclusive",
+		},
+		{
+			name:   "reject and dedupe duplicate ids",
+			input:  ConvertMessagesInput{Format: model.FormatOpenAI, RejectDuplicateIDs: true, DedupeByID: true},
+			errMsg: "RejectDuplicateIDs and DedupeByID are mutually exclusive",
+		},
+		{
+			name:   "negative max output bytes",
+			input:  ConvertMessagesInput{Format: model.FormatOpenAI, MaxOutputBytes: -1},
+			errMsg: "MaxOutputBytes must not be negative, got -1",
+		},
+		{
+			name:   "negative max tokens",
+			input:  ConvertMessagesInput{Format: model.FormatAnthropic, MaxTokens: -1},
+			errMsg: "MaxTokens must not be negative, got -1",
+		},
+		{
+			name:   "trailing only without trim",
+			input:  ConvertMessagesInput{Format: model.FormatOpenAI, TrimTrailingOnly: true},
+			errMsg: "TrimTrailingOnly requires TrimText",
+		},
+		{
+			name:   "unknown empty assistant policy",
+			input:  ConvertMessagesInput{Format: model.FormatOpenAI, EmptyAssistant: "keep"},
+			errMsg: "unknown EmptyAssistant policy: keep",
+		},
+		{
+			name:   "anthropic option on openai",
+			input:  ConvertMessagesInput{Format: model.FormatOpenAI, ImageFirst: true},
+			errMsg: `ImageFirst is only supported for anthropic, got format "openai"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.input.Messages = messages
+
+			err := tt.input.Validate()
+			require.Error(t, err)
+			assert.Equal(t, tt.errMsg, err.Error())
+
+			_, err = ConvertMessages(context.Background(), tt.input)
+			assert.EqualError(t, err, tt.errMsg)
+		})
+	}
+
+	t.Run("valid input", func(t *testing.T) {
+		input := ConvertMessagesInput{Messages: messages, Format: model.FormatAnthropic, ImageFirst: true, TrimText: true}
+		assert.NoError(t, input.Validate())
+	})
+}
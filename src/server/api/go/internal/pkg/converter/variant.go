@@ -0,0 +1,62 @@
+package converter
+
+import (
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+)
+
+// selectImageVariants replaces the asset of every image part with its variant
+// named name, when the asset has one and publicURLs holds a URL for it.
+// messages are not modified.
+func selectImageVariants(messages []model.Message, publicURLs map[string]service.PublicURL, name string) []model.Message {
+	result := make([]model.Message, len(messages))
+
+	for i, msg := range messages {
+		result[i] = msg
+
+		var parts []model.Part
+		for j, part := range msg.Parts {
+			asset := variantAsset(part, publicURLs, name)
+			if asset == nil {
+				continue
+			}
+			if parts == nil {
+				parts = append([]model.Part(nil), msg.Parts...)
+			}
+			parts[j].Asset = asset
+		}
+
+		if parts != nil {
+			result[i].Parts = parts
+		}
+	}
+
+	return result
+}
+
+// variantAsset returns a copy of the asset of an image part describing its
+// variant named name, or nil when the part should keep its original
+func variantAsset(part model.Part, publicURLs map[string]service.PublicURL, name string) *model.Asset {
+	if part.Type != "image" || part.Asset == nil {
+		return nil
+	}
+	variant, ok := part.Asset.Variants[name]
+	if !ok || variant.S3Key == "" {
+		return nil
+	}
+	if _, ok := publicURLKey(&model.Asset{S3Key: variant.S3Key, SHA256: variant.SHA256}, publicURLs); !ok {
+		return nil
+	}
+
+	asset := *part.Asset
+	asset.S3Key = variant.S3Key
+	asset.SHA256 = variant.SHA256
+	asset.SizeB = variant.SizeB
+	asset.Width = variant.Width
+	asset.Height = variant.Height
+	if variant.MIME != "" {
+		asset.MIME = variant.MIME
+	}
+	asset.Variants = nil
+	return &asset
+}
@@ -0,0 +1,57 @@
+package converter
+
+import (
+	"context"
+	"testing"
+
+	openai "github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+)
+
+func TestConvertMessages_PreferredVariant(t *testing.T) {
+	withMedium := &model.Asset{
+		S3Key: "assets/cat.png", SHA256: "aaa", MIME: "image/png",
+		Variants: map[string]model.AssetVariant{
+			"medium": {S3Key: "assets/cat-medium.jpg", SHA256: "bbb", MIME: "image/jpeg"},
+		},
+	}
+	original := &model.Asset{S3Key: "assets/dog.png", SHA256: "ccc", MIME: "image/png"}
+
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{
+			{Type: "text", Text: "Compare"},
+			{Type: "image", Asset: withMedium},
+			{Type: "image", Asset: original},
+		}, nil),
+	}
+	publicURLs := map[string]service.PublicURL{
+		"assets/cat.png":        {URL: "https://cdn.example.com/cat.png"},
+		"assets/cat-medium.jpg": {URL: "https://cdn.example.com/cat-medium.jpg"},
+		"assets/dog.png":        {URL: "https://cdn.example.com/dog.png"},
+	}
+
+	imageURLs := func(variant string) []string {
+		result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages:         messages,
+			Format:           model.FormatOpenAI,
+			PublicURLs:       publicURLs,
+			PreferredVariant: variant,
+		})
+		require.NoError(t, err)
+
+		msgs := result.([]openai.ChatCompletionMessageParamUnion)
+		require.Len(t, msgs, 1)
+		parts := msgs[0].OfUser.Content.OfArrayOfContentParts
+		require.Len(t, parts, 3)
+		return []string{parts[1].OfImageURL.ImageURL.URL, parts[2].OfImageURL.ImageURL.URL}
+	}
+
+	assert.Equal(t, []string{"https://cdn.example.com/cat-medium.jpg", "https://cdn.example.com/dog.png"}, imageURLs("medium"))
+	assert.Equal(t, []string{"https://cdn.example.com/cat.png", "https://cdn.example.com/dog.png"}, imageURLs("thumbnail"))
+	assert.Equal(t, []string{"https://cdn.example.com/cat.png", "https://cdn.example.com/dog.png"}, imageURLs(""))
+	assert.Equal(t, "assets/cat.png", messages[0].Parts[1].Asset.S3Key, "input messages must not be modified")
+}
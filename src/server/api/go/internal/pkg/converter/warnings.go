@@ -0,0 +1,117 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+)
+
+// Conversion warning codes
+const (
+	// WarningImageSkipped: an image part had no resolvable URL and was left out
+	WarningImageSkipped = "image_skipped"
+	// WarningToolCallSkipped: a tool-call part lacked an id or name and was left out
+	WarningToolCallSkipped = "tool_call_skipped"
+	// WarningMessageDropped: the message had nothing the format could send and was left out
+	WarningMessageDropped = "message_dropped"
+	// WarningRoleCoerced: the message role is not supported by the format and was mapped to user
+	WarningRoleCoerced = "role_coerced"
+)
+
+// ConversionWarning reports a non-fatal transformation made during conversion.
+// MessageIndex refers to the messages after preprocessing (placeholder, system
+// consolidation, image dedupe, turn splitting), which equals the input index
+// when none of those options are set.
+type ConversionWarning struct {
+	Code         string `json:"code"`
+	MessageIndex int    `json:"message_index"`
+	Detail       string `json:"detail"`
+}
+
+// ConvertMessagesWithWarnings behaves like ConvertMessages and also reports the
+// content the converter dropped or changed. The converted result is identical.
+func ConvertMessagesWithWarnings(ctx context.Context, input ConvertMessagesInput) (interface{}, []ConversionWarning, error) {
+	result, messages, format, err := observedConvert(ctx, input)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, collectWarnings(messages, format, input), nil
+}
+
+// collectWarnings mirrors the skip rules of the chat converters
+func collectWarnings(messages []model.Message, format model.MessageFormat, input ConvertMessagesInput) []ConversionWarning {
+	var warnings []ConversionWarning
+	add := func(code string, i int, detail string, args ...any) {
+		warnings = append(warnings, ConversionWarning{Code: code, MessageIndex: i, Detail: fmt.Sprintf(detail, args...)})
+	}
+
+	switch format {
+	case model.FormatOpenAI, model.FormatAzureOpenAI:
+		c := &OpenAIConverter{EmptyAssistant: input.EmptyAssistant}
+		for i, msg := range messages {
+			if msg.Role == "assistant" && c.EmptyAssistant != EmptyAssistantEmptyContent &&
+				c.isEmptyAssistant(c.convertToAssistantMessage(msg).OfAssistant) {
+				add(WarningMessageDropped, i, "assistant message without content or tool calls")
+			}
+			switch msg.Role {
+			case "user", "assistant", "system":
+			default:
+				add(WarningRoleCoerced, i, "role %q sent as user", msg.Role)
+			}
+			for j, part := range msg.Parts {
+				switch {
+				case part.Type == "image" && msg.Role != "assistant" && msg.Role != "system" &&
+					c.getAssetURL(part.Asset, input.PublicURLs) == "" &&
+					(part.Asset == nil || input.InlineAssetResolver == nil):
+					add(WarningImageSkipped, i, "part %d: no public URL for image", j)
+				case part.Type == "tool-call" && msg.Role == "assistant" && c.convertToToolCall(part) == nil:
+					add(WarningToolCallSkipped, i, "part %d: tool call without id or name", j)
+				}
+			}
+		}
+	case model.FormatAnthropic:
+		c := &AnthropicConverter{}
+		for i, msg := range messages {
+			if msg.Role != "user" && msg.Role != "assistant" && msg.Role != "system" {
+				add(WarningRoleCoerced, i, "role %q sent as user", msg.Role)
+			}
+			for j, part := range msg.Parts {
+				if part.Type != "image" {
+					continue
+				}
+				url := c.getAssetURL(part.Asset, input.PublicURLs)
+				if url == "" && part.Meta != nil {
+					url, _ = part.Meta["url"].(string)
+				}
+				if url == "" {
+					add(WarningImageSkipped, i, "part %d: no public URL for image", j)
+				}
+			}
+		}
+	case model.FormatGemini:
+		for i, msg := range messages {
+			for j, part := range msg.Parts {
+				if part.Type != "image" || assetPublicURL(part.Asset, input.PublicURLs) != "" {
+					continue
+				}
+				if url, _ := part.Meta["url"].(string); url != "" {
+					continue
+				}
+				if part.Asset == nil || input.InlineAssetResolver == nil {
+					add(WarningImageSkipped, i, "part %d: no public URL for image", j)
+				}
+			}
+		}
+	case model.FormatCohere:
+		for i, msg := range messages {
+			for j, part := range msg.Parts {
+				if part.Type == "image" {
+					add(WarningImageSkipped, i, "part %d: cohere chat does not accept images", j)
+				}
+			}
+		}
+	}
+
+	return warnings
+}
@@ -0,0 +1,73 @@
+package converter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertMessagesWithWarnings_MissingImageURL(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Hi"}}, nil),
+		createTestMessage("user", []model.Part{
+			{Type: "text", Text: "Look at these"},
+			{Type: "image", Asset: &model.Asset{S3Key: "assets/known.png"}},
+			{Type: "image", Asset: &model.Asset{S3Key: "assets/missing.png"}},
+		}, nil),
+	}
+	input := ConvertMessagesInput{
+		Messages: messages,
+		Format:   model.FormatOpenAI,
+		PublicURLs: map[string]service.PublicURL{
+			"assets/known.png": {URL: "https://example.com/known.png"},
+		},
+	}
+
+	result, warnings, err := ConvertMessagesWithWarnings(context.Background(), input)
+	require.NoError(t, err)
+
+	assert.Equal(t, []ConversionWarning{{
+		Code:         WarningImageSkipped,
+		MessageIndex: 1,
+		Detail:       "part 2: no public URL for image",
+	}}, warnings)
+
+	// The converted output matches ConvertMessages
+	plain, err := ConvertMessages(context.Background(), input)
+	require.NoError(t, err)
+	assert.Equal(t, plain, result)
+}
+
+func TestConvertMessagesWithWarnings_Anthropic(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("system", []model.Part{{Type: "text", Text: "Be brief."}}, nil),
+		createTestMessage("user", []model.Part{{Type: "image"}}, nil),
+	}
+
+	_, warnings, err := ConvertMessagesWithWarnings(context.Background(), ConvertMessagesInput{
+		Messages: messages,
+		Format:   model.FormatAnthropic,
+	})
+	require.NoError(t, err)
+	// The system message becomes the system prompt rather than a user turn
+	require.Len(t, warnings, 1)
+	assert.Equal(t, WarningImageSkipped, warnings[0].Code)
+	assert.Equal(t, 1, warnings[0].MessageIndex)
+}
+
+func TestConvertMessagesWithWarnings_None(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Hi"}}, nil),
+	}
+
+	_, warnings, err := ConvertMessagesWithWarnings(context.Background(), ConvertMessagesInput{
+		Messages: messages,
+		Format:   model.FormatOpenAI,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+}
@@ -0,0 +1,31 @@
+package metrics
+
+import "time"
+
+// Recorder receives operation metrics from the service and converter layers.
+// It keeps the core packages free of any concrete metrics library; wire a
+// Prometheus (or other) backed implementation at bootstrap time.
+// Implementations must be safe for concurrent use.
+type Recorder interface {
+	// ObserveOperation reports a single service operation
+	ObserveOperation(name string, duration time.Duration, err error)
+
+	// ObserveConversion reports a single message conversion
+	ObserveConversion(c Conversion)
+}
+
+// Conversion describes one ConvertMessages call
+type Conversion struct {
+	Format       string
+	MessageCount int
+	BytesOut     int
+	Duration     time.Duration
+	Err          error
+}
+
+// Nop is a Recorder that discards everything
+type Nop struct{}
+
+func (Nop) ObserveOperation(string, time.Duration, error) {}
+
+func (Nop) ObserveConversion(Conversion) {}
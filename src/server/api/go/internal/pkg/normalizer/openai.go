@@ -95,6 +95,14 @@ func normalizeOpenAIAssistantMessage(msg openai.ChatCompletionAssistantMessagePa
 		}
 	}
 
+	// Handle top-level refusal
+	if !param.IsOmitted(msg.Refusal) && msg.Refusal.Value != "" {
+		parts = append(parts, service.PartIn{
+			Type: "refusal",
+			Text: msg.Refusal.Value,
+		})
+	}
+
 	// Handle tool calls - UNIFIED FORMAT
 	for _, toolCall := range msg.ToolCalls {
 		if toolCall.OfFunction != nil {
@@ -246,11 +254,8 @@ func normalizeOpenAIAssistantContentPart(partUnion openai.ChatCompletionAssistan
 		}, nil
 	} else if partUnion.OfRefusal != nil {
 		return service.PartIn{
-			Type: "text",
+			Type: "refusal",
 			Text: partUnion.OfRefusal.Refusal,
-			Meta: map[string]interface{}{
-				"is_refusal": true,
-			},
 		}, nil
 	}
 
@@ -1,13 +1,31 @@
 package paging
 
 import (
+	"encoding/base64"
+	"encoding/binary"
+	"hash/crc32"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+// decodeTimeID decodes the (time, id) cursor the list endpoints use
+func decodeTimeID(cursor string) (time.Time, uuid.UUID, error) {
+	var t time.Time
+	var id uuid.UUID
+	err := DecodeCursor(cursor, &t, &id)
+	return t, id, err
+}
+
+// rawCursor encodes body as a cursor with a valid checksum
+func rawCursor(body ...byte) string {
+	body = binary.BigEndian.AppendUint32(body, crc32.ChecksumIEEE(body))
+	return base64.RawURLEncoding.EncodeToString(body)
+}
+
 func TestEncodeCursor(t *testing.T) {
 	tests := []struct {
 		name string
@@ -44,7 +62,7 @@ func TestEncodeCursor(t *testing.T) {
 			assert.NotEmpty(t, cursor)
 
 			// Verify can decode back to original values
-			decodedTime, decodedID, err := DecodeCursor(cursor)
+			decodedTime, decodedID, err := decodeTimeID(cursor)
 			assert.NoError(t, err)
 			assert.Equal(t, tt.time.UTC().UnixNano(), decodedTime.UnixNano())
 			assert.Equal(t, tt.id, decodedID)
@@ -79,28 +97,49 @@ func TestDecodeCursor(t *testing.T) {
 			name:    "invalid base64 encoding",
 			cursor:  "invalid-base64!@#",
 			wantErr: true,
+			errMsg:  "bad cursor",
+		},
+		{
+			name:    "corrupted cursor",
+			cursor:  tamper(validCursor),
+			wantErr: true,
+			errMsg:  "checksum mismatch",
+		},
+		{
+			name:    "unknown version",
+			cursor:  rawCursor(2, tagTime, 0, 0, 0, 0, 0, 0, 0, 1),
+			wantErr: true,
+			errMsg:  "unsupported cursor version",
+		},
+		{
+			name:    "too short",
+			cursor:  rawCursor(),
+			wantErr: true,
+			errMsg:  "bad cursor",
 		},
 		{
-			name:    "malformed cursor (missing separator)",
-			cursor:  "MTcwNDE3NjQwMDAwMDAwMDAwMHNvbWV0aGluZw", // base64 encoded invalid format
+			name:    "missing field",
+			cursor:  EncodeCursor(testTime),
 			wantErr: true,
 			errMsg:  "bad cursor",
 		},
 		{
-			name:    "invalid timestamp",
-			cursor:  "aW52YWxpZF90aW1lc3RhbXB8MTIzZTQ1NjctZTg5Yi0xMmQzLWE0NTYtNDI2NjE0MTc0MDAw", // "invalid_timestamp|123e4567-e89b-12d3-a456-426614174000"
+			name:    "extra field",
+			cursor:  EncodeCursor(testTime, testID, "more"),
 			wantErr: true,
+			errMsg:  "bad cursor",
 		},
 		{
-			name:    "invalid UUID",
-			cursor:  "MTcwNDE3NjQwMDAwMDAwMDAwMHxpbnZhbGlkLXV1aWQ", // "1704176400000000000|invalid-uuid"
+			name:    "fields in the wrong order",
+			cursor:  EncodeCursor(testID, testTime),
 			wantErr: true,
+			errMsg:  "bad cursor",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			decodedTime, decodedID, err := DecodeCursor(tt.cursor)
+			decodedTime, decodedID, err := decodeTimeID(tt.cursor)
 
 			if tt.wantErr {
 				assert.Error(t, err)
@@ -118,6 +157,19 @@ func TestDecodeCursor(t *testing.T) {
 	}
 }
 
+func TestDecodeCursor_Legacy(t *testing.T) {
+	legacy := "MTcwNDE3NjQwMDAwMDAwMDAwMHwxMjNlNDU2Ny1lODliLTEyZDMtYTQ1Ni00MjY2MTQxNzQwMDA" // "1704176400000000000|123e4567-e89b-12d3-a456-426614174000"
+
+	decodedTime, decodedID, err := decodeTimeID(legacy)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1704176400000000000), decodedTime.UnixNano())
+	assert.Equal(t, uuid.MustParse("123e4567-e89b-12d3-a456-426614174000"), decodedID)
+
+	// Only the (time, id) shape existed before versioning
+	var s string
+	assert.ErrorIs(t, DecodeCursor(legacy, &s), ErrBadCursor)
+}
+
 func TestEncodeDecode_Roundtrip(t *testing.T) {
 	tests := []struct {
 		name string
@@ -148,7 +200,7 @@ func TestEncodeDecode_Roundtrip(t *testing.T) {
 			assert.NotEmpty(t, cursor)
 
 			// Decode
-			decodedTime, decodedID, err := DecodeCursor(cursor)
+			decodedTime, decodedID, err := decodeTimeID(cursor)
 			assert.NoError(t, err)
 
 			// Verify round trip consistency
@@ -165,7 +217,7 @@ func TestCursor_EdgeCases(t *testing.T) {
 		testID := uuid.New()
 
 		cursor := EncodeCursor(farFuture, testID)
-		decodedTime, decodedID, err := DecodeCursor(cursor)
+		decodedTime, decodedID, err := decodeTimeID(cursor)
 
 		assert.NoError(t, err)
 		assert.Equal(t, farFuture.UnixNano(), decodedTime.UnixNano())
@@ -178,7 +230,7 @@ func TestCursor_EdgeCases(t *testing.T) {
 		testID := uuid.New()
 
 		cursor := EncodeCursor(earlyTime, testID)
-		decodedTime, decodedID, err := DecodeCursor(cursor)
+		decodedTime, decodedID, err := decodeTimeID(cursor)
 
 		assert.NoError(t, err)
 		assert.Equal(t, earlyTime.UnixNano(), decodedTime.UnixNano())
@@ -191,7 +243,7 @@ func TestCursor_EdgeCases(t *testing.T) {
 		testID := uuid.New()
 
 		cursor := EncodeCursor(localTime, testID)
-		decodedTime, decodedID, err := DecodeCursor(cursor)
+		decodedTime, decodedID, err := decodeTimeID(cursor)
 
 		assert.NoError(t, err)
 		assert.Equal(t, localTime.UTC().UnixNano(), decodedTime.UnixNano())
@@ -236,3 +288,34 @@ func TestCursor_URLSafe(t *testing.T) {
 		assert.NotContains(t, cursor, "=") // RawURLEncoding does not include padding characters
 	})
 }
+
+// tamper flips one bit of the encoded fields of cursor
+func tamper(cursor string) string {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		panic(err)
+	}
+	b[2] ^= 1
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func TestCursor_Fields(t *testing.T) {
+	cursor := EncodeCursor(int64(-42), 7, "block:é", uuid.Nil)
+
+	var sort int64
+	var offset int
+	var key string
+	var id uuid.UUID
+	require.NoError(t, DecodeCursor(cursor, &sort, &offset, &key, &id))
+	assert.Equal(t, int64(-42), sort)
+	assert.Equal(t, 7, offset)
+	assert.Equal(t, "block:é", key)
+	assert.Equal(t, uuid.Nil, id)
+
+	// A failed decode leaves the destinations alone
+	key = "unchanged"
+	assert.Error(t, DecodeCursor(cursor, &sort, &offset, &key))
+	assert.Equal(t, "unchanged", key)
+
+	assert.Panics(t, func() { EncodeCursor(3.5) })
+}
@@ -0,0 +1,281 @@
+// Package propschema validates block props against a JSON Schema. Schemas are
+// compiled by github.com/santhosh-tekuri/jsonschema as draft 2020-12 unless they
+// declare another $schema; only references within the schema itself resolve.
+package propschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// ErrInvalidSchema is returned when the schema itself is malformed
+var ErrInvalidSchema = errors.New("invalid props schema")
+
+// schemaURL names the schema resource handed to the compiler
+const schemaURL = "propschema.json"
+
+// Violation is one way a document fails its schema. Path is the dotted key of the
+// offending value, with array indexes in brackets; it is empty for the document root.
+type Violation struct {
+	Path    string
+	Message string
+}
+
+// ValidationError lists every violation found in a document, ordered by path
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		if v.Path == "" {
+			parts[i] = v.Message
+		} else {
+			parts[i] = v.Path + ": " + v.Message
+		}
+	}
+	return "props do not match schema: " + strings.Join(parts, "; ")
+}
+
+// Keys returns the distinct paths of the violations
+func (e *ValidationError) Keys() []string {
+	keys := make([]string, 0, len(e.Violations))
+	seen := make(map[string]struct{}, len(e.Violations))
+	for _, v := range e.Violations {
+		if _, ok := seen[v.Path]; !ok {
+			seen[v.Path] = struct{}{}
+			keys = append(keys, v.Path)
+		}
+	}
+	return keys
+}
+
+// Validate checks doc against schema. It returns a *ValidationError naming every
+// offending key, or an error wrapping ErrInvalidSchema when schema is malformed.
+// A nil or empty schema accepts any document.
+func Validate(schema map[string]any, doc map[string]any) error {
+	if len(schema) == 0 {
+		return nil
+	}
+	if doc == nil {
+		doc = map[string]any{}
+	}
+
+	// Schemas and docs built in Go may hold typed slices and ints; bring them to
+	// decoded JSON, which is all the validator accepts
+	rawSchema, err := normalize(schema)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	instance, err := normalize(doc)
+	if err != nil {
+		return err
+	}
+
+	compiled, err := compile(schema)
+	if err != nil {
+		return err
+	}
+
+	err = compiled.Validate(instance)
+	if err == nil {
+		return nil
+	}
+	var verr *jsonschema.ValidationError
+	if !errors.As(err, &verr) {
+		return err
+	}
+
+	var violations []Violation
+	for _, leaf := range leaves(verr) {
+		violations = append(violations, explain(leaf, rawSchema, instance)...)
+	}
+	sort.SliceStable(violations, func(i, j int) bool { return violations[i].Path < violations[j].Path })
+	return &ValidationError{Violations: violations}
+}
+
+func compile(schema map[string]any) (*jsonschema.Schema, error) {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft2020
+	// Schemas come from API callers, so they may not reach files or the network
+	compiler.LoadURL = func(s string) (io.ReadCloser, error) {
+		return nil, fmt.Errorf("external reference %s is not allowed", s)
+	}
+	if err := compiler.AddResource(schemaURL, bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	compiled, err := compiler.Compile(schemaURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	return compiled, nil
+}
+
+// leaves returns the innermost errors of a validation error tree, which name the
+// keywords that actually failed
+func leaves(err *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(err.Causes) == 0 {
+		return []*jsonschema.ValidationError{err}
+	}
+	var out []*jsonschema.ValidationError
+	for _, cause := range err.Causes {
+		out = append(out, leaves(cause)...)
+	}
+	return out
+}
+
+// explain turns a failed keyword into violations. The validator reports missing
+// and disallowed properties once for their object; they are split per key here so
+// each offending key is named.
+func explain(leaf *jsonschema.ValidationError, schema, instance any) []Violation {
+	value, path := locate(instance, leaf.InstanceLocation)
+	obj, isObject := value.(map[string]any)
+
+	_, fragment, _ := strings.Cut(leaf.AbsoluteKeywordLocation, "#")
+	tokens := pointerTokens(fragment)
+	keyword := ""
+	if len(tokens) > 0 {
+		keyword = tokens[len(tokens)-1]
+	}
+
+	switch {
+	case keyword == "required" && isObject:
+		var out []Violation
+		required, _ := lookup(schema, tokens).([]any)
+		for _, item := range required {
+			key, _ := item.(string)
+			if _, present := obj[key]; !present {
+				out = append(out, Violation{Path: join(path, key), Message: "is required"})
+			}
+		}
+		return out
+	case keyword == "additionalProperties" && isObject:
+		var out []Violation
+		parent, _ := lookup(schema, tokens[:len(tokens)-1]).(map[string]any)
+		for key := range obj {
+			if !declared(parent, key) {
+				out = append(out, Violation{Path: join(path, key), Message: "is not allowed"})
+			}
+		}
+		return out
+	}
+	return []Violation{{Path: path, Message: leaf.Message}}
+}
+
+// declared reports whether key is matched by the properties or patternProperties
+// of an object schema
+func declared(schema map[string]any, key string) bool {
+	if properties, ok := schema["properties"].(map[string]any); ok {
+		if _, ok := properties[key]; ok {
+			return true
+		}
+	}
+	if patterns, ok := schema["patternProperties"].(map[string]any); ok {
+		for pattern := range patterns {
+			if re, err := regexp.Compile(pattern); err == nil && re.MatchString(key) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// locate follows a JSON pointer into doc and returns the value found with its
+// path in Violation form
+func locate(doc any, pointer string) (any, string) {
+	path := ""
+	value := doc
+	for _, token := range pointerTokens(pointer) {
+		switch v := value.(type) {
+		case map[string]any:
+			value = v[token]
+			path = join(path, token)
+		case []any:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, path
+			}
+			value = v[i]
+			path = fmt.Sprintf("%s[%d]", path, i)
+		default:
+			return nil, path
+		}
+	}
+	return value, path
+}
+
+// lookup follows pointer tokens into a decoded schema
+func lookup(schema any, tokens []string) any {
+	value := schema
+	for _, token := range tokens {
+		switch v := value.(type) {
+		case map[string]any:
+			value = v[token]
+		case []any:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil
+			}
+			value = v[i]
+		default:
+			return nil
+		}
+	}
+	return value
+}
+
+// pointerTokens splits a JSON pointer as the validator writes it, URL-escaped
+// on top of the usual ~0 and ~1 escapes
+func pointerTokens(pointer string) []string {
+	if pointer == "" || pointer == "/" {
+		return nil
+	}
+	parts := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	for i, part := range parts {
+		if unescaped, err := url.PathUnescape(part); err == nil {
+			part = unescaped
+		}
+		part = strings.ReplaceAll(part, "~1", "/")
+		parts[i] = strings.ReplaceAll(part, "~0", "~")
+	}
+	return parts
+}
+
+// normalize brings a value to the shapes encoding/json decodes into, keeping
+// numbers exact as json.Number
+func normalize(value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var decoded any
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,121 @@
+package propschema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var taskSchema = map[string]any{
+	"type":     "object",
+	"required": []any{"status", "priority"},
+	"properties": map[string]any{
+		"status":   map[string]any{"type": "string", "enum": []any{"todo", "done"}},
+		"priority": map[string]any{"type": "integer", "minimum": 1, "maximum": 5},
+		"tags":     map[string]any{"type": "array", "items": map[string]any{"type": "string", "maxLength": 10}},
+		"note":     map[string]any{"type": []any{"string", "null"}},
+	},
+	"additionalProperties": false,
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      map[string]any
+		wantKeys []string
+	}{
+		{
+			name: "passing doc",
+			doc:  map[string]any{"status": "todo", "priority": float64(3), "tags": []any{"a", "b"}, "note": nil},
+		},
+		{
+			name: "go values",
+			doc:  map[string]any{"status": "done", "priority": 2, "tags": []string{"x"}},
+		},
+		{
+			name:     "type mismatch",
+			doc:      map[string]any{"status": "todo", "priority": "high"},
+			wantKeys: []string{"priority"},
+		},
+		{
+			name:     "missing required field",
+			doc:      map[string]any{"status": "todo"},
+			wantKeys: []string{"priority"},
+		},
+		{
+			name:     "every offending key",
+			doc:      map[string]any{"priority": 1.5, "status": "later", "extra": true, "tags": []any{"ok", 3}},
+			wantKeys: []string{"extra", "priority", "status", "tags[1]"},
+		},
+		{
+			name:     "out of range",
+			doc:      map[string]any{"status": "todo", "priority": 9, "tags": []any{"far too long a tag"}},
+			wantKeys: []string{"priority", "tags[0]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(taskSchema, tt.doc)
+			if tt.wantKeys == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var verr *ValidationError
+			require.ErrorAs(t, err, &verr)
+			assert.Equal(t, tt.wantKeys, verr.Keys())
+			for _, key := range tt.wantKeys {
+				assert.Contains(t, err.Error(), key)
+			}
+		})
+	}
+}
+
+func TestValidate_Messages(t *testing.T) {
+	err := Validate(taskSchema, map[string]any{"priority": "high"})
+	require.Error(t, err)
+	assert.Equal(t, "props do not match schema: priority: expected integer, but got string; status: is required", err.Error())
+}
+
+func TestValidate_NoSchema(t *testing.T) {
+	assert.NoError(t, Validate(nil, map[string]any{"anything": 1}))
+	assert.NoError(t, Validate(map[string]any{}, nil))
+}
+
+func TestValidate_InvalidSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema map[string]any
+	}{
+		{name: "bad type", schema: map[string]any{"type": 5}},
+		{name: "bad required", schema: map[string]any{"required": "status"}},
+		{name: "bad pattern", schema: map[string]any{"properties": map[string]any{"s": map[string]any{"pattern": "("}}}},
+		{name: "bad minimum", schema: map[string]any{"properties": map[string]any{"n": map[string]any{"minimum": "1"}}}},
+		{name: "external ref", schema: map[string]any{"$ref": "file:///etc/passwd"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.schema, map[string]any{"s": "x", "n": 2})
+			assert.ErrorIs(t, err, ErrInvalidSchema)
+		})
+	}
+}
+
+func TestValidate_LocalRef(t *testing.T) {
+	schema := map[string]any{
+		"$defs": map[string]any{
+			"level": map[string]any{"type": "integer", "minimum": 1},
+		},
+		"properties": map[string]any{
+			"priority": map[string]any{"$ref": "#/$defs/level"},
+		},
+	}
+	assert.NoError(t, Validate(schema, map[string]any{"priority": 2}))
+
+	err := Validate(schema, map[string]any{"priority": 0})
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, []string{"priority"}, verr.Keys())
+}
@@ -0,0 +1,159 @@
+// Package sortkey generates fractional ordering keys: strings that sort
+// byte-wise in the order of the items they belong to, and between any two of
+// which another key can always be generated. A list ordered by such keys can
+// place an item anywhere by giving it a key between its neighbors' keys.
+//
+// A key is a base-62 fraction 0.d1d2d3... written as its digits d1d2d3...,
+// using 0-9A-Za-z so byte order matches numeric order. Keys never end in '0',
+// which keeps every fraction to a single spelling. Keys must be compared
+// byte-wise (COLLATE "C" in Postgres).
+package sortkey
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+)
+
+const digits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+const base = len(digits)
+
+// jitterDigits is the number of random digits JitteredBetween appends
+const jitterDigits = 3
+
+// ErrInvalidKey is returned for a key with a character outside the digit set or a trailing '0'
+var ErrInvalidKey = errors.New("invalid sort key")
+
+// ErrKeyOrder is returned when the lower bound does not sort before the upper bound
+var ErrKeyOrder = errors.New("sort key bounds out of order")
+
+// Between returns the key halfway between a and b. An empty a means the start
+// of the list and an empty b its end, so Between("", "") is the first key of
+// an empty list.
+func Between(a, b string) (string, error) {
+	if err := checkBounds(a, b); err != nil {
+		return "", err
+	}
+	return midpoint(a, b, nil), nil
+}
+
+// JitteredBetween returns a random key between a and b. Clients inserting at
+// the same position concurrently each get a different key, where Between
+// would give them all the same one.
+func JitteredBetween(a, b string) (string, error) {
+	if err := checkBounds(a, b); err != nil {
+		return "", err
+	}
+	return midpoint(a, b, rand.IntN), nil
+}
+
+// NBetween returns n ascending keys between a and b, spread so the keys stay
+// short. It is used to key an existing list in one go.
+func NBetween(a, b string, n int) ([]string, error) {
+	if err := checkBounds(a, b); err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, n)
+	return appendBetween(keys, a, b, n), nil
+}
+
+func appendBetween(keys []string, a, b string, n int) []string {
+	if n <= 0 {
+		return keys
+	}
+	mid := midpoint(a, b, nil)
+	left := (n - 1) / 2
+	keys = appendBetween(keys, a, mid, left)
+	keys = append(keys, mid)
+	return appendBetween(keys, mid, b, n-1-left)
+}
+
+// midpoint returns a key strictly between a and b. With pick set, the digit
+// that decides the position is chosen by pick and random digits are appended.
+func midpoint(a, b string, pick func(n int) int) string {
+	if b != "" {
+		// Keep the prefix both share, reading missing digits of a as '0'
+		n := 0
+		for n < len(b) && digitAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			rest := ""
+			if n < len(a) {
+				rest = a[n:]
+			}
+			return b[:n] + midpoint(rest, b[n:], pick)
+		}
+	}
+
+	lo := 0
+	if a != "" {
+		lo = strings.IndexByte(digits, a[0])
+	}
+	hi := base
+	if b != "" {
+		hi = strings.IndexByte(digits, b[0])
+	}
+
+	if hi-lo > 1 {
+		if pick == nil {
+			return string(digits[(lo+hi)/2])
+		}
+		return string(digits[lo+1+pick(hi-lo-1)]) + jitter(pick)
+	}
+	if pick == nil && len(b) > 1 {
+		// b's first digit alone sorts before b and after a
+		return b[:1]
+	}
+
+	// The first digits are adjacent: keep a's and go above the rest of a
+	rest := ""
+	if a != "" {
+		rest = a[1:]
+	}
+	return string(digits[lo]) + midpoint(rest, "", pick)
+}
+
+func jitter(pick func(n int) int) string {
+	var sb strings.Builder
+	for i := 0; i < jitterDigits-1; i++ {
+		sb.WriteByte(digits[pick(base)])
+	}
+	sb.WriteByte(digits[1+pick(base-1)])
+	return sb.String()
+}
+
+func digitAt(key string, i int) byte {
+	if i < len(key) {
+		return key[i]
+	}
+	return digits[0]
+}
+
+func checkBounds(a, b string) error {
+	for _, key := range []string{a, b} {
+		if err := Validate(key); err != nil {
+			return err
+		}
+	}
+	if a != "" && b != "" && a >= b {
+		return fmt.Errorf("%w: %q >= %q", ErrKeyOrder, a, b)
+	}
+	return nil
+}
+
+// Validate checks that key is a well-formed key. The empty string, which
+// stands for an open bound, is accepted.
+func Validate(key string) error {
+	for i := 0; i < len(key); i++ {
+		if strings.IndexByte(digits, key[i]) < 0 {
+			return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+		}
+	}
+	if strings.HasSuffix(key, digits[:1]) {
+		return fmt.Errorf("%w: %q ends in %q", ErrInvalidKey, key, digits[:1])
+	}
+	return nil
+}
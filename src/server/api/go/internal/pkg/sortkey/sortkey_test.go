@@ -0,0 +1,126 @@
+package sortkey
+
+import (
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBetween(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{name: "empty list", a: "", b: "", want: "V"},
+		{name: "before first", a: "", b: "V", want: "F"},
+		{name: "after last", a: "V", b: "", want: "k"},
+		{name: "adjacent digits", a: "V", b: "W", want: "VV"},
+		{name: "shared prefix", a: "V1", b: "V3", want: "V2"},
+		{name: "b longer", a: "V", b: "W1", want: "W"},
+		{name: "zero padded a", a: "1", b: "102", want: "101"},
+		{name: "before a tiny key", a: "", b: "01", want: "00V"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Between(tt.a, tt.b)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assertBetween(t, tt.a, got, tt.b)
+		})
+	}
+}
+
+func TestBetween_Invalid(t *testing.T) {
+	_, err := Between("W", "V")
+	assert.ErrorIs(t, err, ErrKeyOrder)
+	_, err = Between("V", "V")
+	assert.ErrorIs(t, err, ErrKeyOrder)
+	_, err = Between("V0", "")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	_, err = Between("", "a-b")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestBetween_RepeatedInsertBetween(t *testing.T) {
+	// Inserting again and again right after the same key never runs out of room
+	lo, hi := "V", "W"
+	for i := 0; i < 200; i++ {
+		key, err := Between(lo, hi)
+		require.NoError(t, err)
+		assertBetween(t, lo, key, hi)
+		hi = key
+	}
+
+	// Nor does inserting at the front or the back
+	first, last := "V", "V"
+	for i := 0; i < 200; i++ {
+		key, err := Between("", first)
+		require.NoError(t, err)
+		assertBetween(t, "", key, first)
+		first = key
+
+		key, err = Between(last, "")
+		require.NoError(t, err)
+		assertBetween(t, last, key, "")
+		last = key
+	}
+}
+
+func TestJitteredBetween_ConcurrentInsert(t *testing.T) {
+	const inserters = 100
+	lo, hi := "V", "W"
+
+	keys := make([]string, inserters)
+	var wg sync.WaitGroup
+	for i := range keys {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key, err := JitteredBetween(lo, hi)
+			assert.NoError(t, err)
+			keys[i] = key
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		assertBetween(t, lo, key, hi)
+		assert.False(t, seen[key], "duplicate key %q", key)
+		seen[key] = true
+	}
+}
+
+func TestNBetween(t *testing.T) {
+	keys, err := NBetween("", "", 1000)
+	require.NoError(t, err)
+	require.Len(t, keys, 1000)
+	assert.True(t, sort.StringsAreSorted(keys))
+	for i, key := range keys {
+		require.NoError(t, Validate(key))
+		assert.LessOrEqual(t, len(key), 3)
+		if i > 0 {
+			assert.Less(t, keys[i-1], key)
+		}
+	}
+
+	keys, err = NBetween("A", "B", 0)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func assertBetween(t *testing.T, a, key, b string) {
+	t.Helper()
+	assert.NoError(t, Validate(key))
+	if a != "" {
+		assert.Less(t, a, key)
+	}
+	if b != "" {
+		assert.Less(t, key, b)
+	}
+}
@@ -0,0 +1,42 @@
+package tokenizer
+
+import "github.com/memodb-io/Acontext/internal/modules/model"
+
+const (
+	// DefaultImageTokens is charged for an image whose dimensions are unknown,
+	// the cost of a 1024x1024 image at high detail
+	DefaultImageTokens = 765
+
+	imageBaseTokens    = 85
+	imageTileTokens    = 170
+	imageTileSize      = 512
+	imageMaxSide       = 2048
+	imageTargetMinSide = 768
+)
+
+// ImageTokens estimates the tokens OpenAI charges for an image part. Low detail is a
+// flat base cost. Otherwise the image is scaled to fit 2048x2048, then down so its
+// shortest side is at most 768, and each 512px tile costs 170 tokens on top of the base.
+func ImageTokens(part model.Part) int {
+	if detail, _ := part.Meta["detail"].(string); detail == "low" {
+		return imageBaseTokens
+	}
+	if part.Asset == nil || part.Asset.Width <= 0 || part.Asset.Height <= 0 {
+		return DefaultImageTokens
+	}
+
+	w, h := float64(part.Asset.Width), float64(part.Asset.Height)
+	if longest := max(w, h); longest > imageMaxSide {
+		w, h = w*imageMaxSide/longest, h*imageMaxSide/longest
+	}
+	if shortest := min(w, h); shortest > imageTargetMinSide {
+		w, h = w*imageTargetMinSide/shortest, h*imageTargetMinSide/shortest
+	}
+
+	tiles := ceilDiv(int(w), imageTileSize) * ceilDiv(int(h), imageTileSize)
+	return imageBaseTokens + imageTileTokens*tiles
+}
+
+func ceilDiv(n, d int) int {
+	return (n + d - 1) / d
+}
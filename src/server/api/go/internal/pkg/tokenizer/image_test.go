@@ -0,0 +1,48 @@
+package tokenizer
+
+import (
+	"testing"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		part model.Part
+		want int
+	}{
+		{
+			name: "2048x2048 high detail",
+			part: model.Part{Type: "image", Asset: &model.Asset{Width: 2048, Height: 2048}, Meta: map[string]any{"detail": "high"}},
+			want: 765, // scaled to 768x768, 2x2 tiles
+		},
+		{
+			name: "oversized wide image",
+			part: model.Part{Type: "image", Asset: &model.Asset{Width: 4096, Height: 1024}},
+			want: 765, // fitted to 2048x512, 4x1 tiles
+		},
+		{
+			name: "single tile",
+			part: model.Part{Type: "image", Asset: &model.Asset{Width: 512, Height: 512}},
+			want: 255,
+		},
+		{
+			name: "low detail",
+			part: model.Part{Type: "image", Asset: &model.Asset{Width: 2048, Height: 2048}, Meta: map[string]any{"detail": "low"}},
+			want: 85,
+		},
+		{
+			name: "unknown dimensions",
+			part: model.Part{Type: "image", Asset: &model.Asset{S3Key: "assets/a.png"}},
+			want: DefaultImageTokens,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ImageTokens(tt.part))
+		})
+	}
+}
@@ -1,4 +1,5 @@
 from dataclasses import dataclass, field
+from datetime import datetime
 from sqlalchemy import (
     String,
     ForeignKey,
@@ -7,6 +8,8 @@ from sqlalchemy import (
     Column,
     Boolean,
     BigInteger,
+    DateTime,
+    text,
 )
 from sqlalchemy.orm import relationship
 from sqlalchemy.dialects.postgresql import JSONB, UUID
@@ -99,9 +102,25 @@ class Block(CommonMixin):
         Index("idx_blocks_space_type", "space_id", "type"),
         Index("idx_blocks_space_title", "space_id", "title"),
         Index("idx_blocks_space_type_archived", "space_id", "type", "is_archived"),
-        # Unique constraint for space, parent, sort combination
+        Index("idx_blocks_deleted_at", "deleted_at"),
+        # Serves props containment queries such as backlinks
         Index(
-            "ux_blocks_space_parent_sort", "space_id", "parent_id", "sort", unique=True
+            "idx_blocks_props",
+            "props",
+            postgresql_using="gin",
+            postgresql_ops={"props": "jsonb_path_ops"},
+        ),
+        # Serves the archived purge candidate scan
+        Index("idx_blocks_archived_updated", "is_archived", "updated_at"),
+        # Unique constraint for space, parent, sort combination; archived and
+        # soft-deleted blocks hold no position
+        Index(
+            "ux_blocks_space_parent_sort",
+            "space_id",
+            "parent_id",
+            "sort",
+            unique=True,
+            postgresql_where=text("is_archived = false AND deleted_at IS NULL"),
         ),
         # Check constraints matching Go version
         CheckConstraint(
@@ -185,6 +204,37 @@ class Block(CommonMixin):
         },
     )
 
+    # The block whose archiving archived this one along with it; None for a
+    # block archived on its own
+    archived_with: Optional[asUUID] = field(
+        default=None,
+        metadata={"db": Column(UUID(as_uuid=True), nullable=True)},
+    )
+
+    is_locked: bool = field(
+        default=False,
+        metadata={
+            "db": Column(
+                Boolean,
+                nullable=False,
+                default=False,
+                server_default="false",
+            )
+        },
+    )
+
+    # When the block last changed parent; None if it never did
+    moved_at: Optional[datetime] = field(
+        default=None,
+        metadata={"db": Column(DateTime(timezone=True), nullable=True)},
+    )
+
+    # Set when the block is soft-deleted; deleted blocks are hidden from reads
+    deleted_at: Optional[datetime] = field(
+        default=None,
+        metadata={"db": Column(DateTime(timezone=True), nullable=True)},
+    )
+
     # Relationships
     space: "Space" = field(
         init=False,
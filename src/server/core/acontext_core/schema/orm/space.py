@@ -36,6 +36,11 @@ class Space(CommonMixin):
         default=None, metadata={"db": Column(JSONB, nullable=True)}
     )
 
+    # Optional JSON Schema the props of the space's blocks must match
+    props_schema: Optional[dict] = field(
+        default=None, metadata={"db": Column(JSONB, nullable=True)}
+    )
+
     # Relationships
     project: "Project" = field(
         init=False, metadata={"db": relationship("Project", back_populates="spaces")}
@@ -23,7 +23,9 @@ async def _find_block_sort(
     block_type: str,
 ) -> Result[int]:
     if par_block_id is not None:
-        query = select(Block.type).where(Block.id == par_block_id)
+        query = select(Block.type).where(
+            Block.id == par_block_id, Block.deleted_at.is_(None)
+        )
         result = await db_session.execute(query)
         par_block = result.mappings().one_or_none()
         if par_block is None:
@@ -38,10 +40,13 @@ async def _find_block_sort(
         return Result.reject(
             f"Parent block {par_block_id}(type {parent_type}) is not allowed to have children of type {block_type}"
         )
+    # Archived and deleted blocks hold no position, as in the Go API
     next_sort_query = (
         select(func.coalesce(func.max(Block.sort), -1) + 1)
         .where(Block.space_id == space_id)
         .where(Block.parent_id == par_block_id)
+        .where(Block.is_archived == False)  # noqa: E712
+        .where(Block.deleted_at.is_(None))
     )
     result = await db_session.execute(next_sort_query)
     next_sort = result.scalar()
@@ -61,6 +66,8 @@ async def update_block_children_sort_by_delta(
         update(Block)
         .where(Block.space_id == space_id)
         .where(Block.parent_id == block_id)
+        .where(Block.is_archived == False)  # noqa: E712
+        .where(Block.deleted_at.is_(None))
         .where(Block.sort > gt_sort)
         .values(sort=Block.sort + delta)
     )
@@ -137,7 +144,9 @@ async def find_all_parent_ids(
     parent_ids: List[asUUID] = [block_id]
     while block_id is not None:
         query = select(Block.parent_id).where(
-            Block.space_id == space_id, Block.id == block_id
+            Block.space_id == space_id,
+            Block.id == block_id,
+            Block.deleted_at.is_(None),
         )
         result = await db_session.execute(query)
         block = result.mappings().one_or_none()
@@ -30,7 +30,10 @@ async def assert_block_type(
     db_session: AsyncSession, space_id: asUUID, block_id: asUUID, block_type: str
 ) -> Result[None]:
     query = (
-        select(Block.type).where(Block.space_id == space_id).where(Block.id == block_id)
+        select(Block.type)
+        .where(Block.space_id == space_id)
+        .where(Block.id == block_id)
+        .where(Block.deleted_at.is_(None))
     )
     result = await db_session.execute(query)
     par_type = result.mappings().one_or_none()
@@ -53,6 +56,7 @@ async def fetch_path_children_by_id(
     query = (
         select(Block.id, Block.title, Block.type, Block.props)
         .where(Block.space_id == space_id)
+        .where(Block.deleted_at.is_(None))
         .where(
             Block.parent_id == block_id,
             Block.type.in_([BLOCK_TYPE_FOLDER, BLOCK_TYPE_PAGE]),
@@ -143,6 +147,7 @@ async def find_block_by_path(
         query = (
             select(Block.id, Block.type, Block.title, Block.props)
             .where(Block.space_id == space_id, Block.parent_id == parent_id)
+            .where(Block.deleted_at.is_(None))
             .where(Block.title == part)
             .where(Block.type.in_([BLOCK_TYPE_FOLDER, BLOCK_TYPE_PAGE]))
         )
@@ -188,7 +193,9 @@ async def recover_path_by_id(
     path_parts = []
     while block_id is not None:
         query = select(Block.id, Block.title, Block.parent_id).where(
-            Block.space_id == space_id, Block.id == block_id
+            Block.space_id == space_id,
+            Block.id == block_id,
+            Block.deleted_at.is_(None),
         )
         result = await db_session.execute(query)
         block = result.mappings().one_or_none()
@@ -206,7 +213,9 @@ async def get_path_info_by_id(
     db_session: AsyncSession, space_id: asUUID, block_id: asUUID
 ) -> Result[tuple[str, PathNode]]:
     query = select(Block.id, Block.type, Block.title, Block.props).where(
-        Block.space_id == space_id, Block.id == block_id
+        Block.space_id == space_id,
+        Block.id == block_id,
+        Block.deleted_at.is_(None),
     )
     result = await db_session.execute(query)
     block = result.mappings().one_or_none()
@@ -255,6 +264,7 @@ async def read_blocks_from_par_id(
     query = (
         select(Block)
         .where(Block.space_id == space_id, Block.parent_id == block_id)
+        .where(Block.deleted_at.is_(None))
         .where(Block.type.in_(allowed_types))
         .order_by(Block.sort)
     )
@@ -267,7 +277,10 @@ async def get_block_by_sort(
     db_session: AsyncSession, space_id: asUUID, par_block_id: asUUID, sort: int
 ) -> Result[Block]:
     query = select(Block).where(
-        Block.space_id == space_id, Block.parent_id == par_block_id, Block.sort == sort
+        Block.space_id == space_id,
+        Block.parent_id == par_block_id,
+        Block.sort == sort,
+        Block.deleted_at.is_(None),
     )
     result = await db_session.execute(query)
     block = result.scalar_one_or_none()
@@ -79,6 +79,7 @@ async def search_blocks(
             Block.space_id == space_id,
             Block.type.in_(block_types),  # Only page and folder blocks
             Block.is_archived == False,  # Exclude archived blocks  # noqa: E712
+            Block.deleted_at.is_(None),  # Exclude soft-deleted blocks
             distance <= threshold,  # Apply distance threshold
         )
         .order_by(distance.asc())  # Best matches first
@@ -0,0 +1,29 @@
+-- Migration: Soft delete for blocks
+-- Date: 2026-10-17
+-- Description: Add blocks.deleted_at and blocks.archived_with, and exclude soft-deleted blocks from the (space_id, parent_id, sort) unique index
+
+BEGIN;
+
+-- Soft-deleted blocks keep their row with deleted_at set
+ALTER TABLE blocks
+ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS idx_blocks_deleted_at
+ON blocks (deleted_at);
+
+-- The block whose archiving archived this one along with it
+ALTER TABLE blocks
+ADD COLUMN IF NOT EXISTS archived_with uuid;
+
+-- Recreate the position index so soft-deleted blocks no longer hold a sort slot
+DROP INDEX IF EXISTS ux_blocks_space_parent_sort;
+
+CREATE UNIQUE INDEX ux_blocks_space_parent_sort
+ON blocks (space_id, parent_id, sort)
+WHERE is_archived = false AND deleted_at IS NULL;
+
+COMMIT;
+
+-- Verify the change
+-- SELECT indexdef FROM pg_indexes WHERE indexname = 'ux_blocks_space_parent_sort';
+-- Expected: ... WHERE ((is_archived = false) AND (deleted_at IS NULL))